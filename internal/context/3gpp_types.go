@@ -35,6 +35,9 @@ const (
 	TimeT3550 time.Duration = 6 * time.Second
 	TimeT3560 time.Duration = 6 * time.Second
 	TimeT3565 time.Duration = 6 * time.Second
+	// the mobile reachable timer is by default 4 minutes greater than T3512 (TS 24.501 5.3.7)
+	MobileReachableTimerMargin         time.Duration = 4 * time.Minute
+	DefaultImplicitDeregistrationTimer time.Duration = 4 * time.Minute
 )
 
 type CauseAll struct {
@@ -28,7 +28,9 @@ type AmfRan struct {
 	Name       string
 	AnType     models.AccessType
 	/* socket Connect*/
-	Conn net.Conn
+	Conn net.Conn // TNL association of the NG Setup, carries the non UE-associated signalling
+	/* TNL associations */
+	TnlaList sync.Map // net.Conn as key, *TNLAssociation
 	/* Supported TA List */
 	SupportedTAList []SupportedTAI
 
@@ -52,7 +54,13 @@ func NewSupportedTAI() (tai SupportedTAI) {
 func (ran *AmfRan) Remove() {
 	ran.Log.Infof("Remove RAN Context[ID: %+v]", ran.RanID())
 	ran.RemoveAllRanUe(true)
-	GetSelf().DeleteAmfRan(ran.Conn)
+	self := GetSelf()
+	ran.TnlaList.Range(func(key, value interface{}) bool {
+		self.AmfRanTnlaPool.Delete(key)
+		ran.TnlaList.Delete(key)
+		return true
+	})
+	self.DeleteAmfRan(ran.Conn)
 }
 
 func (ran *AmfRan) NewRanUe(ranUeNgapID int64) (*RanUe, error) {
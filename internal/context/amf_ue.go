@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/mohae/deepcopy"
 	"github.com/sirupsen/logrus"
 
 	"github.com/free5gc/amf/internal/logger"
@@ -32,6 +33,9 @@ const (
 	OnGoingProcedurePaging       OnGoingProcedure = "Paging"
 	OnGoingProcedureN2Handover   OnGoingProcedure = "N2Handover"
 	OnGoingProcedureRegistration OnGoingProcedure = "Registration"
+	// Xn handover is only known to the AMF once completed (Path Switch Request), the procedure is never
+	// ongoing but keys the NAS PDUs the NG-RAN node failed to deliver because of an Xn handover
+	OnGoingProcedureXnHandover OnGoingProcedure = "XnHandover"
 )
 
 const (
@@ -70,6 +74,7 @@ type AmfUe struct {
 	NasPduValue                        []byte
 	RetransmissionOfInitialNASMsg      bool
 	RequestIdentityType                uint8
+	EmergencyRegistered                bool // registered for emergency services, kept after the registration
 	/* Used for AMF relocation */
 	TargetAmfProfile *models.NrfNfDiscoveryNfProfile
 	TargetAmfUri     string
@@ -82,16 +87,25 @@ type AmfUe struct {
 	Pei                    string
 	Tmsi                   int32 // 5G-Tmsi
 	Guti                   string
+	OldTmsi                int32  // valid until the UE acknowledges the reallocated 5G-GUTI
+	OldGuti                string // valid until the UE acknowledges the reallocated 5G-GUTI
 	GroupID                string
 	EBI                    int32
 	EventSubscriptionsInfo map[string]*AmfUeEventSubscription
 	/* User Location */
 	RatType                  models.RatType
-	Location                 models.UserLocation
+	Location                 models.UserLocation // written with SetLocation, read by others with LocationCopy
+	locationMu               sync.RWMutex
 	Tai                      models.Tai
 	LocationChanged          bool
 	LastVisitedRegisteredTai models.Tai
 	TimeZone                 string // "[+-]HH:MM[+][1-2]", Refer to TS 29.571 - 5.2.2 Simple Data Types
+	/* Location Reporting */
+	AreaOfInterestList sync.Map // map[int64]*AreaOfInterest, Location Reporting Reference ID as key
+	aoiMu              sync.Mutex
+	/* Positioning */
+	PositioningSessionList sync.Map // map[uint8]*PositioningSession, routing identifier as key
+	positioningMu          sync.Mutex
 	/* context about udm */
 	UdmId                             string
 	NudmUECMUri                       string
@@ -123,6 +137,21 @@ type AmfUe struct {
 	AmPolicyUri                  string
 	AmPolicyAssociation          *models.PcfAmPolicyControlPolicyAssociation
 	RequestTriggerLocationChange bool // true if AmPolicyAssociation.Trigger contains RequestTrigger_LOC_CH
+	/* context about SMSF */
+	SmsfId     string
+	SmsfUri    string
+	SmsAllowed bool // SMS over NAS activated in the SMSF, indicated in the 5GS registration result
+	/* Steering of Roaming */
+	SorInfo             *models.UdmSdmSorInfo
+	SorAckRequested     bool       // the UDM requested the acknowledgement of the UE for SorInfo
+	SorProvisioningTime *time.Time // provisioning time of SorInfo, provided to the UDM with the acknowledgement
+	/* UE Parameters Update */
+	UpuInfo         *models.UdmSdmUpuInfo
+	UpuAckRequested bool   // the UDM requested the acknowledgement of the UE for UpuInfo
+	UpuAckTimer     *Timer // supervision of the acknowledgement, the UDM is told the UE is not reachable at expiry
+	/* DL payloads pending until the UE is reachable */
+	pendingDLPayloads  []*PayloadContainerEntry
+	pendingDLPayloadMu sync.Mutex
 	/* UeContextForHandover */
 	HandoverNotifyUri string
 	/* N1N2Message */
@@ -131,6 +160,10 @@ type AmfUe struct {
 	N1N2MessageSubscribeIDGenerator *idgenerator.IDGenerator
 	// map[int64]models.UeN1N2InfoSubscriptionCreateData; use n1n2MessageSubscriptionID as key
 	N1N2MessageSubscription sync.Map
+	/* Downlink NAS PDUs not delivered by the NG-RAN node */
+	n1n2NasPduList        []n1n2NasPdu // latest NAS PDUs sent for an N1N2 Message Transfer
+	undeliveredNasPduList []*UndeliveredNasPdu
+	nasNonDeliveryMu      sync.Mutex
 	/* Pdu Sesseion context */
 	SmContextList sync.Map // map[int32]*SmContext, pdu session id as key
 	/* Related Context */
@@ -140,11 +173,14 @@ type AmfUe struct {
 	UeRadioCapability               string // OCTET string
 	Capability5GMM                  nasType.Capability5GMM
 	ConfigurationUpdateIndication   nasType.ConfigurationUpdateIndication
-	ConfigurationUpdateCommandFlags *ConfigurationUpdateCommandFlags
+	ConfigurationUpdateCommandFlags *ConfigurationUpdateCommandFlags // parameters waiting to be sent to the UE
+	ConfigurationUpdate             *ConfigurationUpdate             // last Configuration Update Command sent
 	/* context related to Paging */
 	UeRadioCapabilityForPaging                 *UERadioCapabilityForPaging
 	InfoOnRecommendedCellsAndRanNodesForPaging *InfoOnRecommendedCellsAndRanNodesForPaging
 	UESpecificDRX                              uint8
+	RequestedEdrx                              *ExtendedDRXParameters
+	NegotiatedEdrx                             *ExtendedDRXParameters // nil if the UE does not use eDRX
 	/* Security Context */
 	SecurityContextAvailable bool
 	UESecurityCapability     nasType.UESecurityCapability // for security command
@@ -158,8 +194,16 @@ type AmfUe struct {
 	NCC                      uint8     // 0..7
 	ULCount                  security.Count
 	DLCount                  security.Count
-	CipheringAlg             uint8
-	IntegrityAlg             uint8
+	ULCountWindow            NasReplayWindow // uplink NAS COUNT values accepted with the current security context
+	// the NAS COUNT is about to wrap around, a new NAS security context is established by a primary authentication
+	NasCountRekeyingRequired  bool
+	NasCountRekeyingRequested bool // the UE is requested to register to establish the new NAS security context
+	// the K_AMF was derived horizontally and is taken into use with the next NAS Security Mode Command, or with the
+	// NAS container of the Handover Request on an N2 handover
+	KamfHorizontallyDerived bool
+	KamfDerivationDirection uint8 // DIRECTION input of the horizontal derivation, see KamfDerivationDirectionXXX
+	CipheringAlg            uint8
+	IntegrityAlg            uint8
 	/* Registration Area */
 	RegistrationArea map[models.AccessType][]models.Tai
 	LadnInfo         []factory.Ladn
@@ -172,6 +216,22 @@ type AmfUe struct {
 	NetworkSlicingSubscriptionChanged bool
 	SdmSubscriptionId                 string
 	UeCmRegistered                    map[models.AccessType]bool
+	/* Network Slice-Specific Authentication and Authorization (TS 23.502 4.2.9) */
+	NssaafId           string
+	NssaafUri          string
+	NssaaRequiredNssai []models.Snssai // subscribed S-NSSAIs subject to NSSAA
+	PendingNssai       []models.Snssai // S-NSSAIs waiting for NSSAA, indicated in the Registration Accept
+	NssaaRejectedNssai []models.Snssai // S-NSSAIs whose NSSAA failed or was revoked
+	NssaaOngoing       *NssaaContext   // at most one NSSAA runs with the UE at a time
+	nssaaContexts      []*NssaaContext
+	nssaaMu            sync.Mutex
+	/* Network Slice Admission Control (TS 23.501 5.15.11) */
+	NsacAdmittedNssai []models.Snssai // S-NSSAIs subject to NSAC the UE is counted in
+	NsacRejectedNssai []models.Snssai // S-NSSAIs rejected as their maximum number of UEs is reached
+	/* PEI check by the 5G-EIR (TS 23.502 4.2.2.2.2 step 14) */
+	EirId         string
+	EirUri        string
+	EirCheckedPei string // PEI last checked by the 5G-EIR
 	/* T3513(Paging) */
 	T3513 *Timer // for paging
 	/* T3565(Notification) */
@@ -186,6 +246,14 @@ type AmfUe struct {
 	T3570 *Timer
 	/* T3555 (for configuration update command) */
 	T3555 *Timer
+	/* T3575 (for network slice-specific authentication command) */
+	T3575 *Timer
+	/* Mobile reachable and implicit deregistration timers (for CM-IDLE UE supervision) */
+	MobileReachableTimer        *Timer
+	ImplicitDeregistrationTimer *Timer
+	/* MICO mode (TS 23.501 5.4.1.3) */
+	MicoMode                    bool
+	MicoAllPlmnRegistrationArea bool
 	/* Ue Context Release Cause */
 	ReleaseCause map[models.AccessType]*CauseAll
 	/* T3502 (Assigned by AMF, and used by UE to initialize registration procedure) */
@@ -267,6 +335,7 @@ type ConfigurationUpdateCommandFlags struct {
 	NeedConfiguredNSSAI                          bool
 	NeedNetworkSlicingIndication                 bool
 	NeedOperatordefinedAccessCategoryDefinitions bool
+	NeedRegistrationRequested                    bool // the UE performs a registration once the connection is released
 }
 
 func (ue *AmfUe) init() {
@@ -317,6 +386,10 @@ func (ue *AmfUe) Remove() {
 	ue.StopT3522()
 	ue.StopT3570()
 	ue.StopT3555()
+	ue.StopT3575()
+	ue.StopMobileReachableTimer()
+	ue.StopImplicitDeregistrationTimer()
+	ue.StopUpuAckTimer()
 
 	for _, ranUe := range ue.RanUe {
 		if err := ranUe.Remove(); err != nil {
@@ -330,8 +403,11 @@ func (ue *AmfUe) Remove() {
 		}
 	}
 	tmsiGenerator.FreeID(int64(ue.Tmsi))
+	ue.ReleaseOldGuti()
 	if len(ue.Supi) > 0 {
 		GetSelf().UePool.Delete(ue.Supi)
+	} else if len(ue.Pei) > 0 {
+		GetSelf().UePool.Delete(ue.Pei)
 	}
 	ue.DeleteAllSmContexts()
 
@@ -355,6 +431,21 @@ func (ue *AmfUe) DetachRanUe(anType models.AccessType) {
 	ue.UpdateLogFields(anType)
 }
 
+// SetLocation updates the last known location of the UE, it may be read at the same time by the procedures of other
+// UEs, e.g. the event reports of the UEs in an area
+func (ue *AmfUe) SetLocation(location models.UserLocation) {
+	ue.locationMu.Lock()
+	defer ue.locationMu.Unlock()
+	ue.Location = location
+}
+
+// LocationCopy returns a copy of the last known location of the UE
+func (ue *AmfUe) LocationCopy() models.UserLocation {
+	ue.locationMu.RLock()
+	defer ue.locationMu.RUnlock()
+	return deepcopy.Copy(ue.Location).(models.UserLocation)
+}
+
 // Don't call this function directly. Use gmm_common.AttachRanUeToAmfUeAndReleaseOldIfAny().
 func (ue *AmfUe) AttachRanUe(ranUe *RanUe) {
 	ue.RanUe[ranUe.Ran.AnType] = ranUe
@@ -497,6 +588,7 @@ func (ue *AmfUe) DerivateKamf() {
 		return
 	}
 	ue.Kamf = hex.EncodeToString(KamfBytes)
+	ue.KamfHorizontallyDerived = false
 }
 
 // Algorithm key Derivation function defined in TS 33.501 Annex A.9
@@ -748,9 +840,13 @@ func (ue *AmfUe) CopyDataFromUeContextModel(ueContext *models.UeContext) {
 	if ueContext.SeafData != nil {
 		seafData := ueContext.SeafData
 
-		ue.NgKsi = *seafData.NgKsi
+		if seafData.NgKsi != nil {
+			ue.NgKsi = *seafData.NgKsi
+		}
 		if seafData.KeyAmf != nil {
-			if seafData.KeyAmf.KeyType == models.KeyAmfType_KAMF {
+			// the K_AMF' is the K_AMF derived horizontally by the source AMF
+			if seafData.KeyAmf.KeyType == models.KeyAmfType_KAMF ||
+				seafData.KeyAmf.KeyType == models.KeyAmfType_KPRIMEAMF {
 				ue.Kamf = seafData.KeyAmf.KeyVal
 			}
 		}
@@ -1001,3 +1097,40 @@ func (ue *AmfUe) StopT3555() {
 	ue.T3555.Stop()
 	ue.T3555 = nil // clear the timer
 }
+
+func (ue *AmfUe) StopMobileReachableTimer() {
+	if ue.MobileReachableTimer == nil {
+		return
+	}
+
+	ue.GmmLog.Infof("Stop mobile reachable timer")
+	ue.MobileReachableTimer.Stop()
+	ue.MobileReachableTimer = nil // clear the timer
+}
+
+func (ue *AmfUe) StopImplicitDeregistrationTimer() {
+	if ue.ImplicitDeregistrationTimer == nil {
+		return
+	}
+
+	ue.GmmLog.Infof("Stop implicit deregistration timer")
+	ue.ImplicitDeregistrationTimer.Stop()
+	ue.ImplicitDeregistrationTimer = nil // clear the timer
+}
+
+// MobileReachableTimerValue returns the expire time of the mobile reachable timer of the UE, by default
+// 4 minutes greater than its T3512 (TS 24.501 5.3.7)
+func (ue *AmfUe) MobileReachableTimerValue() time.Duration {
+	if cfg := GetSelf().MobileReachableCfg; cfg.ExpireTime > 0 {
+		return cfg.ExpireTime
+	}
+	return time.Duration(ue.T3512Value)*time.Second + MobileReachableTimerMargin
+}
+
+// ImplicitDeregistrationTimerValue returns the expire time of the implicit deregistration timer of the UE
+func (ue *AmfUe) ImplicitDeregistrationTimerValue() time.Duration {
+	if cfg := GetSelf().ImplicitDeregistrationCfg; cfg.ExpireTime > 0 {
+		return cfg.ExpireTime
+	}
+	return DefaultImplicitDeregistrationTimer
+}
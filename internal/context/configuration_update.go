@@ -0,0 +1,74 @@
+package context
+
+import (
+	"github.com/free5gc/openapi/models"
+)
+
+// ConfigurationUpdateStatus is the acknowledgement of a Configuration Update Command by the UE
+type ConfigurationUpdateStatus string
+
+const (
+	// the command carries NITZ information only, no acknowledgement is requested
+	ConfigurationUpdateStatusNotRequested ConfigurationUpdateStatus = "NOT_REQUESTED"
+	// T3555 is running
+	ConfigurationUpdateStatusPending ConfigurationUpdateStatus = "PENDING"
+	// the UE sent a Configuration Update Complete
+	ConfigurationUpdateStatusAcknowledged ConfigurationUpdateStatus = "ACKNOWLEDGED"
+	// T3555 expired the maximum number of times, the procedure is aborted (TS 24.501 5.4.4.5)
+	ConfigurationUpdateStatusNotAcknowledged ConfigurationUpdateStatus = "NOT_ACKNOWLEDGED"
+)
+
+// ConfigurationUpdate is a Configuration Update Command sent to the UE (TS 24.501 5.4.4)
+type ConfigurationUpdate struct {
+	AccessType models.AccessType
+	Flags      ConfigurationUpdateCommandFlags
+	Status     ConfigurationUpdateStatus
+}
+
+// Merge adds the parameters of other to the parameters to send
+func (flags *ConfigurationUpdateCommandFlags) Merge(other *ConfigurationUpdateCommandFlags) {
+	if other == nil {
+		return
+	}
+	flags.NeedGUTI = flags.NeedGUTI || other.NeedGUTI
+	flags.NeedNITZ = flags.NeedNITZ || other.NeedNITZ
+	flags.NeedTaiList = flags.NeedTaiList || other.NeedTaiList
+	flags.NeedRejectNSSAI = flags.NeedRejectNSSAI || other.NeedRejectNSSAI
+	flags.NeedAllowedNSSAI = flags.NeedAllowedNSSAI || other.NeedAllowedNSSAI
+	flags.NeedSmsIndication = flags.NeedSmsIndication || other.NeedSmsIndication
+	flags.NeedMicoIndication = flags.NeedMicoIndication || other.NeedMicoIndication
+	flags.NeedLadnInformation = flags.NeedLadnInformation || other.NeedLadnInformation
+	flags.NeedServiceAreaList = flags.NeedServiceAreaList || other.NeedServiceAreaList
+	flags.NeedConfiguredNSSAI = flags.NeedConfiguredNSSAI || other.NeedConfiguredNSSAI
+	flags.NeedNetworkSlicingIndication = flags.NeedNetworkSlicingIndication || other.NeedNetworkSlicingIndication
+	flags.NeedOperatordefinedAccessCategoryDefinitions = flags.NeedOperatordefinedAccessCategoryDefinitions ||
+		other.NeedOperatordefinedAccessCategoryDefinitions
+	flags.NeedRegistrationRequested = flags.NeedRegistrationRequested || other.NeedRegistrationRequested
+}
+
+// AddPendingConfigurationUpdate batches the parameters with the ones waiting to be sent to the UE
+func (ue *AmfUe) AddPendingConfigurationUpdate(flags *ConfigurationUpdateCommandFlags) {
+	if ue.ConfigurationUpdateCommandFlags == nil {
+		ue.ConfigurationUpdateCommandFlags = new(ConfigurationUpdateCommandFlags)
+	}
+	ue.ConfigurationUpdateCommandFlags.Merge(flags)
+}
+
+// ReallocateGuti assigns a new 5G-GUTI to the UE, the UE is still found by the old one until it acknowledges
+// the new one
+func (context *AMFContext) ReallocateGuti(ue *AmfUe) {
+	ue.ReleaseOldGuti()
+	ue.OldTmsi = ue.Tmsi
+	ue.OldGuti = ue.Guti
+	context.AllocateGutiToUe(ue)
+}
+
+// ReleaseOldGuti frees the 5G-GUTI the UE used before its reallocation
+func (ue *AmfUe) ReleaseOldGuti() {
+	if ue.OldGuti == "" {
+		return
+	}
+	tmsiGenerator.FreeID(int64(ue.OldTmsi))
+	ue.OldTmsi = 0
+	ue.OldGuti = ""
+}
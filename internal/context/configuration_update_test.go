@@ -0,0 +1,57 @@
+package context
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/free5gc/openapi/models"
+)
+
+func TestConfigurationUpdateCommandFlagsMerge(t *testing.T) {
+	ue := &AmfUe{}
+	ue.AddPendingConfigurationUpdate(&ConfigurationUpdateCommandFlags{NeedGUTI: true})
+	ue.AddPendingConfigurationUpdate(&ConfigurationUpdateCommandFlags{NeedNITZ: true, NeedTaiList: true})
+	ue.AddPendingConfigurationUpdate(nil)
+
+	require.Equal(t, &ConfigurationUpdateCommandFlags{
+		NeedGUTI:    true,
+		NeedNITZ:    true,
+		NeedTaiList: true,
+	}, ue.ConfigurationUpdateCommandFlags)
+}
+
+func TestReallocateGuti(t *testing.T) {
+	self := GetSelf()
+	servedGuamiList := self.ServedGuamiList
+	self.ServedGuamiList = []models.Guami{{
+		PlmnId: &models.PlmnIdNid{Mcc: "208", Mnc: "93"},
+		AmfId:  "cafe00",
+	}}
+	t.Cleanup(func() {
+		self.ServedGuamiList = servedGuamiList
+	})
+
+	ue := &AmfUe{Supi: "imsi-208930000000001"}
+	self.AllocateGutiToUe(ue)
+	self.UePool.Store(ue.Supi, ue)
+	t.Cleanup(func() {
+		self.UePool.Delete(ue.Supi)
+	})
+	oldGuti := ue.Guti
+
+	self.ReallocateGuti(ue)
+	require.NotEqual(t, oldGuti, ue.Guti)
+	require.Equal(t, oldGuti, ue.OldGuti)
+	found, ok := self.AmfUeFindByGuti(oldGuti)
+	require.True(t, ok, "the UE is found by its old 5G-GUTI until it acknowledges the new one")
+	require.Same(t, ue, found)
+	found, ok = self.AmfUeFindByGuti(ue.Guti)
+	require.True(t, ok)
+	require.Same(t, ue, found)
+
+	ue.ReleaseOldGuti()
+	require.Empty(t, ue.OldGuti)
+	_, ok = self.AmfUeFindByGuti(oldGuti)
+	require.False(t, ok)
+}
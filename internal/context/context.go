@@ -15,6 +15,7 @@ import (
 	"github.com/free5gc/amf/pkg/factory"
 	"github.com/free5gc/nas/nasConvert"
 	"github.com/free5gc/nas/security"
+	"github.com/free5gc/ngap/ngapType"
 	"github.com/free5gc/openapi"
 	"github.com/free5gc/openapi/models"
 	"github.com/free5gc/openapi/oauth"
@@ -29,15 +30,16 @@ var (
 )
 
 func init() {
-	GetSelf().LadnPool = make(map[string]factory.Ladn)
+	GetSelf().ladnPool = make(map[string]factory.Ladn)
 	GetSelf().EventSubscriptionIDGenerator = idgenerator.NewGenerator(1, math.MaxInt32)
 	GetSelf().Name = "amf"
 	GetSelf().UriScheme = models.UriScheme_HTTPS
 	GetSelf().RelativeCapacity = 0xff
+	GetSelf().TNLWeightFactor = 0xff
 	GetSelf().ServedGuamiList = make([]models.Guami, 0, MaxNumOfServedGuamiList)
 	GetSelf().PlmnSupportList = make([]factory.PlmnSupportItem, 0, MaxNumOfPLMNs)
 	GetSelf().NfService = make(map[models.ServiceName]models.NrfNfManagementNfService)
-	GetSelf().NetworkName.Full = "free5GC"
+	GetSelf().networkName.Full = "free5GC"
 	tmsiGenerator = idgenerator.NewGenerator(1, math.MaxInt32)
 	amfStatusSubscriptionIDGenerator = idgenerator.NewGenerator(1, math.MaxInt32)
 	amfUeNGAPIDGenerator = idgenerator.NewGenerator(1, MaxValueOfAmfUeNgapId)
@@ -55,7 +57,8 @@ type AMFContext struct {
 	UePool                       sync.Map                // map[supi]*AmfUe
 	RanUePool                    sync.Map                // map[AmfUeNgapID]*RanUe
 	AmfRanPool                   sync.Map                // map[net.Conn]*AmfRan
-	LadnPool                     map[string]factory.Ladn // dnn as key
+	AmfRanTnlaPool               sync.Map                // map[net.Conn]*AmfRan, additional TNL associations
+	ladnPool                     map[string]factory.Ladn // dnn as key, guarded by networkConfigLock
 	SupportTaiLists              []models.Tai
 	ServedGuamiList              []models.Guami
 	PlmnSupportList              []factory.PlmnSupportItem
@@ -68,19 +71,25 @@ type AMFContext struct {
 	SBIPort                      int
 	RegisterIPv4                 string
 	HttpIPv6Address              string
-	TNLWeightFactor              int64
+	TNLWeightFactor              int64 // default TNL Address Weight Factor of the NGAP endpoints
 	SupportDnnLists              []string
 	AMFStatusSubscriptions       sync.Map // map[subscriptionID]models.SubscriptionData
 	NrfUri                       string
 	NrfCertPem                   string
 	SecurityAlgorithm            SecurityAlgorithm
-	NetworkName                  factory.NetworkName
-	NgapIpList                   []string // NGAP Server IP
+	NasReplayWindow              uint32 // 0 if not configured, see NasReplayWindowSize
+	NasCountRekeyingMargin       uint32 // 0 if not configured, see NasCountRekeyingThreshold
+	HorizontalKamfDerivation     factory.HorizontalKamfDerivation
+	networkName                  factory.NetworkName // guarded by networkConfigLock
+	NgapIpList                   []string            // NGAP Server IP
 	NgapPort                     int
+	NgapTnlEndpointList          []AmfTnlEndpoint
 	T3502Value                   int    // unit is second
 	T3512Value                   int    // unit is second
 	Non3gppDeregTimerValue       int    // unit is second
-	TimeZone                     string // "[+-]HH:MM[+][1-2]", Refer to TS 29.571 - 5.2.2 Simple Data Types
+	timeZone                     string // "[+-]HH:MM[+][1-2]", TS 29.571 5.2.2, guarded by networkConfigLock
+	// the network name, the local time zone and the LADNs change while the UEs are served
+	networkConfigLock sync.RWMutex
 	// read-only fields
 	T3513Cfg factory.TimerValue
 	T3522Cfg factory.TimerValue
@@ -89,7 +98,23 @@ type AMFContext struct {
 	T3565Cfg factory.TimerValue
 	T3570Cfg factory.TimerValue
 	T3555Cfg factory.TimerValue
-	Locality string
+	// network slice-specific authentication command supervision, disabled if not configured
+	T3575Cfg factory.TimerValue
+	// N2 handover supervision on the source side, only ExpireTime is used
+	TRelocPrepCfg    factory.TimerValue
+	TRelocOverallCfg factory.TimerValue
+	// supervision of the CM-IDLE UEs, the expire time defaults to the one of TS 24.501 5.3.7
+	MobileReachableCfg        factory.TimerValue
+	ImplicitDeregistrationCfg factory.TimerValue
+	UpuAckCfg                 factory.TimerValue // supervision of the acknowledgement of the UE parameters update
+	Locality                  string
+	Emergency                 *factory.Emergency // nil if emergency services are not supported
+	Mico                      *factory.Mico      // nil if the MICO mode is not supported
+	Edrx                      *factory.Edrx      // nil if the extended idle mode DRX is not supported
+	Nsacf                     *LocalNsacf        // nil if the Network Slice Admission Control is not enabled
+	Eir                       *factory.Eir       // nil if the PEI is not checked by the 5G-EIR
+	RequestImeisv             bool               // request the IMEISV of every UE in the Security Mode Command
+	LocalAusf                 *LocalAusf         // nil if the UEs are authenticated by the AUSF
 
 	OAuth2Required bool
 }
@@ -105,9 +130,10 @@ type AMFContextEventSubscription struct {
 type SecurityAlgorithm struct {
 	IntegrityOrder []uint8 // slice of security.AlgIntegrityXXX
 	CipheringOrder []uint8 // slice of security.AlgCipheringXXX
+	Policies       []SecurityAlgorithmPolicy
 }
 
-func InitAmfContext(context *AMFContext) {
+func InitAmfContext(context *AMFContext) error {
 	config := factory.AmfConfig
 	logger.UtilLog.Infof("amfconfig Info: Version[%s]", config.GetVersion())
 	configuration := config.Configuration
@@ -121,6 +147,8 @@ func InitAmfContext(context *AMFContext) {
 		context.NgapIpList = []string{"127.0.0.1"} // default localhost
 	}
 	context.NgapPort = config.GetNgapPort()
+	context.NgapTnlEndpointList = getNgapTnlEndpointList(context.NgapIpList,
+		configuration.NgapTnlAssociationList, context.TNLWeightFactor)
 	context.UriScheme = models.UriScheme(config.GetSbiScheme())
 	context.RegisterIPv4 = config.GetSbiRegisterIP()
 	context.SBIPort = config.GetSbiPort()
@@ -131,18 +159,23 @@ func InitAmfContext(context *AMFContext) {
 	context.SupportTaiLists = configuration.SupportTAIList
 	context.PlmnSupportList = configuration.PlmnSupportList
 	context.SupportDnnLists = configuration.SupportDnnList
-	for _, ladn := range configuration.SupportLadnList {
-		context.LadnPool[ladn.Dnn] = ladn
-	}
+	context.SetLadnList(configuration.SupportLadnList)
 	context.NrfUri = config.GetNrfUri()
 	context.NrfCertPem = configuration.NrfCertPem
 	security := configuration.Security
 	if security != nil {
 		context.SecurityAlgorithm.IntegrityOrder = getIntAlgOrder(security.IntegrityOrder)
 		context.SecurityAlgorithm.CipheringOrder = getEncAlgOrder(security.CipheringOrder)
+		context.SecurityAlgorithm.Policies = getSecurityAlgorithmPolicies(security.AlgorithmPolicies)
+		context.NasReplayWindow = uint32(security.NasReplayWindow)
+		context.NasCountRekeyingMargin = uint32(security.NasCountRekeyingMargin)
+		context.RequestImeisv = security.RequestImeisv
+		if security.HorizontalKamfDerivation != nil {
+			context.HorizontalKamfDerivation = *security.HorizontalKamfDerivation
+		}
 	}
-	context.NetworkName = configuration.NetworkName
-	context.TimeZone = nasConvert.GetTimeZone(time.Now())
+	context.SetNetworkName(configuration.NetworkName)
+	context.UpdateTimeZone(time.Now())
 	context.T3502Value = configuration.T3502Value
 	context.T3512Value = configuration.T3512Value
 	context.Non3gppDeregTimerValue = configuration.Non3gppDeregTimerValue
@@ -153,7 +186,74 @@ func InitAmfContext(context *AMFContext) {
 	context.T3565Cfg = configuration.T3565
 	context.T3570Cfg = configuration.T3570
 	context.T3555Cfg = configuration.T3555
+	context.T3575Cfg = configuration.T3575
+	context.TRelocPrepCfg = configuration.TRelocPrep
+	context.TRelocOverallCfg = configuration.TRelocOverall
+	// the CM-IDLE UEs are supervised with the timer values of TS 24.501 5.3.7 unless configured otherwise
+	context.MobileReachableCfg = factory.TimerValue{Enable: true}
+	if configuration.MobileReachable != nil {
+		context.MobileReachableCfg = *configuration.MobileReachable
+	}
+	context.ImplicitDeregistrationCfg = factory.TimerValue{Enable: true}
+	if configuration.ImplicitDeregistration != nil {
+		context.ImplicitDeregistrationCfg = *configuration.ImplicitDeregistration
+	}
+	context.UpuAckCfg = configuration.UpuAck
 	context.Locality = configuration.Locality
+	if configuration.Emergency != nil && configuration.Emergency.Enable {
+		context.Emergency = configuration.Emergency
+	}
+	if configuration.Mico != nil && configuration.Mico.Enable {
+		context.Mico = configuration.Mico
+	}
+	if configuration.Edrx != nil && configuration.Edrx.Enable {
+		context.Edrx = configuration.Edrx
+	}
+	if configuration.Nsac != nil && configuration.Nsac.Enable {
+		context.Nsacf = NewLocalNsacf(configuration.Nsac.Quotas)
+	}
+	if configuration.Eir != nil && configuration.Eir.Enable {
+		context.Eir = configuration.Eir
+	}
+	if configuration.LocalAuthentication != nil && configuration.LocalAuthentication.Enable {
+		subscribers, err := configuration.LocalAuthentication.LoadSubscribers()
+		if err != nil {
+			return fmt.Errorf("local authentication: %w", err)
+		}
+		if context.LocalAusf, err = NewLocalAusf(configuration.LocalAuthentication, subscribers); err != nil {
+			return fmt.Errorf("local authentication: %w", err)
+		}
+		logger.CtxLog.Warnln("Local authentication enabled, the UEs are not authenticated by the AUSF: " +
+			"for test setups only")
+	}
+	return nil
+}
+
+func getNgapTnlEndpointList(ngapIpList []string, tnlaList []factory.TnlAssociation, defaultWeightFactor int64,
+) (endpointList []AmfTnlEndpoint) {
+	for _, ip := range ngapIpList {
+		endpoint := AmfTnlEndpoint{
+			Ip:           ip,
+			Usage:        ngapType.TNLAssociationUsagePresentBoth,
+			WeightFactor: defaultWeightFactor,
+		}
+		for _, tnla := range tnlaList {
+			if tnla.Ip != ip {
+				continue
+			}
+			switch tnla.Usage {
+			case "ue":
+				endpoint.Usage = ngapType.TNLAssociationUsagePresentUe
+			case "non-ue":
+				endpoint.Usage = ngapType.TNLAssociationUsagePresentNonUe
+			}
+			if tnla.WeightFactor != nil {
+				endpoint.WeightFactor = *tnla.WeightFactor
+			}
+		}
+		endpointList = append(endpointList, endpoint)
+	}
+	return
 }
 
 func getIntAlgOrder(integrityOrder []string) (intOrder []uint8) {
@@ -376,14 +476,19 @@ func (context *AMFContext) NewAmfRan(conn net.Conn) *AmfRan {
 	}
 
 	context.AmfRanPool.Store(conn, &ran)
+	ran.AddTNLAssociation(conn)
 	return &ran
 }
 
 // use net.Conn to find RAN context, return *AmfRan and ok bit
+// conn may be any of the TNL associations of the RAN
 func (context *AMFContext) AmfRanFindByConn(conn net.Conn) (*AmfRan, bool) {
 	if value, ok := context.AmfRanPool.Load(conn); ok {
 		return value.(*AmfRan), ok
 	}
+	if value, ok := context.AmfRanTnlaPool.Load(conn); ok {
+		return value.(*AmfRan), ok
+	}
 	return nil, false
 }
 
@@ -447,12 +552,80 @@ func (context *AMFContext) InPlmnSupportList(snssai models.Snssai) bool {
 	return false
 }
 
+// NetworkName returns the full and short network name sent to the UEs
+func (context *AMFContext) NetworkName() factory.NetworkName {
+	context.networkConfigLock.RLock()
+	defer context.networkConfigLock.RUnlock()
+	return context.networkName
+}
+
+// SetNetworkName changes the network name sent to the UEs, it returns false if the network name is unchanged
+func (context *AMFContext) SetNetworkName(networkName factory.NetworkName) bool {
+	context.networkConfigLock.Lock()
+	defer context.networkConfigLock.Unlock()
+	if context.networkName == networkName {
+		return false
+	}
+	context.networkName = networkName
+	return true
+}
+
+// TimeZone returns the local time zone of the AMF
+func (context *AMFContext) TimeZone() string {
+	context.networkConfigLock.RLock()
+	defer context.networkConfigLock.RUnlock()
+	return context.timeZone
+}
+
+// UpdateTimeZone sets the local time zone of the AMF at the given time, it returns false if the time zone and the
+// daylight saving time are unchanged
+func (context *AMFContext) UpdateTimeZone(now time.Time) bool {
+	timeZone := nasConvert.GetTimeZone(now)
+	context.networkConfigLock.Lock()
+	defer context.networkConfigLock.Unlock()
+	if context.timeZone == timeZone {
+		return false
+	}
+	context.timeZone = timeZone
+	return true
+}
+
+// FindLadn returns the LADN of the DNN
+func (context *AMFContext) FindLadn(dnn string) (factory.Ladn, bool) {
+	context.networkConfigLock.RLock()
+	defer context.networkConfigLock.RUnlock()
+	ladn, ok := context.ladnPool[dnn]
+	return ladn, ok
+}
+
+// LadnList returns the LADNs of the AMF
+func (context *AMFContext) LadnList() []factory.Ladn {
+	context.networkConfigLock.RLock()
+	defer context.networkConfigLock.RUnlock()
+	ladnList := make([]factory.Ladn, 0, len(context.ladnPool))
+	for _, ladn := range context.ladnPool {
+		ladnList = append(ladnList, ladn)
+	}
+	return ladnList
+}
+
+// SetLadnList replaces the LADNs of the AMF
+func (context *AMFContext) SetLadnList(ladnList []factory.Ladn) {
+	ladnPool := make(map[string]factory.Ladn, len(ladnList))
+	for _, ladn := range ladnList {
+		ladnPool[ladn.Dnn] = ladn
+	}
+	context.networkConfigLock.Lock()
+	defer context.networkConfigLock.Unlock()
+	context.ladnPool = ladnPool
+}
+
 func (context *AMFContext) AmfUeFindByGuti(guti string) (*AmfUe, bool) {
 	var ue *AmfUe
 	var ok bool
 	context.UePool.Range(func(key, value interface{}) bool {
 		candidate := value.(*AmfUe)
-		if ok = (candidate.Guti == guti); ok {
+		if ok = (candidate.Guti == guti || (candidate.OldGuti != "" && candidate.OldGuti == guti)); ok {
 			ue = candidate
 			return false
 		}
@@ -482,6 +655,20 @@ func (context *AMFContext) RanUeFindByAmfUeNgapID(amfUeNgapID int64) *RanUe {
 	return nil
 }
 
+// ServingNetworkName returns the serving network name of the first served GUAMI, the input of the derivation of the
+// 5G AKA keys (TS 24.501 9.12.1)
+func (context *AMFContext) ServingNetworkName() (string, error) {
+	if len(context.ServedGuamiList) == 0 || context.ServedGuamiList[0].PlmnId == nil {
+		return "", fmt.Errorf("no served GUAMI")
+	}
+	plmnId := context.ServedGuamiList[0].PlmnId
+	mnc, err := strconv.Atoi(plmnId.Mnc)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("5G:mnc%03d.mcc%s.3gppnetwork.org", mnc, plmnId.Mcc), nil
+}
+
 func (context *AMFContext) GetIPv4Uri() string {
 	return fmt.Sprintf("%s://%s:%d", context.UriScheme, context.RegisterIPv4, context.SBIPort)
 }
@@ -520,9 +707,7 @@ func (context *AMFContext) Reset() {
 		context.UePool.Delete(key)
 		return true
 	})
-	for key := range context.LadnPool {
-		delete(context.LadnPool, key)
-	}
+	context.SetLadnList(nil)
 	context.RanUePool.Range(func(key, value interface{}) bool {
 		context.RanUePool.Delete(key)
 		return true
@@ -4,8 +4,10 @@ import (
 	"net"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/free5gc/amf/internal/logger"
+	"github.com/free5gc/amf/pkg/factory"
 	"github.com/free5gc/nas/security"
 	"github.com/free5gc/openapi/models"
 	"github.com/free5gc/util/idgenerator"
@@ -347,3 +349,31 @@ func TestTmsiGenerator_Lifecycle(t *testing.T) {
 		assert.NotEqual(t, -1, val)
 	})
 }
+
+func TestAMFContext_NetworkConfiguration(t *testing.T) {
+	ctx := newTestAmfContext()
+
+	networkName := factory.NetworkName{Full: "free5GC", Short: "free"}
+	require.True(t, ctx.SetNetworkName(networkName))
+	require.False(t, ctx.SetNetworkName(networkName))
+	require.Equal(t, networkName, ctx.NetworkName())
+
+	// a daylight saving time change is a time zone change
+	location, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	require.True(t, ctx.UpdateTimeZone(time.Date(2026, 3, 29, 0, 0, 0, 0, location)))
+	require.Equal(t, "+01:00", ctx.TimeZone())
+	require.False(t, ctx.UpdateTimeZone(time.Date(2026, 3, 29, 1, 0, 0, 0, location)))
+	require.True(t, ctx.UpdateTimeZone(time.Date(2026, 3, 29, 4, 0, 0, 0, location)))
+	require.Equal(t, "+01:00+1", ctx.TimeZone())
+
+	ladn := factory.Ladn{Dnn: "ladn"}
+	ctx.SetLadnList([]factory.Ladn{ladn})
+	found, ok := ctx.FindLadn("ladn")
+	require.True(t, ok)
+	require.Equal(t, ladn, found)
+	require.Equal(t, []factory.Ladn{ladn}, ctx.LadnList())
+	ctx.SetLadnList(nil)
+	_, ok = ctx.FindLadn("ladn")
+	require.False(t, ok)
+}
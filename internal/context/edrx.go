@@ -0,0 +1,79 @@
+package context
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/free5gc/openapi/models"
+)
+
+const (
+	edrxCycleUnit        = 2560 * time.Millisecond // NR eDRX cycle of value 0000
+	edrxCycleMaxValue    = 0x0c                    // 10485.76 seconds, other values are interpreted as it
+	pagingTimeWindowUnit = 1280 * time.Millisecond
+)
+
+// LongEdrxCycleThreshold is the eDRX cycle above which a UE is not paged immediately, the consumer is told to wait
+// for it to become reachable (TS 23.502 4.2.3.3)
+const LongEdrxCycleThreshold = 10240 * time.Millisecond
+
+// ExtendedDRXParameters is the value part of the extended DRX parameters IE, TS 24.501 9.11.3.60
+type ExtendedDRXParameters struct {
+	PagingTimeWindow uint8
+	EdrxValue        uint8
+}
+
+func NewExtendedDRXParameters(octet uint8) *ExtendedDRXParameters {
+	return &ExtendedDRXParameters{
+		PagingTimeWindow: octet >> 4,
+		EdrxValue:        octet & 0x0f,
+	}
+}
+
+func (p *ExtendedDRXParameters) Octet() uint8 {
+	return p.PagingTimeWindow<<4 | p.EdrxValue&0x0f
+}
+
+// Cycle returns the eDRX cycle length for NR, TS 24.008 table 10.5.5.32
+func (p *ExtendedDRXParameters) Cycle() time.Duration {
+	value := p.EdrxValue
+	if value > edrxCycleMaxValue {
+		value = edrxCycleMaxValue
+	}
+	return edrxCycleUnit << value
+}
+
+// PagingTimeWindowLength returns the paging time window length for NR, TS 24.008 table 10.5.5.32
+func (p *ExtendedDRXParameters) PagingTimeWindowLength() time.Duration {
+	return time.Duration(p.PagingTimeWindow+1) * pagingTimeWindowUnit
+}
+
+// SubscribedEdrxValue returns the eDRX value subscribed for NR, false if there is none (TS 29.503 6.1.6.2.84)
+func (ue *AmfUe) SubscribedEdrxValue() (uint8, bool) {
+	if ue.AccessAndMobilitySubscriptionData == nil {
+		return 0, false
+	}
+	for _, edrx := range ue.AccessAndMobilitySubscriptionData.EdrxParametersList {
+		if edrx.RatType != models.RatType_NR {
+			continue
+		}
+		value, err := strconv.ParseUint(edrx.EdrxValue, 2, 4)
+		if err != nil {
+			ue.GmmLog.Warnf("Invalid subscribed eDRX value[%s]", edrx.EdrxValue)
+			return 0, false
+		}
+		return uint8(value), true
+	}
+	return 0, false
+}
+
+// EdrxMaxWaitingTime estimates the time in seconds within which a UE in CM-IDLE using a long eDRX cycle becomes
+// reachable for paging, 0 is returned if the UE can be paged immediately
+func (ue *AmfUe) EdrxMaxWaitingTime() int32 {
+	edrx := ue.NegotiatedEdrx
+	if edrx == nil || edrx.Cycle() <= LongEdrxCycleThreshold {
+		return 0
+	}
+	// the next paging time window starts within an eDRX cycle
+	return int32((edrx.Cycle() + edrx.PagingTimeWindowLength()) / time.Second)
+}
@@ -0,0 +1,69 @@
+package context
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/free5gc/openapi/models"
+)
+
+func TestExtendedDRXParameters(t *testing.T) {
+	testCases := []struct {
+		name                   string
+		octet                  uint8
+		cycle                  time.Duration
+		pagingTimeWindowLength time.Duration
+		maxWaitingTime         int32
+	}{
+		{
+			name:                   "Short eDRX cycle",
+			octet:                  0x02,
+			cycle:                  10240 * time.Millisecond,
+			pagingTimeWindowLength: 1280 * time.Millisecond,
+		},
+		{
+			name:                   "Long eDRX cycle",
+			octet:                  0x35,
+			cycle:                  81920 * time.Millisecond,
+			pagingTimeWindowLength: 5120 * time.Millisecond,
+			maxWaitingTime:         87,
+		},
+		{
+			name:                   "Reserved eDRX value",
+			octet:                  0x0f,
+			cycle:                  10485760 * time.Millisecond,
+			pagingTimeWindowLength: 1280 * time.Millisecond,
+			maxWaitingTime:         10487,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			edrx := NewExtendedDRXParameters(tc.octet)
+			require.Equal(t, tc.octet, edrx.Octet())
+			require.Equal(t, tc.cycle, edrx.Cycle())
+			require.Equal(t, tc.pagingTimeWindowLength, edrx.PagingTimeWindowLength())
+
+			ue := &AmfUe{NegotiatedEdrx: edrx}
+			require.Equal(t, tc.maxWaitingTime, ue.EdrxMaxWaitingTime())
+		})
+	}
+}
+
+func TestSubscribedEdrxValue(t *testing.T) {
+	ue := &AmfUe{}
+	_, ok := ue.SubscribedEdrxValue()
+	require.False(t, ok)
+
+	ue.AccessAndMobilitySubscriptionData = &models.AccessAndMobilitySubscriptionData{
+		EdrxParametersList: []models.EdrxParameters{
+			{RatType: models.RatType_EUTRA, EdrxValue: "0001"},
+			{RatType: models.RatType_NR, EdrxValue: "0110"},
+		},
+	}
+	value, ok := ue.SubscribedEdrxValue()
+	require.True(t, ok)
+	require.Equal(t, uint8(0x06), value)
+}
@@ -0,0 +1,31 @@
+package context
+
+import "github.com/free5gc/nas/nasMessage"
+
+// EirCheckRequired reports whether the PEI of the UE is checked by the 5G-EIR, the UEs of the PLMNs configured to
+// skip the check are not checked. The PEI is checked in an initial or emergency registration, and in a mobility or
+// periodic registration update only if it is not the PEI last checked.
+func (context *AMFContext) EirCheckRequired(ue *AmfUe) bool {
+	if context.Eir == nil || ue.Pei == "" {
+		return false
+	}
+	switch ue.RegistrationType5GS {
+	case nasMessage.RegistrationType5GSInitialRegistration, nasMessage.RegistrationType5GSEmergencyRegistration:
+	default:
+		if ue.Pei == ue.EirCheckedPei {
+			return false
+		}
+	}
+	for _, plmnId := range context.Eir.SkipPlmnList {
+		if plmnId == ue.PlmnId {
+			return false
+		}
+	}
+	return true
+}
+
+// ImeisvRequired reports whether the Security Mode Command requests the IMEISV of the UE, it is requested if the PEI
+// of the UE is not known or the AMF is configured to request it
+func (context *AMFContext) ImeisvRequired(ue *AmfUe) bool {
+	return ue.Pei == "" || context.RequestImeisv
+}
@@ -0,0 +1,62 @@
+package context
+
+import (
+	"github.com/free5gc/amf/pkg/factory"
+	"github.com/free5gc/openapi/models"
+)
+
+// EmergencyServicesSupported tells whether emergency registrations and emergency PDU sessions are accepted
+func (context *AMFContext) EmergencyServicesSupported() bool {
+	return context.Emergency != nil
+}
+
+// EmergencyAllowedWithoutAuthentication tells whether the emergency policy allows emergency services to a UE which
+// is not authenticated (TS 23.501 5.16.4.1), withSupi is false for a UE identified by its IMEI only
+func (context *AMFContext) EmergencyAllowedWithoutAuthentication(withSupi bool) bool {
+	if context.Emergency == nil {
+		return false
+	}
+	switch context.Emergency.Policy {
+	case factory.EmergencyPolicyAll:
+		return true
+	case factory.EmergencyPolicyUnauthenticatedSupi:
+		return withSupi
+	default:
+		return false
+	}
+}
+
+// EmergencySnssaiAndDnn returns the S-NSSAI and DNN the SMF of the emergency PDU sessions is selected with
+// (TS 23.501 5.16.4.5)
+func (context *AMFContext) EmergencySnssaiAndDnn() (models.Snssai, string) {
+	return *context.Emergency.Snssai, context.Emergency.Dnn
+}
+
+// AddAmfUeToUePoolByPei stores the UE identified by its PEI only, an emergency registered UE may have no SUPI
+func (context *AMFContext) AddAmfUeToUePoolByPei(ue *AmfUe) {
+	context.UePool.Store(ue.Pei, ue)
+}
+
+// UnauthenticatedEmergency tells whether the UE obtains emergency services without being authenticated, its NAS
+// security context then uses the null algorithms (TS 33.501 10.2.2)
+func (ue *AmfUe) UnauthenticatedEmergency() bool {
+	return ue.EmergencyRegistered && ue.UnauthenticatedSupi
+}
+
+// UeContextId returns the identifier of the UE context towards the other NFs, the PEI if the SUPI is not known
+// (TS 29.518 6.1.3.2.2)
+func (ue *AmfUe) UeContextId() string {
+	if ue.Supi != "" {
+		return ue.Supi
+	}
+	return ue.Pei
+}
+
+// ServiceAreaRestriction returns the service area restriction the UE is subject to, mobility restrictions do not
+// apply to an emergency registered UE (TS 23.501 5.3.4.1.1)
+func (ue *AmfUe) ServiceAreaRestriction() *models.ServiceAreaRestriction {
+	if ue.EmergencyRegistered || ue.AmPolicyAssociation == nil {
+		return nil
+	}
+	return ue.AmPolicyAssociation.ServAreaRes
+}
@@ -0,0 +1,71 @@
+package context
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/free5gc/amf/pkg/factory"
+	"github.com/free5gc/openapi/models"
+)
+
+func TestEmergencyAllowedWithoutAuthentication(t *testing.T) {
+	testCases := []struct {
+		name        string
+		emergency   *factory.Emergency
+		withSupi    bool
+		withoutSupi bool
+	}{
+		{
+			name: "Emergency services not supported",
+		},
+		{
+			name:      "Authenticated UEs only",
+			emergency: &factory.Emergency{Policy: factory.EmergencyPolicyAuthenticated},
+		},
+		{
+			name:      "Default policy",
+			emergency: &factory.Emergency{},
+		},
+		{
+			name:      "Unauthenticated SUPI",
+			emergency: &factory.Emergency{Policy: factory.EmergencyPolicyUnauthenticatedSupi},
+			withSupi:  true,
+		},
+		{
+			name:        "All UEs",
+			emergency:   &factory.Emergency{Policy: factory.EmergencyPolicyAll},
+			withSupi:    true,
+			withoutSupi: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			amfContext := &AMFContext{Emergency: tc.emergency}
+			require.Equal(t, tc.emergency != nil, amfContext.EmergencyServicesSupported())
+			require.Equal(t, tc.withSupi, amfContext.EmergencyAllowedWithoutAuthentication(true))
+			require.Equal(t, tc.withoutSupi, amfContext.EmergencyAllowedWithoutAuthentication(false))
+		})
+	}
+}
+
+func TestEmergencyRegisteredUe(t *testing.T) {
+	servAreaRes := &models.ServiceAreaRestriction{RestrictionType: models.RestrictionType_NOT_ALLOWED_AREAS}
+	ue := &AmfUe{
+		Pei:                 "imei-356938035643803",
+		AmPolicyAssociation: &models.PcfAmPolicyControlPolicyAssociation{ServAreaRes: servAreaRes},
+	}
+	require.Equal(t, "imei-356938035643803", ue.UeContextId())
+	require.Equal(t, servAreaRes, ue.ServiceAreaRestriction())
+
+	// no mobility restriction applies to an emergency registered UE
+	ue.EmergencyRegistered = true
+	require.Nil(t, ue.ServiceAreaRestriction())
+
+	ue.Supi = "imsi-208930000000001"
+	require.Equal(t, "imsi-208930000000001", ue.UeContextId())
+	require.False(t, ue.UnauthenticatedEmergency())
+	ue.UnauthenticatedSupi = true
+	require.True(t, ue.UnauthenticatedEmergency())
+}
@@ -0,0 +1,105 @@
+package context
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/free5gc/nas/security"
+	"github.com/free5gc/openapi/models"
+	"github.com/free5gc/util/ueauth"
+)
+
+// FC of the K_AMF to K_AMF' derivation in mobility (TS 33.501 A.13)
+const FC_FOR_KAMF_HORIZONTAL_DERIVATION = "72"
+
+// DIRECTION input of the K_AMF to K_AMF' derivation (TS 33.501 A.13)
+const (
+	// mobility registration update, the COUNT is the uplink NAS COUNT of the Registration Request
+	KamfDerivationDirectionMobility uint8 = 0x00
+	// N2 handover, the COUNT is the downlink NAS COUNT
+	KamfDerivationDirectionHandover uint8 = 0x01
+)
+
+// DerivateKamfPrime derives the K_AMF' from the K_AMF in mobility between AMFs (TS 33.501 A.13)
+func DerivateKamfPrime(kamf []byte, direction uint8, count uint32) ([]byte, error) {
+	P0 := []byte{direction}
+	L0 := ueauth.KDFLen(P0)
+	P1 := make([]byte, 4)
+	binary.BigEndian.PutUint32(P1, count)
+	L1 := ueauth.KDFLen(P1)
+
+	return ueauth.GetKDFValue(kamf, FC_FOR_KAMF_HORIZONTAL_DERIVATION, P0, L0, P1, L1)
+}
+
+// HorizontalKamfDerivationRequired reports whether the local policy requires a new K_AMF when the UE comes from
+// another AMF, by a mobility registration update or by an N2 handover according to the direction
+func (context *AMFContext) HorizontalKamfDerivationRequired(direction uint8) bool {
+	if direction == KamfDerivationDirectionHandover {
+		return context.HorizontalKamfDerivation.Handover
+	}
+	return context.HorizontalKamfDerivation.MobilityRegistration
+}
+
+// DerivateKamfHorizontally replaces the K_AMF of the UE with the K_AMF' derived from it (TS 33.501 6.9.2.3.3, 6.9.3),
+// the new K_AMF is taken into use with a NAS Security Mode Command
+func (ue *AmfUe) DerivateKamfHorizontally(direction uint8, count uint32) error {
+	kamf, err := hex.DecodeString(ue.Kamf)
+	if err != nil {
+		return fmt.Errorf("decode K_AMF failed: %w", err)
+	}
+	kamfPrime, err := DerivateKamfPrime(kamf, direction, count)
+	if err != nil {
+		return err
+	}
+	ue.Kamf = hex.EncodeToString(kamfPrime)
+	ue.KamfHorizontallyDerived = true
+	ue.KamfDerivationDirection = direction
+	return nil
+}
+
+// DerivateKamfOnMobility derives a new K_AMF for the UE coming from another AMF with its security context, if the
+// source AMF indicates it in the security context or the local policy requires it (TS 33.501 6.9.2.3.3, 6.9.3)
+func (ue *AmfUe) DerivateKamfOnMobility(seafData *models.SeafData, direction uint8, count uint32) {
+	if seafData == nil || (!seafData.KeyAmfHDerivationInd && !GetSelf().HorizontalKamfDerivationRequired(direction)) {
+		return
+	}
+	if err := ue.DerivateKamfHorizontally(direction, count); err != nil {
+		ue.GmmLog.Errorf("K_AMF horizontal derivation failed: %+v", err)
+		return
+	}
+	ue.GmmLog.Infof("Derive K_AMF horizontally with NAS COUNT[%d] and DIRECTION[%d]", count, direction)
+}
+
+// KamfChangeIndicationRequired reports whether the K_AMF' derived for an N2 handover is still to be indicated to the
+// UE in the NAS container of the Handover Request
+func (ue *AmfUe) KamfChangeIndicationRequired() bool {
+	return ue.KamfHorizontallyDerived && ue.KamfDerivationDirection == KamfDerivationDirectionHandover
+}
+
+// IntraN1ModeNasTransparentContainer builds the value of the Intra N1 mode NAS transparent container
+// (TS 24.501 9.11.2.6) sent to the UE in the NASC IE of the Handover Request to take the K_AMF' derived with the
+// downlink NAS COUNT into use (TS 33.501 6.9.2.3.3). The NAS MAC is calculated with the new K_NASint over the
+// octets following it, the downlink NAS COUNT is then increased by one.
+func (ue *AmfUe) IntraN1ModeNasTransparentContainer() ([]byte, error) {
+	const kamfChangeFlag = 0x10
+	container := make([]byte, 4, 7)
+	container = append(container, ue.CipheringAlg<<4|ue.IntegrityAlg&0x0f,
+		kamfChangeFlag|uint8(ue.NgKsi.Ksi)&0x07, ue.DLCount.SQN())
+	mac, err := security.NASMacCalculate(ue.IntegrityAlg, ue.KnasInt, ue.DLCount.Get(), security.Bearer3GPP,
+		security.DirectionDownlink, container[4:])
+	if err != nil {
+		return nil, fmt.Errorf("calculate NAS MAC of the NAS container failed: %w", err)
+	}
+	copy(container[:4], mac)
+	ue.DLCount.AddOne()
+	ue.KamfHorizontallyDerived = false
+	return container, nil
+}
+
+// HorizontalDerivationParameter reports whether the UE derives the K_AMF' on the receipt of the NAS Security Mode
+// Command (TS 24.501 9.11.3.12), which is the case for a K_AMF' derived with the uplink NAS COUNT of a mobility
+// registration update
+func (ue *AmfUe) HorizontalDerivationParameter() bool {
+	return ue.KamfHorizontallyDerived && ue.KamfDerivationDirection == KamfDerivationDirectionMobility
+}
@@ -0,0 +1,169 @@
+package context
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/free5gc/amf/internal/logger"
+	"github.com/free5gc/amf/pkg/factory"
+	"github.com/free5gc/nas/security"
+	"github.com/free5gc/openapi/models"
+)
+
+// TS 33.501 does not provide test data for the derivation of A.13, the K_AMF' values are computed with an
+// independent implementation of the KDF of TS 33.220 B.2.0 with S = 0x72 || DIRECTION || 0x0001 || COUNT || 0x0004
+func TestDerivateKamfPrime(t *testing.T) {
+	testCases := []struct {
+		name      string
+		kamf      string
+		direction uint8
+		count     uint32
+		kamfPrime string
+	}{
+		{
+			name:      "mobility registration with the first uplink NAS COUNT",
+			kamf:      "0f8a6ee1b6e1c63f0bd8b7e6b8f4a7e27c8f1b1f6cb9d14e0a5f4b3c2d1e0f9a",
+			direction: KamfDerivationDirectionMobility,
+			count:     0,
+			kamfPrime: "d06f5ebeebddce302616d4a1885dbb2c06d10d431fabcfe903b6ed665a2f1b6d",
+		},
+		{
+			name:      "mobility registration",
+			kamf:      "0f8a6ee1b6e1c63f0bd8b7e6b8f4a7e27c8f1b1f6cb9d14e0a5f4b3c2d1e0f9a",
+			direction: KamfDerivationDirectionMobility,
+			count:     0x2a,
+			kamfPrime: "7b05bc5a34f77b5d19aaf139280f25ba56034cb0da7d6b8e6be090179b12c74b",
+		},
+		{
+			name:      "handover with the same NAS COUNT",
+			kamf:      "0f8a6ee1b6e1c63f0bd8b7e6b8f4a7e27c8f1b1f6cb9d14e0a5f4b3c2d1e0f9a",
+			direction: KamfDerivationDirectionHandover,
+			count:     0x2a,
+			kamfPrime: "93d944eb531b9f999fd86ac57581274acd9800a851359a797cd4720bd48497d0",
+		},
+		{
+			name:      "handover with the highest downlink NAS COUNT",
+			kamf:      "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+			direction: KamfDerivationDirectionHandover,
+			count:     0xffffff,
+			kamfPrime: "a2f8cdc4bf56c0436eecefb9af5f77b6d77e0fb0c832f13e4e0d36ba1fa2eca3",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			kamf, err := hex.DecodeString(tc.kamf)
+			require.NoError(t, err)
+			kamfPrime, err := DerivateKamfPrime(kamf, tc.direction, tc.count)
+			require.NoError(t, err)
+			require.Equal(t, tc.kamfPrime, hex.EncodeToString(kamfPrime))
+		})
+	}
+}
+
+func TestDerivateKamfOnMobility(t *testing.T) {
+	const kamf = "0f8a6ee1b6e1c63f0bd8b7e6b8f4a7e27c8f1b1f6cb9d14e0a5f4b3c2d1e0f9a"
+
+	self := GetSelf()
+	policy := self.HorizontalKamfDerivation
+	defer func() {
+		self.HorizontalKamfDerivation = policy
+	}()
+
+	testCases := []struct {
+		name         string
+		policy       factory.HorizontalKamfDerivation
+		seafData     *models.SeafData
+		direction    uint8
+		kamf         string
+		derived      bool
+		hdpIndicated bool
+		indicated    bool
+	}{
+		{
+			name:      "no security context",
+			policy:    factory.HorizontalKamfDerivation{MobilityRegistration: true, Handover: true},
+			direction: KamfDerivationDirectionMobility,
+			kamf:      kamf,
+		},
+		{
+			name:      "not required",
+			seafData:  &models.SeafData{},
+			direction: KamfDerivationDirectionMobility,
+			kamf:      kamf,
+		},
+		{
+			name:         "indicated by the source AMF",
+			seafData:     &models.SeafData{KeyAmfHDerivationInd: true},
+			direction:    KamfDerivationDirectionMobility,
+			kamf:         "7b05bc5a34f77b5d19aaf139280f25ba56034cb0da7d6b8e6be090179b12c74b",
+			derived:      true,
+			hdpIndicated: true,
+		},
+		{
+			name:         "required by the local policy of mobility registration",
+			policy:       factory.HorizontalKamfDerivation{MobilityRegistration: true},
+			seafData:     &models.SeafData{},
+			direction:    KamfDerivationDirectionMobility,
+			kamf:         "7b05bc5a34f77b5d19aaf139280f25ba56034cb0da7d6b8e6be090179b12c74b",
+			derived:      true,
+			hdpIndicated: true,
+		},
+		{
+			name:      "local policy of mobility registration on handover",
+			policy:    factory.HorizontalKamfDerivation{MobilityRegistration: true},
+			seafData:  &models.SeafData{},
+			direction: KamfDerivationDirectionHandover,
+			kamf:      kamf,
+		},
+		{
+			name:      "required by the local policy of handover",
+			policy:    factory.HorizontalKamfDerivation{Handover: true},
+			seafData:  &models.SeafData{},
+			direction: KamfDerivationDirectionHandover,
+			kamf:      "93d944eb531b9f999fd86ac57581274acd9800a851359a797cd4720bd48497d0",
+			derived:   true,
+			indicated: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			self.HorizontalKamfDerivation = tc.policy
+			ue := &AmfUe{Kamf: kamf, GmmLog: logger.GmmLog}
+
+			ue.DerivateKamfOnMobility(tc.seafData, tc.direction, 0x2a)
+			require.Equal(t, tc.kamf, ue.Kamf)
+			require.Equal(t, tc.derived, ue.KamfHorizontallyDerived)
+			require.Equal(t, tc.indicated, ue.KamfChangeIndicationRequired())
+			require.Equal(t, tc.hdpIndicated, ue.HorizontalDerivationParameter())
+		})
+	}
+}
+
+func TestIntraN1ModeNasTransparentContainer(t *testing.T) {
+	ue := &AmfUe{
+		IntegrityAlg: security.AlgIntegrity128NIA2,
+		CipheringAlg: security.AlgCiphering128NEA2,
+		NgKsi:        models.NgKsi{Tsc: models.ScType_NATIVE, Ksi: 3},
+		GmmLog:       logger.GmmLog,
+	}
+	copy(ue.KnasInt[:], []byte("0123456789abcdef"))
+	ue.DLCount.Set(0, 0x2a)
+	ue.KamfHorizontallyDerived = true
+	ue.KamfDerivationDirection = KamfDerivationDirectionHandover
+
+	// the K_AMF change flag and the sequence number of the downlink NAS COUNT follow the NAS MAC
+	nasc, err := ue.IntraN1ModeNasTransparentContainer()
+	require.NoError(t, err)
+	require.Len(t, nasc, 7)
+	require.Equal(t, []byte{0x22, 0x13, 0x2a}, nasc[4:])
+	mac, err := security.NASMacCalculate(security.AlgIntegrity128NIA2, ue.KnasInt, 0x2a, security.Bearer3GPP,
+		security.DirectionDownlink, nasc[4:])
+	require.NoError(t, err)
+	require.Equal(t, mac, nasc[:4])
+	require.Equal(t, uint32(0x2b), ue.DLCount.Get())
+	require.False(t, ue.KamfChangeIndicationRequired())
+}
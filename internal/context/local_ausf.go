@@ -0,0 +1,276 @@
+package context
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/free5gc/amf/internal/logger"
+	"github.com/free5gc/amf/pkg/factory"
+	"github.com/free5gc/nas/nasType"
+	"github.com/free5gc/openapi/models"
+	"github.com/free5gc/util/milenage"
+	"github.com/free5gc/util/ueauth"
+)
+
+// default AMF field of the AUTN (TS 33.102 6.3.2), the separation bit is set for 5G
+const defaultAuthenticationManagementField = "8000"
+
+const localAusfConfirmationUri = "/nausf-auth/v1/ue-authentications/%s/5g-aka-confirmation"
+
+// LocalAusf is the stand-in of the AUSF in the AMF for test setups, it de-conceals the SUCIs with the private keys
+// of the home network and authenticates the UEs with 5G AKA from the subscribers of the local authentication
+// configuration (TS 33.501 6.1.3.2)
+type LocalAusf struct {
+	// SkipUdm and SkipPcf are set if the test setup has no UDM or no PCF
+	SkipUdm bool
+	SkipPcf bool
+
+	privateKeys map[HomeNetworkKeyId][]byte
+
+	mu          sync.Mutex
+	subscribers map[string]*localSubscriber // by SUPI
+	authCtxs    map[string]*localAuthCtx    // by authentication context ID
+}
+
+type localSubscriber struct {
+	k   []byte
+	opc []byte
+	sqn uint64
+	amf []byte
+}
+
+type localAuthCtx struct {
+	supi     string
+	xresStar string
+	kseaf    string
+}
+
+// NewLocalAusf returns the local AUSF of the configuration, which authenticates the subscribers
+func NewLocalAusf(config *factory.LocalAuthentication, subscribers []factory.LocalSubscriber) (*LocalAusf, error) {
+	ausf := &LocalAusf{
+		SkipUdm:     config.SkipUdm,
+		SkipPcf:     config.SkipPcf,
+		privateKeys: make(map[HomeNetworkKeyId][]byte),
+		subscribers: make(map[string]*localSubscriber),
+		authCtxs:    make(map[string]*localAuthCtx),
+	}
+	for _, key := range config.HomeNetworkKeys {
+		privateKey, err := hex.DecodeString(key.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("decode private key %d failed: %w", key.Id, err)
+		}
+		scheme := SuciProfileA
+		if key.Scheme == factory.SuciProfileB {
+			scheme = SuciProfileB
+		}
+		ausf.privateKeys[HomeNetworkKeyId{Scheme: scheme, Id: key.Id}] = privateKey
+	}
+	for _, s := range subscribers {
+		subscriber := &localSubscriber{}
+		var err error
+		if subscriber.k, err = hex.DecodeString(s.K); err != nil {
+			return nil, fmt.Errorf("decode K of %s failed: %w", s.Supi, err)
+		}
+		if subscriber.opc, err = hex.DecodeString(s.Opc); err != nil {
+			return nil, fmt.Errorf("decode OPc of %s failed: %w", s.Supi, err)
+		}
+		sqn, err := hex.DecodeString(s.Sqn)
+		if err != nil {
+			return nil, fmt.Errorf("decode SQN of %s failed: %w", s.Supi, err)
+		}
+		subscriber.sqn = sqnToUint64(sqn)
+		amf := s.Amf
+		if amf == "" {
+			amf = defaultAuthenticationManagementField
+		}
+		if subscriber.amf, err = hex.DecodeString(amf); err != nil {
+			return nil, fmt.Errorf("decode AMF of %s failed: %w", s.Supi, err)
+		}
+		ausf.subscribers[s.Supi] = subscriber
+	}
+	return ausf, nil
+}
+
+// SendUEAuthenticationAuthenticateRequest generates a 5G HE AV for the UE, or a new one after the re-synchronisation
+// of the SQN if the UE reported a synch failure (TS 33.501 6.1.3.2.0, 6.1.3.3.2)
+func (a *LocalAusf) SendUEAuthenticationAuthenticateRequest(ue *AmfUe,
+	resynchronizationInfo *models.ResynchronizationInfo,
+) (*models.UeAuthenticationCtx, *models.ProblemDetails, error) {
+	supi := ue.Suci
+	if strings.HasPrefix(ue.Suci, "suci-") {
+		var err error
+		if supi, err = SuciToSupi(ue.Suci, a.privateKeys); err != nil {
+			ue.GmmLog.Warnf("De-conceal SUCI failed: %+v", err)
+			return nil, localAusfProblem(http.StatusForbidden, "AUTHENTICATION_REJECTED"), nil
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	subscriber, ok := a.subscribers[supi]
+	if !ok {
+		return nil, localAusfProblem(http.StatusNotFound, "USER_NOT_FOUND"), nil
+	}
+
+	if resynchronizationInfo != nil {
+		randValue, err := hex.DecodeString(resynchronizationInfo.Rand)
+		if err != nil {
+			return nil, nil, err
+		}
+		auts, err := hex.DecodeString(resynchronizationInfo.Auts)
+		if err != nil {
+			return nil, nil, err
+		}
+		sqnMs, err := milenage.ValidateAUTS(subscriber.opc, subscriber.k, randValue, auts)
+		if err != nil {
+			ue.GmmLog.Warnf("AUTS validation failed: %+v", err)
+			return nil, localAusfProblem(http.StatusForbidden, "AUTHENTICATION_REJECTED"), nil
+		}
+		subscriber.sqn = sqnToUint64(sqnMs) + 1
+	}
+
+	randValue := make([]byte, 16)
+	if _, err := rand.Read(randValue); err != nil {
+		return nil, nil, err
+	}
+	sqn := make([]byte, 8)
+	binary.BigEndian.PutUint64(sqn, subscriber.sqn)
+	ik, ck, xres, autn, err := milenage.GenerateAKAParameters(subscriber.opc, subscriber.k, randValue, sqn[2:],
+		subscriber.amf)
+	if err != nil {
+		return nil, nil, err
+	}
+	subscriber.sqn = (subscriber.sqn + 1) & 0xffffffffffff
+
+	servingNetworkName, err := GetSelf().ServingNetworkName()
+	if err != nil {
+		return nil, nil, err
+	}
+	xresStar, kseaf, err := deriveLocal5gAkaKeys(append(ck, ik...), servingNetworkName, randValue, xres, autn[:6])
+	if err != nil {
+		return nil, nil, err
+	}
+	hxresStar := sha256.Sum256(append(randValue, xresStar...))
+
+	authCtxId := uuid.New().String()
+	a.authCtxs[authCtxId] = &localAuthCtx{
+		supi:     supi,
+		xresStar: hex.EncodeToString(xresStar),
+		kseaf:    hex.EncodeToString(kseaf),
+	}
+	return &models.UeAuthenticationCtx{
+		AuthType: models.AusfUeAuthenticationAuthType__5_G_AKA,
+		Var5gAuthData: map[string]interface{}{
+			"rand":      hex.EncodeToString(randValue),
+			"autn":      hex.EncodeToString(autn),
+			"hxresStar": hex.EncodeToString(hxresStar[16:]),
+		},
+		Links: map[string][]models.Link{
+			"5g-aka": {{Href: fmt.Sprintf(localAusfConfirmationUri, authCtxId)}},
+		},
+		ServingNetworkName: servingNetworkName,
+	}, nil, nil
+}
+
+// SendAuth5gAkaConfirmRequest compares the RES* of the UE with the XRES* of its authentication context, the K_SEAF
+// is returned if they match (TS 33.501 6.1.3.2.0 step 11)
+func (a *LocalAusf) SendAuth5gAkaConfirmRequest(ue *AmfUe, resStar string) (
+	*models.ConfirmationDataResponse, *models.ProblemDetails, error,
+) {
+	if ue.AuthenticationCtx == nil || len(ue.AuthenticationCtx.Links["5g-aka"]) == 0 {
+		return nil, nil, fmt.Errorf("no 5G AKA confirmation link")
+	}
+	// splitUri = ["","nausf-auth","v1","ue-authentications",{authCtxId},"5g-aka-confirmation"]
+	splitUri := strings.Split(ue.AuthenticationCtx.Links["5g-aka"][0].Href, "/")
+	if len(splitUri) != 6 {
+		return nil, nil, fmt.Errorf("invalid 5G AKA confirmation link")
+	}
+	authCtxId := splitUri[4]
+
+	a.mu.Lock()
+	authCtx, ok := a.authCtxs[authCtxId]
+	delete(a.authCtxs, authCtxId)
+	a.mu.Unlock()
+	if !ok {
+		return nil, localAusfProblem(http.StatusNotFound, "CONTEXT_NOT_FOUND"), nil
+	}
+
+	if !strings.EqualFold(resStar, authCtx.xresStar) {
+		return &models.ConfirmationDataResponse{
+			AuthResult: models.AusfUeAuthenticationAuthResult_FAILURE,
+		}, nil, nil
+	}
+	return &models.ConfirmationDataResponse{
+		AuthResult: models.AusfUeAuthenticationAuthResult_SUCCESS,
+		Supi:       authCtx.supi,
+		Kseaf:      authCtx.kseaf,
+	}, nil, nil
+}
+
+// SendEapAuthConfirmRequest is not supported, the local authentication runs 5G AKA only
+func (a *LocalAusf) SendEapAuthConfirmRequest(ue *AmfUe, eapMsg nasType.EAPMessage) (
+	*models.EapSession, *models.ProblemDetails, error,
+) {
+	logger.GmmLog.Warnf("EAP-AKA' is not supported by the local authentication")
+	return nil, localAusfProblem(http.StatusNotImplemented, "UNSUPPORTED_AUTH_METHOD"), nil
+}
+
+// SubscribedNssai returns the S-NSSAIs subscribed by the UEs of the local authentication when the UDM is skipped, all
+// the S-NSSAIs supported by the AMF are default ones
+func (a *LocalAusf) SubscribedNssai() (subscribedNssai []models.SubscribedSnssai) {
+	for _, plmnSupportItem := range GetSelf().PlmnSupportList {
+		for i := range plmnSupportItem.SNssaiList {
+			subscribedNssai = append(subscribedNssai, models.SubscribedSnssai{
+				SubscribedSnssai:  &plmnSupportItem.SNssaiList[i],
+				DefaultIndication: true,
+			})
+		}
+	}
+	return subscribedNssai
+}
+
+// deriveLocal5gAkaKeys derives the XRES* and the K_SEAF from the CK and IK (TS 33.501 A.2, A.4, A.6)
+func deriveLocal5gAkaKeys(ckIk []byte, servingNetworkName string, randValue, xres, sqnXorAk []byte) (
+	xresStar, kseaf []byte, err error,
+) {
+	P0 := []byte(servingNetworkName)
+	kausf, err := ueauth.GetKDFValue(ckIk, ueauth.FC_FOR_KAUSF_DERIVATION, P0, ueauth.KDFLen(P0), sqnXorAk,
+		ueauth.KDFLen(sqnXorAk))
+	if err != nil {
+		return nil, nil, err
+	}
+	xresStarFull, err := ueauth.GetKDFValue(ckIk, ueauth.FC_FOR_RES_STAR_XRES_STAR_DERIVATION, P0,
+		ueauth.KDFLen(P0), randValue, ueauth.KDFLen(randValue), xres, ueauth.KDFLen(xres))
+	if err != nil {
+		return nil, nil, err
+	}
+	kseaf, err = ueauth.GetKDFValue(kausf, ueauth.FC_FOR_KSEAF_DERIVATION, P0, ueauth.KDFLen(P0))
+	if err != nil {
+		return nil, nil, err
+	}
+	return xresStarFull[len(xresStarFull)-16:], kseaf, nil
+}
+
+func sqnToUint64(sqn []byte) uint64 {
+	var value uint64
+	for _, b := range sqn {
+		value = value<<8 | uint64(b)
+	}
+	return value
+}
+
+func localAusfProblem(status int32, cause string) *models.ProblemDetails {
+	return &models.ProblemDetails{
+		Status: status,
+		Cause:  cause,
+	}
+}
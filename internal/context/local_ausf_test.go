@@ -0,0 +1,108 @@
+package context
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/stretchr/testify/require"
+
+	"github.com/free5gc/amf/internal/logger"
+	"github.com/free5gc/amf/pkg/factory"
+	"github.com/free5gc/openapi/models"
+	"github.com/free5gc/util/milenage"
+	"github.com/free5gc/util/ueauth"
+)
+
+const (
+	localAusfTestK   = "8baf473f2f8fd09487cccbd7097c6862"
+	localAusfTestOpc = "8e27b6af0e692e750f32667a3b14605d"
+)
+
+// localAusfTestResStar runs the 5G AKA of the UE on the authentication vector (TS 33.501 6.1.3.2.0 step 5)
+func localAusfTestResStar(t *testing.T, authCtx *models.UeAuthenticationCtx) (resStar string, sqn []byte) {
+	var av5gAka models.Av5gAka
+	require.NoError(t, mapstructure.Decode(authCtx.Var5gAuthData, &av5gAka))
+	k, _ := hex.DecodeString(localAusfTestK)
+	opc, _ := hex.DecodeString(localAusfTestOpc)
+	randValue, err := hex.DecodeString(av5gAka.Rand)
+	require.NoError(t, err)
+	autn, err := hex.DecodeString(av5gAka.Autn)
+	require.NoError(t, err)
+
+	sqn, _, ik, ck, res, err := milenage.GenerateKeysWithAUTN(opc, k, randValue, autn)
+	require.NoError(t, err)
+	P0 := []byte(authCtx.ServingNetworkName)
+	kdfValue, err := ueauth.GetKDFValue(append(ck, ik...), ueauth.FC_FOR_RES_STAR_XRES_STAR_DERIVATION, P0,
+		ueauth.KDFLen(P0), randValue, ueauth.KDFLen(randValue), res, ueauth.KDFLen(res))
+	require.NoError(t, err)
+	return hex.EncodeToString(kdfValue[len(kdfValue)-16:]), sqn
+}
+
+func TestLocalAusf(t *testing.T) {
+	self := GetSelf()
+	servedGuamiList := self.ServedGuamiList
+	defer func() {
+		self.ServedGuamiList = servedGuamiList
+	}()
+	self.ServedGuamiList = []models.Guami{
+		{PlmnId: &models.PlmnIdNid{Mcc: "208", Mnc: "93"}, AmfId: "cafe00"},
+	}
+
+	ausf, err := NewLocalAusf(&factory.LocalAuthentication{Enable: true}, []factory.LocalSubscriber{
+		{Supi: "imsi-208930000000001", K: localAusfTestK, Opc: localAusfTestOpc, Sqn: "000000000020"},
+	})
+	require.NoError(t, err)
+	ue := &AmfUe{Suci: "suci-0-208-93-0000-0-0-0000000001", GmmLog: logger.GmmLog}
+
+	// 5G AKA succeeds with the RES* of the UE
+	authCtx, problemDetails, err := ausf.SendUEAuthenticationAuthenticateRequest(ue, nil)
+	require.NoError(t, err)
+	require.Nil(t, problemDetails)
+	require.Equal(t, models.AusfUeAuthenticationAuthType__5_G_AKA, authCtx.AuthType)
+	require.Equal(t, "5G:mnc093.mcc208.3gppnetwork.org", authCtx.ServingNetworkName)
+	ue.AuthenticationCtx = authCtx
+	resStar, sqn := localAusfTestResStar(t, authCtx)
+	require.Equal(t, "000000000020", hex.EncodeToString(sqn))
+	confirmation, problemDetails, err := ausf.SendAuth5gAkaConfirmRequest(ue, resStar)
+	require.NoError(t, err)
+	require.Nil(t, problemDetails)
+	require.Equal(t, models.AusfUeAuthenticationAuthResult_SUCCESS, confirmation.AuthResult)
+	require.Equal(t, "imsi-208930000000001", confirmation.Supi)
+	require.Len(t, confirmation.Kseaf, 64)
+
+	// 5G AKA fails with a wrong RES*, the SQN of the next vector is incremented
+	authCtx, _, err = ausf.SendUEAuthenticationAuthenticateRequest(ue, nil)
+	require.NoError(t, err)
+	ue.AuthenticationCtx = authCtx
+	_, sqn = localAusfTestResStar(t, authCtx)
+	require.Equal(t, "000000000021", hex.EncodeToString(sqn))
+	confirmation, _, err = ausf.SendAuth5gAkaConfirmRequest(ue, "00000000000000000000000000000000")
+	require.NoError(t, err)
+	require.Equal(t, models.AusfUeAuthenticationAuthResult_FAILURE, confirmation.AuthResult)
+
+	// the SQN is re-synchronised from the AUTS of the UE
+	var av5gAka models.Av5gAka
+	require.NoError(t, mapstructure.Decode(authCtx.Var5gAuthData, &av5gAka))
+	k, _ := hex.DecodeString(localAusfTestK)
+	opc, _ := hex.DecodeString(localAusfTestOpc)
+	randValue, _ := hex.DecodeString(av5gAka.Rand)
+	sqnMs, _ := hex.DecodeString("000000000100")
+	auts, err := milenage.GenerateAUTS(opc, k, randValue, sqnMs)
+	require.NoError(t, err)
+	authCtx, problemDetails, err = ausf.SendUEAuthenticationAuthenticateRequest(ue, &models.ResynchronizationInfo{
+		Rand: av5gAka.Rand,
+		Auts: hex.EncodeToString(auts),
+	})
+	require.NoError(t, err)
+	require.Nil(t, problemDetails)
+	_, sqn = localAusfTestResStar(t, authCtx)
+	require.Equal(t, "000000000101", hex.EncodeToString(sqn))
+
+	// unknown subscriber
+	ue.Suci = "suci-0-208-93-0000-0-0-0000000002"
+	_, problemDetails, err = ausf.SendUEAuthenticationAuthenticateRequest(ue, nil)
+	require.NoError(t, err)
+	require.NotNil(t, problemDetails)
+	require.Equal(t, "USER_NOT_FOUND", problemDetails.Cause)
+}
@@ -0,0 +1,170 @@
+package context
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/free5gc/openapi/models"
+)
+
+// Location Reporting Reference ID range, TS 38.413 9.3.1.76
+const (
+	MinLocationReportingReferenceID int64 = 1
+	MaxLocationReportingReferenceID int64 = 64
+)
+
+// AreaOfInterest is an area in which the NG-RAN is requested to report the UE presence (TS 23.502 4.10).
+// It is requested either by an AMF event exposure subscription (PRESENCE_IN_AOI_REPORT) or by the PCF as a
+// Presence Reporting Area of the AM policy association (TS 23.503 6.1.2.5).
+type AreaOfInterest struct {
+	ReferenceID    int64               // Location Reporting Reference ID used towards NG-RAN
+	SubscriptionID string              // AMF event subscription ID, empty if requested by the PCF
+	PraId          string              // Presence Reporting Area ID, empty if requested by an event subscription
+	PresenceInfo   models.PresenceInfo // the area and the last known presence state of the UE
+}
+
+// NewAreaOfInterest allocates a Location Reporting Reference ID for the area and stores it in the UE context.
+// The initial presence state is derived from the last known UE location.
+func (ue *AmfUe) NewAreaOfInterest(subscriptionID, praId string, info models.PresenceInfo) (*AreaOfInterest, error) {
+	ue.aoiMu.Lock()
+	defer ue.aoiMu.Unlock()
+
+	for id := MinLocationReportingReferenceID; id <= MaxLocationReportingReferenceID; id++ {
+		if _, exist := ue.AreaOfInterestList.Load(id); exist {
+			continue
+		}
+		aoi := &AreaOfInterest{
+			ReferenceID:    id,
+			SubscriptionID: subscriptionID,
+			PraId:          praId,
+			PresenceInfo:   info,
+		}
+		if aoi.PresenceInfo.PraId == "" {
+			aoi.PresenceInfo.PraId = praId
+		}
+		aoi.PresenceInfo.PresenceState = PresenceStateInArea(&aoi.PresenceInfo, ue.Location)
+		ue.AreaOfInterestList.Store(id, aoi)
+		return aoi, nil
+	}
+	return nil, fmt.Errorf("no Location Reporting Reference ID available")
+}
+
+func (ue *AmfUe) AreaOfInterestFindByReferenceID(referenceID int64) (*AreaOfInterest, bool) {
+	if value, ok := ue.AreaOfInterestList.Load(referenceID); ok {
+		return value.(*AreaOfInterest), true
+	}
+	return nil, false
+}
+
+func (ue *AmfUe) AreaOfInterestFindByPraId(praId string) (*AreaOfInterest, bool) {
+	var found *AreaOfInterest
+	ue.AreaOfInterestList.Range(func(key, value interface{}) bool {
+		aoi := value.(*AreaOfInterest)
+		if aoi.PraId == praId {
+			found = aoi
+			return false
+		}
+		return true
+	})
+	return found, found != nil
+}
+
+func (ue *AmfUe) AreaOfInterestListBySubscriptionID(subscriptionID string) (aoiList []*AreaOfInterest) {
+	ue.AreaOfInterestList.Range(func(key, value interface{}) bool {
+		aoi := value.(*AreaOfInterest)
+		if aoi.SubscriptionID == subscriptionID {
+			aoiList = append(aoiList, aoi)
+		}
+		return true
+	})
+	return aoiList
+}
+
+func (ue *AmfUe) DeleteAreaOfInterest(referenceID int64) {
+	ue.AreaOfInterestList.Delete(referenceID)
+}
+
+// PresenceStateInArea tells whether the location is inside the area described by info,
+// TS 29.571 5.4.4.27. Only the NR location is taken into account.
+func PresenceStateInArea(info *models.PresenceInfo, location models.UserLocation) models.PresenceState {
+	nrLocation := location.NrLocation
+	if nrLocation == nil {
+		return models.PresenceState_UNKNOWN
+	}
+
+	if nrLocation.Tai != nil && InTaiList(*nrLocation.Tai, info.TrackingAreaList) {
+		return models.PresenceState_IN_AREA
+	}
+	if nrLocation.Ncgi != nil {
+		for _, ncgi := range info.NcgiList {
+			if reflect.DeepEqual(ncgi.PlmnId, nrLocation.Ncgi.PlmnId) &&
+				strings.EqualFold(ncgi.NrCellId, nrLocation.Ncgi.NrCellId) {
+				return models.PresenceState_IN_AREA
+			}
+		}
+	}
+	if nrLocation.GlobalGnbId != nil {
+		for _, ranNodeId := range info.GlobalRanNodeIdList {
+			if reflect.DeepEqual(ranNodeId, *nrLocation.GlobalGnbId) {
+				return models.PresenceState_IN_AREA
+			}
+		}
+	}
+	return models.PresenceState_OUT_OF_AREA
+}
+
+// UpdatePresenceReportingArea adds the PCF Presence Reporting Area praId, or replaces the area if it
+// is already tracked so that the Location Reporting Reference ID is kept.
+func (ue *AmfUe) UpdatePresenceReportingArea(praId string, info models.PresenceInfo) (*AreaOfInterest, error) {
+	if aoi, ok := ue.AreaOfInterestFindByPraId(praId); ok {
+		updated := *aoi
+		updated.PresenceInfo = info
+		updated.PresenceInfo.PraId = praId
+		updated.PresenceInfo.PresenceState = PresenceStateInArea(&updated.PresenceInfo, ue.Location)
+		ue.AreaOfInterestList.Store(updated.ReferenceID, &updated)
+		return &updated, nil
+	}
+	return ue.NewAreaOfInterest("", praId, info)
+}
+
+// UpdatePresenceReportingAreas applies the "pras" of a PCF policy update (TS 29.507 5.6.2.3),
+// a null entry removes the area. The removed areas are returned.
+func (ue *AmfUe) UpdatePresenceReportingAreas(pras map[string]*models.PresenceInfoRm) (removed []*AreaOfInterest) {
+	for praId, infoRm := range pras {
+		if infoRm == nil {
+			if aoi, ok := ue.AreaOfInterestFindByPraId(praId); ok {
+				ue.DeleteAreaOfInterest(aoi.ReferenceID)
+				removed = append(removed, aoi)
+			}
+			continue
+		}
+		info := models.PresenceInfo{
+			PraId:               infoRm.PraId,
+			AdditionalPraId:     infoRm.AdditionalPraId,
+			TrackingAreaList:    infoRm.TrackingAreaList,
+			EcgiList:            infoRm.EcgiList,
+			NcgiList:            infoRm.NcgiList,
+			GlobalRanNodeIdList: infoRm.GlobalRanNodeIdList,
+			GlobaleNbIdList:     infoRm.GlobaleNbIdList,
+		}
+		if _, err := ue.UpdatePresenceReportingArea(praId, info); err != nil {
+			ue.ProducerLog.Warnf("Presence Reporting Area[%s]: %v", praId, err)
+		}
+	}
+	return removed
+}
+
+// ClearPresenceReportingAreas removes all the PCF Presence Reporting Areas, used when the PRA_CH policy
+// control request trigger is no longer armed. The removed areas are returned.
+func (ue *AmfUe) ClearPresenceReportingAreas() (removed []*AreaOfInterest) {
+	ue.AreaOfInterestList.Range(func(key, value interface{}) bool {
+		aoi := value.(*AreaOfInterest)
+		if aoi.PraId != "" {
+			ue.AreaOfInterestList.Delete(key)
+			removed = append(removed, aoi)
+		}
+		return true
+	})
+	return removed
+}
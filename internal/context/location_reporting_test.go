@@ -0,0 +1,117 @@
+package context
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/free5gc/openapi/models"
+)
+
+func TestPresenceStateInArea(t *testing.T) {
+	plmnId := &models.PlmnId{Mcc: "208", Mnc: "93"}
+	location := models.UserLocation{
+		NrLocation: &models.NrLocation{
+			Tai:  &models.Tai{PlmnId: plmnId, Tac: "000001"},
+			Ncgi: &models.Ncgi{PlmnId: plmnId, NrCellId: "000000010"},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		info     models.PresenceInfo
+		location models.UserLocation
+		expected models.PresenceState
+	}{
+		{
+			name:     "Unknown location",
+			info:     models.PresenceInfo{TrackingAreaList: []models.Tai{*location.NrLocation.Tai}},
+			location: models.UserLocation{},
+			expected: models.PresenceState_UNKNOWN,
+		},
+		{
+			name:     "In tracking area",
+			info:     models.PresenceInfo{TrackingAreaList: []models.Tai{*location.NrLocation.Tai}},
+			location: location,
+			expected: models.PresenceState_IN_AREA,
+		},
+		{
+			name: "In NR cell",
+			info: models.PresenceInfo{NcgiList: []models.Ncgi{{PlmnId: plmnId, NrCellId: "00000001A"}}},
+			location: models.UserLocation{
+				NrLocation: &models.NrLocation{Ncgi: &models.Ncgi{PlmnId: plmnId, NrCellId: "00000001a"}},
+			},
+			expected: models.PresenceState_IN_AREA,
+		},
+		{
+			name:     "Out of area",
+			info:     models.PresenceInfo{TrackingAreaList: []models.Tai{{PlmnId: plmnId, Tac: "000002"}}},
+			location: location,
+			expected: models.PresenceState_OUT_OF_AREA,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, PresenceStateInArea(&tc.info, tc.location))
+		})
+	}
+}
+
+func TestAreaOfInterestReferenceID(t *testing.T) {
+	ue := &AmfUe{}
+
+	for id := MinLocationReportingReferenceID; id <= MaxLocationReportingReferenceID; id++ {
+		aoi, err := ue.NewAreaOfInterest("sub", "", models.PresenceInfo{})
+		require.NoError(t, err)
+		require.Equal(t, id, aoi.ReferenceID)
+	}
+	_, err := ue.NewAreaOfInterest("sub", "", models.PresenceInfo{})
+	require.Error(t, err)
+
+	// The lowest released ID is allocated again
+	ue.DeleteAreaOfInterest(3)
+	aoi, err := ue.NewAreaOfInterest("", "pra-1", models.PresenceInfo{})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), aoi.ReferenceID)
+	require.Equal(t, "pra-1", aoi.PresenceInfo.PraId)
+
+	// A PCF update keeps the reference ID, a null entry removes the area
+	updated, err := ue.UpdatePresenceReportingArea("pra-1", models.PresenceInfo{
+		TrackingAreaList: []models.Tai{{Tac: "000001"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), updated.ReferenceID)
+
+	removed := ue.UpdatePresenceReportingAreas(map[string]*models.PresenceInfoRm{"pra-1": nil})
+	require.Len(t, removed, 1)
+	_, ok := ue.AreaOfInterestFindByPraId("pra-1")
+	require.False(t, ok)
+	require.Len(t, ue.AreaOfInterestListBySubscriptionID("sub"), int(MaxLocationReportingReferenceID)-1)
+}
+
+func TestAmfUeLocationCopy(t *testing.T) {
+	plmnId := &models.PlmnId{Mcc: "208", Mnc: "93"}
+	ue := new(AmfUe)
+	ue.SetLocation(models.UserLocation{
+		NrLocation: &models.NrLocation{Tai: &models.Tai{PlmnId: plmnId, Tac: "000001"}},
+	})
+
+	// the location read for the report of another UE is not changed by the next location update
+	location := ue.LocationCopy()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			ue.SetLocation(models.UserLocation{
+				NrLocation: &models.NrLocation{Tai: &models.Tai{PlmnId: plmnId, Tac: "000002"}},
+			})
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		ue.LocationCopy()
+	}
+	<-done
+	require.Equal(t, "000001", location.NrLocation.Tai.Tac)
+	require.Equal(t, "000002", ue.LocationCopy().NrLocation.Tai.Tac)
+}
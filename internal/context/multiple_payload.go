@@ -0,0 +1,224 @@
+package context
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/free5gc/nas/nasMessage"
+)
+
+// Optional IEs of a payload container entry, TS 24.501 9.11.3.39 Table 9.11.3.39.1
+const (
+	payloadOptionalIePduSessionId          uint8 = 0x12
+	payloadOptionalIeSNssai                uint8 = 0x22
+	payloadOptionalIeAdditionalInformation uint8 = 0x24
+	payloadOptionalIeDnn                   uint8 = 0x25
+	payloadOptionalIeBackoffTimer          uint8 = 0x37
+	payloadOptionalIe5gmmCause             uint8 = 0x58
+	payloadOptionalIeOldPduSessionId       uint8 = 0x59
+	payloadOptionalIeRequestType           uint8 = 0x80 // type 1 IE, the value is in bits 1 to 3
+)
+
+// PayloadContainerEntry is a payload of a NAS Transport with its optional IEs, several of them are carried in a
+// payload container of type multiple payloads (TS 24.501 9.11.3.39)
+type PayloadContainerEntry struct {
+	Type                  uint8
+	Contents              []byte
+	PduSessionId          *uint8
+	OldPduSessionId       *uint8
+	RequestType           *uint8
+	SNssai                []byte // value of the S-NSSAI IE
+	Dnn                   []byte // value of the DNN IE
+	AdditionalInformation []byte
+	Cause                 *uint8
+	BackoffTimer          []byte // value of the GPRS timer 3 IE
+}
+
+// DecodeMultiplePayload splits the payload container of type multiple payloads into its entries
+func DecodeMultiplePayload(buf []byte) ([]*PayloadContainerEntry, error) {
+	if len(buf) < 1 {
+		return nil, fmt.Errorf("multiple payload is empty")
+	}
+	numOfEntries := int(buf[0])
+	if numOfEntries == 0 {
+		return nil, fmt.Errorf("multiple payload has no entry")
+	}
+
+	var entries []*PayloadContainerEntry
+	offset := 1
+	for i := 0; i < numOfEntries; i++ {
+		if len(buf) < offset+2 {
+			return nil, fmt.Errorf("payload container entry[%d] is truncated", i)
+		}
+		entryLen := int(binary.BigEndian.Uint16(buf[offset:]))
+		offset += 2
+		if entryLen < 1 || len(buf) < offset+entryLen {
+			return nil, fmt.Errorf("payload container entry[%d] length[%d] is invalid", i, entryLen)
+		}
+		entry, err := decodePayloadContainerEntry(buf[offset : offset+entryLen])
+		if err != nil {
+			return nil, fmt.Errorf("payload container entry[%d]: %w", i, err)
+		}
+		entries = append(entries, entry)
+		offset += entryLen
+	}
+	if offset != len(buf) {
+		return nil, fmt.Errorf("multiple payload has %d trailing octets", len(buf)-offset)
+	}
+	return entries, nil
+}
+
+func decodePayloadContainerEntry(buf []byte) (*PayloadContainerEntry, error) {
+	entry := &PayloadContainerEntry{
+		Type: buf[0] & 0x0f,
+	}
+	if entry.Type == nasMessage.PayloadContainerTypeMultiplePayload {
+		return nil, fmt.Errorf("multiple payload cannot be nested")
+	}
+	numOfOptionalIes := int(buf[0] >> 4)
+	offset := 1
+	for i := 0; i < numOfOptionalIes; i++ {
+		if len(buf) < offset+1 {
+			return nil, fmt.Errorf("optional IE[%d] is truncated", i)
+		}
+		iei := buf[offset]
+		offset++
+
+		// type 1 IEs, the unknown ones are ignored (TS 24.501 7.6.1)
+		if iei >= 0x80 {
+			if iei&0xf0 == payloadOptionalIeRequestType {
+				requestType := iei & 0x07
+				entry.RequestType = &requestType
+			}
+			continue
+		}
+		// type 3 IEs of one octet value
+		switch iei {
+		case payloadOptionalIePduSessionId, payloadOptionalIe5gmmCause, payloadOptionalIeOldPduSessionId:
+			if len(buf) < offset+1 {
+				return nil, fmt.Errorf("optional IE[0x%02x] is truncated", iei)
+			}
+			value := buf[offset]
+			offset++
+			switch iei {
+			case payloadOptionalIePduSessionId:
+				entry.PduSessionId = &value
+			case payloadOptionalIe5gmmCause:
+				entry.Cause = &value
+			default:
+				entry.OldPduSessionId = &value
+			}
+			continue
+		}
+		// type 4 IEs
+		if len(buf) < offset+1 || len(buf) < offset+1+int(buf[offset]) {
+			return nil, fmt.Errorf("optional IE[0x%02x] is truncated", iei)
+		}
+		value := buf[offset+1 : offset+1+int(buf[offset])]
+		offset += 1 + len(value)
+		switch iei {
+		case payloadOptionalIeSNssai:
+			entry.SNssai = value
+		case payloadOptionalIeDnn:
+			entry.Dnn = value
+		case payloadOptionalIeAdditionalInformation:
+			entry.AdditionalInformation = value
+		case payloadOptionalIeBackoffTimer:
+			entry.BackoffTimer = value
+		default:
+			// TS 24.501 7.6.1, unknown optional IEs are ignored
+		}
+	}
+	entry.Contents = buf[offset:]
+	return entry, nil
+}
+
+// EncodeMultiplePayload builds the payload container of type multiple payloads carrying the entries
+func EncodeMultiplePayload(entries []*PayloadContainerEntry) ([]byte, error) {
+	if len(entries) == 0 || len(entries) > 0xff {
+		return nil, fmt.Errorf("invalid number of payload container entries[%d]", len(entries))
+	}
+	buf := []byte{uint8(len(entries))}
+	for i, entry := range entries {
+		if entry.Type == nasMessage.PayloadContainerTypeMultiplePayload {
+			return nil, fmt.Errorf("payload container entry[%d]: multiple payload cannot be nested", i)
+		}
+		var optionalIes []byte
+		numOfOptionalIes := 0
+		appendTV := func(iei uint8, value *uint8) {
+			if value != nil {
+				optionalIes = append(optionalIes, iei, *value)
+				numOfOptionalIes++
+			}
+		}
+		appendTLV := func(iei uint8, value []byte) {
+			if len(value) > 0 {
+				optionalIes = append(optionalIes, iei, uint8(len(value)))
+				optionalIes = append(optionalIes, value...)
+				numOfOptionalIes++
+			}
+		}
+		appendTV(payloadOptionalIePduSessionId, entry.PduSessionId)
+		appendTV(payloadOptionalIeOldPduSessionId, entry.OldPduSessionId)
+		if entry.RequestType != nil {
+			optionalIes = append(optionalIes, payloadOptionalIeRequestType|*entry.RequestType&0x07)
+			numOfOptionalIes++
+		}
+		appendTLV(payloadOptionalIeSNssai, entry.SNssai)
+		appendTLV(payloadOptionalIeDnn, entry.Dnn)
+		appendTLV(payloadOptionalIeAdditionalInformation, entry.AdditionalInformation)
+		appendTV(payloadOptionalIe5gmmCause, entry.Cause)
+		appendTLV(payloadOptionalIeBackoffTimer, entry.BackoffTimer)
+		if numOfOptionalIes > 0x0f {
+			return nil, fmt.Errorf("payload container entry[%d] has too many optional IEs", i)
+		}
+
+		entryLen := 1 + len(optionalIes) + len(entry.Contents)
+		if entryLen > 0xffff {
+			return nil, fmt.Errorf("payload container entry[%d] is too long", i)
+		}
+		buf = binary.BigEndian.AppendUint16(buf, uint16(entryLen))
+		buf = append(buf, uint8(numOfOptionalIes)<<4|entry.Type&0x0f)
+		buf = append(buf, optionalIes...)
+		buf = append(buf, entry.Contents...)
+	}
+	return buf, nil
+}
+
+// AppendPendingDLPayload keeps a payload until it is sent to the UE, the payloads pending together are sent in
+// one DL NAS Transport
+func (ue *AmfUe) AppendPendingDLPayload(entry *PayloadContainerEntry) {
+	ue.pendingDLPayloadMu.Lock()
+	defer ue.pendingDLPayloadMu.Unlock()
+	ue.pendingDLPayloads = append(ue.pendingDLPayloads, entry)
+}
+
+// TakePendingDLPayloads returns the payloads pending for the UE and removes them
+func (ue *AmfUe) TakePendingDLPayloads() []*PayloadContainerEntry {
+	ue.pendingDLPayloadMu.Lock()
+	defer ue.pendingDLPayloadMu.Unlock()
+	entries := ue.pendingDLPayloads
+	ue.pendingDLPayloads = nil
+	return entries
+}
+
+// HasPendingDLPayloads reports whether payloads are pending for the UE
+func (ue *AmfUe) HasPendingDLPayloads() bool {
+	ue.pendingDLPayloadMu.Lock()
+	defer ue.pendingDLPayloadMu.Unlock()
+	return len(ue.pendingDLPayloads) > 0
+}
+
+// RemovePendingDLPayloads removes the pending payloads of the payload container type, they are obsoleted by
+// information sent to the UE in another message
+func (ue *AmfUe) RemovePendingDLPayloads(payloadContainerType uint8) {
+	ue.pendingDLPayloadMu.Lock()
+	defer ue.pendingDLPayloadMu.Unlock()
+	entries := ue.pendingDLPayloads[:0]
+	for _, entry := range ue.pendingDLPayloads {
+		if entry.Type != payloadContainerType {
+			entries = append(entries, entry)
+		}
+	}
+	ue.pendingDLPayloads = entries
+}
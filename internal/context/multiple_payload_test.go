@@ -0,0 +1,92 @@
+package context
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/free5gc/nas/nasMessage"
+)
+
+func TestMultiplePayload(t *testing.T) {
+	pduSessionId := uint8(5)
+	requestType := nasMessage.ULNASTransportRequestTypeInitialRequest
+	cause := nasMessage.Cause5GMMPayloadWasNotForwarded
+	entries := []*PayloadContainerEntry{
+		{
+			Type:         nasMessage.PayloadContainerTypeN1SMInfo,
+			Contents:     []byte{0x2e, 0x05, 0x01, 0xc1},
+			PduSessionId: &pduSessionId,
+			RequestType:  &requestType,
+			SNssai:       []byte{0x01, 0x01, 0x02, 0x03},
+			Dnn:          []byte{0x08, 'i', 'n', 't', 'e', 'r', 'n', 'e', 't'},
+		},
+		{
+			Type:     nasMessage.PayloadContainerTypeSOR,
+			Contents: []byte{0x01, 0x02},
+			Cause:    &cause,
+		},
+		{
+			Type:                  nasMessage.PayloadContainerTypeLPP,
+			Contents:              []byte{0x92},
+			AdditionalInformation: []byte{0x07},
+		},
+	}
+
+	buf, err := EncodeMultiplePayload(entries)
+	require.NoError(t, err)
+	require.Equal(t, uint8(len(entries)), buf[0])
+	// entry of the SOR container: 1 optional IE and the payload container type
+	require.Equal(t, []byte{0x00, 0x05, 0x14, 0x58, cause, 0x01, 0x02}, buf[1+2+25:1+2+25+7])
+
+	decoded, err := DecodeMultiplePayload(buf)
+	require.NoError(t, err)
+	require.Equal(t, entries, decoded)
+}
+
+func TestDecodeMultiplePayload_UnknownTypeOneIe(t *testing.T) {
+	// N1 SM information with an unknown type 1 IE before the PDU session ID
+	buf := []byte{0x01, 0x00, 0x05, 0x21, 0xa1, 0x12, 0x05, 0x2e}
+
+	entries, err := DecodeMultiplePayload(buf)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, nasMessage.PayloadContainerTypeN1SMInfo, entries[0].Type)
+	require.NotNil(t, entries[0].PduSessionId)
+	require.Equal(t, uint8(5), *entries[0].PduSessionId)
+	require.Nil(t, entries[0].RequestType)
+	require.Equal(t, []byte{0x2e}, entries[0].Contents)
+}
+
+func TestDecodeMultiplePayload_Invalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		buf  []byte
+	}{
+		{name: "Empty", buf: []byte{}},
+		{name: "No entry", buf: []byte{0x00}},
+		{name: "Truncated entry", buf: []byte{0x01, 0x00, 0x05, 0x09, 0x01}},
+		{name: "Truncated optional IE", buf: []byte{0x01, 0x00, 0x02, 0x11, 0x12}},
+		{name: "Nested multiple payload", buf: []byte{0x01, 0x00, 0x01, 0x0f}},
+		{name: "Trailing octets", buf: []byte{0x01, 0x00, 0x01, 0x09, 0xff}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := DecodeMultiplePayload(tc.buf)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestPendingDLPayloads(t *testing.T) {
+	ue := &AmfUe{}
+	ue.AppendPendingDLPayload(&PayloadContainerEntry{Type: nasMessage.PayloadContainerTypeSOR})
+	ue.AppendPendingDLPayload(&PayloadContainerEntry{Type: nasMessage.PayloadContainerTypeUEPolicy})
+	ue.RemovePendingDLPayloads(nasMessage.PayloadContainerTypeSOR)
+
+	entries := ue.TakePendingDLPayloads()
+	require.Len(t, entries, 1)
+	require.Equal(t, nasMessage.PayloadContainerTypeUEPolicy, entries[0].Type)
+	require.Empty(t, ue.TakePendingDLPayloads())
+}
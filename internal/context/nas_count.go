@@ -0,0 +1,100 @@
+package context
+
+import (
+	"github.com/free5gc/amf/pkg/factory"
+)
+
+// Defaults of the NAS COUNT protection, used if not configured
+const (
+	DefaultNasReplayWindow        uint32 = 8
+	DefaultNasCountRekeyingMargin uint32 = 0x1000
+)
+
+// NasReplayWindow keeps the uplink NAS COUNT values accepted from the UE, each value is accepted only once
+// (TS 24.501 4.4.3.1). The values older than the window are not accepted anymore.
+type NasReplayWindow struct {
+	valid   bool
+	highest uint32
+	bitmap  uint64 // bit i is set if the value highest - i was accepted
+}
+
+// Reset forgets the values accepted, e.g. when a new NAS security context is taken into use
+func (w *NasReplayWindow) Reset() {
+	*w = NasReplayWindow{}
+}
+
+// Valid reports whether a value was accepted since the last reset
+func (w *NasReplayWindow) Valid() bool {
+	return w.valid
+}
+
+// Highest returns the highest value accepted
+func (w *NasReplayWindow) Highest() uint32 {
+	return w.highest
+}
+
+// Replayed reports whether the value was accepted already or is older than the window of the size given
+func (w *NasReplayWindow) Replayed(count, size uint32) bool {
+	if !w.valid || count > w.highest {
+		return false
+	}
+	age := w.highest - count
+	if age >= size {
+		return true
+	}
+	return w.bitmap&(1<<age) != 0
+}
+
+// Accept records the value as accepted
+func (w *NasReplayWindow) Accept(count uint32) {
+	switch {
+	case !w.valid:
+		w.valid = true
+		w.highest = count
+		w.bitmap = 1
+	case count > w.highest:
+		if shift := count - w.highest; shift < factory.MaxNasReplayWindow {
+			w.bitmap = w.bitmap<<shift | 1
+		} else {
+			w.bitmap = 1
+		}
+		w.highest = count
+	default:
+		if age := w.highest - count; age < factory.MaxNasReplayWindow {
+			w.bitmap |= 1 << age
+		}
+	}
+}
+
+// NasReplayWindowSize returns the number of uplink NAS COUNT values accepted below the highest one received
+func (context *AMFContext) NasReplayWindowSize() uint32 {
+	if context.NasReplayWindow == 0 {
+		return DefaultNasReplayWindow
+	}
+	return context.NasReplayWindow
+}
+
+// NasCountRekeyingThreshold returns the NAS COUNT value from which a new NAS security context is required
+func (context *AMFContext) NasCountRekeyingThreshold() uint32 {
+	margin := context.NasCountRekeyingMargin
+	if margin == 0 {
+		margin = DefaultNasCountRekeyingMargin
+	}
+	return factory.MaxNasCount - margin
+}
+
+// NasCountExhausted reports whether the uplink or downlink NAS COUNT is about to wrap around, the NAS COUNT
+// shall not wrap around with the same NAS security context (TS 33.501 6.4.3.1)
+func (ue *AmfUe) NasCountExhausted() bool {
+	threshold := GetSelf().NasCountRekeyingThreshold()
+	return ue.ULCount.Get() >= threshold || ue.DLCount.Get() >= threshold
+}
+
+// ResetNasCounts takes a new NAS security context into use
+func (ue *AmfUe) ResetNasCounts() {
+	ue.ULCount.Set(0, 0)
+	ue.DLCount.Set(0, 0)
+	ue.ULCountWindow.Reset()
+	ue.NasCountRekeyingRequired = false
+	ue.NasCountRekeyingRequested = false
+}
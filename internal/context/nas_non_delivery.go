@@ -0,0 +1,136 @@
+package context
+
+import (
+	"bytes"
+
+	"github.com/free5gc/ngap/ngapType"
+)
+
+const (
+	// MaxNumOfUndeliveredNasPdu bounds the downlink NAS PDUs kept per UE for retransmission
+	MaxNumOfUndeliveredNasPdu = 8
+	// MaxNumOfNasPduRetransmission is the number of times an undelivered downlink NAS PDU is sent again
+	MaxNumOfNasPduRetransmission = 1
+)
+
+// N1N2MessageOrigin identifies the N1N2 Message Transfer a downlink NAS PDU was sent for, so that the
+// requesting NF is notified if the NAS PDU cannot be delivered to the UE (TS 29.518 5.2.2.3.1)
+type N1N2MessageOrigin struct {
+	N1n2FailureTxfNotifURI string
+	N1n2MsgDataUri         string
+}
+
+type n1n2NasPdu struct {
+	nasPdu []byte
+	origin N1N2MessageOrigin
+}
+
+// UndeliveredNasPdu is a downlink NAS PDU the NG-RAN node returned in a NAS Non Delivery Indication
+// (TS 38.413 8.6.4). It is buffered until the procedure which prevented its delivery is over.
+type UndeliveredNasPdu struct {
+	NasPdu          []byte
+	Cause           ngapType.Cause
+	Procedure       OnGoingProcedure
+	Origin          *N1N2MessageOrigin // nil if the NAS PDU was not sent for an N1N2 Message Transfer
+	Retransmissions int
+
+	pending bool // waiting for the end of Procedure to be sent again
+}
+
+// RecordN1N2NasPdu remembers the origin of a NAS PDU sent to the UE for an N1N2 Message Transfer, only the
+// latest MaxNumOfUndeliveredNasPdu NAS PDUs are remembered
+func (ue *AmfUe) RecordN1N2NasPdu(nasPdu []byte, origin N1N2MessageOrigin) {
+	ue.nasNonDeliveryMu.Lock()
+	defer ue.nasNonDeliveryMu.Unlock()
+
+	ue.n1n2NasPduList = append(ue.n1n2NasPduList, n1n2NasPdu{nasPdu: nasPdu, origin: origin})
+	if len(ue.n1n2NasPduList) > MaxNumOfUndeliveredNasPdu {
+		ue.n1n2NasPduList = ue.n1n2NasPduList[len(ue.n1n2NasPduList)-MaxNumOfUndeliveredNasPdu:]
+	}
+}
+
+// N1N2MessageOriginOf returns the N1N2 Message Transfer the NAS PDU was sent for, nil if it was originated
+// by the AMF or is no longer remembered
+func (ue *AmfUe) N1N2MessageOriginOf(nasPdu []byte) *N1N2MessageOrigin {
+	ue.nasNonDeliveryMu.Lock()
+	defer ue.nasNonDeliveryMu.Unlock()
+
+	return ue.n1n2MessageOriginOf(nasPdu)
+}
+
+func (ue *AmfUe) n1n2MessageOriginOf(nasPdu []byte) *N1N2MessageOrigin {
+	for i := len(ue.n1n2NasPduList) - 1; i >= 0; i-- {
+		if bytes.Equal(ue.n1n2NasPduList[i].nasPdu, nasPdu) {
+			origin := ue.n1n2NasPduList[i].origin
+			return &origin
+		}
+	}
+	return nil
+}
+
+// BufferUndeliveredNasPdu buffers the NAS PDU for retransmission at the end of the procedure. The NAS PDU is
+// not buffered, and false is returned, if it was already sent again MaxNumOfNasPduRetransmission times or if
+// MaxNumOfUndeliveredNasPdu NAS PDUs are already waiting for retransmission.
+func (ue *AmfUe) BufferUndeliveredNasPdu(nasPdu []byte, cause ngapType.Cause, procedure OnGoingProcedure) (
+	*UndeliveredNasPdu, bool,
+) {
+	ue.nasNonDeliveryMu.Lock()
+	defer ue.nasNonDeliveryMu.Unlock()
+
+	numOfPending := 0
+	for i, undelivered := range ue.undeliveredNasPduList {
+		if !bytes.Equal(undelivered.NasPdu, nasPdu) {
+			if undelivered.pending {
+				numOfPending++
+			}
+			continue
+		}
+		undelivered.Cause = cause
+		undelivered.Procedure = procedure
+		if undelivered.Retransmissions >= MaxNumOfNasPduRetransmission {
+			ue.undeliveredNasPduList = append(ue.undeliveredNasPduList[:i], ue.undeliveredNasPduList[i+1:]...)
+			return undelivered, false
+		}
+		undelivered.pending = true
+		return undelivered, true
+	}
+
+	undelivered := &UndeliveredNasPdu{
+		NasPdu:    nasPdu,
+		Cause:     cause,
+		Procedure: procedure,
+		Origin:    ue.n1n2MessageOriginOf(nasPdu),
+		pending:   true,
+	}
+	if numOfPending >= MaxNumOfUndeliveredNasPdu {
+		return undelivered, false
+	}
+	// the NAS PDUs already sent again are kept to detect their repeated non delivery, the oldest one makes
+	// room for the new one
+	if len(ue.undeliveredNasPduList) >= MaxNumOfUndeliveredNasPdu {
+		for i, buffered := range ue.undeliveredNasPduList {
+			if !buffered.pending {
+				ue.undeliveredNasPduList = append(ue.undeliveredNasPduList[:i], ue.undeliveredNasPduList[i+1:]...)
+				break
+			}
+		}
+	}
+	ue.undeliveredNasPduList = append(ue.undeliveredNasPduList, undelivered)
+	return undelivered, true
+}
+
+// TakeUndeliveredNasPdus returns the NAS PDUs waiting for the end of the procedure, in the order they were
+// sent, and accounts them as sent again
+func (ue *AmfUe) TakeUndeliveredNasPdus(procedure OnGoingProcedure) (undeliveredList []*UndeliveredNasPdu) {
+	ue.nasNonDeliveryMu.Lock()
+	defer ue.nasNonDeliveryMu.Unlock()
+
+	for _, undelivered := range ue.undeliveredNasPduList {
+		if undelivered.pending && undelivered.Procedure == procedure {
+			undelivered.pending = false
+			undelivered.Retransmissions++
+			undeliveredList = append(undeliveredList, undelivered)
+		}
+	}
+	return undeliveredList
+}
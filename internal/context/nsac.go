@@ -0,0 +1,102 @@
+package context
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	business_metrics "github.com/free5gc/amf/internal/metrics/business"
+	"github.com/free5gc/amf/pkg/factory"
+	"github.com/free5gc/openapi/models"
+)
+
+// LocalNsacf is the stand-in of the NSACF in the AMF, it counts the UEs registered to each S-NSSAI subject to
+// Network Slice Admission Control against the quotas of the configuration (TS 23.502 4.2.11.2)
+type LocalNsacf struct {
+	mu          sync.Mutex
+	maxNumOfUes map[string]int
+	ues         map[string]map[string]struct{} // SUPIs of the UEs admitted to each S-NSSAI
+}
+
+func NewLocalNsacf(quotas []factory.NsacQuota) *LocalNsacf {
+	nsacf := &LocalNsacf{
+		maxNumOfUes: make(map[string]int),
+		ues:         make(map[string]map[string]struct{}),
+	}
+	for _, quota := range quotas {
+		key := nsacSnssaiKey(*quota.Snssai)
+		nsacf.maxNumOfUes[key] = quota.MaxNumOfUes
+		nsacf.ues[key] = make(map[string]struct{})
+		business_metrics.SetNsacRegisteredUeGauge(key, 0)
+	}
+	return nsacf
+}
+
+// SubjectToNsac returns true if a quota is configured for the S-NSSAI
+func (n *LocalNsacf) SubjectToNsac(snssai models.Snssai) bool {
+	_, ok := n.maxNumOfUes[nsacSnssaiKey(snssai)]
+	return ok
+}
+
+// Increase admits the UE to the S-NSSAI, false is returned if the maximum number of UEs is reached. A UE already
+// admitted is counted once.
+func (n *LocalNsacf) Increase(supi string, snssai models.Snssai) bool {
+	key := nsacSnssaiKey(snssai)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	maxNumOfUes, ok := n.maxNumOfUes[key]
+	if !ok {
+		return true
+	}
+	ues := n.ues[key]
+	if _, ok = ues[supi]; ok {
+		return true
+	}
+	if len(ues) >= maxNumOfUes {
+		return false
+	}
+	ues[supi] = struct{}{}
+	business_metrics.SetNsacRegisteredUeGauge(key, len(ues))
+	return true
+}
+
+// Decrease removes the UE from the UEs admitted to the S-NSSAI
+func (n *LocalNsacf) Decrease(supi string, snssai models.Snssai) {
+	key := nsacSnssaiKey(snssai)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	ues, ok := n.ues[key]
+	if !ok {
+		return
+	}
+	if _, ok = ues[supi]; !ok {
+		return
+	}
+	delete(ues, supi)
+	business_metrics.SetNsacRegisteredUeGauge(key, len(ues))
+}
+
+// NumOfUes returns the number of UEs admitted to the S-NSSAI
+func (n *LocalNsacf) NumOfUes(snssai models.Snssai) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.ues[nsacSnssaiKey(snssai)])
+}
+
+// AddNsacAdmittedSnssai records an S-NSSAI the UE is admitted to, it is released when the UE deregisters
+func (ue *AmfUe) AddNsacAdmittedSnssai(snssai models.Snssai) {
+	if !snssaiListContains(ue.NsacAdmittedNssai, snssai) {
+		ue.NsacAdmittedNssai = append(ue.NsacAdmittedNssai, snssai)
+	}
+}
+
+// RemoveNsacAdmittedSnssai takes the S-NSSAI out of the S-NSSAIs the UE is admitted to
+func (ue *AmfUe) RemoveNsacAdmittedSnssai(snssai models.Snssai) {
+	ue.NsacAdmittedNssai = removeSnssai(ue.NsacAdmittedNssai, snssai)
+}
+
+func nsacSnssaiKey(snssai models.Snssai) string {
+	return fmt.Sprintf("%02x%s", snssai.Sst, strings.ToLower(snssai.Sd))
+}
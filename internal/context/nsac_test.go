@@ -0,0 +1,34 @@
+package context
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/free5gc/amf/pkg/factory"
+	"github.com/free5gc/openapi/models"
+)
+
+func TestLocalNsacf(t *testing.T) {
+	quotaSnssai := models.Snssai{Sst: 1, Sd: "0A0B0C"}
+	freeSnssai := models.Snssai{Sst: 2}
+	nsacf := NewLocalNsacf([]factory.NsacQuota{
+		{Snssai: &quotaSnssai, MaxNumOfUes: 2},
+	})
+
+	require.True(t, nsacf.SubjectToNsac(models.Snssai{Sst: 1, Sd: "0a0b0c"}))
+	require.False(t, nsacf.SubjectToNsac(freeSnssai))
+	require.True(t, nsacf.Increase("imsi-208930000000001", freeSnssai))
+
+	require.True(t, nsacf.Increase("imsi-208930000000001", quotaSnssai))
+	require.True(t, nsacf.Increase("imsi-208930000000001", quotaSnssai), "an admitted UE is counted once")
+	require.True(t, nsacf.Increase("imsi-208930000000002", quotaSnssai))
+	require.Equal(t, 2, nsacf.NumOfUes(quotaSnssai))
+	require.False(t, nsacf.Increase("imsi-208930000000003", quotaSnssai))
+
+	nsacf.Decrease("imsi-208930000000003", quotaSnssai)
+	require.Equal(t, 2, nsacf.NumOfUes(quotaSnssai))
+	nsacf.Decrease("imsi-208930000000001", quotaSnssai)
+	require.Equal(t, 1, nsacf.NumOfUes(quotaSnssai))
+	require.True(t, nsacf.Increase("imsi-208930000000003", quotaSnssai))
+}
@@ -0,0 +1,111 @@
+package context
+
+import (
+	"github.com/free5gc/openapi"
+	"github.com/free5gc/openapi/models"
+)
+
+// NssaaContext is the state of the Network Slice-Specific Authentication and Authorization of an S-NSSAI,
+// TS 23.502 4.2.9
+type NssaaContext struct {
+	Snssai    models.Snssai
+	AuthCtxId string // allocated by the NSSAAF with the first EAP message relayed
+	Status    models.AuthStatus
+}
+
+// NssaaRequired returns true if the S-NSSAI is subject to NSSAA according to the subscription
+func (ue *AmfUe) NssaaRequired(snssai models.Snssai) bool {
+	return snssaiListContains(ue.NssaaRequiredNssai, snssai)
+}
+
+// InPendingNssai returns true if the S-NSSAI is waiting for the completion of its NSSAA
+func (ue *AmfUe) InPendingNssai(snssai models.Snssai) bool {
+	return snssaiListContains(ue.PendingNssai, snssai)
+}
+
+// NssaaContextOf returns the NSSAA context of the S-NSSAI, it is created with a PENDING status if none exists
+func (ue *AmfUe) NssaaContextOf(snssai models.Snssai) *NssaaContext {
+	ue.nssaaMu.Lock()
+	defer ue.nssaaMu.Unlock()
+	for _, nssaaCtx := range ue.nssaaContexts {
+		if openapi.SnssaiEqualFold(nssaaCtx.Snssai, snssai) {
+			return nssaaCtx
+		}
+	}
+	nssaaCtx := &NssaaContext{
+		Snssai: snssai,
+		Status: models.AuthStatus_PENDING,
+	}
+	ue.nssaaContexts = append(ue.nssaaContexts, nssaaCtx)
+	return nssaaCtx
+}
+
+// NssaaStatus returns the result of the last NSSAA of the S-NSSAI, false if it has never been authenticated
+func (ue *AmfUe) NssaaStatus(snssai models.Snssai) (models.AuthStatus, bool) {
+	ue.nssaaMu.Lock()
+	defer ue.nssaaMu.Unlock()
+	for _, nssaaCtx := range ue.nssaaContexts {
+		if openapi.SnssaiEqualFold(nssaaCtx.Snssai, snssai) {
+			return nssaaCtx.Status, true
+		}
+	}
+	return "", false
+}
+
+// RemovePendingSnssai takes the S-NSSAI out of the pending NSSAI once its NSSAA is completed
+func (ue *AmfUe) RemovePendingSnssai(snssai models.Snssai) {
+	ue.PendingNssai = removeSnssai(ue.PendingNssai, snssai)
+}
+
+// RemoveAllowedSnssai takes the S-NSSAI out of the allowed NSSAI of the access type
+func (ue *AmfUe) RemoveAllowedSnssai(snssai models.Snssai, anType models.AccessType) {
+	allowedNssai := ue.AllowedNssai[anType][:0]
+	for _, allowedSnssai := range ue.AllowedNssai[anType] {
+		if !openapi.SnssaiEqualFold(*allowedSnssai.AllowedSnssai, snssai) {
+			allowedNssai = append(allowedNssai, allowedSnssai)
+		}
+	}
+	ue.AllowedNssai[anType] = allowedNssai
+}
+
+// AddNssaaRejectedSnssai records an S-NSSAI whose NSSAA failed or was revoked, it is indicated in the rejected
+// NSSAI with cause "S-NSSAI not available due to the failed or revoked NSSAA"
+func (ue *AmfUe) AddNssaaRejectedSnssai(snssai models.Snssai) {
+	if !snssaiListContains(ue.NssaaRejectedNssai, snssai) {
+		ue.NssaaRejectedNssai = append(ue.NssaaRejectedNssai, snssai)
+	}
+}
+
+// RemoveNssaaRejectedSnssai takes the S-NSSAI out of the rejected NSSAI after a successful re-authentication
+func (ue *AmfUe) RemoveNssaaRejectedSnssai(snssai models.Snssai) {
+	ue.NssaaRejectedNssai = removeSnssai(ue.NssaaRejectedNssai, snssai)
+}
+
+func (ue *AmfUe) StopT3575() {
+	if ue.T3575 == nil {
+		return
+	}
+
+	ue.GmmLog.Infof("Stop T3575 timer")
+	ue.T3575.Stop()
+	ue.T3575 = nil // clear the timer
+}
+
+func snssaiListContains(snssaiList []models.Snssai, snssai models.Snssai) bool {
+	for _, s := range snssaiList {
+		if openapi.SnssaiEqualFold(s, snssai) {
+			return true
+		}
+	}
+	return false
+}
+
+func removeSnssai(snssaiList []models.Snssai, snssai models.Snssai) []models.Snssai {
+	var remaining []models.Snssai
+	for _, s := range snssaiList {
+		if !openapi.SnssaiEqualFold(s, snssai) {
+			remaining = append(remaining, s)
+		}
+	}
+	return remaining
+}
@@ -0,0 +1,82 @@
+package context
+
+// MaxNumOfPositioningSession bounds the positioning sessions of a UE, the routing identifier is one octet
+const MaxNumOfPositioningSession = 255
+
+// PositioningSession is an LCS session between the UE and an LMF (TS 23.273 6.11.1). The AMF carries the routing
+// identifier of the session in the Additional Information of the DL NAS Transport of the LPP messages from the LMF,
+// the UE returns it with its LPP messages so that they are routed to the same LMF (TS 24.501 5.4.5.2.3, 5.4.5.3.1).
+type PositioningSession struct {
+	RoutingId        uint8
+	LmfId            string
+	LcsCorrelationId string
+}
+
+// RoutingInformation returns the Additional Information value identifying the session, nil for a nil session
+func (session *PositioningSession) RoutingInformation() []byte {
+	if session == nil {
+		return nil
+	}
+	return []byte{session.RoutingId}
+}
+
+// NewPositioningSession returns the positioning session of the LMF for the LCS correlation, it is created if it does
+// not exist yet. nil is returned if the UE has MaxNumOfPositioningSession sessions already.
+func (ue *AmfUe) NewPositioningSession(lmfId, lcsCorrelationId string) *PositioningSession {
+	ue.positioningMu.Lock()
+	defer ue.positioningMu.Unlock()
+
+	var found *PositioningSession
+	used := make(map[uint8]bool)
+	ue.PositioningSessionList.Range(func(key, value interface{}) bool {
+		session := value.(*PositioningSession)
+		if session.LmfId == lmfId && session.LcsCorrelationId == lcsCorrelationId {
+			found = session
+			return false
+		}
+		used[session.RoutingId] = true
+		return true
+	})
+	if found != nil {
+		return found
+	}
+
+	for routingId := 1; routingId <= MaxNumOfPositioningSession; routingId++ {
+		if !used[uint8(routingId)] {
+			session := &PositioningSession{
+				RoutingId:        uint8(routingId),
+				LmfId:            lmfId,
+				LcsCorrelationId: lcsCorrelationId,
+			}
+			ue.PositioningSessionList.Store(session.RoutingId, session)
+			return session
+		}
+	}
+	return nil
+}
+
+// RemovePositioningSessions removes the positioning sessions of the LMF so that their routing identifiers can be
+// reused, all the sessions of the UE are removed for an empty LMF ID
+func (ue *AmfUe) RemovePositioningSessions(lmfId string) {
+	ue.positioningMu.Lock()
+	defer ue.positioningMu.Unlock()
+
+	ue.PositioningSessionList.Range(func(key, value interface{}) bool {
+		if session := value.(*PositioningSession); lmfId == "" || session.LmfId == lmfId {
+			ue.PositioningSessionList.Delete(key)
+		}
+		return true
+	})
+}
+
+// PositioningSessionFindByRoutingInformation finds the session identified by the Additional Information the UE
+// sent with an LPP message
+func (ue *AmfUe) PositioningSessionFindByRoutingInformation(routingInformation []byte) (*PositioningSession, bool) {
+	if len(routingInformation) != 1 {
+		return nil, false
+	}
+	if value, ok := ue.PositioningSessionList.Load(routingInformation[0]); ok {
+		return value.(*PositioningSession), true
+	}
+	return nil, false
+}
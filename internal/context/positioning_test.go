@@ -0,0 +1,32 @@
+package context
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPositioningSessionReuse(t *testing.T) {
+	ue := &AmfUe{}
+	for i := 1; i <= MaxNumOfPositioningSession; i++ {
+		session := ue.NewPositioningSession("lmf-1", fmt.Sprintf("lcs-%d", i))
+		require.NotNil(t, session)
+		require.Equal(t, uint8(i), session.RoutingId)
+	}
+	// no routing identifier is left
+	require.Nil(t, ue.NewPositioningSession("lmf-2", "lcs-1"))
+
+	// the routing identifiers of the sessions released by the LMF are reused
+	ue.RemovePositioningSessions("lmf-1")
+	session := ue.NewPositioningSession("lmf-2", "lcs-1")
+	require.NotNil(t, session)
+	require.Equal(t, uint8(1), session.RoutingId)
+	_, ok := ue.PositioningSessionFindByRoutingInformation([]byte{2})
+	require.False(t, ok)
+
+	// all the sessions end with the NAS signalling connection
+	ue.RemovePositioningSessions("")
+	_, ok = ue.PositioningSessionFindByRoutingInformation(session.RoutingInformation())
+	require.False(t, ok)
+}
@@ -3,6 +3,7 @@ package context
 import (
 	"encoding/hex"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/mohae/deepcopy"
@@ -37,6 +38,10 @@ type RanUe struct {
 	SuccessPduSessionId []int32
 	SourceUe            *RanUe
 	TargetUe            *RanUe
+	/* TRELOCprep (source side, HandoverRequest -> HandoverRequestAcknowledge) */
+	TRelocPrep *Timer
+	/* TRELOCoverall (source side, HandoverCommand -> HandoverNotify) */
+	TRelocOverall *Timer
 
 	/* UserLocation*/
 	Tai      models.Tai
@@ -50,7 +55,8 @@ type RanUe struct {
 	/* Related Context*/
 	AmfUe        *AmfUe
 	Ran          *AmfRan
-	HoldingAmfUe *AmfUe // The AmfUe that is already exist (CM-Idle, Re-Registration)
+	tnla         atomic.Pointer[TNLAssociation] // UE TNLA binding
+	HoldingAmfUe *AmfUe                         // The AmfUe that is already exist (CM-Idle, Re-Registration)
 
 	/* Routing ID */
 	RoutingID string
@@ -79,6 +85,9 @@ func (ranUe *RanUe) Remove() error {
 	if ran == nil {
 		return fmt.Errorf("RanUe not found in Ran")
 	}
+	ranUe.StopTRelocPrep()
+	ranUe.StopTRelocOverall()
+	ranUe.UnbindTNLAssociation()
 	if ranUe.AmfUe != nil {
 		ranUe.AmfUe.DetachRanUe(ran.AnType)
 		ranUe.DetachAmfUe()
@@ -110,6 +119,9 @@ func (ranUe *RanUe) SwitchToRan(newRan *AmfRan, ranUeNgapId int64) error {
 	// remove ranUe from oldRan
 	oldRan.RanUeList.Delete(ranUe.RanUeNgapId)
 
+	// the UE TNLA binding is towards the old RAN
+	ranUe.UnbindTNLAssociation()
+
 	// add ranUe to newRan
 	newRan.RanUeList.Store(ranUeNgapId, ranUe)
 
@@ -191,8 +203,8 @@ func (ranUe *RanUe) UpdateLocation(userLocationInformation *ngapType.UserLocatio
 			if ranUe.AmfUe.Tai != ranUe.Tai {
 				ranUe.AmfUe.LocationChanged = true
 			}
-			ranUe.AmfUe.Location = deepcopy.Copy(ranUe.Location).(models.UserLocation)
-			ranUe.AmfUe.Tai = deepcopy.Copy(*ranUe.AmfUe.Location.EutraLocation.Tai).(models.Tai)
+			ranUe.AmfUe.SetLocation(deepcopy.Copy(ranUe.Location).(models.UserLocation))
+			ranUe.AmfUe.Tai = deepcopy.Copy(*ranUe.Location.EutraLocation.Tai).(models.Tai)
 		}
 	case ngapType.UserLocationInformationPresentUserLocationInformationNR:
 		locationInfoNR := userLocationInformation.UserLocationInformationNR
@@ -228,8 +240,8 @@ func (ranUe *RanUe) UpdateLocation(userLocationInformation *ngapType.UserLocatio
 			if ranUe.AmfUe.Tai != ranUe.Tai {
 				ranUe.AmfUe.LocationChanged = true
 			}
-			ranUe.AmfUe.Location = deepcopy.Copy(ranUe.Location).(models.UserLocation)
-			ranUe.AmfUe.Tai = deepcopy.Copy(*ranUe.AmfUe.Location.NrLocation.Tai).(models.Tai)
+			ranUe.AmfUe.SetLocation(deepcopy.Copy(ranUe.Location).(models.UserLocation))
+			ranUe.AmfUe.Tai = deepcopy.Copy(*ranUe.Location.NrLocation.Tai).(models.Tai)
 		}
 	case ngapType.UserLocationInformationPresentUserLocationInformationN3IWF:
 		locationInfoN3IWF := userLocationInformation.UserLocationInformationN3IWF
@@ -254,7 +266,7 @@ func (ranUe *RanUe) UpdateLocation(userLocationInformation *ngapType.UserLocatio
 		ranUe.Tai = deepcopy.Copy(*ranUe.Location.N3gaLocation.N3gppTai).(models.Tai)
 
 		if ranUe.AmfUe != nil {
-			ranUe.AmfUe.Location = deepcopy.Copy(ranUe.Location).(models.UserLocation)
+			ranUe.AmfUe.SetLocation(deepcopy.Copy(ranUe.Location).(models.UserLocation))
 			ranUe.AmfUe.Tai = *ranUe.Location.N3gaLocation.N3gppTai
 		}
 	case ngapType.UserLocationInformationPresentChoiceExtensions:
@@ -284,7 +296,7 @@ func (ranUe *RanUe) UpdateLocation(userLocationInformation *ngapType.UserLocatio
 			ranUe.Tai = deepcopy.Copy(*ranUe.Location.N3gaLocation.N3gppTai).(models.Tai)
 
 			if ranUe.AmfUe != nil {
-				ranUe.AmfUe.Location = deepcopy.Copy(ranUe.Location).(models.UserLocation)
+				ranUe.AmfUe.SetLocation(deepcopy.Copy(ranUe.Location).(models.UserLocation))
 				ranUe.AmfUe.Tai = *ranUe.Location.N3gaLocation.N3gppTai
 			}
 		case ngapType.ProtocolIEIDUserLocationInformationTWIF:
@@ -313,7 +325,7 @@ func (ranUe *RanUe) UpdateLocation(userLocationInformation *ngapType.UserLocatio
 			ranUe.Tai = deepcopy.Copy(*ranUe.Location.N3gaLocation.N3gppTai).(models.Tai)
 
 			if ranUe.AmfUe != nil {
-				ranUe.AmfUe.Location = deepcopy.Copy(ranUe.Location).(models.UserLocation)
+				ranUe.AmfUe.SetLocation(deepcopy.Copy(ranUe.Location).(models.UserLocation))
 				ranUe.AmfUe.Tai = *ranUe.Location.N3gaLocation.N3gppTai
 			}
 
@@ -324,3 +336,23 @@ func (ranUe *RanUe) UpdateLocation(userLocationInformation *ngapType.UserLocatio
 	case ngapType.UserLocationInformationPresentNothing:
 	}
 }
+
+func (ranUe *RanUe) StopTRelocPrep() {
+	if ranUe.TRelocPrep == nil {
+		return
+	}
+
+	ranUe.Log.Infof("Stop TRELOCprep timer")
+	ranUe.TRelocPrep.Stop()
+	ranUe.TRelocPrep = nil // clear the timer
+}
+
+func (ranUe *RanUe) StopTRelocOverall() {
+	if ranUe.TRelocOverall == nil {
+		return
+	}
+
+	ranUe.Log.Infof("Stop TRELOCoverall timer")
+	ranUe.TRelocOverall.Stop()
+	ranUe.TRelocOverall = nil // clear the timer
+}
@@ -0,0 +1,131 @@
+package context
+
+import (
+	"fmt"
+
+	"github.com/free5gc/amf/pkg/factory"
+	"github.com/free5gc/nas/nasConvert"
+	"github.com/free5gc/nas/security"
+	"github.com/free5gc/openapi"
+	"github.com/free5gc/openapi/models"
+)
+
+// SecurityAlgorithmPolicy is the order of the NAS security algorithms selected for the UEs of a PLMN, requesting an
+// S-NSSAI or registering over an access type, a nil PLMN ID or S-NSSAI and an empty access type match all the UEs
+type SecurityAlgorithmPolicy struct {
+	PlmnId         *models.PlmnId
+	Snssai         *models.Snssai
+	AccessType     models.AccessType
+	IntegrityOrder []uint8 // slice of security.AlgIntegrityXXX
+	CipheringOrder []uint8 // slice of security.AlgCipheringXXX
+}
+
+func getSecurityAlgorithmPolicies(algorithmPolicies []factory.SecurityAlgorithmPolicy) (
+	policies []SecurityAlgorithmPolicy,
+) {
+	for _, algorithmPolicy := range algorithmPolicies {
+		policies = append(policies, SecurityAlgorithmPolicy{
+			PlmnId:         algorithmPolicy.PlmnId,
+			Snssai:         algorithmPolicy.Snssai,
+			AccessType:     algorithmPolicy.AccessType,
+			IntegrityOrder: getIntAlgOrder(algorithmPolicy.IntegrityOrder),
+			CipheringOrder: getEncAlgOrder(algorithmPolicy.CipheringOrder),
+		})
+	}
+	return
+}
+
+// specificity is the number of criteria of the policy, -1 if the policy does not match the UE
+func (p *SecurityAlgorithmPolicy) specificity(plmnId models.PlmnId, snssaiList []models.Snssai,
+	anType models.AccessType,
+) int {
+	specificity := 0
+	if p.PlmnId != nil {
+		if *p.PlmnId != plmnId {
+			return -1
+		}
+		specificity++
+	}
+	if p.Snssai != nil {
+		found := false
+		for _, snssai := range snssaiList {
+			if openapi.SnssaiEqualFold(*p.Snssai, snssai) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return -1
+		}
+		specificity++
+	}
+	if p.AccessType != "" {
+		if p.AccessType != anType {
+			return -1
+		}
+		specificity++
+	}
+	return specificity
+}
+
+// SecurityAlgorithmOrder returns the integrity and ciphering algorithm orders of the UE registering over the access,
+// from the most specific policy matching the UE or the first one configured among the most specific ones. The orders
+// of the AMF apply if no policy matches.
+func (context *AMFContext) SecurityAlgorithmOrder(ue *AmfUe, anType models.AccessType) (
+	intOrder []uint8, encOrder []uint8,
+) {
+	if ue.UnauthenticatedEmergency() {
+		// TS 33.501 10.2.2, no keys are available for an unauthenticated UE
+		return []uint8{security.AlgIntegrity128NIA0}, []uint8{security.AlgCiphering128NEA0}
+	}
+
+	intOrder = context.SecurityAlgorithm.IntegrityOrder
+	encOrder = context.SecurityAlgorithm.CipheringOrder
+	if len(context.SecurityAlgorithm.Policies) == 0 {
+		return intOrder, encOrder
+	}
+
+	snssaiList := ue.securityAlgorithmSnssaiList(anType)
+	best := -1
+	for i := range context.SecurityAlgorithm.Policies {
+		policy := &context.SecurityAlgorithm.Policies[i]
+		if specificity := policy.specificity(ue.PlmnId, snssaiList, anType); specificity > best {
+			best = specificity
+			intOrder, encOrder = policy.IntegrityOrder, policy.CipheringOrder
+		}
+	}
+	return intOrder, encOrder
+}
+
+// securityAlgorithmSnssaiList returns the S-NSSAIs the UE requests in the Registration Request, or its allowed
+// S-NSSAIs over the access if the requested NSSAI is not known yet
+func (ue *AmfUe) securityAlgorithmSnssaiList(anType models.AccessType) (snssaiList []models.Snssai) {
+	if ue.RegistrationRequest != nil && ue.RegistrationRequest.RequestedNSSAI != nil {
+		requestedNssai, err := nasConvert.RequestedNssaiToModels(ue.RegistrationRequest.RequestedNSSAI)
+		if err == nil {
+			for _, requestedSnssai := range requestedNssai {
+				if requestedSnssai.ServingSnssai != nil {
+					snssaiList = append(snssaiList, *requestedSnssai.ServingSnssai)
+				}
+			}
+			return snssaiList
+		}
+		ue.GmmLog.Warnf("Decode RequestedNSSAI failed: %+v", err)
+	}
+	for _, allowedSnssai := range ue.AllowedNssai[anType] {
+		if allowedSnssai.AllowedSnssai != nil {
+			snssaiList = append(snssaiList, *allowedSnssai.AllowedSnssai)
+		}
+	}
+	return snssaiList
+}
+
+// IntegrityAlgorithmName returns the name of the integrity algorithm, e.g. NIA2
+func IntegrityAlgorithmName(alg uint8) string {
+	return fmt.Sprintf("NIA%d", alg)
+}
+
+// CipheringAlgorithmName returns the name of the ciphering algorithm, e.g. NEA2
+func CipheringAlgorithmName(alg uint8) string {
+	return fmt.Sprintf("NEA%d", alg)
+}
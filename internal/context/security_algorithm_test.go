@@ -0,0 +1,141 @@
+package context
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/free5gc/amf/internal/logger"
+	"github.com/free5gc/amf/pkg/factory"
+	"github.com/free5gc/nas/nasMessage"
+	"github.com/free5gc/nas/nasType"
+	"github.com/free5gc/nas/security"
+	"github.com/free5gc/openapi/models"
+)
+
+func TestSecurityAlgorithmOrder(t *testing.T) {
+	testPlmn := models.PlmnId{Mcc: "001", Mnc: "01"}
+	plmn := models.PlmnId{Mcc: "208", Mnc: "93"}
+	enterpriseSnssai := models.Snssai{Sst: 1, Sd: "0a0b0c"}
+
+	amfSelf := &AMFContext{
+		SecurityAlgorithm: SecurityAlgorithm{
+			IntegrityOrder: []uint8{security.AlgIntegrity128NIA2, security.AlgIntegrity128NIA1},
+			CipheringOrder: []uint8{security.AlgCiphering128NEA2, security.AlgCiphering128NEA1},
+			Policies: getSecurityAlgorithmPolicies([]factory.SecurityAlgorithmPolicy{
+				{
+					PlmnId:         &testPlmn,
+					IntegrityOrder: []string{"NIA1"},
+					CipheringOrder: []string{"NEA0"},
+					TestPlmn:       true,
+				},
+				{
+					Snssai:         &enterpriseSnssai,
+					IntegrityOrder: []string{"NIA2"},
+					CipheringOrder: []string{"NEA2"},
+				},
+				{
+					AccessType:     models.AccessType_NON_3_GPP_ACCESS,
+					IntegrityOrder: []string{"NIA3"},
+					CipheringOrder: []string{"NEA3"},
+				},
+				{
+					Snssai:         &enterpriseSnssai,
+					AccessType:     models.AccessType_NON_3_GPP_ACCESS,
+					IntegrityOrder: []string{"NIA2", "NIA3"},
+					CipheringOrder: []string{"NEA2", "NEA3"},
+				},
+				{
+					AccessType:     models.AccessType_NON_3_GPP_ACCESS,
+					IntegrityOrder: []string{"NIA1"},
+					CipheringOrder: []string{"NEA1"},
+				},
+			}),
+		},
+	}
+
+	requestedNssai := nasType.NewRequestedNSSAI(nasMessage.RegistrationRequestRequestedNSSAIType)
+	requestedNssai.SetLen(5)
+	requestedNssai.SetSNSSAIValue([]uint8{0x04, 0x01, 0x0a, 0x0b, 0x0c})
+
+	testCases := []struct {
+		name                string
+		ue                  *AmfUe
+		anType              models.AccessType
+		integrityOrder      []uint8
+		cipheringOrder      []uint8
+		emergencyRegistered bool
+	}{
+		{
+			name:           "no policy matches",
+			ue:             &AmfUe{PlmnId: plmn},
+			anType:         models.AccessType__3_GPP_ACCESS,
+			integrityOrder: []uint8{security.AlgIntegrity128NIA2, security.AlgIntegrity128NIA1},
+			cipheringOrder: []uint8{security.AlgCiphering128NEA2, security.AlgCiphering128NEA1},
+		},
+		{
+			name:           "test PLMN",
+			ue:             &AmfUe{PlmnId: testPlmn},
+			anType:         models.AccessType__3_GPP_ACCESS,
+			integrityOrder: []uint8{security.AlgIntegrity128NIA1},
+			cipheringOrder: []uint8{security.AlgCiphering128NEA0},
+		},
+		{
+			name: "requested S-NSSAI",
+			ue: &AmfUe{PlmnId: plmn, RegistrationRequest: &nasMessage.RegistrationRequest{
+				RequestedNSSAI: requestedNssai,
+			}},
+			anType:         models.AccessType__3_GPP_ACCESS,
+			integrityOrder: []uint8{security.AlgIntegrity128NIA2},
+			cipheringOrder: []uint8{security.AlgCiphering128NEA2},
+		},
+		{
+			name: "allowed S-NSSAI",
+			ue: &AmfUe{PlmnId: plmn, AllowedNssai: map[models.AccessType][]models.AllowedSnssai{
+				models.AccessType__3_GPP_ACCESS: {{AllowedSnssai: &enterpriseSnssai}},
+			}},
+			anType:         models.AccessType__3_GPP_ACCESS,
+			integrityOrder: []uint8{security.AlgIntegrity128NIA2},
+			cipheringOrder: []uint8{security.AlgCiphering128NEA2},
+		},
+		{
+			name:           "first policy of the access type",
+			ue:             &AmfUe{PlmnId: plmn},
+			anType:         models.AccessType_NON_3_GPP_ACCESS,
+			integrityOrder: []uint8{security.AlgIntegrity128NIA3},
+			cipheringOrder: []uint8{security.AlgCiphering128NEA3},
+		},
+		{
+			name: "most specific policy",
+			ue: &AmfUe{PlmnId: plmn, AllowedNssai: map[models.AccessType][]models.AllowedSnssai{
+				models.AccessType_NON_3_GPP_ACCESS: {{AllowedSnssai: &enterpriseSnssai}},
+			}},
+			anType:         models.AccessType_NON_3_GPP_ACCESS,
+			integrityOrder: []uint8{security.AlgIntegrity128NIA2, security.AlgIntegrity128NIA3},
+			cipheringOrder: []uint8{security.AlgCiphering128NEA2, security.AlgCiphering128NEA3},
+		},
+		{
+			name:                "unauthenticated emergency UE",
+			ue:                  &AmfUe{PlmnId: plmn, UnauthenticatedSupi: true},
+			anType:              models.AccessType__3_GPP_ACCESS,
+			integrityOrder:      []uint8{security.AlgIntegrity128NIA0},
+			cipheringOrder:      []uint8{security.AlgCiphering128NEA0},
+			emergencyRegistered: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.ue.GmmLog = logger.GmmLog
+			tc.ue.EmergencyRegistered = tc.emergencyRegistered
+			integrityOrder, cipheringOrder := amfSelf.SecurityAlgorithmOrder(tc.ue, tc.anType)
+			require.Equal(t, tc.integrityOrder, integrityOrder)
+			require.Equal(t, tc.cipheringOrder, cipheringOrder)
+		})
+	}
+}
+
+func TestSecurityAlgorithmName(t *testing.T) {
+	require.Equal(t, "NIA2", IntegrityAlgorithmName(security.AlgIntegrity128NIA2))
+	require.Equal(t, "NEA0", CipheringAlgorithmName(security.AlgCiphering128NEA0))
+}
@@ -0,0 +1,82 @@
+package context
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/free5gc/openapi/models"
+)
+
+// SOR header of the SOR transparent container, TS 24.501 9.11.3.51
+const (
+	SorDataTypeSteeringInformation uint8 = 0x00
+	SorDataTypeAcknowledgement     uint8 = 0x01
+	sorHeaderAckRequested          uint8 = 0x08
+)
+
+const (
+	sorMacLen     = 16
+	counterSorLen = 2
+)
+
+// SetSorInfo keeps the Steering of Roaming information provided by the UDM until it is sent to the UE
+// (TS 23.122 Annex C.2, C.4)
+func (ue *AmfUe) SetSorInfo(sorInfo *models.UdmSdmSorInfo) {
+	ue.SorInfo = sorInfo
+	ue.SorAckRequested = sorInfo != nil && sorInfo.AckInd
+	ue.SorProvisioningTime = nil
+	if sorInfo != nil {
+		ue.SorProvisioningTime = sorInfo.ProvisioningTime
+	}
+}
+
+// SorInfoSent discards the Steering of Roaming information once sent to the UE so that it is not sent again, the
+// acknowledgement of the UE is still expected if the UDM requested it
+func (ue *AmfUe) SorInfoSent() {
+	ue.SorInfo = nil
+}
+
+// SorTransparentContainer encodes the SOR transparent container of the Steering of Roaming information
+// provided by the UDM, nil is returned if there is none
+func (ue *AmfUe) SorTransparentContainer() ([]byte, error) {
+	sorInfo := ue.SorInfo
+	if sorInfo == nil {
+		return nil, nil
+	}
+	// the UDM may provide the container the AMF forwards as is
+	if sorInfo.SorTransparentContainer != "" {
+		container, err := base64.StdEncoding.DecodeString(sorInfo.SorTransparentContainer)
+		if err != nil {
+			return nil, fmt.Errorf("decode sorTransparentContainer failed: %+v", err)
+		}
+		return container, nil
+	}
+
+	sorMacIausf, err := hex.DecodeString(sorInfo.SorMacIausf)
+	if err != nil || len(sorMacIausf) != sorMacLen {
+		return nil, fmt.Errorf("invalid sorMacIausf[%s]", sorInfo.SorMacIausf)
+	}
+	counterSor, err := hex.DecodeString(sorInfo.Countersor)
+	if err != nil || len(counterSor) != counterSorLen {
+		return nil, fmt.Errorf("invalid countersor[%s]", sorInfo.Countersor)
+	}
+
+	// no list of preferred PLMN/access technology combinations is provided, the UE keeps its list
+	header := SorDataTypeSteeringInformation
+	if sorInfo.AckInd {
+		header |= sorHeaderAckRequested
+	}
+	container := []byte{header}
+	container = append(container, sorMacIausf...)
+	container = append(container, counterSor...)
+	return container, nil
+}
+
+// SorAckToModels returns the SOR-MAC-IUE of the acknowledgement sent by the UE in a SOR transparent container
+func SorAckToModels(container []byte) (string, error) {
+	if len(container) != 1+sorMacLen || container[0]&0x01 != SorDataTypeAcknowledgement {
+		return "", fmt.Errorf("NAS SOR Ack is not valid")
+	}
+	return hex.EncodeToString(container[1:]), nil
+}
@@ -0,0 +1,84 @@
+package context
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/free5gc/openapi/models"
+)
+
+func TestSorTransparentContainer(t *testing.T) {
+	sorMacIausf := "000102030405060708090a0b0c0d0e0f"
+	testCases := []struct {
+		name          string
+		sorInfo       *models.UdmSdmSorInfo
+		expected      []byte
+		ackRequested  bool
+		expectedError bool
+	}{
+		{
+			name: "No Steering of Roaming information",
+		},
+		{
+			name: "Acknowledgement requested",
+			sorInfo: &models.UdmSdmSorInfo{
+				AckInd:      true,
+				SorMacIausf: sorMacIausf,
+				Countersor:  "0102",
+			},
+			expected: []byte{
+				0x08, 0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+				0x01, 0x02,
+			},
+			ackRequested: true,
+		},
+		{
+			name: "Container provided by the UDM",
+			sorInfo: &models.UdmSdmSorInfo{
+				SorTransparentContainer: base64.StdEncoding.EncodeToString([]byte{0x00, 0x01, 0x02}),
+			},
+			expected: []byte{0x00, 0x01, 0x02},
+		},
+		{
+			name: "Invalid SOR-MAC-IAUSF",
+			sorInfo: &models.UdmSdmSorInfo{
+				SorMacIausf: "0001",
+				Countersor:  "0102",
+			},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ue := &AmfUe{}
+			ue.SetSorInfo(tc.sorInfo)
+			require.Equal(t, tc.ackRequested, ue.SorAckRequested)
+
+			container, err := ue.SorTransparentContainer()
+			if tc.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, container)
+		})
+	}
+}
+
+func TestSorAckToModels(t *testing.T) {
+	ack := []byte{0x01, 0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f}
+	sorMacIue, err := SorAckToModels(ack)
+	require.NoError(t, err)
+	require.Equal(t, "000102030405060708090a0b0c0d0e0f", sorMacIue)
+
+	// steering information is not an acknowledgement
+	ack[0] = 0x00
+	_, err = SorAckToModels(ack)
+	require.Error(t, err)
+
+	_, err = SorAckToModels(ack[:4])
+	require.Error(t, err)
+}
@@ -0,0 +1,157 @@
+package context
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// SUCI protection schemes (TS 33.501 C.1)
+const (
+	SuciNullScheme = "0"
+	SuciProfileA   = "1"
+	SuciProfileB   = "2"
+)
+
+// sizes of the ECIES parameters of the profiles A and B (TS 33.501 C.3.4)
+const (
+	eciesEncKeyLen    = 16
+	eciesIcbLen       = 16
+	eciesMacKeyLen    = 32
+	eciesMacTagLen    = 8
+	profileAPubKeyLen = 32
+	profileBPubKeyLen = 33 // compressed point
+)
+
+// HomeNetworkKeyId identifies a private key of the home network by the protection scheme and the home network
+// public key ID of the SUCI, the IDs of the profiles A and B are distinct (TS 23.003 2.2B)
+type HomeNetworkKeyId struct {
+	Scheme string // SuciProfileA or SuciProfileB
+	Id     uint8
+}
+
+// SuciToSupi de-conceals the SUCI in the form suci-0-<MCC>-<MNC>-<routing indicator>-<protection scheme>-
+// <home network public key ID>-<scheme output> into the IMSI-based SUPI, with the private keys of the home network
+// for the profiles A and B (TS 33.501 6.12.2, C.3)
+func SuciToSupi(suci string, privateKeys map[HomeNetworkKeyId][]byte) (string, error) {
+	parts := strings.Split(suci, "-")
+	if len(parts) != 8 || parts[0] != "suci" || parts[1] != "0" {
+		return "", fmt.Errorf("unsupported SUCI %s", suci)
+	}
+	mcc, mnc, scheme, keyId, schemeOutput := parts[2], parts[3], parts[5], parts[6], parts[7]
+
+	if scheme == SuciNullScheme {
+		return "imsi-" + mcc + mnc + schemeOutput, nil
+	}
+
+	var id uint8
+	if _, err := fmt.Sscanf(keyId, "%d", &id); err != nil {
+		return "", fmt.Errorf("invalid home network public key ID %s", keyId)
+	}
+	privateKey, ok := privateKeys[HomeNetworkKeyId{Scheme: scheme, Id: id}]
+	if !ok {
+		return "", fmt.Errorf("no home network private key %d of protection scheme %s", id, scheme)
+	}
+	output, err := hex.DecodeString(schemeOutput)
+	if err != nil {
+		return "", fmt.Errorf("decode scheme output failed: %w", err)
+	}
+
+	var plaintext []byte
+	switch scheme {
+	case SuciProfileA:
+		plaintext, err = eciesDecrypt(ecdh.X25519(), privateKey, output, profileAPubKeyLen)
+	case SuciProfileB:
+		plaintext, err = eciesDecrypt(ecdh.P256(), privateKey, output, profileBPubKeyLen)
+	default:
+		return "", fmt.Errorf("unsupported protection scheme %s", scheme)
+	}
+	if err != nil {
+		return "", err
+	}
+	return "imsi-" + mcc + mnc + msinFromBcd(plaintext), nil
+}
+
+// eciesDecrypt decrypts the scheme output of the ECIES profiles: the ephemeral public key of the UE, the ciphertext
+// and the MAC tag (TS 33.501 C.3.3)
+func eciesDecrypt(curve ecdh.Curve, privateKey, output []byte, pubKeyLen int) ([]byte, error) {
+	if len(output) <= pubKeyLen+eciesMacTagLen {
+		return nil, fmt.Errorf("scheme output too short")
+	}
+	ephPubKey := output[:pubKeyLen]
+	ciphertext := output[pubKeyLen : len(output)-eciesMacTagLen]
+	macTag := output[len(output)-eciesMacTagLen:]
+
+	priv, err := curve.NewPrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid home network private key: %w", err)
+	}
+	rawPubKey := ephPubKey
+	if curve == ecdh.P256() {
+		// crypto/ecdh takes the uncompressed points only
+		x, y := elliptic.UnmarshalCompressed(elliptic.P256(), ephPubKey)
+		if x == nil {
+			return nil, fmt.Errorf("invalid ephemeral public key")
+		}
+		rawPubKey = elliptic.Marshal(elliptic.P256(), x, y)
+	}
+	pub, err := curve.NewPublicKey(rawPubKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ephemeral public key: %w", err)
+	}
+	sharedKey, err := priv.ECDH(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	keyData := ansiX963Kdf(sharedKey, ephPubKey, eciesEncKeyLen+eciesIcbLen+eciesMacKeyLen)
+	encKey := keyData[:eciesEncKeyLen]
+	icb := keyData[eciesEncKeyLen : eciesEncKeyLen+eciesIcbLen]
+	macKey := keyData[eciesEncKeyLen+eciesIcbLen:]
+
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(ciphertext)
+	if !hmac.Equal(mac.Sum(nil)[:eciesMacTagLen], macTag) {
+		return nil, fmt.Errorf("MAC tag mismatch")
+	}
+
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCTR(block, icb).XORKeyStream(plaintext, ciphertext)
+	return plaintext, nil
+}
+
+// ansiX963Kdf is the KDF of ANSI X9.63 with SHA-256 (TS 33.501 C.3.4)
+func ansiX963Kdf(sharedKey, sharedInfo []byte, length int) []byte {
+	var keyData []byte
+	counter := make([]byte, 4)
+	for i := uint32(1); len(keyData) < length; i++ {
+		binary.BigEndian.PutUint32(counter, i)
+		h := sha256.New()
+		h.Write(sharedKey)
+		h.Write(counter)
+		h.Write(sharedInfo)
+		keyData = h.Sum(keyData)
+	}
+	return keyData[:length]
+}
+
+// msinFromBcd decodes the MSIN in BCD with the digits swapped in each octet and a trailing filler
+func msinFromBcd(bcd []byte) string {
+	swapped := make([]byte, len(bcd))
+	for i, b := range bcd {
+		swapped[i] = bits.RotateLeft8(b, 4)
+	}
+	return strings.TrimSuffix(hex.EncodeToString(swapped), "f")
+}
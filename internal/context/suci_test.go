@@ -0,0 +1,80 @@
+package context
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// test data of TS 33.501 C.4.3 and C.4.4
+func TestSuciToSupi(t *testing.T) {
+	profileAKey, err := hex.DecodeString("c53c22208b61860b06c62e5406a7b330c2b577aa5558981510d128247d38bd1d")
+	require.NoError(t, err)
+	profileBKey, err := hex.DecodeString("f1ab1074477ebcc7f554ea1c5fc368b1616730155e0041ac447d6301975fecda")
+	require.NoError(t, err)
+	privateKeys := map[HomeNetworkKeyId][]byte{
+		{Scheme: SuciProfileA, Id: 1}: profileAKey,
+		{Scheme: SuciProfileB, Id: 2}: profileBKey,
+	}
+
+	testCases := []struct {
+		name    string
+		suci    string
+		supi    string
+		wantErr bool
+	}{
+		{
+			name: "null scheme",
+			suci: "suci-0-208-93-0000-0-0-0000000001",
+			supi: "imsi-208930000000001",
+		},
+		{
+			name: "profile A",
+			suci: "suci-0-274-012-0000-1-1-b2e92f836055a255837debf850b528997ce0201cb82adfe4be1f587d07d8457d" +
+				"cb02352410cddd9e730ef3fa87",
+			supi: "imsi-274012001002086",
+		},
+		{
+			name: "profile B",
+			suci: "suci-0-274-012-0000-2-2-039aab8376597021e855679a9778ea0b67396e68c66df32c0f41e9acca2da9b9d1" +
+				"46a33fc2716ac7dae96aa30a4d",
+			supi: "imsi-274012001002086",
+		},
+		{
+			name: "MAC tag mismatch",
+			suci: "suci-0-274-012-0000-1-1-b2e92f836055a255837debf850b528997ce0201cb82adfe4be1f587d07d8457d" +
+				"cb02352410cddd9e730ef3fa88",
+			wantErr: true,
+		},
+		{
+			name: "unknown home network public key",
+			suci: "suci-0-274-012-0000-1-3-b2e92f836055a255837debf850b528997ce0201cb82adfe4be1f587d07d8457d" +
+				"cb02352410cddd9e730ef3fa87",
+			wantErr: true,
+		},
+		{
+			name: "home network public key of another scheme",
+			suci: "suci-0-274-012-0000-1-2-b2e92f836055a255837debf850b528997ce0201cb82adfe4be1f587d07d8457d" +
+				"cb02352410cddd9e730ef3fa87",
+			wantErr: true,
+		},
+		{
+			name:    "NAI",
+			suci:    "nai-1-user@example.com",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			supi, err := SuciToSupi(tc.suci, privateKeys)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.supi, supi)
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package context
+
+import (
+	"net"
+	"strings"
+	"sync/atomic"
+
+	"github.com/free5gc/aper"
+	"github.com/free5gc/ngap/ngapType"
+)
+
+// AmfTnlEndpoint is an AMF NGAP endpoint together with the use NG-RAN nodes shall make of the TNL
+// associations set up towards it, advertised in the AMF Configuration Update (TS 38.413 8.7.3)
+type AmfTnlEndpoint struct {
+	Ip           string
+	Usage        aper.Enumerated // ngapType.TNLAssociationUsagePresentXxx
+	WeightFactor int64           // TNL Address Weight Factor, 0..255
+}
+
+// TNLAssociation is an SCTP association between the AMF and a NG-RAN node. A NG-RAN node may set up
+// several TNL associations towards the AMF endpoints (TS 38.412 7).
+type TNLAssociation struct {
+	Conn         net.Conn
+	Usage        aper.Enumerated
+	WeightFactor int64
+
+	numUe atomic.Int64 // number of UEs bound to the association
+}
+
+// UeAssociated tells whether the association may carry UE-associated signalling
+func (tnla *TNLAssociation) UeAssociated() bool {
+	return tnla.Usage != ngapType.TNLAssociationUsagePresentNonUe && tnla.WeightFactor > 0
+}
+
+func (tnla *TNLAssociation) NumOfUe() int64 {
+	return tnla.numUe.Load()
+}
+
+func addrHosts(addr net.Addr) []string {
+	if addr == nil {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		host = addr.String()
+	}
+	// SCTP multi-homed address: "ip1/ip2/...:port"
+	return strings.Split(host, "/")
+}
+
+// TnlEndpointByAddr returns the AMF endpoint the local address belongs to, an endpoint which is not
+// configured carries both UE and non UE-associated signalling with the default TNL weight factor
+func (context *AMFContext) TnlEndpointByAddr(addr net.Addr) AmfTnlEndpoint {
+	hosts := addrHosts(addr)
+	for _, endpoint := range context.NgapTnlEndpointList {
+		for _, host := range hosts {
+			if host == endpoint.Ip {
+				return endpoint
+			}
+		}
+	}
+	endpoint := AmfTnlEndpoint{
+		Usage:        ngapType.TNLAssociationUsagePresentBoth,
+		WeightFactor: context.TNLWeightFactor,
+	}
+	if len(hosts) > 0 {
+		endpoint.Ip = hosts[0]
+	}
+	return endpoint
+}
+
+// AddTNLAssociation adds an SCTP association of the NG-RAN node, its use is the one of the AMF endpoint it
+// terminates on
+func (ran *AmfRan) AddTNLAssociation(conn net.Conn) *TNLAssociation {
+	endpoint := GetSelf().TnlEndpointByAddr(conn.LocalAddr())
+	tnla := &TNLAssociation{
+		Conn:         conn,
+		Usage:        endpoint.Usage,
+		WeightFactor: endpoint.WeightFactor,
+	}
+	if value, loaded := ran.TnlaList.LoadOrStore(conn, tnla); loaded {
+		return value.(*TNLAssociation)
+	}
+	if conn != ran.Conn {
+		GetSelf().AmfRanTnlaPool.Store(conn, ran)
+	}
+	return tnla
+}
+
+// RemoveTNLAssociation removes an SCTP association of the NG-RAN node. The non UE-associated signalling
+// moves to another association if it was carried by the removed one. The UEs bound to the removed
+// association are unbound and returned.
+func (ran *AmfRan) RemoveTNLAssociation(conn net.Conn) (unbound []*RanUe) {
+	value, ok := ran.TnlaList.LoadAndDelete(conn)
+	if !ok {
+		return nil
+	}
+	tnla := value.(*TNLAssociation)
+
+	self := GetSelf()
+	self.AmfRanTnlaPool.Delete(conn)
+	if conn == ran.Conn {
+		self.AmfRanPool.Delete(conn)
+		if next := ran.nonUeTNLAssociation(); next != nil {
+			ran.Log.Infof("Move non UE-associated signalling to TNL association[%s]", next.Conn.RemoteAddr())
+			ran.Conn = next.Conn
+			self.AmfRanTnlaPool.Delete(next.Conn)
+			self.AmfRanPool.Store(next.Conn, ran)
+		}
+	}
+
+	ran.RanUeList.Range(func(key, value interface{}) bool {
+		ranUe := value.(*RanUe)
+		if ranUe.TNLAssociation() == tnla {
+			ranUe.UnbindTNLAssociation()
+			unbound = append(unbound, ranUe)
+		}
+		return true
+	})
+	return unbound
+}
+
+func (ran *AmfRan) TNLAssociationFindByConn(conn net.Conn) (*TNLAssociation, bool) {
+	if value, ok := ran.TnlaList.Load(conn); ok {
+		return value.(*TNLAssociation), true
+	}
+	return nil, false
+}
+
+func (ran *AmfRan) TNLAssociationNum() int {
+	num := 0
+	ran.TnlaList.Range(func(key, value interface{}) bool {
+		num++
+		return true
+	})
+	return num
+}
+
+func (ran *AmfRan) nonUeTNLAssociation() *TNLAssociation {
+	var selected *TNLAssociation
+	ran.TnlaList.Range(func(key, value interface{}) bool {
+		tnla := value.(*TNLAssociation)
+		if tnla.Usage != ngapType.TNLAssociationUsagePresentUe {
+			selected = tnla
+			return false
+		}
+		if selected == nil {
+			selected = tnla
+		}
+		return true
+	})
+	return selected
+}
+
+// SelectTNLAssociation selects the association for the UE-associated signalling of a UE which is not bound
+// yet: the least loaded one with regard to the TNL address weight factors
+func (ran *AmfRan) SelectTNLAssociation() *TNLAssociation {
+	var selected *TNLAssociation
+	ran.TnlaList.Range(func(key, value interface{}) bool {
+		tnla := value.(*TNLAssociation)
+		if !tnla.UeAssociated() {
+			return true
+		}
+		// (numUe+1)/weight < (selectedNumUe+1)/selectedWeight
+		if selected == nil ||
+			(tnla.NumOfUe()+1)*selected.WeightFactor < (selected.NumOfUe()+1)*tnla.WeightFactor {
+			selected = tnla
+		}
+		return true
+	})
+	return selected
+}
+
+// UnbalancedRanUes returns the UEs whose binding should be released: the ones bound to an association
+// which may no longer carry UE-associated signalling, and the ones exceeding the share of their association
+// given by the TNL address weight factors
+func (ran *AmfRan) UnbalancedRanUes() (ranUeList []*RanUe) {
+	var sumWeight, sumUe int64
+	ran.TnlaList.Range(func(key, value interface{}) bool {
+		tnla := value.(*TNLAssociation)
+		if tnla.UeAssociated() {
+			sumWeight += tnla.WeightFactor
+		}
+		sumUe += tnla.NumOfUe()
+		return true
+	})
+
+	count := make(map[*TNLAssociation]int64)
+	ran.RanUeList.Range(func(key, value interface{}) bool {
+		ranUe := value.(*RanUe)
+		tnla := ranUe.TNLAssociation()
+		if tnla == nil {
+			return true
+		}
+		if _, ok := ran.TnlaList.Load(tnla.Conn); !ok || !tnla.UeAssociated() {
+			ranUeList = append(ranUeList, ranUe)
+			return true
+		}
+		// ceil(sumUe * weight / sumWeight)
+		share := (sumUe*tnla.WeightFactor + sumWeight - 1) / sumWeight
+		if count[tnla] >= share {
+			ranUeList = append(ranUeList, ranUe)
+			return true
+		}
+		count[tnla]++
+		return true
+	})
+	return ranUeList
+}
+
+// BindTNLAssociation binds the UE-associated signalling of the UE to the association, TS 38.412 7
+func (ranUe *RanUe) BindTNLAssociation(tnla *TNLAssociation) {
+	old := ranUe.tnla.Swap(tnla)
+	if old == tnla {
+		return
+	}
+	if old != nil {
+		old.numUe.Add(-1)
+	}
+	if tnla != nil {
+		tnla.numUe.Add(1)
+	}
+}
+
+func (ranUe *RanUe) UnbindTNLAssociation() {
+	ranUe.BindTNLAssociation(nil)
+}
+
+// TNLAssociation returns the association the UE is bound to, nil if the UE is not bound
+func (ranUe *RanUe) TNLAssociation() *TNLAssociation {
+	return ranUe.tnla.Load()
+}
@@ -0,0 +1,147 @@
+package context
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/free5gc/nas/nasConvert"
+	"github.com/free5gc/openapi/models"
+)
+
+// UPU header of the UE parameters update transparent container, TS 24.501 9.11.3.53A
+const (
+	UpuDataTypeUeParametersUpdateList uint8 = 0x00
+	UpuDataTypeAcknowledgement        uint8 = 0x01
+	upuHeaderAckRequested             uint8 = 0x02
+	upuHeaderRegistrationRequested    uint8 = 0x04
+)
+
+// UE parameters update data set types, TS 24.501 9.11.3.53A
+const (
+	UpuDataSetTypeRoutingIndicator       uint8 = 0x01
+	UpuDataSetTypeDefaultConfiguredNssai uint8 = 0x02
+)
+
+const (
+	upuMacLen     = 16
+	counterUpuLen = 2
+)
+
+// SetUpuInfo keeps the UE parameters update information provided by the UDM until it is sent to the UE
+// (TS 23.502 4.20.2)
+func (ue *AmfUe) SetUpuInfo(upuInfo *models.UdmSdmUpuInfo) {
+	ue.UpuInfo = upuInfo
+	ue.UpuAckRequested = upuInfo != nil && upuInfo.UpuAckInd
+}
+
+// UpuTransparentContainer encodes the UE parameters update transparent container of the UE parameters update
+// information provided by the UDM, nil is returned if there is none
+func (ue *AmfUe) UpuTransparentContainer() ([]byte, error) {
+	upuInfo := ue.UpuInfo
+	if upuInfo == nil {
+		return nil, nil
+	}
+	// the UDM may provide the container the AMF forwards as is
+	if upuInfo.UpuTransparentContainer != "" {
+		container, err := base64.StdEncoding.DecodeString(upuInfo.UpuTransparentContainer)
+		if err != nil {
+			return nil, fmt.Errorf("decode upuTransparentContainer failed: %+v", err)
+		}
+		return container, nil
+	}
+
+	upuMacIausf, err := hex.DecodeString(upuInfo.UpuMacIausf)
+	if err != nil || len(upuMacIausf) != upuMacLen {
+		return nil, fmt.Errorf("invalid upuMacIausf[%s]", upuInfo.UpuMacIausf)
+	}
+	counterUpu, err := hex.DecodeString(upuInfo.CounterUpu)
+	if err != nil || len(counterUpu) != counterUpuLen {
+		return nil, fmt.Errorf("invalid counterUpu[%s]", upuInfo.CounterUpu)
+	}
+
+	header := UpuDataTypeUeParametersUpdateList
+	if upuInfo.UpuAckInd {
+		header |= upuHeaderAckRequested
+	}
+	if upuInfo.UpuRegInd {
+		header |= upuHeaderRegistrationRequested
+	}
+	container := []byte{header}
+	container = append(container, upuMacIausf...)
+	container = append(container, counterUpu...)
+	for _, upuData := range upuInfo.UpuDataList {
+		dataSets, err := upuDataSets(&upuData)
+		if err != nil {
+			return nil, err
+		}
+		container = append(container, dataSets...)
+	}
+	return container, nil
+}
+
+// upuDataSets encodes the UE parameters update data sets of the UPU data, each one is its type, the length of its
+// contents and its contents
+func upuDataSets(upuData *models.AusfUpuProtectionUpuData) ([]byte, error) {
+	if upuData.SecPacket != "" {
+		return nil, fmt.Errorf("secured packet of the UE parameters update data is not supported")
+	}
+	var dataSets []byte
+	appendDataSet := func(dataSetType uint8, contents []byte) {
+		dataSets = append(dataSets, dataSetType)
+		dataSets = binary.BigEndian.AppendUint16(dataSets, uint16(len(contents)))
+		dataSets = append(dataSets, contents...)
+	}
+
+	if upuData.RoutingId != "" {
+		routingIndicator, err := routingIndicatorToNas(upuData.RoutingId)
+		if err != nil {
+			return nil, err
+		}
+		appendDataSet(UpuDataSetTypeRoutingIndicator, routingIndicator)
+	}
+	if len(upuData.DefaultConfNssai) > 0 {
+		var nssai []byte
+		for _, snssai := range upuData.DefaultConfNssai {
+			nssai = append(nssai, nasConvert.SnssaiToNas(snssai)...)
+		}
+		appendDataSet(UpuDataSetTypeDefaultConfiguredNssai, nssai)
+	}
+	return dataSets, nil
+}
+
+// routingIndicatorToNas encodes the routing indicator of 1 to 4 digits as in the SUCI, the unused digits are coded as
+// "1111" (TS 24.501 9.11.3.4)
+func routingIndicatorToNas(routingId string) ([]byte, error) {
+	if len(routingId) == 0 || len(routingId) > 4 {
+		return nil, fmt.Errorf("invalid routingId[%s]", routingId)
+	}
+	digits := [4]uint8{0x0f, 0x0f, 0x0f, 0x0f}
+	for i, c := range routingId {
+		if c < '0' || c > '9' {
+			return nil, fmt.Errorf("invalid routingId[%s]", routingId)
+		}
+		digits[i] = uint8(c - '0')
+	}
+	return []byte{digits[1]<<4 | digits[0], digits[3]<<4 | digits[2]}, nil
+}
+
+// UpuAckToModels returns the UPU-MAC-IUE of the acknowledgement sent by the UE in a UE parameters update transparent
+// container
+func UpuAckToModels(container []byte) (string, error) {
+	if len(container) != 1+upuMacLen || container[0]&0x01 != UpuDataTypeAcknowledgement {
+		return "", fmt.Errorf("NAS UPU Ack is not valid")
+	}
+	return hex.EncodeToString(container[1:]), nil
+}
+
+func (ue *AmfUe) StopUpuAckTimer() {
+	if ue.UpuAckTimer == nil {
+		return
+	}
+
+	ue.GmmLog.Infof("Stop UPU ACK timer")
+	ue.UpuAckTimer.Stop()
+	ue.UpuAckTimer = nil // clear the timer
+}
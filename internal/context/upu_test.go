@@ -0,0 +1,120 @@
+package context
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/free5gc/openapi/models"
+)
+
+func TestUpuTransparentContainer(t *testing.T) {
+	upuMacIausf := "000102030405060708090a0b0c0d0e0f"
+	testCases := []struct {
+		name          string
+		upuInfo       *models.UdmSdmUpuInfo
+		expected      []byte
+		ackRequested  bool
+		expectedError bool
+	}{
+		{
+			name: "No UE parameters update information",
+		},
+		{
+			name: "Routing indicator with acknowledgement and registration requested",
+			upuInfo: &models.UdmSdmUpuInfo{
+				UpuAckInd:   true,
+				UpuRegInd:   true,
+				UpuMacIausf: upuMacIausf,
+				CounterUpu:  "0001",
+				UpuDataList: []models.AusfUpuProtectionUpuData{{RoutingId: "12"}},
+			},
+			expected: []byte{
+				0x06, 0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+				0x00, 0x01, 0x01, 0x00, 0x02, 0x21, 0xff,
+			},
+			ackRequested: true,
+		},
+		{
+			name: "Default configured NSSAI",
+			upuInfo: &models.UdmSdmUpuInfo{
+				UpuMacIausf: upuMacIausf,
+				CounterUpu:  "0001",
+				UpuDataList: []models.AusfUpuProtectionUpuData{
+					{DefaultConfNssai: []models.Snssai{{Sst: 1}, {Sst: 1, Sd: "010203"}}},
+				},
+			},
+			expected: []byte{
+				0x00, 0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+				0x00, 0x01, 0x02, 0x00, 0x07, 0x01, 0x01, 0x04, 0x01, 0x01, 0x02, 0x03,
+			},
+		},
+		{
+			name: "Container provided by the UDM",
+			upuInfo: &models.UdmSdmUpuInfo{
+				UpuAckInd:               true,
+				UpuTransparentContainer: base64.StdEncoding.EncodeToString([]byte{0x02, 0x01, 0x02}),
+			},
+			expected:     []byte{0x02, 0x01, 0x02},
+			ackRequested: true,
+		},
+		{
+			name: "Invalid CounterUPU",
+			upuInfo: &models.UdmSdmUpuInfo{
+				UpuMacIausf: upuMacIausf,
+				CounterUpu:  "01",
+			},
+			expectedError: true,
+		},
+		{
+			name: "Invalid routing indicator",
+			upuInfo: &models.UdmSdmUpuInfo{
+				UpuMacIausf: upuMacIausf,
+				CounterUpu:  "0001",
+				UpuDataList: []models.AusfUpuProtectionUpuData{{RoutingId: "12345"}},
+			},
+			expectedError: true,
+		},
+		{
+			name: "Secured packet",
+			upuInfo: &models.UdmSdmUpuInfo{
+				UpuMacIausf: upuMacIausf,
+				CounterUpu:  "0001",
+				UpuDataList: []models.AusfUpuProtectionUpuData{{SecPacket: "0102"}},
+			},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ue := &AmfUe{}
+			ue.SetUpuInfo(tc.upuInfo)
+			require.Equal(t, tc.ackRequested, ue.UpuAckRequested)
+
+			container, err := ue.UpuTransparentContainer()
+			if tc.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, container)
+		})
+	}
+}
+
+func TestUpuAckToModels(t *testing.T) {
+	ack := []byte{0x01, 0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f}
+	upuMacIue, err := UpuAckToModels(ack)
+	require.NoError(t, err)
+	require.Equal(t, "000102030405060708090a0b0c0d0e0f", upuMacIue)
+
+	// a UE parameters update list is not an acknowledgement
+	ack[0] = 0x00
+	_, err = UpuAckToModels(ack)
+	require.Error(t, err)
+
+	_, err = UpuAckToModels(nil)
+	require.Error(t, err)
+}
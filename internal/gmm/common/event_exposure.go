@@ -0,0 +1,77 @@
+package common
+
+import (
+	"time"
+
+	"github.com/free5gc/amf/internal/context"
+	callback "github.com/free5gc/amf/internal/sbi/processor/notifier"
+	"github.com/free5gc/openapi/models"
+)
+
+// AmfEventNotify is an event report to be sent to the subscriber of the event
+type AmfEventNotify struct {
+	Subscription *models.ExtAmfEventSubscription
+	Report       models.AmfEventReport
+}
+
+// NewAmfEventReport builds the report of eventType for the UE subscription and accounts it against the
+// maximum number of reports, the subscription is removed from the UE once it is no longer active.
+// The caller shall hold ue.Lock.
+func NewAmfEventReport(ue *context.AmfUe, subscriptionID string, eventType models.AmfEventType) (
+	*AmfEventNotify, bool,
+) {
+	ueSubscription, ok := ue.EventSubscriptionsInfo[subscriptionID]
+	if !ok || ueSubscription.EventSubscription == nil {
+		return nil, false
+	}
+
+	var event *models.AmfEvent
+	for i := range ueSubscription.EventSubscription.EventList {
+		if ueSubscription.EventSubscription.EventList[i].Type == eventType {
+			event = &ueSubscription.EventSubscription.EventList[i]
+			break
+		}
+	}
+	if event == nil {
+		return nil, false
+	}
+
+	now := time.Now().UTC()
+	report := models.AmfEventReport{
+		Type:      eventType,
+		State:     &models.AmfEventState{Active: true},
+		TimeStamp: &now,
+		AnyUe:     ueSubscription.AnyUe,
+		Supi:      ue.Supi,
+		RefId:     event.RefId,
+	}
+
+	options := ueSubscription.EventSubscription.Options
+	if options != nil && options.Trigger == models.AmfEventTrigger_ONE_TIME {
+		report.State.Active = false
+	} else if remainReports := ueSubscription.RemainReports; remainReports != nil {
+		*remainReports--
+		if *remainReports <= 0 {
+			report.State.Active = false
+		} else {
+			report.State.RemainReports = *remainReports
+		}
+	}
+	if !report.State.Active {
+		delete(ue.EventSubscriptionsInfo, subscriptionID)
+		StopAreaOfInterestReporting(ue, ue.AreaOfInterestListBySubscriptionID(subscriptionID))
+	}
+
+	return &AmfEventNotify{
+		Subscription: ueSubscription.EventSubscription,
+		Report:       report,
+	}, true
+}
+
+// SendAmfEventNotifies sends the event reports to their subscribers, ue.Lock shall not be held
+func SendAmfEventNotifies(ue *context.AmfUe, notifyList []*AmfEventNotify) {
+	for _, notify := range notifyList {
+		ue.GmmLog.Infof("Notify %s to %s", notify.Report.Type, notify.Subscription.EventNotifyUri)
+		callback.SendAmfEventNotify(notify.Subscription, []models.AmfEventReport{notify.Report})
+	}
+}
@@ -0,0 +1,98 @@
+package common
+
+import (
+	"github.com/free5gc/amf/internal/context"
+	ngap_message "github.com/free5gc/amf/internal/ngap/message"
+	"github.com/free5gc/ngap/ngapType"
+	"github.com/free5gc/openapi/models"
+)
+
+// ConfigureLocationReporting requests the NG-RAN serving the UE over 3GPP access to report the UE location for
+// the LOCATION_REPORT event subscriptions, and the UE presence in the areas of interest (TS 23.502 4.10).
+// Nothing is sent in CM-IDLE, the reporting is configured again at the next Initial Context Setup.
+// The caller shall hold ue.Lock.
+func ConfigureLocationReporting(ue *context.AmfUe) {
+	ranUe := ue.RanUe[models.AccessType__3_GPP_ACCESS]
+	if ranUe == nil {
+		return
+	}
+
+	var oneTime, continuous bool
+	for _, ueSubscription := range ue.EventSubscriptionsInfo {
+		subscription := ueSubscription.EventSubscription
+		if subscription == nil {
+			continue
+		}
+		for _, event := range subscription.EventList {
+			if event.Type != models.AmfEventType_LOCATION_REPORT {
+				continue
+			}
+			if subscription.Options != nil && subscription.Options.Trigger == models.AmfEventTrigger_ONE_TIME {
+				oneTime = true
+			} else {
+				continuous = true
+			}
+		}
+	}
+
+	if continuous {
+		ngap_message.SendLocationReportingControl(ranUe, nil, 0, ngapType.EventType{
+			Value: ngapType.EventTypePresentChangeOfServeCell,
+		})
+	} else if oneTime {
+		ngap_message.SendLocationReportingControl(ranUe, nil, 0, ngapType.EventType{
+			Value: ngapType.EventTypePresentDirect,
+		})
+	}
+
+	aoiList := ngapType.AreaOfInterestList{}
+	ue.AreaOfInterestList.Range(func(key, value interface{}) bool {
+		aoi := value.(*context.AreaOfInterest)
+		ngap_message.AppendAreaOfInterestList(&aoiList, aoi.ReferenceID, aoi.PresenceInfo)
+		return true
+	})
+	if len(aoiList.List) > 0 {
+		ngap_message.SendLocationReportingControl(ranUe, &aoiList, 0, ngapType.EventType{
+			Value: ngapType.EventTypePresentUePresenceInAreaOfInterest,
+		})
+	}
+}
+
+// StopAreaOfInterestReporting removes the areas of interest from the UE context and, in CM-CONNECTED,
+// requests the NG-RAN to stop reporting the UE presence in them.
+func StopAreaOfInterestReporting(ue *context.AmfUe, aoiList []*context.AreaOfInterest) {
+	ranUe := ue.RanUe[models.AccessType__3_GPP_ACCESS]
+	for _, aoi := range aoiList {
+		// the Reference ID of an area already removed may have been given to a new area
+		ue.AreaOfInterestList.CompareAndDelete(aoi.ReferenceID, aoi)
+		if ranUe != nil {
+			ngap_message.SendLocationReportingControl(ranUe, nil, aoi.ReferenceID, ngapType.EventType{
+				Value: ngapType.EventTypePresentStopUePresenceInAreaOfInterest,
+			})
+		}
+	}
+}
+
+// UpdatePresenceReportingAreas applies the Presence Reporting Areas of a PCF AM policy update
+// (TS 23.503 6.1.2.5, TS 23.501 5.6.11): the NG-RAN stops reporting the UE presence in the removed areas and
+// is requested to report it in the new ones. All the areas are removed once PRA_CH is no longer armed.
+// The caller shall hold ue.Lock.
+func UpdatePresenceReportingAreas(ue *context.AmfUe, policyUpdate *models.PcfAmPolicyControlPolicyUpdate) {
+	requestTriggerPraChange := false
+	for _, trigger := range policyUpdate.Triggers {
+		if trigger == models.PcfAmPolicyControlRequestTrigger_PRA_CH {
+			requestTriggerPraChange = true
+		}
+	}
+
+	var removedPras []*context.AreaOfInterest
+	if requestTriggerPraChange {
+		removedPras = ue.UpdatePresenceReportingAreas(policyUpdate.Pras)
+	} else {
+		removedPras = ue.ClearPresenceReportingAreas()
+	}
+	StopAreaOfInterestReporting(ue, removedPras)
+	if requestTriggerPraChange && len(policyUpdate.Pras) > 0 {
+		ConfigureLocationReporting(ue)
+	}
+}
@@ -0,0 +1,22 @@
+package common
+
+import (
+	"github.com/free5gc/amf/internal/context"
+	"github.com/free5gc/amf/internal/sbi/consumer"
+	"github.com/free5gc/openapi/models"
+)
+
+// ReleaseNsacAdmission counts the UE out of the S-NSSAIs subject to Network Slice Admission Control it is no
+// longer registered to (TS 23.502 4.2.11.2)
+func ReleaseNsacAdmission(ue *context.AmfUe, snssaiList []models.Snssai) {
+	if context.GetSelf().Nsacf == nil || len(snssaiList) == 0 {
+		return
+	}
+	if _, err := consumer.GetConsumer().NumOfUEsUpdate(ue, snssaiList, consumer.NsacUpdateFlagDecrease); err != nil {
+		ue.GmmLog.Errorf("NSAC Number of UEs Update Error[%+v]", err)
+		return
+	}
+	for _, snssai := range snssaiList {
+		ue.RemoveNsacAdmittedSnssai(snssai)
+	}
+}
@@ -0,0 +1,88 @@
+package common
+
+import (
+	"github.com/free5gc/amf/internal/context"
+	"github.com/free5gc/openapi/models"
+)
+
+// StartMobileReachableTimer supervises the registered UE entering CM-IDLE over 3GPP access (TS 24.501 5.3.7).
+// A UE in MICO mode is not reachable as soon as it is in CM-IDLE (TS 23.501 5.4.1.3), any other UE once the
+// mobile reachable timer expires. The implicit deregistration timer is then started.
+func StartMobileReachableTimer(ue *context.AmfUe) {
+	if !ue.State[models.AccessType__3_GPP_ACCESS].Is(context.Registered) {
+		return
+	}
+
+	ue.StopMobileReachableTimer()
+	ue.StopImplicitDeregistrationTimer()
+	if ue.MicoMode {
+		go notifyReachabilityReport(ue, models.UeReachability_UNREACHABLE)
+	}
+
+	if !context.GetSelf().MobileReachableCfg.Enable {
+		return
+	}
+	ue.GmmLog.Infof("Start mobile reachable timer")
+	ue.MobileReachableTimer = context.NewTimer(ue.MobileReachableTimerValue(), 0, func(expireTimes int32) {}, func() {
+		ue.GmmLog.Warnf("Mobile reachable timer expires, the UE is not reachable")
+		ue.MobileReachableTimer = nil // clear the timer
+		notifyReachabilityReport(ue, models.UeReachability_UNREACHABLE)
+		startImplicitDeregistrationTimer(ue)
+	})
+}
+
+// StopMobileReachableTimer stops the supervision of the UE in CM-IDLE once it is in CM-CONNECTED over 3GPP
+// access, the UE is reachable again
+func StopMobileReachableTimer(ue *context.AmfUe) {
+	ue.StopMobileReachableTimer()
+	ue.StopImplicitDeregistrationTimer()
+	if ue.Reachability == models.UeReachability_UNREACHABLE {
+		// the caller may hold ue.Lock
+		go notifyReachabilityReport(ue, models.UeReachability_REACHABLE)
+	}
+}
+
+func startImplicitDeregistrationTimer(ue *context.AmfUe) {
+	if !context.GetSelf().ImplicitDeregistrationCfg.Enable {
+		return
+	}
+
+	ue.GmmLog.Infof("Start implicit deregistration timer")
+	ue.ImplicitDeregistrationTimer = context.NewTimer(ue.ImplicitDeregistrationTimerValue(), 0,
+		func(expireTimes int32) {}, func() {
+			ue.GmmLog.Warnf("Implicit deregistration timer expires, deregister the UE")
+			ue.ImplicitDeregistrationTimer = nil // clear the timer
+
+			ue.Lock.Lock()
+			defer ue.Lock.Unlock()
+			// the UE is in CM-IDLE, the UDM registration is not purged with its N2 connection
+			if err := PurgeSubscriberData(ue, models.AccessType__3_GPP_ACCESS); err != nil {
+				ue.GmmLog.Errorf("Purge subscriber data Error[%v]", err)
+			}
+			RemoveAmfUe(ue, true)
+		})
+}
+
+// notifyReachabilityReport updates the reachability of the UE and reports it to the REACHABILITY_REPORT
+// subscribers if it changed
+func notifyReachabilityReport(ue *context.AmfUe, reachability models.UeReachability) {
+	var notifyList []*AmfEventNotify
+
+	ue.Lock.Lock()
+	if ue.Reachability == reachability {
+		ue.Lock.Unlock()
+		return
+	}
+	ue.Reachability = reachability
+	for subscriptionID := range ue.EventSubscriptionsInfo {
+		notify, ok := NewAmfEventReport(ue, subscriptionID, models.AmfEventType_REACHABILITY_REPORT)
+		if !ok {
+			continue
+		}
+		notify.Report.Reachability = reachability
+		notifyList = append(notifyList, notify)
+	}
+	ue.Lock.Unlock()
+
+	SendAmfEventNotifies(ue, notifyList)
+}
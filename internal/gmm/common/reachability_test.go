@@ -0,0 +1,143 @@
+package common
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/free5gc/amf/internal/context"
+	"github.com/free5gc/amf/internal/sbi/consumer"
+	"github.com/free5gc/amf/pkg/factory"
+	"github.com/free5gc/openapi/models"
+)
+
+func newReachabilityNotificationServer(t *testing.T) (*httptest.Server, chan models.UeReachability) {
+	rcvCh := make(chan models.UeReachability, 4)
+	nf := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notification models.AmfEventNotification
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&notification))
+		for _, report := range notification.ReportList {
+			require.Equal(t, models.AmfEventType_REACHABILITY_REPORT, report.Type)
+			rcvCh <- report.Reachability
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	nf.Config.Protocols = new(http.Protocols)
+	nf.Config.Protocols.SetUnencryptedHTTP2(true)
+	nf.Start()
+	t.Cleanup(nf.Close)
+	return nf, rcvCh
+}
+
+func newReachabilityTestUe(t *testing.T, supi, notifyUri string,
+	mobileReachableCfg, implicitDeregistrationCfg factory.TimerValue,
+) *context.AmfUe {
+	_, err := consumer.NewConsumer(nil)
+	require.NoError(t, err)
+
+	amfSelf := context.GetSelf()
+	servedGuamiList := amfSelf.ServedGuamiList
+	oldMobileReachableCfg := amfSelf.MobileReachableCfg
+	oldImplicitDeregistrationCfg := amfSelf.ImplicitDeregistrationCfg
+	amfSelf.ServedGuamiList = []models.Guami{
+		{PlmnId: &models.PlmnIdNid{Mcc: "208", Mnc: "93"}, AmfId: "cafe00"},
+	}
+	amfSelf.MobileReachableCfg = mobileReachableCfg
+	amfSelf.ImplicitDeregistrationCfg = implicitDeregistrationCfg
+
+	ue := amfSelf.NewAmfUe(supi)
+	ue.State[models.AccessType__3_GPP_ACCESS].Set(context.Registered)
+	ue.EventSubscriptionsInfo["1"] = &context.AmfUeEventSubscription{
+		EventSubscription: &models.ExtAmfEventSubscription{
+			EventList:      []models.AmfEvent{{Type: models.AmfEventType_REACHABILITY_REPORT}},
+			EventNotifyUri: notifyUri,
+		},
+	}
+	t.Cleanup(func() {
+		ue.Remove()
+		amfSelf.ServedGuamiList = servedGuamiList
+		amfSelf.MobileReachableCfg = oldMobileReachableCfg
+		amfSelf.ImplicitDeregistrationCfg = oldImplicitDeregistrationCfg
+	})
+	return ue
+}
+
+func TestMobileReachableTimer(t *testing.T) {
+	nf, rcvCh := newReachabilityNotificationServer(t)
+	supi := "imsi-208930000000101"
+	ue := newReachabilityTestUe(t, supi, nf.URL,
+		factory.TimerValue{Enable: true, ExpireTime: 10 * time.Millisecond},
+		factory.TimerValue{Enable: true, ExpireTime: 10 * time.Millisecond})
+
+	// the UE is not reachable once the mobile reachable timer expires, and is implicitly deregistered
+	// once the implicit deregistration timer expires
+	StartMobileReachableTimer(ue)
+	select {
+	case reachability := <-rcvCh:
+		require.Equal(t, models.UeReachability_UNREACHABLE, reachability)
+	case <-time.After(time.Second):
+		t.Fatal("REACHABILITY_REPORT not notified")
+	}
+	require.Eventually(t, func() bool {
+		_, ok := context.GetSelf().AmfUeFindBySupi(supi)
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestMobileReachableTimerStopped(t *testing.T) {
+	nf, rcvCh := newReachabilityNotificationServer(t)
+	ue := newReachabilityTestUe(t, "imsi-208930000000102", nf.URL,
+		factory.TimerValue{Enable: true, ExpireTime: time.Minute},
+		factory.TimerValue{Enable: true, ExpireTime: time.Minute})
+
+	StartMobileReachableTimer(ue)
+	require.NotNil(t, ue.MobileReachableTimer)
+
+	// the UE enters CM-CONNECTED before the timer expires, it has always been reachable
+	StopMobileReachableTimer(ue)
+	require.Nil(t, ue.MobileReachableTimer)
+	require.Nil(t, ue.ImplicitDeregistrationTimer)
+	require.Never(t, func() bool { return len(rcvCh) > 0 }, 50*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestMicoModeReachability(t *testing.T) {
+	nf, rcvCh := newReachabilityNotificationServer(t)
+	ue := newReachabilityTestUe(t, "imsi-208930000000103", nf.URL,
+		factory.TimerValue{}, factory.TimerValue{})
+	ue.MicoMode = true
+
+	// a UE in MICO mode is not reachable as soon as it enters CM-IDLE
+	StartMobileReachableTimer(ue)
+	require.Nil(t, ue.MobileReachableTimer)
+	select {
+	case reachability := <-rcvCh:
+		require.Equal(t, models.UeReachability_UNREACHABLE, reachability)
+	case <-time.After(time.Second):
+		t.Fatal("REACHABILITY_REPORT not notified")
+	}
+
+	StopMobileReachableTimer(ue)
+	select {
+	case reachability := <-rcvCh:
+		require.Equal(t, models.UeReachability_REACHABLE, reachability)
+	case <-time.After(time.Second):
+		t.Fatal("REACHABILITY_REPORT not notified")
+	}
+}
+
+func TestMobileReachableTimerValue(t *testing.T) {
+	ue := newReachabilityTestUe(t, "imsi-208930000000104", "",
+		factory.TimerValue{Enable: true}, factory.TimerValue{Enable: true})
+	ue.T3512Value = 3600
+
+	// TS 24.501 5.3.7, 4 minutes greater than T3512 by default
+	require.Equal(t, 64*time.Minute, ue.MobileReachableTimerValue())
+	require.Equal(t, context.DefaultImplicitDeregistrationTimer, ue.ImplicitDeregistrationTimerValue())
+
+	context.GetSelf().MobileReachableCfg.ExpireTime = 2 * time.Hour
+	require.Equal(t, 2*time.Hour, ue.MobileReachableTimerValue())
+}
@@ -0,0 +1,74 @@
+package common
+
+import (
+	"github.com/free5gc/amf/internal/context"
+	"github.com/free5gc/amf/internal/sbi/consumer"
+	"github.com/free5gc/amf/internal/util"
+	"github.com/free5gc/openapi/models"
+	Nnrf_NFDiscovery "github.com/free5gc/openapi/nrf/NFDiscovery"
+)
+
+// selectSmsf discovers an SMSF serving the UE through the NRF, TS 23.502 4.13.3.1 step 2
+func selectSmsf(ue *context.AmfUe) bool {
+	amfSelf := context.GetSelf()
+	param := Nnrf_NFDiscovery.SearchNFInstancesRequest{
+		Supi: &ue.Supi,
+	}
+	if amfSelf.Locality != "" {
+		param.PreferredLocality = &amfSelf.Locality
+	}
+
+	resp, err := consumer.GetConsumer().SendSearchNFInstances(
+		amfSelf.NrfUri, models.NrfNfManagementNfType_SMSF, models.NrfNfManagementNfType_AMF, &param)
+	if err != nil {
+		ue.GmmLog.Errorf("AMF can not select an SMSF by NRF: %+v", err)
+		return false
+	}
+	for index := range resp.NfInstances {
+		smsfUri := util.SearchNFServiceUri(&resp.NfInstances[index], models.ServiceName_NSMSF_SMS,
+			models.NfServiceStatus_REGISTERED)
+		if smsfUri != "" {
+			ue.SmsfId = resp.NfInstances[index].NfInstanceId
+			ue.SmsfUri = smsfUri
+			return true
+		}
+	}
+	ue.GmmLog.Error("AMF can not select an SMSF by NRF")
+	return false
+}
+
+// ActivateSmsOverNas activates SMS over NAS for the UE which requested it in its registration, the UE context
+// for SMS is created in the SMSF (TS 23.502 4.13.3.1). The result is kept in ue.SmsAllowed and indicated to
+// the UE in the 5GS registration result.
+func ActivateSmsOverNas(ue *context.AmfUe, anType models.AccessType) {
+	if ue.SmsfUri == "" && !selectSmsf(ue) {
+		ue.SmsAllowed = false
+		return
+	}
+
+	problemDetails, err := consumer.GetConsumer().SMServiceActivate(ue, anType)
+	if problemDetails != nil {
+		ue.GmmLog.Errorf("SMService Activate Failed Problem[%+v]", problemDetails)
+	} else if err != nil {
+		ue.GmmLog.Errorf("SMService Activate Error[%+v]", err)
+	}
+	ue.SmsAllowed = problemDetails == nil && err == nil
+}
+
+// DeactivateSmsOverNas deletes the UE context for SMS in the SMSF, when the UE no longer requests SMS over NAS
+// or is deregistered (TS 23.502 4.13.3.2)
+func DeactivateSmsOverNas(ue *context.AmfUe) {
+	if ue.SmsfUri == "" {
+		return
+	}
+
+	problemDetails, err := consumer.GetConsumer().SMServiceDeactivate(ue)
+	if problemDetails != nil {
+		ue.GmmLog.Errorf("SMService Deactivate Failed Problem[%+v]", problemDetails)
+	} else if err != nil {
+		ue.GmmLog.Errorf("SMService Deactivate Error[%+v]", err)
+	}
+	ue.SmsfId = ""
+	ue.SmsfUri = ""
+	ue.SmsAllowed = false
+}
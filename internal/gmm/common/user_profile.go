@@ -34,8 +34,11 @@ func RemoveAmfUe(ue *context.AmfUe, notifyNF bool) {
 				ue.GmmLog.Errorf("AM Policy Control Delete Error[%v]", err.Error())
 			}
 		}
+
+		DeactivateSmsOverNas(ue)
 	}
 
+	ReleaseNsacAdmission(ue, ue.NsacAdmittedNssai)
 	PurgeAmfUeSubscriberData(ue)
 	ue.Remove()
 }
@@ -76,6 +79,9 @@ func AttachRanUeToAmfUeAndReleaseOldIfAny(amfUe *context.AmfUe, ranUe *context.R
 	}
 
 	amfUe.AttachRanUe(ranUe)
+	if ranUe.Ran.AnType == models.AccessType__3_GPP_ACCESS {
+		StopMobileReachableTimer(amfUe)
+	}
 }
 
 func AttachRanUeToAmfUeAndReleaseOldHandover(amfUe *context.AmfUe, sourceRanUe, targetRanUe *context.RanUe) {
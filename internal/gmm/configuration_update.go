@@ -0,0 +1,135 @@
+package gmm
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/free5gc/amf/internal/context"
+	gmm_message "github.com/free5gc/amf/internal/gmm/message"
+	"github.com/free5gc/amf/pkg/factory"
+	"github.com/free5gc/openapi/models"
+)
+
+// RequestConfigurationUpdate updates the configuration of the UE with a Configuration Update Command
+// (TS 23.502 4.2.4.2), the parameters are sent once the UE is in CM-CONNECTED state over the access
+func RequestConfigurationUpdate(ue *context.AmfUe, anType models.AccessType,
+	flags *context.ConfigurationUpdateCommandFlags,
+) {
+	ue.AddPendingConfigurationUpdate(flags)
+	SendPendingConfigurationUpdate(ue, anType)
+}
+
+// SendPendingConfigurationUpdate sends the parameters waiting to be sent to the UE in a single Configuration Update
+// Command, a command not yet acknowledged by the UE is superseded by the new one which carries its parameters too
+func SendPendingConfigurationUpdate(ue *context.AmfUe, anType models.AccessType) {
+	if ue.ConfigurationUpdateCommandFlags == nil || !ue.CmConnect(anType) {
+		return
+	}
+
+	flags := ue.ConfigurationUpdateCommandFlags
+	ue.ConfigurationUpdateCommandFlags = nil
+	if last := ue.ConfigurationUpdate; last != nil && last.AccessType == anType &&
+		last.Status == context.ConfigurationUpdateStatusPending {
+		flags.Merge(&last.Flags)
+	}
+	gmm_message.SendConfigurationUpdateCommand(ue, anType, flags)
+}
+
+// ReallocateGuti assigns a new 5G-GUTI to a registered UE and sends it in a Configuration Update Command
+// (TS 23.502 4.2.4.2), the old 5G-GUTI identifies the UE until it acknowledges the new one
+func ReallocateGuti(ue *context.AmfUe) error {
+	anType, ok := registeredAccessType(ue)
+	if !ok {
+		return fmt.Errorf("the UE is not registered")
+	}
+	context.GetSelf().ReallocateGuti(ue)
+	ue.GmmLog.Infof("Reallocate 5G-GUTI[%s], old 5G-GUTI[%s]", ue.Guti, ue.OldGuti)
+	RequestConfigurationUpdate(ue, anType, &context.ConfigurationUpdateCommandFlags{
+		NeedGUTI: true,
+	})
+	return nil
+}
+
+// ConfigurationUpdateAllUes updates the configuration of all the registered UEs, e.g. after a change of the network
+// name or of the local time zone
+func ConfigurationUpdateAllUes(flags *context.ConfigurationUpdateCommandFlags) {
+	context.GetSelf().UePool.Range(func(key, value interface{}) bool {
+		ue := value.(*context.AmfUe)
+		ue.Lock.Lock()
+		defer ue.Lock.Unlock()
+		if anType, ok := registeredAccessType(ue); ok {
+			RequestConfigurationUpdate(ue, anType, flags)
+		}
+		return true
+	})
+}
+
+// UpdateLadnInformation sends the LADN information to the registered UEs after a change of the LADNs, the UEs whose
+// LADN information changed get the new one, which no longer has the LADNs removed from the AMF
+func UpdateLadnInformation() {
+	anType := models.AccessType__3_GPP_ACCESS
+	context.GetSelf().UePool.Range(func(key, value interface{}) bool {
+		ue := value.(*context.AmfUe)
+		ue.Lock.Lock()
+		defer ue.Lock.Unlock()
+		if ue.State[anType] == nil || !ue.State[anType].Is(context.Registered) {
+			return true
+		}
+		if updateLadnInfo(ue, anType) {
+			RequestConfigurationUpdate(ue, anType, &context.ConfigurationUpdateCommandFlags{
+				NeedLadnInformation: true,
+			})
+		}
+		return true
+	})
+}
+
+// updateLadnInfo updates the LADN information of a registered UE, the LADN indication of its registration request is
+// no longer available so the LADNs already sent to the UE and the ones of its subscribed DNNs are kept if their
+// service area is in the registration area. It returns whether the LADN information changed.
+func updateLadnInfo(ue *context.AmfUe, anType models.AccessType) bool {
+	if anType != models.AccessType__3_GPP_ACCESS {
+		return false
+	}
+	var dnns []string
+	for _, ladn := range ue.LadnInfo {
+		dnns = append(dnns, ladn.Dnn)
+	}
+	if ue.SmfSelectionData != nil {
+		for _, snssaiInfos := range ue.SmfSelectionData.SubscribedSnssaiInfos {
+			for _, dnnInfo := range snssaiInfos.DnnInfos {
+				if dnn, ok := dnnInfo.Dnn.(string); ok && dnn != "*" && !slices.Contains(dnns, dnn) {
+					dnns = append(dnns, dnn)
+				}
+			}
+		}
+	}
+
+	var ladnInfo []factory.Ladn
+	for _, dnn := range dnns {
+		if ladn, ok := context.GetSelf().FindLadn(dnn); ok && ue.TaiListInRegistrationArea(ladn.TaiList, anType) {
+			ladnInfo = append(ladnInfo, ladn)
+		}
+	}
+	if reflect.DeepEqual(ladnInfo, ue.LadnInfo) || len(ladnInfo) == 0 && len(ue.LadnInfo) == 0 {
+		return false
+	}
+	ue.LadnInfo = ladnInfo
+	return true
+}
+
+// RequestNasCountRekeying requests the registered UE to perform a registration once its NAS COUNT is about to wrap
+// around, the primary authentication of the registration establishes a new NAS security context
+// (TS 33.501 6.4.3.1, TS 24.501 5.4.4.2)
+func RequestNasCountRekeying(ue *context.AmfUe, anType models.AccessType) {
+	if !ue.NasCountRekeyingRequired || ue.NasCountRekeyingRequested ||
+		ue.State[anType] == nil || !ue.State[anType].Is(context.Registered) {
+		return
+	}
+	ue.GmmLog.Info("Request the UE to register for a new NAS security context")
+	ue.NasCountRekeyingRequested = true
+	RequestConfigurationUpdate(ue, anType, &context.ConfigurationUpdateCommandFlags{
+		NeedRegistrationRequested: true,
+	})
+}
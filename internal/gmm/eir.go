@@ -0,0 +1,89 @@
+package gmm
+
+import (
+	"fmt"
+
+	"github.com/free5gc/amf/internal/context"
+	gmm_message "github.com/free5gc/amf/internal/gmm/message"
+	business_metrics "github.com/free5gc/amf/internal/metrics/business"
+	"github.com/free5gc/amf/internal/sbi/consumer"
+	"github.com/free5gc/amf/internal/util"
+	"github.com/free5gc/nas/nasMessage"
+	"github.com/free5gc/openapi/models"
+	Nnrf_NFDiscovery "github.com/free5gc/openapi/nrf/NFDiscovery"
+)
+
+// eirCheckStatusUnknown labels the PEI checks the 5G-EIR did not answer
+const eirCheckStatusUnknown = "UNKNOWN"
+
+func selectEir(ue *context.AmfUe) bool {
+	if ue.EirUri != "" {
+		return true
+	}
+	amfSelf := context.GetSelf()
+	param := Nnrf_NFDiscovery.SearchNFInstancesRequest{}
+	if ue.Supi != "" {
+		param.Supi = &ue.Supi
+	}
+	if amfSelf.Locality != "" {
+		param.PreferredLocality = &amfSelf.Locality
+	}
+
+	resp, err := consumer.GetConsumer().SendSearchNFInstances(
+		amfSelf.NrfUri, models.NrfNfManagementNfType__5_G_EIR, models.NrfNfManagementNfType_AMF, &param)
+	if err != nil {
+		ue.GmmLog.Errorf("AMF can not select a 5G-EIR by NRF: %+v", err)
+		return false
+	}
+	for index := range resp.NfInstances {
+		eirUri := util.SearchNFServiceUri(&resp.NfInstances[index], models.ServiceName_N5G_EIR_EIC,
+			models.NfServiceStatus_REGISTERED)
+		if eirUri != "" {
+			ue.EirId = resp.NfInstances[index].NfInstanceId
+			ue.EirUri = eirUri
+			return true
+		}
+	}
+	ue.GmmLog.Error("AMF can not select a 5G-EIR by NRF")
+	return false
+}
+
+// checkEquipmentIdentity checks the PEI of the UE with the 5G-EIR (TS 23.502 4.2.2.2.2 step 14), the registration of
+// a blacklisted UE is rejected unless it is an emergency registration. The registration goes on if the 5G-EIR can not
+// be reached or does not know the PEI, and the PEI is checked again in the next registration update.
+func checkEquipmentIdentity(ue *context.AmfUe, anType models.AccessType) error {
+	if !context.GetSelf().EirCheckRequired(ue) {
+		return nil
+	}
+	if !selectEir(ue) {
+		business_metrics.IncrEirCheckCounter(eirCheckStatusUnknown)
+		return nil
+	}
+
+	eirResponseData, problemDetails, err := consumer.GetConsumer().EquipmentIdentityCheck(ue)
+	if problemDetails != nil || err != nil {
+		business_metrics.IncrEirCheckCounter(eirCheckStatusUnknown)
+		if problemDetails != nil {
+			ue.GmmLog.Warnf("N5g-eir_EquipmentIdentityCheck failed: %+v", problemDetails)
+		} else {
+			ue.GmmLog.Warnf("N5g-eir_EquipmentIdentityCheck error: %+v", err)
+		}
+		return nil
+	}
+	business_metrics.IncrEirCheckCounter(string(eirResponseData.Status))
+	ue.EirCheckedPei = ue.Pei
+
+	switch eirResponseData.Status {
+	case consumer.EquipmentStatusBlacklisted:
+		if ue.EmergencyRegistered {
+			ue.GmmLog.Warnf("PEI[%s] is blacklisted, continue the emergency registration", ue.Pei)
+			return nil
+		}
+		ue.GmmLog.Errorf("PEI[%s] is blacklisted", ue.Pei)
+		gmm_message.SendRegistrationReject(ue.RanUe[anType], nasMessage.Cause5GMMIllegalME, "")
+		return fmt.Errorf("PEI[%s] is blacklisted", ue.Pei)
+	case consumer.EquipmentStatusGreylisted:
+		ue.GmmLog.Warnf("PEI[%s] is greylisted", ue.Pei)
+	}
+	return nil
+}
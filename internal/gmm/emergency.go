@@ -0,0 +1,61 @@
+package gmm
+
+import (
+	"github.com/free5gc/amf/internal/context"
+	"github.com/free5gc/amf/internal/logger"
+	ngap_message "github.com/free5gc/amf/internal/ngap/message"
+	"github.com/free5gc/ngap/ngapType"
+	"github.com/free5gc/openapi/models"
+	"github.com/free5gc/util/fsm"
+)
+
+// emergencyWithoutAuthentication tells whether the emergency registration of a UE which cannot be authenticated
+// continues, as allowed by the emergency policy (TS 33.501 10.2.1). The NAS security context of the UE is then
+// established with the null algorithms.
+func emergencyWithoutAuthentication(ue *context.AmfUe) bool {
+	if !ue.EmergencyRegistered {
+		return false
+	}
+	withSupi := ue.Supi != "" || ue.Suci != ""
+	if !context.GetSelf().EmergencyAllowedWithoutAuthentication(withSupi) {
+		return false
+	}
+	ue.GmmLog.Warnln("Emergency registration continues without authentication")
+	ue.UnauthenticatedSupi = true
+	ue.AuthenticationCtx = nil
+	ue.Kamf = ""
+	return true
+}
+
+// continueEmergencyWithoutAuthentication moves the emergency registration of a UE whose authentication failed on to
+// the security mode control if the emergency policy allows it, false is returned if the UE has to be rejected
+func continueEmergencyWithoutAuthentication(ue *context.AmfUe, accessType models.AccessType) (bool, error) {
+	if !emergencyWithoutAuthentication(ue) {
+		return false, nil
+	}
+	return true, GmmFSM.SendEvent(ue.State[accessType], AuthSuccessEvent, fsm.ArgsType{
+		ArgAmfUe:      ue,
+		ArgAccessType: accessType,
+		ArgEAPSuccess: false,
+		ArgEAPMessage: "",
+	}, logger.GmmLog)
+}
+
+// TS 23.502 4.13.4.2, the NG-RAN is requested to move the UE to the RAT or system providing the emergency services,
+// no Service Accept is sent to the UE (TS 24.501 5.6.1.4.1)
+func sendEmergencyServicesFallback(ue *context.AmfUe, anType models.AccessType) {
+	ue.GmmLog.Info("Emergency services fallback")
+
+	emergencyFallbackIndicator := &ngapType.EmergencyFallbackIndicator{
+		EmergencyFallbackRequestIndicator: ngapType.EmergencyFallbackRequestIndicator{
+			Value: ngapType.EmergencyFallbackRequestIndicatorPresentEmergencyFallbackRequested,
+		},
+	}
+	if ue.RanUe[anType].InitialContextSetup {
+		ngap_message.SendUEContextModificationRequest(ue, anType, nil, nil, nil, nil, emergencyFallbackIndicator)
+		return
+	}
+	// update Kgnb/Kn3iwf
+	ue.UpdateSecurityContext(anType)
+	ngap_message.SendInitialContextSetupRequest(ue, anType, nil, nil, nil, nil, emergencyFallbackIndicator)
+}
@@ -19,6 +19,7 @@ import (
 	gmm_common "github.com/free5gc/amf/internal/gmm/common"
 	gmm_message "github.com/free5gc/amf/internal/gmm/message"
 	"github.com/free5gc/amf/internal/logger"
+	business_metrics "github.com/free5gc/amf/internal/metrics/business"
 	ngap_message "github.com/free5gc/amf/internal/ngap/message"
 	"github.com/free5gc/amf/internal/sbi/consumer"
 	callback "github.com/free5gc/amf/internal/sbi/processor/notifier"
@@ -49,33 +50,148 @@ func HandleULNASTransport(ue *context.AmfUe, anType models.AccessType,
 		return fmt.Errorf("NAS message integrity check failed")
 	}
 
+	return transportPayload(ue, anType, ulNasTransport)
+}
+
+func transportPayload(ue *context.AmfUe, anType models.AccessType,
+	ulNasTransport *nasMessage.ULNASTransport,
+) error {
 	switch ulNasTransport.GetPayloadContainerType() {
 	// TS 24.501 5.4.5.2.3 case a)
 	case nasMessage.PayloadContainerTypeN1SMInfo:
 		return transport5GSMMessage(ue, anType, ulNasTransport)
 	case nasMessage.PayloadContainerTypeSMS:
-		return fmt.Errorf("PayloadContainerTypeSMS has not been implemented yet in UL NAS TRANSPORT")
+		return transportSMS(ue, anType, ulNasTransport)
 	case nasMessage.PayloadContainerTypeLPP:
-		return fmt.Errorf("PayloadContainerTypeLPP has not been implemented yet in UL NAS TRANSPORT")
+		return transportLPP(ue, anType, ulNasTransport)
 	case nasMessage.PayloadContainerTypeSOR:
-		return fmt.Errorf("PayloadContainerTypeSOR has not been implemented yet in UL NAS TRANSPORT")
+		return handleSorAck(ue, ulNasTransport.PayloadContainer.GetPayloadContainerContents())
 	case nasMessage.PayloadContainerTypeUEPolicy:
 		ue.GmmLog.Infoln("AMF Transfer UEPolicy To PCF")
 		callback.SendN1MessageNotify(ue, models.N1MessageClass_UPDP,
 			ulNasTransport.PayloadContainer.GetPayloadContainerContents(), nil)
 	case nasMessage.PayloadContainerTypeUEParameterUpdate:
-		ue.GmmLog.Infoln("AMF Transfer UEParameterUpdate To UDM")
-		upuMac, err := nasConvert.UpuAckToModels(ulNasTransport.PayloadContainer.GetPayloadContainerContents())
-		if err != nil {
-			return err
-		}
-		err = consumer.GetConsumer().PutUpuAck(ue, upuMac)
-		if err != nil {
-			return err
-		}
-		ue.GmmLog.Debugf("UpuMac[%s] in UPU ACK NAS Msg", upuMac)
+		return handleUpuAck(ue, ulNasTransport.PayloadContainer.GetPayloadContainerContents())
 	case nasMessage.PayloadContainerTypeMultiplePayload:
-		return fmt.Errorf("PayloadContainerTypeMultiplePayload has not been implemented yet in UL NAS TRANSPORT")
+		return transportMultiplePayload(ue, anType, ulNasTransport)
+	}
+	return nil
+}
+
+// n1N2MessagePayload returns the payload of the N1 message of the N1N2 Message Transfer kept until the UE is
+// reachable
+func n1N2MessagePayload(ue *context.AmfUe, requestData *models.N1N2MessageTransferReqData,
+	n1Msg []byte,
+) *context.PayloadContainerEntry {
+	payload := &context.PayloadContainerEntry{
+		Contents: n1Msg,
+	}
+	switch requestData.N1MessageContainer.N1MessageClass {
+	case models.N1MessageClass_SM:
+		pduSessionId := uint8(requestData.PduSessionId)
+		payload.Type = nasMessage.PayloadContainerTypeN1SMInfo
+		payload.PduSessionId = &pduSessionId
+	case models.N1MessageClass_LPP:
+		session := ue.NewPositioningSession(requestData.N1MessageContainer.NfId, requestData.LcsCorrelationId)
+		payload.Type = nasMessage.PayloadContainerTypeLPP
+		payload.AdditionalInformation = session.RoutingInformation()
+	case models.N1MessageClass_SMS:
+		payload.Type = nasMessage.PayloadContainerTypeSMS
+	case models.N1MessageClass_UPDP:
+		payload.Type = nasMessage.PayloadContainerTypeUEPolicy
+	default:
+		ue.GmmLog.Warnf("N1 message class[%s] is not supported", requestData.N1MessageContainer.N1MessageClass)
+		return nil
+	}
+	return payload
+}
+
+// handleSorAck provides the acknowledgement of the Steering of Roaming information sent by the UE to the UDM
+// (TS 23.122 Annex C.2, C.4)
+func handleSorAck(ue *context.AmfUe, sorContainer []byte) error {
+	if !ue.SorAckRequested {
+		ue.GmmLog.Warn("Discard SOR Ack not requested by the UDM")
+		return nil
+	}
+	sorMacIue, err := context.SorAckToModels(sorContainer)
+	if err != nil {
+		return err
+	}
+	ue.GmmLog.Debugf("SorMacIue[%s] in SOR ACK NAS Msg", sorMacIue)
+	if err = consumer.GetConsumer().SorAckInfo(ue, sorMacIue); err != nil {
+		return err
+	}
+	ue.SorAckRequested = false
+	return nil
+}
+
+// handleUpuAck provides the acknowledgement of the UE parameters update sent by the UE to the UDM (TS 23.502 4.20.2)
+func handleUpuAck(ue *context.AmfUe, upuContainer []byte) error {
+	// the UPU ACK timer and the UPU notified by the UDM update the UPU state with ue.Lock held
+	ue.Lock.Lock()
+	defer ue.Lock.Unlock()
+	if !ue.UpuAckRequested {
+		ue.GmmLog.Warn("Discard UPU Ack not requested by the UDM")
+		return nil
+	}
+	upuMacIue, err := context.UpuAckToModels(upuContainer)
+	if err != nil {
+		return err
+	}
+	ue.GmmLog.Debugf("UpuMacIue[%s] in UPU ACK NAS Msg", upuMacIue)
+	ue.StopUpuAckTimer()
+	ue.UpuAckRequested = false
+	return consumer.GetConsumer().PutUpuAck(ue, upuMacIue)
+}
+
+// TS 24.501 5.4.5.2.3 case b), TS 23.502 4.13.3.3
+func transportSMS(ue *context.AmfUe, anType models.AccessType, ulNasTransport *nasMessage.ULNASTransport) error {
+	ue.GmmLog.Info("Transport SMS to SMSF")
+
+	smsPayload := ulNasTransport.PayloadContainer.GetPayloadContainerContents()
+	if !ue.SmsAllowed {
+		ue.GmmLog.Warn("SMS over NAS is not allowed")
+		gmm_message.SendDLNASTransport(ue.RanUe[anType], nasMessage.PayloadContainerTypeSMS,
+			smsPayload, 0, nasMessage.Cause5GMMPayloadWasNotForwarded, nil, 0)
+		return nil
+	}
+
+	deliveryData, problemDetails, err := consumer.GetConsumer().SMServiceUplinkSMS(ue, anType, smsPayload)
+	if problemDetails != nil || err != nil {
+		if problemDetails != nil {
+			ue.GmmLog.Errorf("SMService UplinkSMS Failed Problem[%+v]", problemDetails)
+		} else {
+			ue.GmmLog.Errorf("SMService UplinkSMS Error[%+v]", err)
+		}
+		gmm_message.SendDLNASTransport(ue.RanUe[anType], nasMessage.PayloadContainerTypeSMS,
+			smsPayload, 0, nasMessage.Cause5GMMPayloadWasNotForwarded, nil, 0)
+		return nil
+	}
+	ue.GmmLog.Debugf("SMS record[%s] delivery status[%s]", deliveryData.SmsRecordId, deliveryData.DeliveryStatus)
+	return nil
+}
+
+// TS 24.501 5.4.5.2.3 case c), the LPP message is routed to the LMF of the positioning session identified by the
+// routing information in the Additional Information
+func transportLPP(ue *context.AmfUe, anType models.AccessType, ulNasTransport *nasMessage.ULNASTransport) error {
+	ue.GmmLog.Info("Transport LPP message to LMF")
+
+	var routingInformation []byte
+	if ulNasTransport.AdditionalInformation != nil {
+		routingInformation = ulNasTransport.AdditionalInformation.GetAdditionalInformationValue()
+	}
+	session, ok := ue.PositioningSessionFindByRoutingInformation(routingInformation)
+	if !ok {
+		ue.GmmLog.Warnf("Positioning session of routing information[%x] not found", routingInformation)
+		gmm_message.SendStatus5GMM(ue.RanUe[anType], nasMessage.Cause5GMMMessageNotCompatibleWithTheProtocolState)
+		return nil
+	}
+
+	if !callback.SendLppMessageNotify(ue, session.LmfId, session.LcsCorrelationId,
+		ulNasTransport.PayloadContainer.GetPayloadContainerContents()) {
+		ue.GmmLog.Warnf("LMF[%s] of positioning session[%d] has no N1 message subscription",
+			session.LmfId, session.RoutingId)
+		gmm_message.SendStatus5GMM(ue.RanUe[anType], nasMessage.Cause5GMMMessageNotCompatibleWithTheProtocolState)
 	}
 	return nil
 }
@@ -109,10 +225,12 @@ func transport5GSMMessage(ue *context.AmfUe, anType models.AccessType,
 			case nasMessage.ULNASTransportRequestTypeInitialEmergencyRequest:
 				fallthrough
 			case nasMessage.ULNASTransportRequestTypeExistingEmergencyPduSession:
-				ue.GmmLog.Warnf("Emergency PDU Session is not supported")
-				gmm_message.SendDLNASTransport(ue.RanUe[anType], nasMessage.PayloadContainerTypeN1SMInfo,
-					smMessage, pduSessionID, nasMessage.Cause5GMMPayloadWasNotForwarded, nil, 0)
-				return nil
+				if !context.GetSelf().EmergencyServicesSupported() {
+					ue.GmmLog.Warnf("Emergency PDU Session is not supported")
+					gmm_message.SendDLNASTransport(ue.RanUe[anType], nasMessage.PayloadContainerTypeN1SMInfo,
+						smMessage, pduSessionID, nasMessage.Cause5GMMPayloadWasNotForwarded, nil, 0)
+					return nil
+				}
 			}
 		}
 
@@ -124,7 +242,8 @@ func transport5GSMMessage(ue *context.AmfUe, anType models.AccessType,
 			}
 
 			switch requestType.GetRequestTypeValue() {
-			case nasMessage.ULNASTransportRequestTypeInitialRequest:
+			case nasMessage.ULNASTransportRequestTypeInitialRequest,
+				nasMessage.ULNASTransportRequestTypeInitialEmergencyRequest:
 				smContext.StoreULNASTransport(ulNasTransport)
 				//  perform a local release of the PDU session identified by the PDU session ID and shall request
 				// the SMF to perform a local release of the PDU session
@@ -184,8 +303,22 @@ func transport5GSMMessage(ue *context.AmfUe, anType models.AccessType,
 			// case iii) if the AMF does not have a PDU session routing context for the PDU session ID and the UE
 			// and the Request type IE is included and is set to "initial request"
 			case nasMessage.ULNASTransportRequestTypeInitialRequest:
+				// TS 24.501 5.4.5.2.5, an emergency registered UE can only establish emergency PDU sessions
+				if ue.EmergencyRegistered {
+					ue.GmmLog.Warnf("Non-emergency PDU Session requested by an emergency registered UE")
+					gmm_message.SendDLNASTransport(ue.RanUe[anType], nasMessage.PayloadContainerTypeN1SMInfo,
+						smMessage, pduSessionID, nasMessage.Cause5GMMPayloadWasNotForwarded, nil, 0)
+					return nil
+				}
+				_, err := CreatePDUSession(ulNasTransport, ue, anType, pduSessionID, smMessage)
+				return err
+			case nasMessage.ULNASTransportRequestTypeInitialEmergencyRequest:
 				_, err := CreatePDUSession(ulNasTransport, ue, anType, pduSessionID, smMessage)
 				return err
+			case nasMessage.ULNASTransportRequestTypeExistingEmergencyPduSession:
+				ue.GmmLog.Warnf("Emergency PDU Session[%d] does not exist", pduSessionID)
+				gmm_message.SendDLNASTransport(ue.RanUe[anType], nasMessage.PayloadContainerTypeN1SMInfo,
+					smMessage, pduSessionID, nasMessage.Cause5GMMPayloadWasNotForwarded, nil, 0)
 			case nasMessage.ULNASTransportRequestTypeModificationRequest:
 				fallthrough
 			case nasMessage.ULNASTransportRequestTypeExistingPduSession:
@@ -226,44 +359,27 @@ func CreatePDUSession(ulNasTransport *nasMessage.ULNASTransport,
 	smMessage []uint8,
 ) (setNewSmContext bool, err error) {
 	var (
-		snssai models.Snssai
-		dnn    string
+		newSmContext *context.SmContext
+		requestType  *models.RequestType
+		cause        uint8
+		errSelectSmf error
 	)
-	// A) AMF shall select an SMF
-
-	// If the S-NSSAI IE is not included and the user's subscription context obtained from UDM. AMF shall
-	// select a default snssai
-	if ulNasTransport.SNSSAI != nil {
-		snssai = nasConvert.SnssaiToModels(ulNasTransport.SNSSAI)
-	} else {
-		if allowedNssai, ok := ue.AllowedNssai[anType]; ok {
-			snssai = *allowedNssai[0].AllowedSnssai
-		} else {
-			return false, errors.New("Ue doesn't have allowedNssai")
-		}
-	}
-
-	if ulNasTransport.DNN != nil {
-		dnn = ulNasTransport.DNN.GetDNN()
+	// A) AMF shall select an SMF, the SMF of an emergency PDU session is selected with the emergency configuration
+	// (TS 23.501 5.16.4.5)
+	if ulNasTransport.RequestType != nil &&
+		ulNasTransport.RequestType.GetRequestTypeValue() == nasMessage.ULNASTransportRequestTypeInitialEmergencyRequest {
+		emergencyRequest := models.RequestType_INITIAL_EMERGENCY_REQUEST
+		requestType = &emergencyRequest
+		newSmContext, cause, errSelectSmf = consumer.GetConsumer().SelectEmergencySmf(ue, anType, pduSessionID)
 	} else {
-		// if user's subscription context obtained from UDM does not contain the default DNN for the,
-		// S-NSSAI, the AMF shall use a locally configured DNN as the DNN
-		dnn = ue.ServingAMF().SupportDnnLists[0]
-
-		if ue.SmfSelectionData != nil {
-			snssaiStr := util.SnssaiModelsToHex(snssai)
-			if snssaiInfo, ok := ue.SmfSelectionData.SubscribedSnssaiInfos[snssaiStr]; ok {
-				for _, dnnInfo := range snssaiInfo.DnnInfos {
-					if dnnInfo.DefaultDnnIndicator {
-						dnn = dnnInfo.Dnn.(string)
-					}
-				}
-			}
+		snssai, dnn, errSnssai := selectSnssaiAndDnn(ue, anType, ulNasTransport)
+		if errSnssai != nil {
+			return false, errSnssai
 		}
+		newSmContext, cause, errSelectSmf = consumer.GetConsumer().SelectSmf(ue, anType, pduSessionID, snssai, dnn)
 	}
 
-	if newSmContext, cause, errSelectSmf := consumer.GetConsumer().SelectSmf(
-		ue, anType, pduSessionID, snssai, dnn); errSelectSmf != nil {
+	if errSelectSmf != nil {
 		ue.GmmLog.Errorf("Select SMF failed: %+v", errSelectSmf)
 		gmm_message.SendDLNASTransport(ue.RanUe[anType], nasMessage.PayloadContainerTypeN1SMInfo,
 			smMessage, pduSessionID, cause, nil, 0)
@@ -272,7 +388,7 @@ func CreatePDUSession(ulNasTransport *nasMessage.ULNASTransport,
 		defer ue.Lock.Unlock()
 
 		smContextRef, errResponse, problemDetail, errSendReq := consumer.GetConsumer().SendCreateSmContextRequest(
-			ue, newSmContext, nil, smMessage)
+			ue, newSmContext, requestType, smMessage)
 		if errSendReq != nil {
 			ue.GmmLog.Errorf("CreateSmContextRequest Error: %+v", errSendReq)
 			return false, nil
@@ -296,6 +412,43 @@ func CreatePDUSession(ulNasTransport *nasMessage.ULNASTransport,
 	return false, nil
 }
 
+// selectSnssaiAndDnn returns the S-NSSAI and DNN the SMF of a new PDU session is selected with
+func selectSnssaiAndDnn(ue *context.AmfUe, anType models.AccessType,
+	ulNasTransport *nasMessage.ULNASTransport,
+) (snssai models.Snssai, dnn string, err error) {
+	// If the S-NSSAI IE is not included and the user's subscription context obtained from UDM. AMF shall
+	// select a default snssai
+	if ulNasTransport.SNSSAI != nil {
+		snssai = nasConvert.SnssaiToModels(ulNasTransport.SNSSAI)
+	} else {
+		if allowedNssai, ok := ue.AllowedNssai[anType]; ok {
+			snssai = *allowedNssai[0].AllowedSnssai
+		} else {
+			return snssai, dnn, errors.New("Ue doesn't have allowedNssai")
+		}
+	}
+
+	if ulNasTransport.DNN != nil {
+		dnn = ulNasTransport.DNN.GetDNN()
+	} else {
+		// if user's subscription context obtained from UDM does not contain the default DNN for the,
+		// S-NSSAI, the AMF shall use a locally configured DNN as the DNN
+		dnn = ue.ServingAMF().SupportDnnLists[0]
+
+		if ue.SmfSelectionData != nil {
+			snssaiStr := util.SnssaiModelsToHex(snssai)
+			if snssaiInfo, ok := ue.SmfSelectionData.SubscribedSnssaiInfos[snssaiStr]; ok {
+				for _, dnnInfo := range snssaiInfo.DnnInfos {
+					if dnnInfo.DefaultDnnIndicator {
+						dnn = dnnInfo.Dnn.(string)
+					}
+				}
+			}
+		}
+	}
+	return snssai, dnn, nil
+}
+
 func forward5GSMMessageToSMF(
 	ue *context.AmfUe,
 	accessType models.AccessType,
@@ -378,7 +531,7 @@ func forward5GSMMessageToSMF(
 
 // Handle cleartext IEs of Registration Request, which cleattext IEs defined in TS 24.501 4.4.6
 func HandleRegistrationRequest(ue *context.AmfUe, anType models.AccessType, procedureCode int64,
-	registrationRequest *nasMessage.RegistrationRequest,
+	registrationRequest *nasMessage.RegistrationRequest, requestedEdrx *context.ExtendedDRXParameters,
 ) error {
 	var guamiFromUeGuti models.Guami
 	amfSelf := context.GetSelf()
@@ -400,6 +553,9 @@ func HandleRegistrationRequest(ue *context.AmfUe, anType models.AccessType, proc
 	ue.StopT3513()
 	ue.StopT3565()
 
+	// the requested extended DRX parameters of the NAS message container replace the ones sent in clear
+	ue.RequestedEdrx = requestedEdrx
+
 	// TS 24.501 8.2.6.21: if the UE is sending a REGISTRATION REQUEST message as an initial NAS message,
 	// the UE has a valid 5G NAS security context and the UE needs to send non-cleartext IEs
 	// TS 24.501 4.4.6: When the UE sends a REGISTRATION REQUEST or SERVICE REQUEST message that includes a NAS message
@@ -415,18 +571,13 @@ func HandleRegistrationRequest(ue *context.AmfUe, anType models.AccessType, proc
 		if err != nil {
 			ue.SecurityContextAvailable = false
 		} else {
-			m := nas.NewMessage()
-			if errGmmMessageDecode := m.GmmMessageDecode(&contents); errGmmMessageDecode != nil {
-				return errGmmMessageDecode
-			}
-
-			messageType := m.GmmMessage.GmmHeader.GetMessageType()
-			if messageType != nas.MsgTypeRegistrationRequest {
-				return errors.New("The payload of NAS Message Container is not Registration Request")
-			}
 			// TS 24.501 4.4.6: The AMF shall consider the NAS message that is obtained from the NAS message container
 			// IE as the initial NAS message that triggered the procedure
-			registrationRequest = m.RegistrationRequest
+			registrationRequest, err = registrationRequestFromContainer(ue, registrationRequest, contents)
+			if err != nil {
+				gmm_message.SendRegistrationReject(ue.RanUe[anType], nasMessage.Cause5GMMSemanticallyIncorrectMessage, "")
+				return err
+			}
 		}
 	}
 	// TS 33.501 6.4.6 step 3: if the initial NAS message was protected but did not pass the integrity check
@@ -438,6 +589,7 @@ func HandleRegistrationRequest(ue *context.AmfUe, anType models.AccessType, proc
 	case nasMessage.RegistrationType5GSInitialRegistration:
 		ue.GmmLog.Infof("RegistrationType: Initial Registration")
 		ue.SecurityContextAvailable = false // need to start authentication procedure later
+		ue.EmergencyRegistered = false
 	case nasMessage.RegistrationType5GSMobilityRegistrationUpdating:
 		ue.GmmLog.Infof("RegistrationType: Mobility Registration Updating")
 		if ue.State[anType].Is(context.Deregistered) {
@@ -451,7 +603,13 @@ func HandleRegistrationRequest(ue *context.AmfUe, anType models.AccessType, proc
 			return fmt.Errorf("periodic registration updating was sent when the UE state was deregistered")
 		}
 	case nasMessage.RegistrationType5GSEmergencyRegistration:
-		return fmt.Errorf("not supported RegistrationType: Emergency Registration")
+		ue.GmmLog.Infof("RegistrationType: Emergency Registration")
+		if !amfSelf.EmergencyServicesSupported() {
+			gmm_message.SendRegistrationReject(ue.RanUe[anType], nasMessage.Cause5GMM5GSServicesNotAllowed, "")
+			return fmt.Errorf("registration reject[emergency services are not supported]")
+		}
+		ue.SecurityContextAvailable = false // need to start authentication procedure later
+		ue.EmergencyRegistered = true
 	case nasMessage.RegistrationType5GSReserved:
 		ue.RegistrationType5GS = nasMessage.RegistrationType5GSInitialRegistration
 		ue.GmmLog.Infof("RegistrationType: Reserved")
@@ -460,6 +618,12 @@ func HandleRegistrationRequest(ue *context.AmfUe, anType models.AccessType, proc
 		ue.RegistrationType5GS = nasMessage.RegistrationType5GSInitialRegistration
 	}
 
+	// TS 33.501 6.4.3.1, the NAS COUNT shall not wrap around with the same NAS security context
+	if ue.NasCountRekeyingRequired {
+		ue.GmmLog.Info("NAS COUNT is about to wrap around, a primary authentication is started")
+		ue.SecurityContextAvailable = false // need to start authentication procedure later
+	}
+
 	mobileIdentity5GSContents := registrationRequest.MobileIdentity5GS.GetMobileIdentity5GSContents()
 	if len(mobileIdentity5GSContents) < 1 {
 		return errors.New("broken MobileIdentity5GS")
@@ -519,6 +683,16 @@ func HandleRegistrationRequest(ue *context.AmfUe, anType models.AccessType, proc
 		ue.GmmLog.Infof("MobileIdentity5GS: PEI[%s]", imeisv)
 	}
 
+	// TS 23.501 5.16.4.1, a UE identified by its IMEI only cannot be authenticated
+	if ue.RegistrationType5GS == nasMessage.RegistrationType5GSEmergencyRegistration && ue.Supi == "" &&
+		(ue.IdentityTypeUsedForRegistration == nasMessage.MobileIdentity5GSTypeImei ||
+			ue.IdentityTypeUsedForRegistration == nasMessage.MobileIdentity5GSTypeImeisv) &&
+		!amfSelf.EmergencyAllowedWithoutAuthentication(false) {
+		gmm_message.SendRegistrationReject(ue.RanUe[anType],
+			nasMessage.Cause5GMMUEIdentityCannotBeDerivedByTheNetwork, "")
+		return fmt.Errorf("registration reject[emergency services are not allowed to UEs without SUPI]")
+	}
+
 	// NgKsi: TS 24.501 9.11.3.32
 	switch registrationRequest.NgksiAndRegistrationType5GS.GetTSC() {
 	case nasMessage.TypeOfSecurityContextFlagNative:
@@ -537,7 +711,7 @@ func HandleRegistrationRequest(ue *context.AmfUe, anType models.AccessType, proc
 	// TODO: This check due to RanUe may release during the process;it should be a better way to make this procedure
 	// as an atomic operation
 	if ue.RanUe[anType] != nil {
-		ue.Location = ue.RanUe[anType].Location
+		ue.SetLocation(ue.RanUe[anType].Location)
 		ue.Tai = ue.RanUe[anType].Tai
 		if ue.RanUe[anType].Ran != nil {
 			// ue.Ratype TS 23.502 4.2.2.1
@@ -616,6 +790,9 @@ func contextTransferFromOldAmf(ue *context.AmfUe, anType models.AccessType, oldA
 
 	ue.CopyDataFromUeContextModel(ueContextTransferRspData.UeContext)
 	if ue.SecurityContextAvailable {
+		// the UL NAS COUNT is the one of the Registration Request verified by the old AMF
+		ue.DerivateKamfOnMobility(ueContextTransferRspData.UeContext.SeafData, context.KamfDerivationDirectionMobility,
+			ue.ULCount.Get())
 		ue.DerivateAlgKey()
 	}
 	return nil
@@ -633,13 +810,16 @@ func HandleInitialRegistration(ue *context.AmfUe, anType models.AccessType) erro
 	// update Kgnb/Kn3iwf
 	ue.UpdateSecurityContext(anType)
 
-	// Registration with AMF re-allocation (TS 23.502 4.2.2.2.3)
-	if len(ue.SubscribedNssai) == 0 {
-		getSubscribedNssai(ue)
-	}
+	// the network slices of an emergency registered UE are not subject to the subscription (TS 23.501 5.16.4.3)
+	if !ue.EmergencyRegistered {
+		// Registration with AMF re-allocation (TS 23.502 4.2.2.2.3)
+		if len(ue.SubscribedNssai) == 0 {
+			getSubscribedNssai(ue)
+		}
 
-	if err := handleRequestedNssai(ue, anType); err != nil {
-		return err
+		if err := handleRequestedNssai(ue, anType); err != nil {
+			return err
+		}
 	}
 
 	if ue.RegistrationRequest.Capability5GMM != nil {
@@ -651,10 +831,7 @@ func HandleInitialRegistration(ue *context.AmfUe, anType models.AccessType) erro
 
 	storeLastVisitedRegisteredTAI(ue, ue.RegistrationRequest.LastVisitedRegisteredTAI)
 
-	if ue.RegistrationRequest.MICOIndication != nil {
-		ue.GmmLog.Warnf("Receive MICO Indication[RAAI: %d], Not Supported",
-			ue.RegistrationRequest.MICOIndication.GetRAAI())
-	}
+	negotiateMicoMode(ue, anType, ue.RegistrationRequest.MICOIndication)
 
 	// TODO: Negotiate DRX value if need (TS 23.501 5.4.5)
 	negotiateDRXParameters(ue, ue.RegistrationRequest.RequestedDRXParameters)
@@ -681,66 +858,76 @@ func HandleInitialRegistration(ue *context.AmfUe, anType models.AccessType) erro
 		return nil
 	}
 
-	// TODO (step 12 optional): the new AMF initiates ME identity check by invoking the
+	// step 12 (optional): the new AMF initiates ME identity check by invoking the
 	// N5g-eir_EquipmentIdentityCheck_Get service operation
+	if err := checkEquipmentIdentity(ue, anType); err != nil {
+		return errors.Wrap(err, "EquipmentIdentityCheck failed")
+	}
 
-	if ue.ServingAmfChanged || ue.State[models.AccessType_NON_3_GPP_ACCESS].Is(context.Registered) ||
-		!ue.ContextValid {
-		if err := communicateWithUDM(ue, anType); err != nil {
+	// no subscription data are retrieved for an unauthenticated SUPI, and an emergency registration is not rejected
+	// because of the UDM (TS 23.502 4.2.2.2.2 step 14)
+	if (ue.ServingAmfChanged || ue.State[models.AccessType_NON_3_GPP_ACCESS].Is(context.Registered) ||
+		!ue.ContextValid) && !ue.UnauthenticatedEmergency() {
+		if err := communicateWithUDM(ue, anType); err != nil && ue.EmergencyRegistered {
+			ue.GmmLog.Warnf("communicateWithUDM error: %v", err)
+		} else if err != nil {
 			ue.GmmLog.Errorf("communicateWithUDM error: %v", err)
 			gmm_message.SendRegistrationReject(ue.RanUe[anType], nasMessage.Cause5GMMPLMNNotAllowed, "")
 			return errors.Wrap(err, "communicateWithUDM failed")
 		}
 	}
 
-	param := Nnrf_NFDiscovery.SearchNFInstancesRequest{
-		Supi: &ue.Supi,
-	}
-	if amfSelf.Locality != "" {
-		param.PreferredLocality = &amfSelf.Locality
-	}
+	// the access and mobility policy does not apply to an emergency registered UE (TS 23.501 5.16.4.3), and no PCF
+	// is used if the local authentication skips it
+	if !ue.EmergencyRegistered && (amfSelf.LocalAusf == nil || !amfSelf.LocalAusf.SkipPcf) {
+		param := Nnrf_NFDiscovery.SearchNFInstancesRequest{
+			Supi: &ue.Supi,
+		}
+		if amfSelf.Locality != "" {
+			param.PreferredLocality = &amfSelf.Locality
+		}
 
-	// TODO: (step 15) Should use PCF ID to select PCF
-	// Retrieve PCF ID from old AMF
-	// if ue.PcfId != "" {
+		// TODO: (step 15) Should use PCF ID to select PCF
+		// Retrieve PCF ID from old AMF
+		// if ue.PcfId != "" {
 
-	// }
-	for {
-		resp, err := consumer.GetConsumer().SendSearchNFInstances(
-			amfSelf.NrfUri, models.NrfNfManagementNfType_PCF, models.NrfNfManagementNfType_AMF, &param)
-		if err != nil {
-			ue.GmmLog.Error("AMF can not select an PCF by NRF")
-		} else {
-			// select the first PCF, TODO: select base on other info
-			var pcfUri string
-			for index := range resp.NfInstances {
-				pcfUri = util.SearchNFServiceUri(&resp.NfInstances[index], models.ServiceName_NPCF_AM_POLICY_CONTROL,
-					models.NfServiceStatus_REGISTERED)
-				if pcfUri != "" {
-					ue.PcfId = resp.NfInstances[index].NfInstanceId
-					break
-				}
-			}
-			if ue.PcfUri = pcfUri; ue.PcfUri == "" {
+		// }
+		for {
+			resp, err := consumer.GetConsumer().SendSearchNFInstances(
+				amfSelf.NrfUri, models.NrfNfManagementNfType_PCF, models.NrfNfManagementNfType_AMF, &param)
+			if err != nil {
 				ue.GmmLog.Error("AMF can not select an PCF by NRF")
 			} else {
-				break
+				// select the first PCF, TODO: select base on other info
+				var pcfUri string
+				for index := range resp.NfInstances {
+					pcfUri = util.SearchNFServiceUri(&resp.NfInstances[index], models.ServiceName_NPCF_AM_POLICY_CONTROL,
+						models.NfServiceStatus_REGISTERED)
+					if pcfUri != "" {
+						ue.PcfId = resp.NfInstances[index].NfInstanceId
+						break
+					}
+				}
+				if ue.PcfUri = pcfUri; ue.PcfUri == "" {
+					ue.GmmLog.Error("AMF can not select an PCF by NRF")
+				} else {
+					break
+				}
 			}
+			time.Sleep(500 * time.Millisecond) // sleep a while when search NF Instance fail
 		}
-		time.Sleep(500 * time.Millisecond) // sleep a while when search NF Instance fail
-	}
 
-	problemDetails, err := consumer.GetConsumer().AMPolicyControlCreate(ue, anType)
-	if problemDetails != nil {
-		ue.GmmLog.Errorf("AM Policy Control Create Failed Problem[%+v]", problemDetails)
-	} else if err != nil {
-		ue.GmmLog.Errorf("AM Policy Control Create Error[%+v]", err)
+		problemDetails, err := consumer.GetConsumer().AMPolicyControlCreate(ue, anType)
+		if problemDetails != nil {
+			ue.GmmLog.Errorf("AM Policy Control Create Failed Problem[%+v]", problemDetails)
+		} else if err != nil {
+			ue.GmmLog.Errorf("AM Policy Control Create Error[%+v]", err)
+		}
 	}
 
 	// Service Area Restriction are applicable only to 3GPP access
 	if anType == models.AccessType__3_GPP_ACCESS {
-		if ue.AmPolicyAssociation != nil && ue.AmPolicyAssociation.ServAreaRes != nil {
-			servAreaRes := ue.AmPolicyAssociation.ServAreaRes
+		if servAreaRes := ue.ServiceAreaRestriction(); servAreaRes != nil {
 			if servAreaRes.RestrictionType == models.RestrictionType_ALLOWED_AREAS {
 				numOfallowedTAs := 0
 				for _, area := range servAreaRes.Areas {
@@ -761,12 +948,23 @@ func HandleInitialRegistration(ue *context.AmfUe, anType models.AccessType) erro
 	// 	TODO: send N2 AMF Mobility Request
 	// }
 
+	if !ue.EmergencyRegistered {
+		handleSmsOverNasRequest(ue, anType)
+	}
+
+	// the eDRX value subscribed is retrieved from the UDM by now
+	negotiateExtendedDRXParameters(ue, anType)
+	selectPendingNssai(ue, anType)
 	amfSelf.AllocateRegistrationArea(ue, anType)
 	ue.GmmLog.Debugf("Use original GUTI[%s]", ue.Guti)
 
 	assignLadnInfo(ue, anType)
 
-	amfSelf.AddAmfUeToUePool(ue, ue.Supi)
+	if ue.Supi != "" {
+		amfSelf.AddAmfUeToUePool(ue, ue.Supi)
+	} else {
+		amfSelf.AddAmfUeToUePoolByPei(ue)
+	}
 	ue.T3502Value = amfSelf.T3502Value
 	if anType == models.AccessType__3_GPP_ACCESS {
 		ue.T3512Value = amfSelf.T3512Value
@@ -808,10 +1006,7 @@ func HandleMobilityAndPeriodicRegistrationUpdating(ue *context.AmfUe, anType mod
 
 	storeLastVisitedRegisteredTAI(ue, ue.RegistrationRequest.LastVisitedRegisteredTAI)
 
-	if ue.RegistrationRequest.MICOIndication != nil {
-		ue.GmmLog.Warnf("Receive MICO Indication[RAAI: %d], Not Supported",
-			ue.RegistrationRequest.MICOIndication.GetRAAI())
-	}
+	negotiateMicoMode(ue, anType, ue.RegistrationRequest.MICOIndication)
 
 	// TODO: Negotiate DRX value if need (TS 23.501 5.4.5)
 	negotiateDRXParameters(ue, ue.RegistrationRequest.RequestedDRXParameters)
@@ -826,8 +1021,11 @@ func HandleMobilityAndPeriodicRegistrationUpdating(ue *context.AmfUe, anType mod
 		return nil
 	}
 
-	// TODO (step 12 optional): the new AMF initiates ME identity check by invoking the
+	// step 12 (optional): the new AMF initiates ME identity check by invoking the
 	// N5g-eir_EquipmentIdentityCheck_Get service operation
+	if err := checkEquipmentIdentity(ue, anType); err != nil {
+		return errors.Wrap(err, "EquipmentIdentityCheck failed")
+	}
 
 	if ue.ServingAmfChanged || ue.State[models.AccessType_NON_3_GPP_ACCESS].Is(context.Registered) ||
 		!ue.ContextValid {
@@ -838,6 +1036,10 @@ func HandleMobilityAndPeriodicRegistrationUpdating(ue *context.AmfUe, anType mod
 		}
 	}
 
+	if ue.RegistrationType5GS != nasMessage.RegistrationType5GSPeriodicRegistrationUpdating {
+		handleSmsOverNasRequest(ue, anType)
+	}
+
 	var reactivationResult *[psiArraySize]bool
 	var errPduSessionId, errCause []uint8
 	cxtList := ngapType.PDUSessionResourceSetupListCxtReq{}
@@ -848,12 +1050,12 @@ func HandleMobilityAndPeriodicRegistrationUpdating(ue *context.AmfUe, anType mod
 		allowReEstablishPduSession := true
 
 		// determines that the UE is in non-allowed area or is not in allowed area
-		if ue.AmPolicyAssociation != nil && ue.AmPolicyAssociation.ServAreaRes != nil {
-			switch ue.AmPolicyAssociation.ServAreaRes.RestrictionType {
+		if servAreaRes := ue.ServiceAreaRestriction(); servAreaRes != nil {
+			switch servAreaRes.RestrictionType {
 			case models.RestrictionType_ALLOWED_AREAS:
-				allowReEstablishPduSession = context.TacInAreas(ue.Tai.Tac, ue.AmPolicyAssociation.ServAreaRes.Areas)
+				allowReEstablishPduSession = context.TacInAreas(ue.Tai.Tac, servAreaRes.Areas)
 			case models.RestrictionType_NOT_ALLOWED_AREAS:
-				allowReEstablishPduSession = !context.TacInAreas(ue.Tai.Tac, ue.AmPolicyAssociation.ServAreaRes.Areas)
+				allowReEstablishPduSession = !context.TacInAreas(ue.Tai.Tac, servAreaRes.Areas)
 			}
 		}
 
@@ -894,20 +1096,10 @@ func HandleMobilityAndPeriodicRegistrationUpdating(ue *context.AmfUe, anType mod
 			gmm_message.SendRegistrationAccept(ue, anType, pduSessionStatus,
 				reactivationResult, errPduSessionId, errCause, &cxtList)
 
-			switch requestData.N1MessageContainer.N1MessageClass {
-			case models.N1MessageClass_SM:
-				gmm_message.SendDLNASTransport(ue.RanUe[anType], nasMessage.PayloadContainerTypeN1SMInfo,
-					n1Msg, requestData.PduSessionId, 0, nil, 0)
-			case models.N1MessageClass_LPP:
-				gmm_message.SendDLNASTransport(ue.RanUe[anType], nasMessage.PayloadContainerTypeLPP,
-					n1Msg, 0, 0, nil, 0)
-			case models.N1MessageClass_SMS:
-				gmm_message.SendDLNASTransport(ue.RanUe[anType], nasMessage.PayloadContainerTypeSMS,
-					n1Msg, 0, 0, nil, 0)
-			case models.N1MessageClass_UPDP:
-				gmm_message.SendDLNASTransport(ue.RanUe[anType], nasMessage.PayloadContainerTypeUEPolicy,
-					n1Msg, 0, 0, nil, 0)
+			if payload := n1N2MessagePayload(ue, requestData, n1Msg); payload != nil {
+				ue.AppendPendingDLPayload(payload)
 			}
+			gmm_message.SendPendingDLPayloads(ue.RanUe[anType])
 			ue.N1N2Message = nil
 			return nil
 		}
@@ -929,11 +1121,15 @@ func HandleMobilityAndPeriodicRegistrationUpdating(ue *context.AmfUe, anType mod
 		updateReq := models.PcfAmPolicyControlPolicyAssociationUpdateRequest{}
 		updateReq.Triggers = append(updateReq.Triggers, models.PcfAmPolicyControlRequestTrigger_LOC_CH)
 		updateReq.UserLoc = &ue.Location
-		problemDetails, err := consumer.GetConsumer().AMPolicyControlUpdate(ue, updateReq)
+		policyUpdate, problemDetails, err := consumer.GetConsumer().AMPolicyControlUpdate(ue, updateReq)
 		if problemDetails != nil {
 			ue.GmmLog.Errorf("AM Policy Control Update Failed Problem[%+v]", problemDetails)
 		} else if err != nil {
 			ue.GmmLog.Errorf("AM Policy Control Update Error[%v]", err)
+		} else {
+			ue.Lock.Lock()
+			gmm_common.UpdatePresenceReportingAreas(ue, policyUpdate)
+			ue.Lock.Unlock()
 		}
 		ue.LocationChanged = false
 	}
@@ -945,6 +1141,9 @@ func HandleMobilityAndPeriodicRegistrationUpdating(ue *context.AmfUe, anType mod
 	// 	TODO: send N2 AMF Mobility Request
 	// }
 
+	// the eDRX value subscribed is retrieved from the UDM by now
+	negotiateExtendedDRXParameters(ue, anType)
+	selectPendingNssai(ue, anType)
 	amfSelf.AllocateRegistrationArea(ue, anType)
 	assignLadnInfo(ue, anType)
 
@@ -956,6 +1155,17 @@ func HandleMobilityAndPeriodicRegistrationUpdating(ue *context.AmfUe, anType mod
 	return nil
 }
 
+// handleSmsOverNasRequest activates SMS over NAS if the UE requests it in the 5GS update type of its registration,
+// and deactivates it if the UE no longer requests it (TS 24.501 5.5.1.2.2, 5.5.1.3.2)
+func handleSmsOverNasRequest(ue *context.AmfUe, anType models.AccessType) {
+	updateType := ue.RegistrationRequest.UpdateType5GS
+	if updateType != nil && updateType.GetSMSRequested() == 1 {
+		gmm_common.ActivateSmsOverNas(ue, anType)
+	} else {
+		gmm_common.DeactivateSmsOverNas(ue)
+	}
+}
+
 // TS 23.502 4.2.2.2.2 step 1
 // If available, the last visited TAI shall be included in order to help the AMF produce Registration Area for the UE
 func storeLastVisitedRegisteredTAI(ue *context.AmfUe, lastVisitedRegisteredTAI *nasType.LastVisitedRegisteredTAI) {
@@ -1001,9 +1211,62 @@ func negotiateDRXParameters(ue *context.AmfUe, requestedDRXParameters *nasType.R
 	}
 }
 
+// TS 24.501 5.5.1.2.4, the eDRX parameters are used if the UE requests them in each registration and the AMF
+// supports eDRX. The eDRX value subscribed for NR is used instead of the requested one as the policy of the AMF asks.
+func negotiateExtendedDRXParameters(ue *context.AmfUe, anType models.AccessType) {
+	ue.NegotiatedEdrx = nil
+	if ue.RequestedEdrx == nil {
+		return
+	}
+
+	edrx := context.GetSelf().Edrx
+	if edrx == nil || anType != models.AccessType__3_GPP_ACCESS || ue.EmergencyRegistered {
+		ue.GmmLog.Infof("eDRX is not accepted")
+		return
+	}
+	negotiated := *ue.RequestedEdrx
+	if edrx.Policy == factory.EdrxPolicySubscription {
+		value, ok := ue.SubscribedEdrxValue()
+		if !ok {
+			ue.GmmLog.Infof("eDRX is not accepted[no eDRX subscription]")
+			return
+		}
+		negotiated.EdrxValue = value
+	}
+	ue.NegotiatedEdrx = &negotiated
+	ue.GmmLog.Infof("eDRX is accepted[cycle: %s, paging time window: %s]", negotiated.Cycle(),
+		negotiated.PagingTimeWindowLength())
+}
+
+// TS 24.501 5.5.1.2.4, the MICO mode is used if the UE requests it in each registration and the AMF accepts it.
+// The all PLMN registration area is allocated according to the RAAI policy of the AMF.
+func negotiateMicoMode(ue *context.AmfUe, anType models.AccessType, micoIndication *nasType.MICOIndication) {
+	ue.MicoMode = false
+	ue.MicoAllPlmnRegistrationArea = false
+	if micoIndication == nil {
+		return
+	}
+
+	mico := context.GetSelf().Mico
+	// the MICO mode is not used for an emergency registered UE (TS 23.501 5.4.1.3)
+	if mico == nil || anType != models.AccessType__3_GPP_ACCESS || ue.EmergencyRegistered {
+		ue.GmmLog.Infof("MICO mode is not accepted")
+		return
+	}
+	ue.MicoMode = true
+	ue.MicoAllPlmnRegistrationArea = micoIndication.GetRAAI() == 1 &&
+		mico.RaaiPolicy == factory.MicoRaaiPolicyAsRequested
+	ue.GmmLog.Infof("MICO mode is accepted[all PLMN registration area: %t]", ue.MicoAllPlmnRegistrationArea)
+}
+
 func communicateWithUDM(ue *context.AmfUe, accessType models.AccessType) error {
 	ue.GmmLog.Debugln("communicateWithUDM")
 	amfSelf := context.GetSelf()
+	if amfSelf.LocalAusf != nil && amfSelf.LocalAusf.SkipUdm {
+		// the UE subscribes to the S-NSSAIs supported by the AMF if the local authentication skips the UDM
+		ue.GmmLog.Debugln("Local authentication enabled, skip the UDM")
+		return nil
+	}
 
 	// UDM selection described in TS 23.501 6.3.8
 	// TODO: consider udm group id, Routing ID part of SUCI, GPSI or External Group ID (e.g., by the NEF)
@@ -1076,6 +1339,10 @@ func communicateWithUDM(ue *context.AmfUe, accessType models.AccessType) error {
 
 func getSubscribedNssai(ue *context.AmfUe) {
 	amfSelf := context.GetSelf()
+	if amfSelf.LocalAusf != nil && amfSelf.LocalAusf.SkipUdm {
+		ue.SubscribedNssai = amfSelf.LocalAusf.SubscribedNssai()
+		return
+	}
 	if ue.NudmSDMUri == "" {
 		param := Nnrf_NFDiscovery.SearchNFInstancesRequest{
 			Supi: &ue.Supi,
@@ -1274,7 +1541,7 @@ func handleRequestedNssai(ue *context.AmfUe, anType models.AccessType) error {
 			}
 		}
 	}
-	return nil
+	return admitAllowedNssai(ue, anType)
 }
 
 func assignLadnInfo(ue *context.AmfUe, accessType models.AccessType) {
@@ -1286,7 +1553,7 @@ func assignLadnInfo(ue *context.AmfUe, accessType models.AccessType) {
 		// request for LADN information
 		if ue.RegistrationRequest.LADNIndication.GetLen() == 0 {
 			if ue.HasWildCardSubscribedDNN() {
-				for _, ladn := range amfSelf.LadnPool {
+				for _, ladn := range amfSelf.LadnList() {
 					if ue.TaiListInRegistrationArea(ladn.TaiList, accessType) {
 						ue.LadnInfo = append(ue.LadnInfo, ladn)
 					}
@@ -1294,7 +1561,7 @@ func assignLadnInfo(ue *context.AmfUe, accessType models.AccessType) {
 			} else {
 				for _, snssaiInfos := range ue.SmfSelectionData.SubscribedSnssaiInfos {
 					for _, dnnInfo := range snssaiInfos.DnnInfos {
-						if ladn, ok := amfSelf.LadnPool[dnnInfo.Dnn.(string)]; ok { // check if this dnn is a ladn
+						if ladn, ok := amfSelf.FindLadn(dnnInfo.Dnn.(string)); ok { // check if this dnn is a ladn
 							if ue.TaiListInRegistrationArea(ladn.TaiList, accessType) {
 								ue.LadnInfo = append(ue.LadnInfo, ladn)
 							}
@@ -1305,7 +1572,7 @@ func assignLadnInfo(ue *context.AmfUe, accessType models.AccessType) {
 		} else {
 			requestedLadnList := nasConvert.LadnToModels(ue.RegistrationRequest.LADNIndication.GetLADNDNNValue())
 			for _, requestedLadn := range requestedLadnList {
-				if ladn, ok := amfSelf.LadnPool[requestedLadn]; ok {
+				if ladn, ok := amfSelf.FindLadn(requestedLadn); ok {
 					if ue.TaiListInRegistrationArea(ladn.TaiList, accessType) {
 						ue.LadnInfo = append(ue.LadnInfo, ladn)
 					}
@@ -1316,7 +1583,7 @@ func assignLadnInfo(ue *context.AmfUe, accessType models.AccessType) {
 		for _, snssaiInfos := range ue.SmfSelectionData.SubscribedSnssaiInfos {
 			for _, dnnInfo := range snssaiInfos.DnnInfos {
 				if dnnInfo.Dnn != "*" {
-					if ladn, ok := amfSelf.LadnPool[dnnInfo.Dnn.(string)]; ok {
+					if ladn, ok := amfSelf.FindLadn(dnnInfo.Dnn.(string)); ok {
 						if ue.TaiListInRegistrationArea(ladn.TaiList, accessType) {
 							ue.LadnInfo = append(ue.LadnInfo, ladn)
 						}
@@ -1652,6 +1919,19 @@ func HandleConfigurationUpdateComplete(ue *context.AmfUe,
 
 	// Stop timer T3555 in TS 24.501 Figure 5.4.4.1.1 in handler
 	ue.StopT3555()
+	if configurationUpdate := ue.ConfigurationUpdate; configurationUpdate != nil &&
+		configurationUpdate.Status == context.ConfigurationUpdateStatusPending {
+		configurationUpdate.Status = context.ConfigurationUpdateStatusAcknowledged
+		// TS 24.501 5.4.4.3, the old 5G-GUTI is no longer valid once the UE acknowledged the new one
+		if configurationUpdate.Flags.NeedGUTI {
+			ue.ReleaseOldGuti()
+		}
+		// TS 24.501 5.4.4.3, the NAS signalling connection is released for the UE to perform the registration
+		if configurationUpdate.Flags.NeedRegistrationRequested && ue.RanUe[configurationUpdate.AccessType] != nil {
+			ngap_message.SendUEContextReleaseCommand(ue.RanUe[configurationUpdate.AccessType],
+				context.UeContextN2NormalRelease, ngapType.CausePresentNas, ngapType.CauseNasPresentNormalRelease)
+		}
+	}
 	// TODO: Send acknowledgment by Nudm_SMD_Info_Service to UDM in handler
 	//		import "github.com/free5gc/openapi/Nudm_SubscriberDataManagement" client.Info
 
@@ -1668,6 +1948,9 @@ func AuthenticationProcedure(ue *context.AmfUe, accessType models.AccessType) (b
 			ue.GmmLog.Debugln("UE has a valid security context - skip the authentication procedure")
 			return true, nil
 		}
+	} else if ue.EmergencyRegistered && ue.Pei != "" && emergencyWithoutAuthentication(ue) {
+		// the UE identified by its PEI only is not authenticated
+		return true, nil
 	} else {
 		// Request UE's SUCI by sending identity request
 		ue.IdentityRequestSendTimes++
@@ -1675,45 +1958,31 @@ func AuthenticationProcedure(ue *context.AmfUe, accessType models.AccessType) (b
 		return false, nil
 	}
 
-	amfSelf := context.GetSelf()
-
-	// TODO: consider ausf group id, Routing ID part of SUCI
-	param := Nnrf_NFDiscovery.SearchNFInstancesRequest{}
-	resp, err := consumer.GetConsumer().SendSearchNFInstances(
-		amfSelf.NrfUri, models.NrfNfManagementNfType_AUSF, models.NrfNfManagementNfType_AMF, &param)
-	if err != nil {
-		ue.GmmLog.Error("AMF can not select an AUSF by NRF")
-		gmm_message.SendRegistrationReject(ue.RanUe[accessType], nasMessage.Cause5GMMCongestion, "")
-		return false, err
-	}
-
-	// select the first AUSF, TODO: select base on other info
-	var ausfUri string
-	for index := range resp.NfInstances {
-		ue.AusfId = resp.NfInstances[index].NfInstanceId
-		ausfUri = util.SearchNFServiceUri(&resp.NfInstances[index], models.ServiceName_NAUSF_AUTH,
-			models.NfServiceStatus_REGISTERED)
-		if ausfUri != "" {
-			break
-		}
-	}
-	if ausfUri == "" {
-		err = fmt.Errorf("AMF can not select an AUSF by NRF")
+	if err := selectAusf(ue); err != nil {
 		ue.GmmLog.Error(err)
+		if emergencyWithoutAuthentication(ue) {
+			return true, nil
+		}
 		gmm_message.SendRegistrationReject(ue.RanUe[accessType], nasMessage.Cause5GMMCongestion, "")
 		return false, err
 	}
-	ue.AusfUri = ausfUri
 
-	response, problemDetails, err := consumer.GetConsumer().SendUEAuthenticationAuthenticateRequest(ue, nil)
+	response, problemDetails, err := consumer.GetConsumer().UEAuthenticator().SendUEAuthenticationAuthenticateRequest(
+		ue, nil)
 	if err != nil {
 		ue.GmmLog.Errorf("Nausf_UEAU Authenticate Request Error: %+v", err)
+		if emergencyWithoutAuthentication(ue) {
+			return true, nil
+		}
 		gmm_message.SendRegistrationReject(ue.RanUe[accessType], nasMessage.Cause5GMMCongestion, "")
 		err = fmt.Errorf("Authentication procedure failed")
 		ue.GmmLog.Error(err)
 		return false, err
 	} else if problemDetails != nil {
 		ue.GmmLog.Warnf("Nausf_UEAU Authenticate Request Failed: %+v", problemDetails)
+		if emergencyWithoutAuthentication(ue) {
+			return true, nil
+		}
 		var cause uint8
 		switch problemDetails.Status {
 		case http.StatusForbidden, http.StatusNotFound:
@@ -1733,6 +2002,39 @@ func AuthenticationProcedure(ue *context.AmfUe, accessType models.AccessType) (b
 	return false, nil
 }
 
+// selectAusf selects the AUSF authenticating the UE by NRF, none is selected if the UEs are authenticated by the
+// local AUSF of the AMF context
+func selectAusf(ue *context.AmfUe) error {
+	amfSelf := context.GetSelf()
+	if amfSelf.LocalAusf != nil {
+		return nil
+	}
+
+	// TODO: consider ausf group id, Routing ID part of SUCI
+	param := Nnrf_NFDiscovery.SearchNFInstancesRequest{}
+	resp, err := consumer.GetConsumer().SendSearchNFInstances(
+		amfSelf.NrfUri, models.NrfNfManagementNfType_AUSF, models.NrfNfManagementNfType_AMF, &param)
+	if err != nil {
+		return errors.Wrap(err, "AMF can not select an AUSF by NRF")
+	}
+
+	// select the first AUSF, TODO: select base on other info
+	var ausfUri string
+	for index := range resp.NfInstances {
+		ue.AusfId = resp.NfInstances[index].NfInstanceId
+		ausfUri = util.SearchNFServiceUri(&resp.NfInstances[index], models.ServiceName_NAUSF_AUTH,
+			models.NfServiceStatus_REGISTERED)
+		if ausfUri != "" {
+			break
+		}
+	}
+	if ausfUri == "" {
+		return fmt.Errorf("AMF can not select an AUSF by NRF")
+	}
+	ue.AusfUri = ausfUri
+	return nil
+}
+
 // TS 24501 5.6.1
 func HandleServiceRequest(ue *context.AmfUe, anType models.AccessType,
 	serviceRequest *nasMessage.ServiceRequest,
@@ -1786,18 +2088,14 @@ func HandleServiceRequest(ue *context.AmfUe, anType models.AccessType,
 		if err != nil {
 			ue.SecurityContextAvailable = false
 		} else {
-			m := nas.NewMessage()
-			if errGmmMessageDecode := m.GmmMessageDecode(&contents); errGmmMessageDecode != nil {
-				return errGmmMessageDecode
-			}
-
-			messageType := m.GmmMessage.GmmHeader.GetMessageType()
-			if messageType != nas.MsgTypeServiceRequest {
-				return errors.New("The payload of NAS message Container is not service request")
-			}
 			// TS 24.501 4.4.6: The AMF shall consider the NAS message that is obtained from the NAS message container
 			// IE as the initial NAS message that triggered the procedure
-			serviceRequest = m.ServiceRequest
+			serviceRequest, err = serviceRequestFromContainer(serviceRequest, contents)
+			if err != nil {
+				gmm_message.SendServiceReject(ue.RanUe[anType], pduStatusResult,
+					nasMessage.Cause5GMMSemanticallyIncorrectMessage)
+				return err
+			}
 		}
 		// TS 33.501 6.4.6 step 3: if the initial NAS message was protected but did not pass the integrity check
 		ue.RetransmissionOfInitialNASMsg = ue.MacFailed
@@ -1809,8 +2107,9 @@ func HandleServiceRequest(ue *context.AmfUe, anType models.AccessType,
 	var dlPduSessionId int32
 	cxtList := ngapType.PDUSessionResourceSetupListCxtReq{}
 
-	if serviceType == nasMessage.ServiceTypeEmergencyServices ||
-		serviceType == nasMessage.ServiceTypeEmergencyServicesFallback {
+	if (serviceType == nasMessage.ServiceTypeEmergencyServices ||
+		serviceType == nasMessage.ServiceTypeEmergencyServicesFallback) &&
+		!context.GetSelf().EmergencyServicesSupported() {
 		ue.GmmLog.Warnf("emergency service is not supported")
 		gmm_message.SendServiceReject(ue.RanUe[anType], pduStatusResult, nasMessage.Cause5GMM5GSServicesNotAllowed)
 		ngap_message.SendUEContextReleaseCommand(ue.RanUe[anType],
@@ -1818,8 +2117,16 @@ func HandleServiceRequest(ue *context.AmfUe, anType models.AccessType,
 		return nil
 	}
 
+	if serviceType == nasMessage.ServiceTypeEmergencyServicesFallback {
+		sendEmergencyServicesFallback(ue, anType)
+		return nil
+	}
+
 	if serviceType == nasMessage.ServiceTypeSignalling {
 		err := gmm_message.SendServiceAccept(ue, anType, cxtList, pduStatusResult, nil, nil, nil)
+		if err == nil {
+			SendPendingConfigurationUpdate(ue, anType)
+		}
 		return err
 	}
 
@@ -1861,25 +2168,15 @@ func HandleServiceRequest(ue *context.AmfUe, anType models.AccessType,
 		if ue.N1N2Message != nil {
 			// downlink signaling only
 			if n2Info == nil {
+				// the N1 message is sent after the Service Accept together with the other pending payloads
+				if payload := n1N2MessagePayload(ue, N1N2ReqData, n1Msg); payload != nil {
+					ue.AppendPendingDLPayload(payload)
+				}
 				err := gmm_message.SendServiceAccept(ue, anType, cxtList, pduStatusResult,
 					reactivationResult, errPduSessionId, errCause)
 				if err != nil {
 					return err
 				}
-				switch N1N2ReqData.N1MessageContainer.N1MessageClass {
-				case models.N1MessageClass_SM:
-					gmm_message.SendDLNASTransport(ue.RanUe[anType],
-						nasMessage.PayloadContainerTypeN1SMInfo, n1Msg, N1N2ReqData.PduSessionId, 0, nil, 0)
-				case models.N1MessageClass_LPP:
-					gmm_message.SendDLNASTransport(ue.RanUe[anType],
-						nasMessage.PayloadContainerTypeLPP, n1Msg, 0, 0, nil, 0)
-				case models.N1MessageClass_SMS:
-					gmm_message.SendDLNASTransport(ue.RanUe[anType],
-						nasMessage.PayloadContainerTypeSMS, n1Msg, 0, 0, nil, 0)
-				case models.N1MessageClass_UPDP:
-					gmm_message.SendDLNASTransport(ue.RanUe[anType],
-						nasMessage.PayloadContainerTypeUEPolicy, n1Msg, 0, 0, nil, 0)
-				}
 				ue.N1N2Message = nil
 				return nil
 			}
@@ -1919,25 +2216,24 @@ func HandleServiceRequest(ue *context.AmfUe, anType models.AccessType,
 			}
 		}
 
-		// downlink signaling
-		if ue.ConfigurationUpdateCommandFlags != nil {
+		// downlink signaling, the pending payloads are sent after the Service Accept
+		if ue.ConfigurationUpdateCommandFlags != nil || (ue.N1N2Message == nil && ue.HasPendingDLPayloads()) {
 			err := gmm_message.SendServiceAccept(ue, anType, cxtList,
 				pduStatusResult, reactivationResult, errPduSessionId, errCause)
 			if err != nil {
 				return err
 			}
-			gmm_message.SendConfigurationUpdateCommand(ue, anType, ue.ConfigurationUpdateCommandFlags)
-			ue.ConfigurationUpdateCommandFlags = nil
+			SendPendingConfigurationUpdate(ue, anType)
 		}
 	case nasMessage.ServiceTypeData:
 		if anType == models.AccessType__3_GPP_ACCESS {
-			if ue.AmPolicyAssociation != nil && ue.AmPolicyAssociation.ServAreaRes != nil {
+			if servAreaRes := ue.ServiceAreaRestriction(); servAreaRes != nil {
 				var accept bool
-				switch ue.AmPolicyAssociation.ServAreaRes.RestrictionType {
+				switch servAreaRes.RestrictionType {
 				case models.RestrictionType_ALLOWED_AREAS:
-					accept = context.TacInAreas(ue.Tai.Tac, ue.AmPolicyAssociation.ServAreaRes.Areas)
+					accept = context.TacInAreas(ue.Tai.Tac, servAreaRes.Areas)
 				case models.RestrictionType_NOT_ALLOWED_AREAS:
-					accept = !context.TacInAreas(ue.Tai.Tac, ue.AmPolicyAssociation.ServAreaRes.Areas)
+					accept = !context.TacInAreas(ue.Tai.Tac, servAreaRes.Areas)
 				}
 
 				if !accept {
@@ -1951,6 +2247,13 @@ func HandleServiceRequest(ue *context.AmfUe, anType models.AccessType,
 		if err != nil {
 			return err
 		}
+	case nasMessage.ServiceTypeEmergencyServices:
+		// the service area restrictions do not apply to emergency services (TS 23.501 5.3.4.1.1)
+		err := gmm_message.SendServiceAccept(ue, anType, cxtList, pduStatusResult,
+			reactivationResult, errPduSessionId, errCause)
+		if err != nil {
+			return err
+		}
 	case nasMessage.ServiceTypeHighPriorityAccess:
 		// TODO: support HighPriorityAccess
 		err := gmm_message.SendServiceAccept(ue, anType, cxtList, pduStatusResult,
@@ -1965,6 +2268,8 @@ func HandleServiceRequest(ue *context.AmfUe, anType models.AccessType,
 		ue.GmmLog.Info(errPduSessionId, errCause)
 	}
 	ue.N1N2Message = nil
+	// the configuration updated while the UE was in CM-IDLE state
+	SendPendingConfigurationUpdate(ue, anType)
 	return nil
 }
 
@@ -2011,6 +2316,9 @@ func HandleAuthenticationResponse(ue *context.AmfUe, accessType models.AccessTyp
 				gmm_message.SendIdentityRequest(ue.RanUe[accessType], accessType, nasMessage.MobileIdentity5GSTypeSuci)
 				return nil
 			} else {
+				if ok, err := continueEmergencyWithoutAuthentication(ue, accessType); ok {
+					return err
+				}
 				gmm_message.SendAuthenticationReject(ue.RanUe[accessType], "", 0, nasMetrics.HRES_AUTH_ERR)
 				return GmmFSM.SendEvent(ue.State[accessType], AuthFailEvent, fsm.ArgsType{
 					ArgAmfUe:      ue,
@@ -2019,7 +2327,7 @@ func HandleAuthenticationResponse(ue *context.AmfUe, accessType models.AccessTyp
 			}
 		}
 
-		response, problemDetails, err := consumer.GetConsumer().SendAuth5gAkaConfirmRequest(
+		response, problemDetails, err := consumer.GetConsumer().UEAuthenticator().SendAuth5gAkaConfirmRequest(
 			ue, hex.EncodeToString(resStar[:]))
 		if err != nil {
 			return err
@@ -2033,7 +2341,6 @@ func HandleAuthenticationResponse(ue *context.AmfUe, accessType models.AccessTyp
 			ue.Kseaf = response.Kseaf
 			ue.Supi = response.Supi
 			ue.DerivateKamf()
-			ue.GmmLog.Debugln("ue.DerivateKamf()", ue.Kamf)
 			return GmmFSM.SendEvent(ue.State[accessType], AuthSuccessEvent, fsm.ArgsType{
 				ArgAmfUe:      ue,
 				ArgAccessType: accessType,
@@ -2046,6 +2353,9 @@ func HandleAuthenticationResponse(ue *context.AmfUe, accessType models.AccessTyp
 				gmm_message.SendIdentityRequest(ue.RanUe[accessType], accessType, nasMessage.MobileIdentity5GSTypeSuci)
 				return nil
 			} else {
+				if ok, err := continueEmergencyWithoutAuthentication(ue, accessType); ok {
+					return err
+				}
 				gmm_message.SendAuthenticationReject(ue.RanUe[accessType], "", 0, nasMetrics.AUSF_AUTH_ERR)
 				return GmmFSM.SendEvent(ue.State[accessType], AuthFailEvent, fsm.ArgsType{
 					ArgAmfUe:      ue,
@@ -2064,7 +2374,8 @@ func HandleAuthenticationResponse(ue *context.AmfUe, accessType models.AccessTyp
 				ArgAccessType: accessType,
 			}, logger.GmmLog)
 		}
-		response, pd, err := consumer.GetConsumer().SendEapAuthConfirmRequest(ue, *authenticationResponse.EAPMessage)
+		response, pd, err := consumer.GetConsumer().UEAuthenticator().SendEapAuthConfirmRequest(ue,
+			*authenticationResponse.EAPMessage)
 		if err != nil {
 			return err
 		} else if pd != nil {
@@ -2093,6 +2404,9 @@ func HandleAuthenticationResponse(ue *context.AmfUe, accessType models.AccessTyp
 				gmm_message.SendIdentityRequest(ue.RanUe[accessType], accessType, nasMessage.MobileIdentity5GSTypeSuci)
 				return nil
 			} else {
+				if ok, err := continueEmergencyWithoutAuthentication(ue, accessType); ok {
+					return err
+				}
 				gmm_message.SendAuthenticationReject(ue.RanUe[accessType], response.EapPayload, 0, nasMetrics.AUSF_AUTH_ERR)
 				return GmmFSM.SendEvent(ue.State[accessType], AuthFailEvent, fsm.ArgsType{
 					ArgAmfUe:      ue,
@@ -2138,6 +2452,9 @@ func HandleAuthenticationFailure(ue *context.AmfUe, anType models.AccessType,
 		switch cause5GMM {
 		case nasMessage.Cause5GMMMACFailure:
 			ue.GmmLog.Warnln("Authentication Failure Cause: Mac Failure")
+			if ok, err := continueEmergencyWithoutAuthentication(ue, anType); ok {
+				return err
+			}
 			gmm_message.SendAuthenticationReject(ue.RanUe[anType], "", cause5GMM, "")
 			return GmmFSM.SendEvent(
 				ue.State[anType],
@@ -2150,6 +2467,9 @@ func HandleAuthenticationFailure(ue *context.AmfUe, anType models.AccessType,
 			)
 		case nasMessage.Cause5GMMNon5GAuthenticationUnacceptable:
 			ue.GmmLog.Warnln("Authentication Failure Cause: Non-5G Authentication Unacceptable")
+			if ok, err := continueEmergencyWithoutAuthentication(ue, anType); ok {
+				return err
+			}
 			gmm_message.SendAuthenticationReject(ue.RanUe[anType], "", cause5GMM, "")
 			return GmmFSM.SendEvent(
 				ue.State[anType],
@@ -2177,6 +2497,9 @@ func HandleAuthenticationFailure(ue *context.AmfUe, anType models.AccessType,
 			ue.AuthFailureCauseSynchFailureTimes++
 			if ue.AuthFailureCauseSynchFailureTimes >= 2 {
 				ue.GmmLog.Warnf("2 consecutive Synch Failure, terminate authentication procedure")
+				if ok, err := continueEmergencyWithoutAuthentication(ue, anType); ok {
+					return err
+				}
 				gmm_message.SendAuthenticationReject(ue.RanUe[anType], "", cause5GMM, "")
 				return GmmFSM.SendEvent(
 					ue.State[anType],
@@ -2204,7 +2527,8 @@ func HandleAuthenticationFailure(ue *context.AmfUe, anType models.AccessType,
 				Rand: av5gAka.Rand,
 			}
 
-			response, pd, err := consumer.GetConsumer().SendUEAuthenticationAuthenticateRequest(ue, resynchronizationInfo)
+			response, pd, err := consumer.GetConsumer().UEAuthenticator().SendUEAuthenticationAuthenticateRequest(ue,
+				resynchronizationInfo)
 			if err != nil {
 				return err
 			} else if pd != nil {
@@ -2260,16 +2584,26 @@ func HandleRegistrationComplete(ue *context.AmfUe, accessType models.AccessType,
 		})
 	}
 
+	// the UE acknowledged the 5G-GUTI of the Registration Accept
+	ue.ReleaseOldGuti()
+
 	// Send NITZ information to UE
-	configurationUpdateCommandFlags := &context.ConfigurationUpdateCommandFlags{
+	RequestConfigurationUpdate(ue, accessType, &context.ConfigurationUpdateCommandFlags{
 		NeedNITZ: true,
+	})
+
+	// TS 23.502 4.2.2.2.2 step 22, the UE acknowledges the Steering of Roaming information if the UDM requested it
+	if registrationComplete.SORTransparentContainer != nil {
+		if err := handleSorAck(ue, registrationComplete.SORTransparentContainer.GetSORContent()); err != nil {
+			ue.GmmLog.Errorf("Handle SOR Ack failed: %+v", err)
+		}
 	}
-	gmm_message.SendConfigurationUpdateCommand(ue, accessType, configurationUpdateCommandFlags)
 
-	// if registrationComplete.SORTransparentContainer != nil {
-	// 	TODO: if at regsitration procedure 14b, udm provide amf Steering of Roaming info & request an ack,
-	// 	AMF provides the UE's ack with Nudm_SDM_Info (SOR not supportted in this stage)
-	// }
+	// the payloads kept while the UE was not reachable, e.g. the UE parameters update, are sent once it is registered
+	gmm_message.SendPendingDLPayloads(ue.RanUe[accessType])
+
+	// TS 23.502 4.2.2.2.2 step 25, the NSSAA of the S-NSSAIs in the pending NSSAI starts once the UE is registered
+	StartNetworkSliceSpecificAuthentication(ue, accessType)
 
 	// TODO: if
 	//	1. AMF has evaluated the support of IMS Voice over PS Sessions (TS 23.501 5.16.3.2)
@@ -2298,6 +2632,8 @@ func HandleSecurityModeComplete(ue *context.AmfUe, anType models.AccessType, pro
 	}
 
 	ue.StopT3560()
+	business_metrics.IncrNasSecurityAlgCounter(anType,
+		context.IntegrityAlgorithmName(ue.IntegrityAlg), context.CipheringAlgorithmName(ue.CipheringAlg))
 
 	if ue.SecurityContextIsValid() {
 		// update Kgnb/Kn3iwf
@@ -2314,21 +2650,33 @@ func HandleSecurityModeComplete(ue *context.AmfUe, anType models.AccessType, pro
 		}
 	}
 
-	// TODO: AMF shall set the NAS COUNTs to zero if horizontal derivation of KAMF is performed
+	// the K_AMF horizontally derived is in use, the NAS COUNTs were set to zero with the Security Mode Command
+	ue.KamfHorizontallyDerived = false
 	if securityModeComplete.NASMessageContainer != nil {
 		contents := securityModeComplete.NASMessageContainer.GetNASMessageContainerContents()
-		m := nas.NewMessage()
-		if err := m.GmmMessageDecode(&contents); err != nil {
-			return err
-		}
-
+		failArgs := fsm.ArgsType{ArgAmfUe: ue, ArgAccessType: anType}
 		argsType := fsm.ArgsType{ArgAmfUe: ue, ArgAccessType: anType, ArgProcedureCode: procedureCode}
 		event := SecurityModeSuccessEvent
-		switch m.GmmMessage.GmmHeader.GetMessageType() {
+		// the NAS message of the container is the initial NAS message which triggered the procedure, the Registration
+		// Request sent in clear is kept until the end of the registration procedure
+		gmmMessage, requestedEdrx, err := decodeNasMessageContainer(contents)
+		if err != nil {
+			ue.GmmLog.Errorf("Invalid NAS message container: %+v", err)
+			rejectInitialNasMessage(ue, anType, nasMessage.Cause5GMMSemanticallyIncorrectMessage)
+			return GmmFSM.SendEvent(ue.State[anType], SecurityModeFailEvent, failArgs, logger.GmmLog)
+		}
+		switch messageType := gmmMessage.GetMessageType(); messageType {
 		case nas.MsgTypeRegistrationRequest:
-			argsType[ArgNASMessage] = m.GmmMessage.RegistrationRequest
+			registrationRequest, errMerge := mergeRegistrationRequestCleartextIEs(ue, ue.RegistrationRequest,
+				gmmMessage.RegistrationRequest, requestedEdrx)
+			if errMerge != nil {
+				ue.GmmLog.Errorf("Invalid NAS message container: %+v", errMerge)
+				gmm_message.SendRegistrationReject(ue.RanUe[anType], nasMessage.Cause5GMMSemanticallyIncorrectMessage, "")
+				return GmmFSM.SendEvent(ue.State[anType], SecurityModeFailEvent, failArgs, logger.GmmLog)
+			}
+			argsType[ArgNASMessage] = registrationRequest
 		case nas.MsgTypeServiceRequest:
-			argsType[ArgNASMessage] = m.GmmMessage.ServiceRequest
+			argsType[ArgNASMessage] = gmmMessage.ServiceRequest
 			if !ue.State[anType].Is(context.Registered) {
 				gmm_message.SendServiceReject(ue.RanUe[anType], nil, nasMessage.Cause5GMMUEIdentityCannotBeDerivedByTheNetwork)
 				ue.GmmLog.Warnf("Service Request was sent when UE state was not Registered")
@@ -2337,11 +2685,16 @@ func HandleSecurityModeComplete(ue *context.AmfUe, anType models.AccessType, pro
 				event = SecurityModeFailEvent
 			}
 		default:
-			ue.GmmLog.Errorln("nas message container Iei type error")
-			return errors.New("nas message container Iei type error")
+			ue.GmmLog.Errorf("Invalid NAS message container: message type %d is not an initial NAS message",
+				messageType)
+			rejectInitialNasMessage(ue, anType, nasMessage.Cause5GMMMessageTypeNotCompatibleWithTheProtocolState)
+			return GmmFSM.SendEvent(ue.State[anType], SecurityModeFailEvent, failArgs, logger.GmmLog)
 		}
 		return GmmFSM.SendEvent(ue.State[anType], event, argsType, logger.GmmLog)
 	}
+	if ue.RetransmissionOfInitialNASMsg {
+		ue.GmmLog.Warnln("Retransmission of the initial NAS message requested, but no NAS message container")
+	}
 	return GmmFSM.SendEvent(ue.State[anType], SecurityModeSuccessEvent, fsm.ArgsType{
 		ArgAmfUe:         ue,
 		ArgAccessType:    anType,
@@ -2350,6 +2703,16 @@ func HandleSecurityModeComplete(ue *context.AmfUe, anType models.AccessType, pro
 	}, logger.GmmLog)
 }
 
+// rejectInitialNasMessage rejects the procedure triggered by the initial NAS message whose NAS message container can
+// not be taken as the initial NAS message
+func rejectInitialNasMessage(ue *context.AmfUe, anType models.AccessType, cause uint8) {
+	if ue.OnGoing(anType).Procedure == context.OnGoingProcedureRegistration {
+		gmm_message.SendRegistrationReject(ue.RanUe[anType], cause, "")
+	} else {
+		gmm_message.SendServiceReject(ue.RanUe[anType], nil, cause)
+	}
+}
+
 func HandleSecurityModeReject(ue *context.AmfUe, anType models.AccessType,
 	securityModeReject *nasMessage.SecurityModeReject,
 ) error {
@@ -2364,12 +2727,10 @@ func HandleSecurityModeReject(ue *context.AmfUe, anType models.AccessType,
 }
 
 // TS 23.502 4.2.2.3
-func HandleDeregistrationRequest(ue *context.AmfUe, anType models.AccessType,
-	deregistrationRequest *nasMessage.DeregistrationRequestUEOriginatingDeregistration,
-) error {
-	ue.GmmLog.Info("Handle Deregistration Request(UE Originating)")
-
-	targetDeregistrationAccessType := deregistrationRequest.GetAccessType()
+// releaseDeregisteredUeResources releases the SM contexts, the AM policy association, the SMS over NAS and the
+// subscriber data of the UE deregistered over the access, both for the UE-initiated and the network-initiated
+// deregistration (TS 23.502 4.2.2.3.2, 4.2.2.3.3)
+func releaseDeregisteredUeResources(ue *context.AmfUe, anType models.AccessType, targetDeregistrationAccessType uint8) {
 	ue.SmContextList.Range(func(key, value interface{}) bool {
 		smContext := value.(*context.SmContext)
 
@@ -2404,7 +2765,27 @@ func HandleDeregistrationRequest(ue *context.AmfUe, anType models.AccessType,
 		}
 	}
 
+	deactivateSms := true
+	switch anType {
+	case models.AccessType__3_GPP_ACCESS:
+		deactivateSms = ue.State[models.AccessType_NON_3_GPP_ACCESS].Is(context.Deregistered)
+	case models.AccessType_NON_3_GPP_ACCESS:
+		deactivateSms = ue.State[models.AccessType__3_GPP_ACCESS].Is(context.Deregistered)
+	}
+	if deactivateSms || targetDeregistrationAccessType == nasMessage.AccessTypeBoth {
+		gmm_common.DeactivateSmsOverNas(ue)
+	}
+
 	gmm_common.PurgeAmfUeSubscriberData(ue)
+}
+
+func HandleDeregistrationRequest(ue *context.AmfUe, anType models.AccessType,
+	deregistrationRequest *nasMessage.DeregistrationRequestUEOriginatingDeregistration,
+) error {
+	ue.GmmLog.Info("Handle Deregistration Request(UE Originating)")
+
+	targetDeregistrationAccessType := deregistrationRequest.GetAccessType()
+	releaseDeregisteredUeResources(ue, anType, targetDeregistrationAccessType)
 
 	// if Deregistration type is not switch-off, send Deregistration Accept
 	if deregistrationRequest.GetSwitchOff() == 0 {
@@ -2491,6 +2872,21 @@ func HandleDeregistrationAccept(ue *context.AmfUe, anType models.AccessType,
 	return nil
 }
 
+// abortingStatusCauses are the causes of a 5GMM STATUS telling that the UE could not handle the last message of the
+// AMF, the AMF would wait for a reply until the expiry of the timer of the procedure (TS 24.501 Annex A.5)
+var abortingStatusCauses = map[uint8]bool{
+	nasMessage.Cause5GMMSemanticallyIncorrectMessage:                 true,
+	nasMessage.Cause5GMMInvalidMandatoryInformation:                  true,
+	nasMessage.Cause5GMMMessageTypeNonExistentOrNotImplemented:       true,
+	nasMessage.Cause5GMMMessageTypeNotCompatibleWithTheProtocolState: true,
+	nasMessage.Cause5GMMConditionalIEError:                           true,
+	nasMessage.Cause5GMMMessageNotCompatibleWithTheProtocolState:     true,
+	nasMessage.Cause5GMMProtocolErrorUnspecified:                     true,
+}
+
+// HandleStatus5GMM takes the local actions on a 5GMM STATUS (TS 24.501 5.4.6): the ongoing registration procedure is
+// aborted if the UE could not handle the Authentication Request, the Security Mode Command or the Registration Accept
+// and the UE goes back to the 5GMM-DEREGISTERED state. A pending Configuration Update Command is not retransmitted.
 func HandleStatus5GMM(ue *context.AmfUe, anType models.AccessType, status5GMM *nasMessage.Status5GMM) error {
 	ue.GmmLog.Info("Handle Staus 5GMM")
 	if ue.MacFailed {
@@ -2498,6 +2894,52 @@ func HandleStatus5GMM(ue *context.AmfUe, anType models.AccessType, status5GMM *n
 	}
 
 	cause := status5GMM.Cause5GMM.GetCauseValue()
+	business_metrics.IncrGmmStatusCounter(business_metrics.GMM_STATUS_RECEIVED_VALUE, cause)
 	ue.GmmLog.Errorf("Error condition [Cause Value: %s]", nasMessage.Cause5GMMToString(cause))
-	return nil
+	if !abortingStatusCauses[cause] {
+		return nil
+	}
+
+	state := ue.State[anType]
+	switch {
+	case state.Is(context.Authentication), state.Is(context.SecurityMode):
+		ue.StopT3560()
+	case state.Is(context.ContextSetup):
+		ue.StopT3550()
+	case state.Is(context.Registered):
+		ue.StopT3555()
+		if configurationUpdate := ue.ConfigurationUpdate; configurationUpdate != nil &&
+			configurationUpdate.Status == context.ConfigurationUpdateStatusPending {
+			configurationUpdate.Status = context.ConfigurationUpdateStatusNotAcknowledged
+		}
+		return nil
+	default:
+		return nil
+	}
+	ue.GmmLog.Warnf("Abort the ongoing procedure at %s state", state.Current())
+
+	// a UE registered to the AMF which performs a mobility or periodic registration update stays registered
+	event := ProcedureAbortEvent
+	if (ue.RegistrationType5GS == nasMessage.RegistrationType5GSMobilityRegistrationUpdating ||
+		ue.RegistrationType5GS == nasMessage.RegistrationType5GSPeriodicRegistrationUpdating) &&
+		ue.UeCmRegistered[anType] {
+		event = AbortToRegisteredEvent
+	}
+	return GmmFSM.SendEvent(state, event, fsm.ArgsType{
+		ArgAmfUe:      ue,
+		ArgAccessType: anType,
+	}, logger.GmmLog)
+}
+
+// handleUnexpectedMessage answers a 5GMM message not expected in the 5GMM state with a 5GMM STATUS, unless it is a
+// 5GMM STATUS itself or it can not be protected (TS 24.501 7.4)
+func handleUnexpectedMessage(ue *context.AmfUe, anType models.AccessType, state *fsm.State,
+	gmmMessage *nas.GmmMessage,
+) {
+	ue.GmmLog.Errorf("state mismatch: receieve gmm message[message type 0x%0x] at %s state",
+		gmmMessage.GetMessageType(), state.Current())
+	if gmmMessage.GetMessageType() == nas.MsgTypeStatus5GMM || !ue.SecurityContextAvailable || ue.MacFailed {
+		return
+	}
+	gmm_message.SendStatus5GMM(ue.RanUe[anType], nasMessage.Cause5GMMMessageTypeNotCompatibleWithTheProtocolState)
 }
@@ -0,0 +1,1022 @@
+package gmm
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/free5gc/amf/internal/context"
+	gmm_message "github.com/free5gc/amf/internal/gmm/message"
+	"github.com/free5gc/amf/internal/logger"
+	nastesting "github.com/free5gc/amf/internal/nas/testing"
+	ngaptesting "github.com/free5gc/amf/internal/ngap/testing"
+	"github.com/free5gc/amf/internal/sbi/consumer"
+	"github.com/free5gc/amf/pkg/factory"
+	"github.com/free5gc/nas"
+	"github.com/free5gc/nas/nasMessage"
+	"github.com/free5gc/nas/nasType"
+	"github.com/free5gc/nas/security"
+	"github.com/free5gc/ngap"
+	"github.com/free5gc/ngap/ngapType"
+	"github.com/free5gc/openapi/models"
+	"github.com/free5gc/util/fsm"
+)
+
+func newH2CServer(handler http.Handler) *httptest.Server {
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Config.Protocols = new(http.Protocols)
+	srv.Config.Protocols.SetUnencryptedHTTP2(true)
+	srv.Start()
+	return srv
+}
+
+// newNrfServer returns an NRF which answers the NF discovery of the NF type with a single NF instance, whose service
+// is served by the stand-in server at apiPrefix
+func newNrfServer(t *testing.T, nfType models.NrfNfManagementNfType, nfInstanceId string,
+	serviceName models.ServiceName, apiPrefix string,
+) *httptest.Server {
+	nrf := newH2CServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, string(nfType), r.URL.Query().Get("target-nf-type"))
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(models.SearchResult{
+			NfInstances: []models.NrfNfDiscoveryNfProfile{
+				{
+					NfInstanceId: nfInstanceId,
+					NfType:       nfType,
+					NfServices: []models.NrfNfDiscoveryNfService{
+						{
+							ServiceName:     serviceName,
+							NfServiceStatus: models.NfServiceStatus_REGISTERED,
+							ApiPrefix:       apiPrefix,
+						},
+					},
+				},
+			},
+		})
+	}))
+	t.Cleanup(nrf.Close)
+	return nrf
+}
+
+// newConnectedTestUe returns a registered UE in CM-CONNECTED over 3GPP access with the null security algorithms
+func newConnectedTestUe(t *testing.T, supi string) (*context.AmfUe, *ngaptesting.SctpConnStub) {
+	_, err := consumer.NewConsumer(nil)
+	require.NoError(t, err)
+
+	amfConfig := factory.AmfConfig
+	factory.AmfConfig = &factory.Config{Configuration: &factory.Configuration{}}
+	amfSelf := context.GetSelf()
+	servedGuamiList := amfSelf.ServedGuamiList
+	amfSelf.ServedGuamiList = []models.Guami{
+		{PlmnId: &models.PlmnIdNid{Mcc: "208", Mnc: "93"}, AmfId: "cafe00"},
+	}
+	conn := new(ngaptesting.SctpConnStub)
+	ran := amfSelf.NewAmfRan(conn)
+	ran.AnType = models.AccessType__3_GPP_ACCESS
+	ranUe, err := ran.NewRanUe(1)
+	require.NoError(t, err)
+
+	ue := amfSelf.NewAmfUe(supi)
+	ue.AttachRanUe(ranUe)
+	ue.State[models.AccessType__3_GPP_ACCESS].Set(context.Registered)
+	ue.SecurityContextAvailable = true
+	ue.IntegrityAlg = security.AlgIntegrity128NIA0
+	ue.CipheringAlg = security.AlgCiphering128NEA0
+	t.Cleanup(func() {
+		ue.Remove()
+		amfSelf.AmfRanPool.Delete(conn)
+		amfSelf.ServedGuamiList = servedGuamiList
+		factory.AmfConfig = amfConfig
+	})
+	return ue, conn
+}
+
+type smsfStub struct {
+	activated  chan consumer.UeSmsContextData
+	uplinkSms  chan []byte
+	deactivate chan string
+}
+
+func (s *smsfStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const ueContextPath = "/nsmsf-sms/v2/ue-contexts/imsi-208930000000001"
+
+	switch {
+	case r.Method == http.MethodPut && r.URL.Path == ueContextPath:
+		var ueSmsContextData consumer.UeSmsContextData
+		if err := json.NewDecoder(r.Body).Decode(&ueSmsContextData); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.activated <- ueSmsContextData
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(ueSmsContextData)
+	case r.Method == http.MethodDelete && r.URL.Path == ueContextPath:
+		s.deactivate <- r.URL.Path
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && r.URL.Path == ueContextPath+"/sendsms":
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		reader := multipart.NewReader(r.Body, params["boundary"])
+		var smsRecord consumer.SmsRecordData
+		var payload []byte
+		for {
+			part, partErr := reader.NextPart()
+			if partErr != nil {
+				break
+			}
+			if part.Header.Get("Content-Type") == "application/json" {
+				if err = json.NewDecoder(part).Decode(&smsRecord); err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+			} else if part.Header.Get("Content-Id") == smsRecord.SmsPayload.ContentId {
+				payload, _ = io.ReadAll(part)
+			}
+		}
+		s.uplinkSms <- payload
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(consumer.SmsRecordDeliveryData{
+			SmsRecordId:    smsRecord.SmsRecordId,
+			DeliveryStatus: "SMS_DELIVERY_SMSF_ACCEPTED",
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestSmsOverNas(t *testing.T) {
+	_, err := consumer.NewConsumer(nil)
+	require.NoError(t, err)
+
+	stub := &smsfStub{
+		activated:  make(chan consumer.UeSmsContextData, 1),
+		uplinkSms:  make(chan []byte, 1),
+		deactivate: make(chan string, 1),
+	}
+	smsf := newH2CServer(stub)
+	defer smsf.Close()
+
+	nrf := newNrfServer(t, models.NrfNfManagementNfType_SMSF, "smsf-1", models.ServiceName_NSMSF_SMS, smsf.URL)
+
+	amfSelf := context.GetSelf()
+	amfSelf.NrfUri = nrf.URL
+	amfSelf.ServedGuamiList = []models.Guami{
+		{PlmnId: &models.PlmnIdNid{Mcc: "208", Mnc: "93"}, AmfId: "cafe00"},
+	}
+	ue := amfSelf.NewAmfUe("imsi-208930000000001")
+	defer ue.Remove()
+	anType := models.AccessType__3_GPP_ACCESS
+
+	// The UE requests SMS over NAS in its registration
+	ue.RegistrationRequest = nasMessage.NewRegistrationRequest(0)
+	ue.RegistrationRequest.UpdateType5GS = nasType.NewUpdateType5GS(nasMessage.RegistrationRequestUpdateType5GSType)
+	ue.RegistrationRequest.UpdateType5GS.SetLen(1)
+	ue.RegistrationRequest.UpdateType5GS.SetSMSRequested(1)
+	handleSmsOverNasRequest(ue, anType)
+	require.True(t, ue.SmsAllowed)
+	require.Equal(t, "smsf-1", ue.SmsfId)
+	require.Len(t, stub.activated, 1)
+	ueSmsContextData := <-stub.activated
+	require.Equal(t, ue.Supi, ueSmsContextData.Supi)
+	require.Equal(t, anType, ueSmsContextData.AccessType)
+
+	// The SMS sent by the UE is relayed to the SMSF
+	sms := []byte{0x01, 0x02, 0x03, 0x04}
+	ulNasTransport := nasMessage.NewULNASTransport(0)
+	ulNasTransport.SetPayloadContainerType(nasMessage.PayloadContainerTypeSMS)
+	ulNasTransport.PayloadContainer.SetLen(uint16(len(sms)))
+	ulNasTransport.PayloadContainer.SetPayloadContainerContents(sms)
+	require.NoError(t, HandleULNASTransport(ue, anType, ulNasTransport))
+	require.Len(t, stub.uplinkSms, 1)
+	require.Equal(t, sms, <-stub.uplinkSms)
+
+	// SMS over NAS is deactivated once the UE no longer requests it
+	ue.RegistrationRequest.UpdateType5GS.SetSMSRequested(0)
+	handleSmsOverNasRequest(ue, anType)
+	require.False(t, ue.SmsAllowed)
+	require.Empty(t, ue.SmsfUri)
+	require.Len(t, stub.deactivate, 1)
+}
+
+func TestLppOverNas(t *testing.T) {
+	_, err := consumer.NewConsumer(nil)
+	require.NoError(t, err)
+
+	type lppNotify struct {
+		notification models.N1MessageNotification
+		lppMsg       []byte
+	}
+	notified := make(chan lppNotify, 1)
+	lmf := newH2CServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, params, parseErr := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if parseErr != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var received lppNotify
+		reader := multipart.NewReader(r.Body, params["boundary"])
+		for {
+			part, partErr := reader.NextPart()
+			if partErr != nil {
+				break
+			}
+			if part.Header.Get("Content-Type") == "application/json" {
+				if err = json.NewDecoder(part).Decode(&received.notification); err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+			} else {
+				received.lppMsg, _ = io.ReadAll(part)
+			}
+		}
+		notified <- received
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer lmf.Close()
+
+	amfSelf := context.GetSelf()
+	amfSelf.ServedGuamiList = []models.Guami{
+		{PlmnId: &models.PlmnIdNid{Mcc: "208", Mnc: "93"}, AmfId: "cafe00"},
+	}
+	ue := amfSelf.NewAmfUe("imsi-208930000000002")
+	defer ue.Remove()
+	anType := models.AccessType__3_GPP_ACCESS
+
+	ue.N1N2MessageSubscription.Store(int64(1), models.UeN1N2InfoSubscriptionCreateData{
+		N1MessageClass:      models.N1MessageClass_LPP,
+		N1NotifyCallbackUri: lmf.URL + "/n1-message-notify",
+		NfId:                "lmf-1",
+	})
+
+	newULNASTransport := func(lppMsg, routingInformation []byte) *nasMessage.ULNASTransport {
+		ulNasTransport := nasMessage.NewULNASTransport(0)
+		ulNasTransport.SetPayloadContainerType(nasMessage.PayloadContainerTypeLPP)
+		ulNasTransport.PayloadContainer.SetLen(uint16(len(lppMsg)))
+		ulNasTransport.PayloadContainer.SetPayloadContainerContents(lppMsg)
+		if routingInformation != nil {
+			ulNasTransport.AdditionalInformation = nasType.NewAdditionalInformation(
+				nasMessage.ULNASTransportAdditionalInformationType)
+			ulNasTransport.AdditionalInformation.SetLen(uint8(len(routingInformation)))
+			ulNasTransport.AdditionalInformation.SetAdditionalInformationValue(routingInformation)
+		}
+		return ulNasTransport
+	}
+	lppMsg := []byte{0x92, 0x2b, 0x08, 0x01}
+
+	// The LPP message of the UE is not forwarded without a positioning session
+	require.NoError(t, HandleULNASTransport(ue, anType, newULNASTransport(lppMsg, []byte{0x01})))
+	require.Empty(t, notified)
+
+	// The positioning sessions of the LMF are distinguished by their LCS correlation
+	session := ue.NewPositioningSession("lmf-1", "lcs-1")
+	require.NotNil(t, session)
+	require.Same(t, session, ue.NewPositioningSession("lmf-1", "lcs-1"))
+	other := ue.NewPositioningSession("lmf-1", "lcs-2")
+	require.NotEqual(t, session.RoutingId, other.RoutingId)
+
+	// The LPP message is forwarded to the LMF of the positioning session identified by the routing information
+	require.NoError(t, HandleULNASTransport(ue, anType, newULNASTransport(lppMsg, other.RoutingInformation())))
+	require.Len(t, notified, 1)
+	received := <-notified
+	require.Equal(t, lppMsg, received.lppMsg)
+	require.Equal(t, "lcs-2", received.notification.LcsCorrelationId)
+	require.Equal(t, "1", received.notification.N1NotifySubscriptionId)
+	require.Equal(t, models.N1MessageClass_LPP, received.notification.N1MessageContainer.N1MessageClass)
+}
+
+func TestSorAckOverNas(t *testing.T) {
+	_, err := consumer.NewConsumer(nil)
+	require.NoError(t, err)
+
+	acknowledged := make(chan models.AcknowledgeInfo, 1)
+	udm := newH2CServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/nudm-sdm/v2/imsi-208930000000003/am-data/sor-ack" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var ackInfo models.AcknowledgeInfo
+		if err = json.NewDecoder(r.Body).Decode(&ackInfo); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		acknowledged <- ackInfo
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer udm.Close()
+
+	amfSelf := context.GetSelf()
+	amfSelf.ServedGuamiList = []models.Guami{
+		{PlmnId: &models.PlmnIdNid{Mcc: "208", Mnc: "93"}, AmfId: "cafe00"},
+	}
+	ue := amfSelf.NewAmfUe("imsi-208930000000003")
+	defer ue.Remove()
+	ue.NudmSDMUri = udm.URL
+	anType := models.AccessType__3_GPP_ACCESS
+
+	sorAck := []byte{0x01, 0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f}
+	ulNasTransport := nasMessage.NewULNASTransport(0)
+	ulNasTransport.SetPayloadContainerType(nasMessage.PayloadContainerTypeSOR)
+	ulNasTransport.PayloadContainer.SetLen(uint16(len(sorAck)))
+	ulNasTransport.PayloadContainer.SetPayloadContainerContents(sorAck)
+
+	// The UDM did not request an acknowledgement
+	require.NoError(t, HandleULNASTransport(ue, anType, ulNasTransport))
+	require.Empty(t, acknowledged)
+
+	// The acknowledgement of the UE is provided to the UDM once, with the provisioning time of the SOR information
+	// already sent to the UE
+	provisioningTime := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	ue.SetSorInfo(&models.UdmSdmSorInfo{
+		AckInd:           true,
+		SorMacIausf:      "000102030405060708090a0b0c0d0e0f",
+		Countersor:       "0001",
+		ProvisioningTime: &provisioningTime,
+	})
+	ue.SorInfoSent()
+	require.NoError(t, HandleULNASTransport(ue, anType, ulNasTransport))
+	require.Len(t, acknowledged, 1)
+	ackInfo := <-acknowledged
+	require.Equal(t, "000102030405060708090a0b0c0d0e0f", ackInfo.SorMacIue)
+	require.True(t, provisioningTime.Equal(*ackInfo.ProvisioningTime))
+	require.False(t, ue.SorAckRequested)
+}
+
+func TestSorInfoSentOnce(t *testing.T) {
+	ue, conn := newConnectedTestUe(t, "imsi-208930000000012")
+	sorInfo := &models.UdmSdmSorInfo{
+		SorMacIausf: "000102030405060708090a0b0c0d0e0f",
+		Countersor:  "0001",
+	}
+	ue.SetSorInfo(sorInfo)
+	sorContainer, err := ue.SorTransparentContainer()
+	require.NoError(t, err)
+	ue.AppendPendingDLPayload(&context.PayloadContainerEntry{
+		Type:     nasMessage.PayloadContainerTypeSOR,
+		Contents: sorContainer,
+	})
+
+	// the SOR information sent in a DL NAS Transport is not in the next Registration Accept
+	gmm_message.SendPendingDLPayloads(ue.RanUe[models.AccessType__3_GPP_ACCESS])
+	require.Len(t, conn.MsgList, 1)
+	require.Nil(t, ue.SorInfo)
+	sorContainer, err = ue.SorTransparentContainer()
+	require.NoError(t, err)
+	require.Nil(t, sorContainer)
+}
+
+func TestSorInfoNotIncludedInRegistrationAccept(t *testing.T) {
+	ue, _ := newConnectedTestUe(t, "imsi-208930000000019")
+	anType := models.AccessType__3_GPP_ACCESS
+	ue.RegistrationRequest = nasMessage.NewRegistrationRequest(0)
+
+	// the SOR information which can not be encoded is kept, it is not in the Registration Accept
+	ue.SetSorInfo(&models.UdmSdmSorInfo{SorTransparentContainer: "not base64"})
+	_, sorIncluded, err := gmm_message.BuildRegistrationAccept(ue, anType, nil, nil, nil, nil)
+	require.NoError(t, err)
+	require.False(t, sorIncluded)
+
+	ue.SetSorInfo(&models.UdmSdmSorInfo{
+		SorMacIausf: "000102030405060708090a0b0c0d0e0f",
+		Countersor:  "0001",
+	})
+	_, sorIncluded, err = gmm_message.BuildRegistrationAccept(ue, anType, nil, nil, nil, nil)
+	require.NoError(t, err)
+	require.True(t, sorIncluded)
+}
+
+func TestUpuAckOverNas(t *testing.T) {
+	_, err := consumer.NewConsumer(nil)
+	require.NoError(t, err)
+
+	acknowledged := make(chan models.AcknowledgeInfo, 1)
+	udm := newH2CServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/nudm-sdm/v2/imsi-208930000000005/am-data/upu-ack" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var ackInfo models.AcknowledgeInfo
+		if err = json.NewDecoder(r.Body).Decode(&ackInfo); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		acknowledged <- ackInfo
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer udm.Close()
+
+	amfSelf := context.GetSelf()
+	amfSelf.ServedGuamiList = []models.Guami{
+		{PlmnId: &models.PlmnIdNid{Mcc: "208", Mnc: "93"}, AmfId: "cafe00"},
+	}
+	ue := amfSelf.NewAmfUe("imsi-208930000000005")
+	defer ue.Remove()
+	ue.NudmSDMUri = udm.URL
+	anType := models.AccessType__3_GPP_ACCESS
+
+	upuAck := []byte{0x01, 0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f}
+	ulNasTransport := nasMessage.NewULNASTransport(0)
+	ulNasTransport.SetPayloadContainerType(nasMessage.PayloadContainerTypeUEParameterUpdate)
+	ulNasTransport.PayloadContainer.SetLen(uint16(len(upuAck)))
+	ulNasTransport.PayloadContainer.SetPayloadContainerContents(upuAck)
+
+	// The UDM did not request an acknowledgement
+	require.NoError(t, HandleULNASTransport(ue, anType, ulNasTransport))
+	require.Empty(t, acknowledged)
+
+	// The acknowledgement of the UE is provided to the UDM once and stops the UPU ACK timer
+	provisioningTime := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	ue.SetUpuInfo(&models.UdmSdmUpuInfo{
+		UpuAckInd:        true,
+		UpuMacIausf:      "000102030405060708090a0b0c0d0e0f",
+		CounterUpu:       "0001",
+		ProvisioningTime: &provisioningTime,
+	})
+	ue.UpuAckTimer = context.NewTimer(time.Minute, 0, func(int32) {}, func() {})
+	require.NoError(t, HandleULNASTransport(ue, anType, ulNasTransport))
+	require.Len(t, acknowledged, 1)
+	ackInfo := <-acknowledged
+	require.Equal(t, "000102030405060708090a0b0c0d0e0f", ackInfo.UpuMacIue)
+	require.False(t, ackInfo.UeNotReachable)
+	require.True(t, provisioningTime.Equal(*ackInfo.ProvisioningTime))
+	require.False(t, ue.UpuAckRequested)
+	require.Nil(t, ue.UpuAckTimer)
+
+	// An invalid acknowledgement is not provided to the UDM
+	ue.SetUpuInfo(&models.UdmSdmUpuInfo{UpuAckInd: true})
+	ulNasTransport.PayloadContainer.SetLen(1)
+	ulNasTransport.PayloadContainer.SetPayloadContainerContents([]byte{0x01})
+	require.Error(t, HandleULNASTransport(ue, anType, ulNasTransport))
+	require.Empty(t, acknowledged)
+	require.True(t, ue.UpuAckRequested)
+}
+
+func TestMultiplePayloadOverNas(t *testing.T) {
+	_, err := consumer.NewConsumer(nil)
+	require.NoError(t, err)
+
+	acknowledged := make(chan models.AcknowledgeInfo, 1)
+	udm := newH2CServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ackInfo models.AcknowledgeInfo
+		if err = json.NewDecoder(r.Body).Decode(&ackInfo); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		acknowledged <- ackInfo
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer udm.Close()
+
+	amfSelf := context.GetSelf()
+	amfSelf.ServedGuamiList = []models.Guami{
+		{PlmnId: &models.PlmnIdNid{Mcc: "208", Mnc: "93"}, AmfId: "cafe00"},
+	}
+	ue := amfSelf.NewAmfUe("imsi-208930000000004")
+	defer ue.Remove()
+	ue.NudmSDMUri = udm.URL
+	ue.SetSorInfo(&models.UdmSdmSorInfo{AckInd: true})
+	anType := models.AccessType__3_GPP_ACCESS
+
+	// A 5GSM message without PDU session ID bundled with a SOR ACK
+	multiplePayload, err := context.EncodeMultiplePayload([]*context.PayloadContainerEntry{
+		{
+			Type:     nasMessage.PayloadContainerTypeN1SMInfo,
+			Contents: []byte{0x2e, 0x05, 0x01, 0xc1},
+		},
+		{
+			Type: nasMessage.PayloadContainerTypeSOR,
+			Contents: []byte{
+				0x01, 0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+			},
+		},
+	})
+	require.NoError(t, err)
+	ulNasTransport := nasMessage.NewULNASTransport(0)
+	ulNasTransport.SetPayloadContainerType(nasMessage.PayloadContainerTypeMultiplePayload)
+	ulNasTransport.PayloadContainer.SetLen(uint16(len(multiplePayload)))
+	ulNasTransport.PayloadContainer.SetPayloadContainerContents(multiplePayload)
+
+	// The error of the 5GSM message is reported and the SOR ACK is still relayed to the UDM
+	err = HandleULNASTransport(ue, anType, ulNasTransport)
+	require.ErrorContains(t, err, "payload container entry[0]: PDU Session ID is nil")
+	require.NotContains(t, err.Error(), "entry[1]")
+	require.Len(t, acknowledged, 1)
+	require.Equal(t, "000102030405060708090a0b0c0d0e0f", (<-acknowledged).SorMacIue)
+}
+
+func TestEmergencyRegistrationWithoutAuthentication(t *testing.T) {
+	_, err := consumer.NewConsumer(nil)
+	require.NoError(t, err)
+
+	// No AUSF can be selected
+	nrf := newH2CServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer nrf.Close()
+
+	amfSelf := context.GetSelf()
+	amfSelf.NrfUri = nrf.URL
+	amfSelf.ServedGuamiList = []models.Guami{
+		{PlmnId: &models.PlmnIdNid{Mcc: "208", Mnc: "93"}, AmfId: "cafe00"},
+	}
+	defer func() {
+		amfSelf.Emergency = nil
+	}()
+
+	testCases := []struct {
+		name                string
+		policy              string
+		supi                string
+		emergencyRegistered bool
+		expectedPass        bool
+	}{
+		{
+			name:                "Initial registration",
+			policy:              factory.EmergencyPolicyAll,
+			supi:                "imsi-208930000000005",
+			emergencyRegistered: false,
+			expectedPass:        false,
+		},
+		{
+			name:                "Authentication required by the policy",
+			policy:              factory.EmergencyPolicyAuthenticated,
+			supi:                "imsi-208930000000005",
+			emergencyRegistered: true,
+			expectedPass:        false,
+		},
+		{
+			name:                "Unauthenticated SUPI allowed by the policy",
+			policy:              factory.EmergencyPolicyUnauthenticatedSupi,
+			supi:                "imsi-208930000000005",
+			emergencyRegistered: true,
+			expectedPass:        true,
+		},
+		{
+			name:                "UE identified by its PEI only",
+			policy:              factory.EmergencyPolicyAll,
+			emergencyRegistered: true,
+			expectedPass:        true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			amfSelf.Emergency = &factory.Emergency{
+				Enable: true,
+				Policy: tc.policy,
+				Dnn:    "sos",
+				Snssai: &models.Snssai{Sst: 1},
+			}
+			ue := amfSelf.NewAmfUe(tc.supi)
+			defer ue.Remove()
+			ue.Pei = "imei-356938035643803"
+			ue.EmergencyRegistered = tc.emergencyRegistered
+			ue.Kamf = "000102030405060708090a0b0c0d0e0f000102030405060708090a0b0c0d0e0f"
+
+			pass, err := AuthenticationProcedure(ue, models.AccessType__3_GPP_ACCESS)
+			require.Equal(t, tc.expectedPass, pass)
+			if tc.expectedPass {
+				// the security mode control selects the null algorithms
+				require.NoError(t, err)
+				require.True(t, ue.UnauthenticatedEmergency())
+				require.Empty(t, ue.Kamf)
+			} else {
+				require.Error(t, err)
+				require.NotEmpty(t, ue.Kamf)
+			}
+		})
+	}
+}
+
+func TestEquipmentIdentityCheck(t *testing.T) {
+	_, err := consumer.NewConsumer(nil)
+	require.NoError(t, err)
+
+	equipmentStatus := map[string]consumer.EquipmentStatus{
+		"imei-356938035643803": consumer.EquipmentStatusWhitelisted,
+		"imei-356938035643811": consumer.EquipmentStatusBlacklisted,
+		"imei-356938035643829": consumer.EquipmentStatusGreylisted,
+	}
+	eir := newH2CServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/n5g-eir-eic/v1/equipment-status", r.URL.Path)
+		status, ok := equipmentStatus[r.URL.Query().Get("pei")]
+		w.Header().Set("Content-Type", "application/json")
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(models.ProblemDetails{Status: http.StatusNotFound, Cause: "ERROR_EQUIPMENT_UNKNOWN"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(consumer.EirResponseData{Status: status})
+	}))
+	defer eir.Close()
+
+	nrf := newNrfServer(t, models.NrfNfManagementNfType__5_G_EIR, "5g-eir-1", models.ServiceName_N5G_EIR_EIC, eir.URL)
+
+	amfSelf := context.GetSelf()
+	amfSelf.NrfUri = nrf.URL
+	amfSelf.ServedGuamiList = []models.Guami{
+		{PlmnId: &models.PlmnIdNid{Mcc: "208", Mnc: "93"}, AmfId: "cafe00"},
+	}
+	defer func() {
+		amfSelf.Eir = nil
+	}()
+
+	testCases := []struct {
+		name                string
+		eir                 *factory.Eir
+		registrationType    uint8
+		pei                 string
+		eirCheckedPei       string
+		emergencyRegistered bool
+		expectedPass        bool
+	}{
+		{
+			name:             "Whitelisted",
+			eir:              &factory.Eir{Enable: true},
+			registrationType: nasMessage.RegistrationType5GSInitialRegistration,
+			pei:              "imei-356938035643803",
+			expectedPass:     true,
+		},
+		{
+			name:             "Blacklisted",
+			eir:              &factory.Eir{Enable: true},
+			registrationType: nasMessage.RegistrationType5GSInitialRegistration,
+			pei:              "imei-356938035643811",
+			expectedPass:     false,
+		},
+		{
+			name:                "Blacklisted emergency registration",
+			eir:                 &factory.Eir{Enable: true},
+			registrationType:    nasMessage.RegistrationType5GSEmergencyRegistration,
+			pei:                 "imei-356938035643811",
+			emergencyRegistered: true,
+			expectedPass:        true,
+		},
+		{
+			name:             "Greylisted",
+			eir:              &factory.Eir{Enable: true},
+			registrationType: nasMessage.RegistrationType5GSInitialRegistration,
+			pei:              "imei-356938035643829",
+			expectedPass:     true,
+		},
+		{
+			name:             "Unknown to the 5G-EIR",
+			eir:              &factory.Eir{Enable: true},
+			registrationType: nasMessage.RegistrationType5GSInitialRegistration,
+			pei:              "imei-356938035643837",
+			expectedPass:     true,
+		},
+		{
+			name: "Check skipped for the PLMN",
+			eir: &factory.Eir{
+				Enable:       true,
+				SkipPlmnList: []models.PlmnId{{Mcc: "208", Mnc: "93"}},
+			},
+			registrationType: nasMessage.RegistrationType5GSInitialRegistration,
+			pei:              "imei-356938035643811",
+			expectedPass:     true,
+		},
+		{
+			name:             "Check disabled",
+			registrationType: nasMessage.RegistrationType5GSInitialRegistration,
+			pei:              "imei-356938035643811",
+			expectedPass:     true,
+		},
+		{
+			name:             "PEI checked in a former registration",
+			eir:              &factory.Eir{Enable: true},
+			registrationType: nasMessage.RegistrationType5GSMobilityRegistrationUpdating,
+			pei:              "imei-356938035643811",
+			eirCheckedPei:    "imei-356938035643811",
+			expectedPass:     true,
+		},
+		{
+			name:             "PEI changed in a periodic registration update",
+			eir:              &factory.Eir{Enable: true},
+			registrationType: nasMessage.RegistrationType5GSPeriodicRegistrationUpdating,
+			pei:              "imei-356938035643811",
+			eirCheckedPei:    "imei-356938035643803",
+			expectedPass:     false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			amfSelf.Eir = tc.eir
+			ue := amfSelf.NewAmfUe("imsi-208930000000006")
+			defer ue.Remove()
+			ue.PlmnId = models.PlmnId{Mcc: "208", Mnc: "93"}
+			ue.RegistrationType5GS = tc.registrationType
+			ue.Pei = tc.pei
+			ue.EirCheckedPei = tc.eirCheckedPei
+			ue.EmergencyRegistered = tc.emergencyRegistered
+
+			err := checkEquipmentIdentity(ue, models.AccessType__3_GPP_ACCESS)
+			if tc.expectedPass {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestNetworkInitiatedDeregistrationAccept(t *testing.T) {
+	amfSelf := context.GetSelf()
+	servedGuamiList := amfSelf.ServedGuamiList
+	defer func() {
+		amfSelf.ServedGuamiList = servedGuamiList
+	}()
+	amfSelf.ServedGuamiList = []models.Guami{
+		{PlmnId: &models.PlmnIdNid{Mcc: "208", Mnc: "93"}, AmfId: "cafe00"},
+	}
+	ue := amfSelf.NewAmfUe("imsi-208930000000007")
+	defer ue.Remove()
+	anType := models.AccessType__3_GPP_ACCESS
+	ue.State[anType].Set(context.Registered)
+	ue.EmergencyRegistered = true
+	ue.DeregistrationTargetAccessType = nasMessage.AccessType3GPP
+	ue.T3522 = context.NewTimer(time.Minute, 0, func(int32) {}, func() {})
+
+	gmmMessage := nas.NewGmmMessage()
+	gmmMessage.GmmHeader.SetMessageType(nas.MsgTypeDeregistrationAcceptUETerminatedDeregistration)
+	gmmMessage.DeregistrationAcceptUETerminatedDeregistration =
+		nasMessage.NewDeregistrationAcceptUETerminatedDeregistration(0)
+	require.NoError(t, GmmFSM.SendEvent(ue.State[anType], GmmMessageEvent, fsm.ArgsType{
+		ArgAmfUe:         ue,
+		ArgAccessType:    anType,
+		ArgNASMessage:    gmmMessage,
+		ArgProcedureCode: int64(0),
+	}, logger.GmmLog))
+
+	// the UE goes through the deregistration initiated state to the deregistered state
+	require.True(t, ue.State[anType].Is(context.Deregistered))
+	require.False(t, ue.EmergencyRegistered)
+	require.Nil(t, ue.T3522)
+	require.Zero(t, ue.DeregistrationTargetAccessType)
+}
+
+func newStatus5GMM(cause uint8) *nasMessage.Status5GMM {
+	status := nasMessage.NewStatus5GMM(0)
+	status.SetCauseValue(cause)
+	return status
+}
+
+func TestStatus5GMMAbortsRegistrationUpdate(t *testing.T) {
+	anType := models.AccessType__3_GPP_ACCESS
+	cause := nasMessage.Cause5GMMMessageTypeNotCompatibleWithTheProtocolState
+
+	testCases := []struct {
+		name             string
+		registrationType uint8
+		ueCmRegistered   bool
+		expected         fsm.StateType
+	}{
+		{
+			name:             "Initial registration",
+			registrationType: nasMessage.RegistrationType5GSInitialRegistration,
+			expected:         context.Deregistered,
+		},
+		{
+			name:             "Mobility registration update from another AMF",
+			registrationType: nasMessage.RegistrationType5GSMobilityRegistrationUpdating,
+			expected:         context.Deregistered,
+		},
+		{
+			name:             "Mobility registration update",
+			registrationType: nasMessage.RegistrationType5GSMobilityRegistrationUpdating,
+			ueCmRegistered:   true,
+			expected:         context.Registered,
+		},
+		{
+			name:             "Periodic registration update",
+			registrationType: nasMessage.RegistrationType5GSPeriodicRegistrationUpdating,
+			ueCmRegistered:   true,
+			expected:         context.Registered,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ue, _ := newConnectedTestUe(t, "imsi-208930000000012")
+			ue.State[anType].Set(context.SecurityMode)
+			ue.RegistrationType5GS = tc.registrationType
+			ue.UeCmRegistered[anType] = tc.ueCmRegistered
+
+			require.NoError(t, HandleStatus5GMM(ue, anType, newStatus5GMM(cause)))
+			require.True(t, ue.State[anType].Is(tc.expected))
+		})
+	}
+}
+
+func TestStatus5GMMAbortsConfigurationUpdate(t *testing.T) {
+	anType := models.AccessType__3_GPP_ACCESS
+	ue, _ := newConnectedTestUe(t, "imsi-208930000000013")
+	ue.ConfigurationUpdate = &context.ConfigurationUpdate{
+		AccessType: anType,
+		Status:     context.ConfigurationUpdateStatusPending,
+	}
+	ue.T3555 = context.NewTimer(time.Minute, 4, func(int32) {}, func() {})
+
+	cause := nasMessage.Cause5GMMMessageTypeNotCompatibleWithTheProtocolState
+	require.NoError(t, HandleStatus5GMM(ue, anType, newStatus5GMM(cause)))
+	require.True(t, ue.State[anType].Is(context.Registered))
+	require.Nil(t, ue.T3555)
+	require.Equal(t, context.ConfigurationUpdateStatusNotAcknowledged, ue.ConfigurationUpdate.Status)
+}
+
+func TestSecurityModeCompleteNasMessageContainer(t *testing.T) {
+	anType := models.AccessType__3_GPP_ACCESS
+	mobileIdentity := nasType.MobileIdentity5GS{Len: 1, Buffer: []uint8{0x01}}
+
+	testCases := []struct {
+		name                string
+		onGoing             context.OnGoingProcedure
+		nasMessageContainer []byte
+		expected            uint8
+	}{
+		{
+			// the Registration Request of a former registration does not make it a Registration Request
+			name:                "Service Request",
+			onGoing:             context.OnGoingProcedureNothing,
+			nasMessageContainer: nastesting.GetServiceRequest(nasMessage.ServiceTypeData),
+			expected:            nas.MsgTypeServiceReject,
+		},
+		{
+			name:    "Not an initial NAS message",
+			onGoing: context.OnGoingProcedureRegistration,
+			nasMessageContainer: nastesting.GetDeregistrationRequest(nasMessage.AccessType3GPP, 0, 0,
+				mobileIdentity),
+			expected: nas.MsgTypeRegistrationReject,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ue, conn := newConnectedTestUe(t, "imsi-208930000000020")
+			ue.State[anType].Set(context.SecurityMode)
+			ue.SetOnGoing(anType, &context.OnGoing{Procedure: tc.onGoing})
+			ue.RegistrationRequest = decodeGmmMessage(t,
+				testRegistrationRequest(nasMessage.RegistrationType5GSInitialRegistration, 0x10, false)).RegistrationRequest
+			securityModeComplete := decodeGmmMessage(t,
+				nastesting.GetSecurityModeComplete(tc.nasMessageContainer)).SecurityModeComplete
+
+			require.NoError(t, HandleSecurityModeComplete(ue, anType, ngapType.ProcedureCodeUplinkNASTransport,
+				securityModeComplete))
+			require.True(t, ue.State[anType].Is(context.Deregistered))
+			require.NotEmpty(t, conn.MsgList)
+			pdu, err := ngap.Decoder(conn.MsgList[0])
+			require.NoError(t, err)
+			require.Equal(t, ngapType.ProcedureCodeDownlinkNASTransport, pdu.InitiatingMessage.ProcedureCode.Value)
+			var nasPdu []byte
+			for _, ie := range pdu.InitiatingMessage.Value.DownlinkNASTransport.ProtocolIEs.List {
+				if ie.Id.Value == ngapType.ProtocolIEIDNASPDU {
+					nasPdu = ie.Value.NASPDU.Value
+				}
+			}
+			// the reject is integrity protected with the NULL integrity algorithm, its plain NAS message follows the
+			// security header
+			require.Greater(t, len(nasPdu), 7)
+			require.Equal(t, tc.expected, decodeGmmMessage(t, nasPdu[7:]).GetMessageType())
+		})
+	}
+}
+
+func TestNegotiateMicoMode(t *testing.T) {
+	amfSelf := context.GetSelf()
+	mico := amfSelf.Mico
+	t.Cleanup(func() { amfSelf.Mico = mico })
+
+	micoIndication := func(raai uint8) *nasType.MICOIndication {
+		indication := nasType.NewMICOIndication(nasMessage.RegistrationRequestMICOIndicationType)
+		indication.SetRAAI(raai)
+		return indication
+	}
+
+	testCases := []struct {
+		name                string
+		mico                *factory.Mico
+		anType              models.AccessType
+		emergencyRegistered bool
+		micoIndication      *nasType.MICOIndication
+		micoMode            bool
+		allPlmnRa           bool
+	}{
+		{
+			name:           "Not requested",
+			mico:           &factory.Mico{Enable: true, RaaiPolicy: factory.MicoRaaiPolicyAsRequested},
+			anType:         models.AccessType__3_GPP_ACCESS,
+			micoIndication: nil,
+		},
+		{
+			name:           "Not enabled",
+			anType:         models.AccessType__3_GPP_ACCESS,
+			micoIndication: micoIndication(0),
+		},
+		{
+			name:           "Non-3GPP access",
+			mico:           &factory.Mico{Enable: true},
+			anType:         models.AccessType_NON_3_GPP_ACCESS,
+			micoIndication: micoIndication(0),
+		},
+		{
+			name:                "Emergency registered",
+			mico:                &factory.Mico{Enable: true},
+			anType:              models.AccessType__3_GPP_ACCESS,
+			emergencyRegistered: true,
+			micoIndication:      micoIndication(0),
+		},
+		{
+			name:           "Accepted",
+			mico:           &factory.Mico{Enable: true, RaaiPolicy: factory.MicoRaaiPolicyAsRequested},
+			anType:         models.AccessType__3_GPP_ACCESS,
+			micoIndication: micoIndication(0),
+			micoMode:       true,
+		},
+		{
+			name:           "Accepted with all PLMN registration area",
+			mico:           &factory.Mico{Enable: true, RaaiPolicy: factory.MicoRaaiPolicyAsRequested},
+			anType:         models.AccessType__3_GPP_ACCESS,
+			micoIndication: micoIndication(1),
+			micoMode:       true,
+			allPlmnRa:      true,
+		},
+		{
+			name:           "Accepted without all PLMN registration area",
+			mico:           &factory.Mico{Enable: true, RaaiPolicy: factory.MicoRaaiPolicyNever},
+			anType:         models.AccessType__3_GPP_ACCESS,
+			micoIndication: micoIndication(1),
+			micoMode:       true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			amfSelf.Mico = tc.mico
+			ue := new(context.AmfUe)
+			ue.GmmLog = logger.GmmLog
+			ue.EmergencyRegistered = tc.emergencyRegistered
+			// the negotiation of a previous registration is not kept
+			ue.MicoMode = true
+			ue.MicoAllPlmnRegistrationArea = true
+
+			negotiateMicoMode(ue, tc.anType, tc.micoIndication)
+			require.Equal(t, tc.micoMode, ue.MicoMode)
+			require.Equal(t, tc.allPlmnRa, ue.MicoAllPlmnRegistrationArea)
+		})
+	}
+}
+
+func TestLocalAuthenticationSkipUdm(t *testing.T) {
+	_, err := consumer.NewConsumer(nil)
+	require.NoError(t, err)
+
+	requests := make(chan string, 1)
+	nrf := newH2CServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r.Method + " " + r.URL.Path
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer nrf.Close()
+
+	amfSelf := context.GetSelf()
+	nrfUri := amfSelf.NrfUri
+	plmnSupportList := amfSelf.PlmnSupportList
+	t.Cleanup(func() {
+		amfSelf.NrfUri = nrfUri
+		amfSelf.PlmnSupportList = plmnSupportList
+		amfSelf.LocalAusf = nil
+	})
+	amfSelf.NrfUri = nrf.URL
+	snssai := models.Snssai{Sst: 1, Sd: "010203"}
+	amfSelf.PlmnSupportList = []factory.PlmnSupportItem{
+		{PlmnId: &models.PlmnId{Mcc: "208", Mnc: "93"}, SNssaiList: []models.Snssai{snssai}},
+	}
+	amfSelf.LocalAusf, err = context.NewLocalAusf(&factory.LocalAuthentication{Enable: true}, nil)
+	require.NoError(t, err)
+	ue := new(context.AmfUe)
+	ue.Supi = "imsi-208930000000016"
+	ue.GmmLog = logger.GmmLog
+	anType := models.AccessType__3_GPP_ACCESS
+
+	// the UDM is still selected with the local authentication
+	require.Error(t, communicateWithUDM(ue, anType))
+	require.Equal(t, "GET /nnrf-disc/v1/nf-instances", <-requests)
+
+	// the UE subscribes to the S-NSSAIs supported by the AMF if the UDM is skipped
+	amfSelf.LocalAusf.SkipUdm = true
+	require.NoError(t, communicateWithUDM(ue, anType))
+	getSubscribedNssai(ue)
+	require.Empty(t, requests)
+	require.Len(t, ue.SubscribedNssai, 1)
+	require.Equal(t, snssai, *ue.SubscribedNssai[0].SubscribedSnssai)
+}
@@ -20,11 +20,15 @@ const (
 	ContextSetupFailEvent     fsm.EventType = "ContextSetup Fail"
 	InitDeregistrationEvent   fsm.EventType = "Initialize Deregistration"
 	DeregistrationAcceptEvent fsm.EventType = "Deregistration Accept"
+	ProcedureAbortEvent       fsm.EventType = "Procedure Abort"
+	AbortToRegisteredEvent    fsm.EventType = "Abort To Registered"
 )
 
 const (
 	ArgAmfUe               string = "AMF Ue"
 	ArgNASMessage          string = "NAS Message"
+	ArgRequestedEdrx       string = "Requested eDRX"
+	ArgNssaaComplete       string = "NSSAA Complete"
 	ArgProcedureCode       string = "Procedure Code"
 	ArgAccessType          string = "Access Type"
 	ArgEAPSuccess          string = "EAP Success"
@@ -51,6 +55,12 @@ var transitions = fsm.Transitions{
 	{Event: ContextSetupFailEvent, From: context.ContextSetup, To: context.Deregistered},
 	{Event: InitDeregistrationEvent, From: context.Registered, To: context.DeregistrationInitiated},
 	{Event: DeregistrationAcceptEvent, From: context.DeregistrationInitiated, To: context.Deregistered},
+	{Event: ProcedureAbortEvent, From: context.Authentication, To: context.Deregistered},
+	{Event: ProcedureAbortEvent, From: context.SecurityMode, To: context.Deregistered},
+	{Event: ProcedureAbortEvent, From: context.ContextSetup, To: context.Deregistered},
+	{Event: AbortToRegisteredEvent, From: context.Authentication, To: context.Registered},
+	{Event: AbortToRegisteredEvent, From: context.SecurityMode, To: context.Registered},
+	{Event: AbortToRegisteredEvent, From: context.ContextSetup, To: context.Registered},
 }
 
 var callbacks = fsm.Callbacks{
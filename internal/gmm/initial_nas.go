@@ -0,0 +1,113 @@
+package gmm
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/free5gc/amf/internal/context"
+	"github.com/free5gc/amf/internal/nas/nas_codec"
+	"github.com/free5gc/nas"
+	"github.com/free5gc/nas/nasMessage"
+)
+
+// The initial NAS message sent with cleartext IEs only, or not passing the integrity check, is sent again in the NAS
+// message container IE of the initial NAS message itself or of the SECURITY MODE COMPLETE message. The AMF considers
+// the NAS message of the container as the initial NAS message that triggered the procedure (TS 24.501 4.4.6).
+
+// decodeNasMessageContainer decodes the entire NAS message in the value part of a NAS message container IE, the
+// requested extended DRX parameters of a Registration Request are taken out first
+func decodeNasMessageContainer(contents []byte) (*nas.GmmMessage, *context.ExtendedDRXParameters, error) {
+	contents, requestedEdrx := nas_codec.StripRequestedExtendedDRXParameters(contents)
+	m := nas.NewMessage()
+	if err := m.GmmMessageDecode(&contents); err != nil {
+		return nil, nil, fmt.Errorf("decode NAS message container failed: %w", err)
+	}
+	return m.GmmMessage, requestedEdrx, nil
+}
+
+// registrationRequestFromContainer returns the Registration Request of the NAS message container. Its cleartext IEs
+// (TS 24.501 4.4.6) must be the ones of the Registration Request sent in clear, and the cleartext IEs it does not
+// include are taken from it.
+func registrationRequestFromContainer(ue *context.AmfUe, cleartext *nasMessage.RegistrationRequest,
+	contents []byte,
+) (*nasMessage.RegistrationRequest, error) {
+	gmmMessage, requestedEdrx, err := decodeNasMessageContainer(contents)
+	if err != nil {
+		return nil, err
+	}
+	if messageType := gmmMessage.GetMessageType(); messageType != nas.MsgTypeRegistrationRequest {
+		return nil, fmt.Errorf("NAS message container of message type %d is not a Registration Request", messageType)
+	}
+	return mergeRegistrationRequestCleartextIEs(ue, cleartext, gmmMessage.RegistrationRequest, requestedEdrx)
+}
+
+// mergeRegistrationRequestCleartextIEs checks the cleartext IEs of the Registration Request of the NAS message
+// container against the ones of the Registration Request sent in clear, if any, and completes it with them
+func mergeRegistrationRequestCleartextIEs(ue *context.AmfUe, cleartext,
+	registrationRequest *nasMessage.RegistrationRequest, requestedEdrx *context.ExtendedDRXParameters,
+) (*nasMessage.RegistrationRequest, error) {
+	if cleartext != nil {
+		if err := checkRegistrationRequestCleartextIEs(cleartext, registrationRequest); err != nil {
+			return nil, err
+		}
+		if registrationRequest.UESecurityCapability == nil {
+			registrationRequest.UESecurityCapability = cleartext.UESecurityCapability
+		}
+		if registrationRequest.AdditionalGUTI == nil {
+			registrationRequest.AdditionalGUTI = cleartext.AdditionalGUTI
+		}
+		if registrationRequest.UEStatus == nil {
+			registrationRequest.UEStatus = cleartext.UEStatus
+		}
+		if registrationRequest.EPSNASMessageContainer == nil {
+			registrationRequest.EPSNASMessageContainer = cleartext.EPSNASMessageContainer
+		}
+	}
+	ue.RequestedEdrx = requestedEdrx
+	return registrationRequest, nil
+}
+
+func checkRegistrationRequestCleartextIEs(cleartext, container *nasMessage.RegistrationRequest) error {
+	if cleartext.GetRegistrationType5GS() != container.GetRegistrationType5GS() {
+		return fmt.Errorf("5GS registration type %d of the NAS message container does not match %d",
+			container.GetRegistrationType5GS(), cleartext.GetRegistrationType5GS())
+	}
+	if cleartext.NgksiAndRegistrationType5GS.GetTSC() != container.NgksiAndRegistrationType5GS.GetTSC() ||
+		cleartext.NgksiAndRegistrationType5GS.GetNasKeySetIdentifiler() !=
+			container.NgksiAndRegistrationType5GS.GetNasKeySetIdentifiler() {
+		return fmt.Errorf("ngKSI of the NAS message container does not match")
+	}
+	if !bytes.Equal(cleartext.GetMobileIdentity5GSContents(), container.GetMobileIdentity5GSContents()) {
+		return fmt.Errorf("5GS mobile identity of the NAS message container does not match")
+	}
+	if cleartext.UESecurityCapability != nil && container.UESecurityCapability != nil &&
+		!bytes.Equal(cleartext.UESecurityCapability.Buffer, container.UESecurityCapability.Buffer) {
+		return fmt.Errorf("UE security capability of the NAS message container does not match")
+	}
+	return nil
+}
+
+// serviceRequestFromContainer returns the Service Request of the NAS message container, its cleartext IEs must be
+// the ones of the Service Request sent in clear (TS 24.501 4.4.6)
+func serviceRequestFromContainer(cleartext *nasMessage.ServiceRequest, contents []byte) (
+	*nasMessage.ServiceRequest, error,
+) {
+	gmmMessage, _, err := decodeNasMessageContainer(contents)
+	if err != nil {
+		return nil, err
+	}
+	if messageType := gmmMessage.GetMessageType(); messageType != nas.MsgTypeServiceRequest {
+		return nil, fmt.Errorf("NAS message container of message type %d is not a Service Request", messageType)
+	}
+	serviceRequest := gmmMessage.ServiceRequest
+
+	if cleartext != nil {
+		if cleartext.ServiceTypeAndNgksi.Octet != serviceRequest.ServiceTypeAndNgksi.Octet {
+			return nil, fmt.Errorf("service type or ngKSI of the NAS message container does not match")
+		}
+		if cleartext.TMSI5GS.Octet != serviceRequest.TMSI5GS.Octet {
+			return nil, fmt.Errorf("5G-S-TMSI of the NAS message container does not match")
+		}
+	}
+	return serviceRequest, nil
+}
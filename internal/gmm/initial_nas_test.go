@@ -0,0 +1,108 @@
+package gmm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/free5gc/amf/internal/context"
+	nastesting "github.com/free5gc/amf/internal/nas/testing"
+	"github.com/free5gc/nas"
+	"github.com/free5gc/nas/nasMessage"
+	"github.com/free5gc/nas/nasType"
+)
+
+func decodeGmmMessage(t *testing.T, pdu []byte) *nas.GmmMessage {
+	m := nas.NewMessage()
+	require.NoError(t, m.PlainNasDecode(&pdu))
+	return m.GmmMessage
+}
+
+func testRegistrationRequest(registrationType uint8, msin uint8, withNonCleartextIEs bool) []byte {
+	suci := []uint8{0x01, 0x02, 0xf8, 0x39, 0xf0, 0xff, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, msin}
+	mobileIdentity := nasType.MobileIdentity5GS{Len: uint16(len(suci)), Buffer: suci}
+	ueSecurityCapability := nasType.NewUESecurityCapability(nasMessage.RegistrationRequestUESecurityCapabilityType)
+	ueSecurityCapability.SetLen(2)
+	ueSecurityCapability.SetEA2_128_5G(1)
+	ueSecurityCapability.SetIA2_128_5G(1)
+	if !withNonCleartextIEs {
+		return nastesting.GetRegistrationRequest(registrationType, mobileIdentity, nil, ueSecurityCapability, nil, nil,
+			nil)
+	}
+	requestedNssai := nasType.NewRequestedNSSAI(nasMessage.RegistrationRequestRequestedNSSAIType)
+	requestedNssai.SetLen(2)
+	requestedNssai.SetSNSSAIValue([]uint8{0x01, 0x01})
+	capability5GMM := &nasType.Capability5GMM{
+		Iei:   nasMessage.RegistrationRequestCapability5GMMType,
+		Len:   1,
+		Octet: [13]uint8{0x07},
+	}
+	return nastesting.GetRegistrationRequest(registrationType, mobileIdentity, requestedNssai, nil, capability5GMM,
+		nil, nil)
+}
+
+func TestRegistrationRequestFromContainer(t *testing.T) {
+	cleartext := decodeGmmMessage(t,
+		testRegistrationRequest(nasMessage.RegistrationType5GSInitialRegistration, 0x10, false)).RegistrationRequest
+
+	testCases := []struct {
+		name      string
+		container []byte
+		expectErr bool
+	}{
+		{
+			name:      "Entire Registration Request",
+			container: testRegistrationRequest(nasMessage.RegistrationType5GSInitialRegistration, 0x10, true),
+		},
+		{
+			name:      "Other registration type",
+			container: testRegistrationRequest(nasMessage.RegistrationType5GSMobilityRegistrationUpdating, 0x10, true),
+			expectErr: true,
+		},
+		{
+			name:      "Other 5GS mobile identity",
+			container: testRegistrationRequest(nasMessage.RegistrationType5GSInitialRegistration, 0x20, true),
+			expectErr: true,
+		},
+		{
+			name:      "Other message type",
+			container: nastesting.GetServiceRequest(nasMessage.ServiceTypeSignalling),
+			expectErr: true,
+		},
+		{
+			name:      "Invalid message",
+			container: []byte{nasMessage.Epd5GSMobilityManagementMessage},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			registrationRequest, err := registrationRequestFromContainer(new(context.AmfUe), cleartext, tc.container)
+			if tc.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			// the non-cleartext IEs come from the container, the missing cleartext IEs from the message sent in clear
+			require.NotNil(t, registrationRequest.Capability5GMM)
+			require.NotNil(t, registrationRequest.RequestedNSSAI)
+			require.Equal(t, cleartext.UESecurityCapability, registrationRequest.UESecurityCapability)
+		})
+	}
+}
+
+func TestServiceRequestFromContainer(t *testing.T) {
+	cleartext := decodeGmmMessage(t, nastesting.GetServiceRequest(nasMessage.ServiceTypeData)).ServiceRequest
+
+	serviceRequest, err := serviceRequestFromContainer(cleartext, nastesting.GetServiceRequest(nasMessage.ServiceTypeData))
+	require.NoError(t, err)
+	require.NotNil(t, serviceRequest.UplinkDataStatus)
+
+	_, err = serviceRequestFromContainer(cleartext, nastesting.GetServiceRequest(nasMessage.ServiceTypeSignalling))
+	require.Error(t, err)
+
+	_, err = serviceRequestFromContainer(cleartext,
+		testRegistrationRequest(nasMessage.RegistrationType5GSInitialRegistration, 0x10, false))
+	require.Error(t, err)
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/free5gc/amf/internal/context"
 	"github.com/free5gc/amf/internal/logger"
+	"github.com/free5gc/amf/internal/nas/nas_codec"
 	"github.com/free5gc/amf/internal/nas/nas_security"
 	"github.com/free5gc/amf/pkg/factory"
 	"github.com/free5gc/nas"
@@ -19,8 +20,63 @@ import (
 	"github.com/free5gc/openapi/models"
 )
 
+// Emergency registered bit of the 5GS registration result IE, TS 24.501 9.11.3.6
+const registrationResultEmergencyRegistered uint8 = 0x20
+
+// NSSAA to be performed bit of the 5GS registration result IE, TS 24.501 9.11.3.6
+const registrationResultNssaaToBePerformed uint8 = 0x10
+
+// Cause of the S-NSSAIs whose NSSAA failed or was revoked in the rejected NSSAI IE, TS 24.501 9.11.3.46
+const rejectedSnssaiCauseNssaaFailedOrRevoked uint8 = 0x02
+
 func BuildDLNASTransport(ue *context.AmfUe, accessType models.AccessType, payloadContainerType uint8, nasPdu []byte,
 	pduSessionId uint8, cause *uint8, backoffTimerUint *uint8, backoffTimer uint8,
+) ([]byte, error) {
+	return buildDLNASTransport(ue, accessType, payloadContainerType, nasPdu, pduSessionId, cause,
+		backoffTimerUint, backoffTimer, nil)
+}
+
+// BuildDLNASTransportLPP builds the DL NAS Transport of an LPP message, the routing information of the positioning
+// session is carried in the Additional Information (TS 24.501 5.4.5.3.1)
+func BuildDLNASTransportLPP(ue *context.AmfUe, accessType models.AccessType, lppMsg []byte,
+	routingInformation []byte,
+) ([]byte, error) {
+	return buildDLNASTransport(ue, accessType, nasMessage.PayloadContainerTypeLPP, lppMsg, 0, nil, nil, 0,
+		routingInformation)
+}
+
+// BuildDLNASTransportPayloads builds one DL NAS Transport carrying the payloads, several payloads are combined in
+// a payload container of type multiple payloads (TS 24.501 5.4.5.3.1)
+func BuildDLNASTransportPayloads(ue *context.AmfUe, accessType models.AccessType,
+	entries []*context.PayloadContainerEntry,
+) ([]byte, error) {
+	if len(entries) != 1 {
+		multiplePayload, err := context.EncodeMultiplePayload(entries)
+		if err != nil {
+			return nil, err
+		}
+		return buildDLNASTransport(ue, accessType, nasMessage.PayloadContainerTypeMultiplePayload, multiplePayload,
+			0, nil, nil, 0, nil)
+	}
+
+	entry := entries[0]
+	var pduSessionId uint8
+	if entry.PduSessionId != nil {
+		pduSessionId = *entry.PduSessionId
+	}
+	var backoffTimerUint *uint8
+	var backoffTimer uint8
+	if len(entry.BackoffTimer) == 1 {
+		unit := entry.BackoffTimer[0] >> 5
+		backoffTimerUint = &unit
+		backoffTimer = entry.BackoffTimer[0] & 0x1f
+	}
+	return buildDLNASTransport(ue, accessType, entry.Type, entry.Contents, pduSessionId, entry.Cause,
+		backoffTimerUint, backoffTimer, entry.AdditionalInformation)
+}
+
+func buildDLNASTransport(ue *context.AmfUe, accessType models.AccessType, payloadContainerType uint8, nasPdu []byte,
+	pduSessionId uint8, cause *uint8, backoffTimerUint *uint8, backoffTimer uint8, additionalInformation []byte,
 ) ([]byte, error) {
 	m := nas.NewMessage()
 	m.GmmMessage = nas.NewGmmMessage()
@@ -56,6 +112,12 @@ func BuildDLNASTransport(ue *context.AmfUe, accessType models.AccessType, payloa
 		dLNASTransport.BackoffTimerValue.SetUnitTimerValue(*backoffTimerUint)
 		dLNASTransport.BackoffTimerValue.SetTimerValue(backoffTimer)
 	}
+	if len(additionalInformation) > 0 {
+		dLNASTransport.AdditionalInformation = new(nasType.AdditionalInformation)
+		dLNASTransport.AdditionalInformation.SetIei(nasMessage.DLNASTransportAdditionalInformationType)
+		dLNASTransport.AdditionalInformation.SetLen(uint8(len(additionalInformation)))
+		dLNASTransport.AdditionalInformation.SetAdditionalInformationValue(additionalInformation)
+	}
 
 	m.GmmMessage.DLNASTransport = dLNASTransport
 
@@ -347,7 +409,11 @@ func BuildRegistrationReject(ue *context.AmfUe, accessType models.AccessType, ca
 		ProtocolDiscriminator: nasMessage.Epd5GSMobilityManagementMessage,
 		SecurityHeaderType:    nas.SecurityHeaderTypeIntegrityProtectedAndCiphered,
 	}
-	return nas_security.Encode(ue, m, accessType)
+	var rejectedNssai []byte
+	if ue != nil {
+		rejectedNssai = nas_codec.EncodeRejectedNSSAI(ue.NsacRejectedNssai)
+	}
+	return nas_security.EncodeWithOptionalIEs(ue, m, accessType, rejectedNssai)
 }
 
 // TS 24.501 8.2.25
@@ -377,12 +443,11 @@ func BuildSecurityModeCommand(ue *context.AmfUe, accessType models.AccessType, e
 	securityModeCommand.ReplayedUESecurityCapabilities.SetLen(ue.UESecurityCapability.GetLen())
 	securityModeCommand.ReplayedUESecurityCapabilities.Buffer = ue.UESecurityCapability.Buffer
 
-	if ue.Pei != "" {
-		securityModeCommand.IMEISVRequest = nasType.NewIMEISVRequest(nasMessage.SecurityModeCommandIMEISVRequestType)
-		securityModeCommand.IMEISVRequest.SetIMEISVRequestValue(nasMessage.IMEISVNotRequested)
-	} else {
-		securityModeCommand.IMEISVRequest = nasType.NewIMEISVRequest(nasMessage.SecurityModeCommandIMEISVRequestType)
+	securityModeCommand.IMEISVRequest = nasType.NewIMEISVRequest(nasMessage.SecurityModeCommandIMEISVRequestType)
+	if context.GetSelf().ImeisvRequired(ue) {
 		securityModeCommand.IMEISVRequest.SetIMEISVRequestValue(nasMessage.IMEISVRequested)
+	} else {
+		securityModeCommand.IMEISVRequest.SetIMEISVRequestValue(nasMessage.IMEISVNotRequested)
 	}
 
 	securityModeCommand.Additional5GSecurityInformation = nasType.
@@ -394,8 +459,8 @@ func BuildSecurityModeCommand(ue *context.AmfUe, accessType models.AccessType, e
 		securityModeCommand.Additional5GSecurityInformation.SetRINMR(0)
 	}
 
-	if ue.RegistrationType5GS == nasMessage.RegistrationType5GSPeriodicRegistrationUpdating ||
-		ue.RegistrationType5GS == nasMessage.RegistrationType5GSMobilityRegistrationUpdating {
+	// the UE derives the K_AMF' from the K_AMF horizontally derived by the AMF (TS 33.501 6.9.3)
+	if ue.HorizontalDerivationParameter() {
 		securityModeCommand.Additional5GSecurityInformation.SetHDP(1)
 	} else {
 		securityModeCommand.Additional5GSecurityInformation.SetHDP(0)
@@ -501,7 +566,7 @@ func BuildRegistrationAccept(
 	pDUSessionStatus *[16]bool,
 	reactivationResult *[16]bool,
 	errPduSessionId, errCause []uint8,
-) ([]byte, error) {
+) (nasMsg []byte, sorIncluded bool, err error) {
 	m := nas.NewMessage()
 	m.GmmMessage = nas.NewGmmMessage()
 	m.GmmHeader.SetMessageType(nas.MsgTypeRegistrationAccept)
@@ -531,12 +596,20 @@ func BuildRegistrationAccept(
 		}
 	}
 	registrationAccept.RegistrationResult5GS.SetRegistrationResultValue5GS(registrationResult)
-	// TODO: set smsAllowed value of RegistrationResult5GS if need
+	if ue.SmsAllowed {
+		registrationAccept.RegistrationResult5GS.SetSMSAllowed(nasMessage.SMSOverNasAllowed)
+	}
+	if ue.EmergencyRegistered {
+		registrationAccept.RegistrationResult5GS.Octet |= registrationResultEmergencyRegistered
+	}
+	if len(ue.PendingNssai) > 0 {
+		registrationAccept.RegistrationResult5GS.Octet |= registrationResultNssaaToBePerformed
+	}
 
 	if ue.Guti != "" {
 		gutiNas, err := nasConvert.GutiToNasWithError(ue.Guti)
 		if err != nil {
-			return nil, fmt.Errorf("encode GUTI failed: %w", err)
+			return nil, false, fmt.Errorf("encode GUTI failed: %w", err)
 		}
 		registrationAccept.GUTI5G = &gutiNas
 		registrationAccept.GUTI5G.SetIei(nasMessage.RegistrationAcceptGUTI5GType)
@@ -553,7 +626,8 @@ func BuildRegistrationAccept(
 		copy(registrationAccept.EquivalentPlmns.Octet[:], buf)
 	}
 
-	if len(ue.RegistrationArea[anType]) > 0 {
+	// no TAI list is included with an all PLMN registration area (TS 24.501 5.5.1.2.4)
+	if len(ue.RegistrationArea[anType]) > 0 && !ue.MicoAllPlmnRegistrationArea {
 		registrationAccept.TAIList = nasType.NewTAIList(nasMessage.RegistrationAcceptTAIListType)
 		taiListNas := nasConvert.TaiListToNas(ue.RegistrationArea[anType])
 		registrationAccept.TAIList.SetLen(uint8(len(taiListNas)))
@@ -570,13 +644,9 @@ func BuildRegistrationAccept(
 		registrationAccept.AllowedNSSAI.SetSNSSAIValue(buf)
 	}
 
-	if ue.NetworkSliceInfo != nil {
-		if len(ue.NetworkSliceInfo.RejectedNssaiInPlmn) != 0 || len(ue.NetworkSliceInfo.RejectedNssaiInTa) != 0 {
-			rejectedNssaiNas := nasConvert.RejectedNssaiToNas(
-				ue.NetworkSliceInfo.RejectedNssaiInPlmn, ue.NetworkSliceInfo.RejectedNssaiInTa)
-			registrationAccept.RejectedNSSAI = &rejectedNssaiNas
-			registrationAccept.RejectedNSSAI.SetIei(nasMessage.RegistrationAcceptRejectedNSSAIType)
-		}
+	if rejectedNssaiNas := rejectedNssaiToNas(ue); rejectedNssaiNas != nil {
+		registrationAccept.RejectedNSSAI = rejectedNssaiNas
+		registrationAccept.RejectedNSSAI.SetIei(nasMessage.RegistrationAcceptRejectedNSSAIType)
 	}
 
 	if includeConfiguredNssaiCheck(ue) {
@@ -647,14 +717,20 @@ func BuildRegistrationAccept(
 		ue.NetworkSlicingSubscriptionChanged = false // reset the value
 	}
 
-	if anType == models.AccessType__3_GPP_ACCESS && ue.AmPolicyAssociation != nil &&
-		ue.AmPolicyAssociation.ServAreaRes != nil {
+	if servAreaRes := ue.ServiceAreaRestriction(); anType == models.AccessType__3_GPP_ACCESS && servAreaRes != nil {
 		registrationAccept.ServiceAreaList = nasType.NewServiceAreaList(nasMessage.RegistrationAcceptServiceAreaListType)
-		partialServiceAreaList := nasConvert.PartialServiceAreaListToNas(ue.PlmnId, *ue.AmPolicyAssociation.ServAreaRes)
+		partialServiceAreaList := nasConvert.PartialServiceAreaListToNas(ue.PlmnId, *servAreaRes)
 		registrationAccept.ServiceAreaList.SetLen(uint8(len(partialServiceAreaList)))
 		registrationAccept.ServiceAreaList.SetPartialServiceAreaList(partialServiceAreaList)
 	}
 
+	if anType == models.AccessType__3_GPP_ACCESS && ue.MicoMode {
+		registrationAccept.MICOIndication = nasType.NewMICOIndication(nasMessage.RegistrationAcceptMICOIndicationType)
+		if ue.MicoAllPlmnRegistrationArea {
+			registrationAccept.MICOIndication.SetRAAI(1)
+		}
+	}
+
 	if anType == models.AccessType__3_GPP_ACCESS && ue.T3512Value != 0 {
 		registrationAccept.T3512Value = nasType.NewT3512Value(nasMessage.RegistrationAcceptT3512ValueType)
 		registrationAccept.T3512Value.SetLen(1)
@@ -684,9 +760,26 @@ func BuildRegistrationAccept(
 		registrationAccept.NegotiatedDRXParameters.SetDRXValue(ue.UESpecificDRX)
 	}
 
+	// TS 23.122 Annex C.2, the Steering of Roaming information obtained from the UDM during the registration
+	if sorContainer, errSor := ue.SorTransparentContainer(); errSor != nil {
+		ue.GmmLog.Warnf("Steering of Roaming information is not included: %+v", errSor)
+	} else if len(sorContainer) > 0 {
+		registrationAccept.SORTransparentContainer = nasType.
+			NewSORTransparentContainer(nasMessage.RegistrationAcceptSORTransparentContainerType)
+		registrationAccept.SORTransparentContainer.SetLen(uint16(len(sorContainer)))
+		registrationAccept.SORTransparentContainer.SetSORContent(sorContainer)
+		sorIncluded = true
+	}
+
 	m.GmmMessage.RegistrationAccept = registrationAccept
 
-	return nas_security.Encode(ue, m, anType)
+	optionalIEs := nas_codec.EncodeNegotiatedExtendedDRXParameters(ue.NegotiatedEdrx)
+	optionalIEs = append(optionalIEs, nas_codec.EncodePendingNSSAI(ue.PendingNssai)...)
+	nasMsg, err = nas_security.EncodeWithOptionalIEs(ue, m, anType, optionalIEs)
+	if err != nil {
+		return nil, false, err
+	}
+	return nasMsg, sorIncluded, nil
 }
 
 func includeConfiguredNssaiCheck(ue *context.AmfUe) bool {
@@ -727,6 +820,51 @@ func BuildStatus5GMM(ue *context.AmfUe, accessType models.AccessType, cause uint
 	return nas_security.Encode(ue, m, accessType)
 }
 
+// BuildNetworkSliceSpecificAuthenticationCommand relays an EAP message of the NSSAA of the S-NSSAI to the UE,
+// TS 24.501 8.2.31
+func BuildNetworkSliceSpecificAuthenticationCommand(ue *context.AmfUe, accessType models.AccessType,
+	snssai models.Snssai, eapMessage []byte,
+) ([]byte, error) {
+	return nas_security.Protect(ue, accessType, nas_codec.EncodeNetworkSliceSpecificAuthenticationMessage(
+		nas_codec.MsgTypeNetworkSliceSpecificAuthenticationCommand, snssai, eapMessage))
+}
+
+// BuildNetworkSliceSpecificAuthenticationResult relays the EAP success or failure message completing the NSSAA
+// of the S-NSSAI to the UE, TS 24.501 8.2.33
+func BuildNetworkSliceSpecificAuthenticationResult(ue *context.AmfUe, accessType models.AccessType,
+	snssai models.Snssai, eapMessage []byte,
+) ([]byte, error) {
+	return nas_security.Protect(ue, accessType, nas_codec.EncodeNetworkSliceSpecificAuthenticationMessage(
+		nas_codec.MsgTypeNetworkSliceSpecificAuthenticationResult, snssai, eapMessage))
+}
+
+// rejectedNssaiToNas returns the rejected NSSAI provided by the NSSF with the S-NSSAIs whose NSSAA failed or was
+// revoked, nil if there is none
+func rejectedNssaiToNas(ue *context.AmfUe) *nasType.RejectedNSSAI {
+	var rejectedNssaiInPlmn, rejectedNssaiInTa []models.Snssai
+	if ue.NetworkSliceInfo != nil {
+		rejectedNssaiInPlmn = ue.NetworkSliceInfo.RejectedNssaiInPlmn
+		rejectedNssaiInTa = ue.NetworkSliceInfo.RejectedNssaiInTa
+	}
+	if len(rejectedNssaiInPlmn) == 0 && len(rejectedNssaiInTa) == 0 && len(ue.NssaaRejectedNssai) == 0 &&
+		len(ue.NsacRejectedNssai) == 0 {
+		return nil
+	}
+
+	rejectedNssaiNas := nasConvert.RejectedNssaiToNas(rejectedNssaiInPlmn, rejectedNssaiInTa)
+	contents := rejectedNssaiNas.GetRejectedNSSAIContents()
+	for _, snssai := range ue.NssaaRejectedNssai {
+		contents = append(contents, nasConvert.RejectedSnssaiToNas(snssai, rejectedSnssaiCauseNssaaFailedOrRevoked)...)
+	}
+	for _, snssai := range ue.NsacRejectedNssai {
+		contents = append(contents, nasConvert.RejectedSnssaiToNas(snssai,
+			nas_codec.RejectedSnssaiCauseMaximumNumberOfUesReached)...)
+	}
+	rejectedNssaiNas.SetLen(uint8(len(contents)))
+	rejectedNssaiNas.SetRejectedNSSAIContents(contents)
+	return &rejectedNssaiNas
+}
+
 // Fllowed by TS 24.501 - 5.4.4 Generic UE configuration update procedure - 5.4.4.1 General
 func BuildConfigurationUpdateCommand(ue *context.AmfUe, anType models.AccessType,
 	flags *context.ConfigurationUpdateCommandFlags,
@@ -746,6 +884,7 @@ func BuildConfigurationUpdateCommand(ue *context.AmfUe, anType models.AccessType
 		configurationUpdateCommand.NetworkSlicingIndication = nasType.
 			NewNetworkSlicingIndication(nasMessage.ConfigurationUpdateCommandNetworkSlicingIndicationType)
 		configurationUpdateCommand.NetworkSlicingIndication.SetNSSCI(0x01)
+		ue.NetworkSlicingSubscriptionChanged = false // the UE is informed of the change
 	}
 
 	if flags.NeedGUTI {
@@ -794,11 +933,8 @@ func BuildConfigurationUpdateCommand(ue *context.AmfUe, anType models.AccessType
 	}
 
 	if flags.NeedRejectNSSAI {
-		if ue.NetworkSliceInfo != nil &&
-			(len(ue.NetworkSliceInfo.RejectedNssaiInPlmn) != 0 || len(ue.NetworkSliceInfo.RejectedNssaiInTa) != 0) {
-			rejectedNssaiNas := nasConvert.RejectedNssaiToNas(
-				ue.NetworkSliceInfo.RejectedNssaiInPlmn, ue.NetworkSliceInfo.RejectedNssaiInTa)
-			configurationUpdateCommand.RejectedNSSAI = &rejectedNssaiNas
+		if rejectedNssaiNas := rejectedNssaiToNas(ue); rejectedNssaiNas != nil {
+			configurationUpdateCommand.RejectedNSSAI = rejectedNssaiNas
 			configurationUpdateCommand.RejectedNSSAI.SetIei(nasMessage.ConfigurationUpdateCommandRejectedNSSAIType)
 		} else {
 			logger.GmmLog.Warnf("Require Rejected NSSAI, but got nothing.")
@@ -817,11 +953,10 @@ func BuildConfigurationUpdateCommand(ue *context.AmfUe, anType models.AccessType
 	}
 
 	if flags.NeedServiceAreaList && anType == models.AccessType__3_GPP_ACCESS {
-		if ue.AmPolicyAssociation != nil && ue.AmPolicyAssociation.ServAreaRes != nil {
+		if servAreaRes := ue.ServiceAreaRestriction(); servAreaRes != nil {
 			configurationUpdateCommand.ServiceAreaList = nasType.
 				NewServiceAreaList(nasMessage.ConfigurationUpdateCommandServiceAreaListType)
-			partialServiceAreaList := nasConvert.
-				PartialServiceAreaListToNas(ue.PlmnId, *ue.AmPolicyAssociation.ServAreaRes)
+			partialServiceAreaList := nasConvert.PartialServiceAreaListToNas(ue.PlmnId, *servAreaRes)
 			configurationUpdateCommand.ServiceAreaList.SetLen(uint8(len(partialServiceAreaList)))
 			configurationUpdateCommand.ServiceAreaList.SetPartialServiceAreaList(partialServiceAreaList)
 		} else {
@@ -841,24 +976,27 @@ func BuildConfigurationUpdateCommand(ue *context.AmfUe, anType models.AccessType
 			configurationUpdateCommand.LADNInformation.SetLen(uint16(len(buf)))
 			configurationUpdateCommand.LADNInformation.SetLADND(buf)
 		} else {
-			logger.GmmLog.Warnf("Require LADN Information, but got nothing.")
+			// TS 24.501 5.4.4.3, an empty LADN information deletes the LADN information of the UE
+			configurationUpdateCommand.LADNInformation = nasType.
+				NewLADNInformation(nasMessage.ConfigurationUpdateCommandLADNInformationType)
 		}
 	}
 
 	amfSelf := context.GetSelf()
 
 	if flags.NeedNITZ {
+		networkName := amfSelf.NetworkName()
 		// Full network name
-		if amfSelf.NetworkName.Full != "" {
-			fullNetworkName := nasConvert.FullNetworkNameToNas(amfSelf.NetworkName.Full)
+		if networkName.Full != "" {
+			fullNetworkName := nasConvert.FullNetworkNameToNas(networkName.Full)
 			configurationUpdateCommand.FullNameForNetwork = &fullNetworkName
 			configurationUpdateCommand.FullNameForNetwork.SetIei(nasMessage.ConfigurationUpdateCommandFullNameForNetworkType)
 		} else {
 			logger.GmmLog.Warnf("Require Full Network Name, but got nothing.")
 		}
 		// Short network name
-		if amfSelf.NetworkName.Short != "" {
-			shortNetworkName := nasConvert.ShortNetworkNameToNas(amfSelf.NetworkName.Short)
+		if networkName.Short != "" {
+			shortNetworkName := nasConvert.ShortNetworkNameToNas(networkName.Short)
 			configurationUpdateCommand.ShortNameForNetwork = &shortNetworkName
 			configurationUpdateCommand.ShortNameForNetwork.SetIei(nasMessage.ConfigurationUpdateCommandShortNameForNetworkType)
 		} else {
@@ -870,8 +1008,8 @@ func BuildConfigurationUpdateCommand(ue *context.AmfUe, anType models.AccessType
 		universalTimeAndLocalTimeZone.SetIei(nasMessage.ConfigurationUpdateCommandUniversalTimeAndLocalTimeZoneType)
 		configurationUpdateCommand.UniversalTimeAndLocalTimeZone = &universalTimeAndLocalTimeZone
 
-		if ue.TimeZone != amfSelf.TimeZone {
-			ue.TimeZone = amfSelf.TimeZone
+		if timeZone := amfSelf.TimeZone(); ue.TimeZone != timeZone {
+			ue.TimeZone = timeZone
 			// Local Time Zone
 			localTimeZone := nasConvert.EncodeLocalTimeZoneToNas(ue.TimeZone)
 			localTimeZone.SetIei(nasMessage.ConfigurationUpdateCommandLocalTimeZoneType)
@@ -909,6 +1047,12 @@ func BuildConfigurationUpdateCommand(ue *context.AmfUe, anType models.AccessType
 		// Allowed NSSAI and Configured NSSAI are optional to request to perform the registration procedure
 		configurationUpdateCommand.ConfigurationUpdateIndication.SetRED(uint8(1))
 	}
+	if flags.NeedRegistrationRequested {
+		// TS 24.501 5.4.4.2, acknowledgement shall be requested if registration is requested
+		configurationUpdateCommand.ConfigurationUpdateIndication.SetRED(uint8(1))
+		configurationUpdateCommand.ConfigurationUpdateIndication.SetACK(uint8(1))
+		needTimer = true
+	}
 
 	// Check if the Configuration Update Command is vaild
 	if configurationUpdateCommand.ConfigurationUpdateIndication.GetACK() == uint8(0) &&
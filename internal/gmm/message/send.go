@@ -6,6 +6,7 @@ import (
 	"github.com/free5gc/amf/internal/context"
 	gmm_common "github.com/free5gc/amf/internal/gmm/common"
 	"github.com/free5gc/amf/internal/logger"
+	business_metrics "github.com/free5gc/amf/internal/metrics/business"
 	ngap_message "github.com/free5gc/amf/internal/ngap/message"
 	callback "github.com/free5gc/amf/internal/sbi/processor/notifier"
 	"github.com/free5gc/nas/nasMessage"
@@ -14,6 +15,13 @@ import (
 	nasMetrics "github.com/free5gc/util/metrics/nas"
 )
 
+// The NAS metrics define no name for the network slice-specific authentication messages
+const (
+	networkSliceSpecificAuthenticationCommand      = "NetworkSliceSpecificAuthenticationCommand"
+	networkSliceSpecificAuthenticationCommandTimer = "NetworkSliceSpecificAuthenticationCommandTimer"
+	networkSliceSpecificAuthenticationResult       = "NetworkSliceSpecificAuthenticationResult"
+)
+
 // backOffTimerUint = 7 means backoffTimer is null
 func SendDLNASTransport(ue *context.RanUe, payloadContainerType uint8, nasPdu []byte,
 	pduSessionId int32, cause uint8, backOffTimerUint *uint8, backOffTimer uint8,
@@ -51,6 +59,39 @@ func SendDLNASTransport(ue *context.RanUe, payloadContainerType uint8, nasPdu []
 	ngap_message.SendDownlinkNasTransport(ue, nasMsg, nil)
 }
 
+// SendPendingDLPayloads sends the payloads pending for the UE in one DL NAS Transport
+func SendPendingDLPayloads(ue *context.RanUe) {
+	if ue == nil || ue.AmfUe == nil {
+		logger.GmmLog.Error("SendPendingDLPayloads: RanUe or AmfUe is nil")
+		return
+	}
+	amfUe := ue.AmfUe
+	entries := amfUe.TakePendingDLPayloads()
+	if len(entries) == 0 {
+		return
+	}
+
+	isNasMsgSent := false
+	additionalCause := ""
+	defer nasMetrics.IncrMetricsSentNasMsgs(nasMetrics.DL_NAS_TRANSPORT, &isNasMsgSent, 0, &additionalCause)
+	amfUe.GmmLog.Infof("Send DL NAS Transport (%d pending payloads)", len(entries))
+
+	nasMsg, err := BuildDLNASTransportPayloads(amfUe, ue.Ran.AnType, entries)
+	if err != nil {
+		additionalCause = nasMetrics.NAS_MSG_BUILD_ERR
+		amfUe.GmmLog.Error(err.Error())
+		return
+	}
+
+	isNasMsgSent = true
+	ngap_message.SendDownlinkNasTransport(ue, nasMsg, nil)
+	for _, entry := range entries {
+		if entry.Type == nasMessage.PayloadContainerTypeSOR {
+			amfUe.SorInfoSent()
+		}
+	}
+}
+
 func SendNotification(ue *context.RanUe, nasMsg []byte) {
 	isNasMsgSent := false
 	additionalCause := ""
@@ -227,6 +268,7 @@ func SendServiceAccept(amfUe *context.AmfUe, anType models.AccessType,
 
 	isNasMsgSent = true
 	ngap_message.SendN2Message(amfUe, anType, nasMsg, &cxtList, nil, nil, nil, nil)
+	SendPendingDLPayloads(amfUe.RanUe[anType])
 	return nil
 }
 
@@ -261,6 +303,18 @@ func SendConfigurationUpdateCommand(amfUe *context.AmfUe,
 	isNasMsgSent = true
 	ngap_message.SendDownlinkNasTransport(amfUe.RanUe[accessType], nasMsg, &mobilityRestrictionList)
 
+	// a new command supersedes the one waiting for an acknowledgement
+	amfUe.StopT3555()
+	configurationUpdate := &context.ConfigurationUpdate{
+		AccessType: accessType,
+		Flags:      *flags,
+		Status:     context.ConfigurationUpdateStatusNotRequested,
+	}
+	if startT3555 {
+		configurationUpdate.Status = context.ConfigurationUpdateStatusPending
+	}
+	amfUe.ConfigurationUpdate = configurationUpdate
+
 	if startT3555 && context.GetSelf().T3555Cfg.Enable {
 		cfg := context.GetSelf().T3555Cfg
 		amfUe.GmmLog.Infof("Start T3555 timer")
@@ -274,6 +328,7 @@ func SendConfigurationUpdateCommand(amfUe *context.AmfUe,
 		}, func() {
 			amfUe.GmmLog.Warnf("T3555 Expires %d times, abort configuration update procedure",
 				cfg.MaxRetryTimes)
+			configurationUpdate.Status = context.ConfigurationUpdateStatusNotAcknowledged
 		},
 		)
 	}
@@ -582,7 +637,8 @@ func SendRegistrationAccept(
 	}
 	amfUe.GmmLog.Info("Send Registration Accept")
 
-	nasMsg, err := BuildRegistrationAccept(amfUe, anType, pDUSessionStatus, reactivationResult, errPduSessionId, errCause)
+	nasMsg, sorIncluded, err := BuildRegistrationAccept(amfUe, anType, pDUSessionStatus, reactivationResult,
+		errPduSessionId, errCause)
 	if err != nil {
 		additionalCause = nasMetrics.NAS_MSG_BUILD_ERR
 		amfUe.GmmLog.Error(err.Error())
@@ -590,6 +646,11 @@ func SendRegistrationAccept(
 	}
 
 	isNasMsgSent = true
+	if sorIncluded {
+		// the Steering of Roaming information is in the Registration Accept
+		amfUe.RemovePendingDLPayloads(nasMessage.PayloadContainerTypeSOR)
+		amfUe.SorInfoSent()
+	}
 	if anType == models.AccessType_NON_3_GPP_ACCESS {
 		// TS 23.502 4.12.2.2 10a ~ 13: if non-3gpp, AMF should send initial context setup request to N3IWF first,
 		// and send registration accept after receiving initial context setup response
@@ -597,7 +658,8 @@ func SendRegistrationAccept(
 		ngap_message.SendInitialContextSetupRequest(amfUe, anType, nil, cxtList, nil, nil, nil)
 	} else {
 		// anType is 3GPP_ACCESS
-		ngap_message.SendN2Message(amfUe, anType, nasMsg, cxtList, nil, nil, nil, nil)
+		ngap_message.SendN2Message(amfUe, anType, nasMsg, cxtList, nil,
+			ngap_message.BuildCoreNetworkAssistanceInformation(amfUe), nil, nil)
 	}
 
 	if context.GetSelf().T3550Cfg.Enable {
@@ -655,6 +717,99 @@ func SendStatus5GMM(ue *context.RanUe, cause uint8) {
 		return
 	}
 	ngap_message.SendDownlinkNasTransport(ue, nasMsg, nil)
+	isNasMsgSent = true
+	business_metrics.IncrGmmStatusCounter(business_metrics.GMM_STATUS_SENT_VALUE, cause)
+}
+
+// SendNetworkSliceSpecificAuthenticationCommand relays an EAP message of the ongoing NSSAA to the UE, it is
+// retransmitted on the expiry of T3575 (TS 24.501 5.4.7.2.1)
+func SendNetworkSliceSpecificAuthenticationCommand(ue *context.RanUe, snssai models.Snssai, eapMessage []byte) {
+	isNasMsgSent := false
+	additionalCause := ""
+	defer nasMetrics.IncrMetricsSentNasMsgs(networkSliceSpecificAuthenticationCommand, &isNasMsgSent, 0,
+		&additionalCause)
+
+	if ue == nil {
+		additionalCause = nasMetrics.RAN_UE_NIL_ERR
+		logger.GmmLog.Error("SendNetworkSliceSpecificAuthenticationCommand: RanUe is nil")
+		return
+	}
+	if ue.AmfUe == nil {
+		additionalCause = nasMetrics.AMF_UE_NIL_ERR
+		logger.GmmLog.Error("SendNetworkSliceSpecificAuthenticationCommand: AmfUe is nil")
+		return
+	}
+	amfUe := ue.AmfUe
+	if ue.Ran == nil {
+		additionalCause = nasMetrics.RAN_NIL_ERR
+		logger.GmmLog.Error("SendNetworkSliceSpecificAuthenticationCommand: Ran is nil")
+		return
+	}
+	amfUe.GmmLog.Infof("Send Network Slice-Specific Authentication Command for S-NSSAI[%+v]", snssai)
+
+	nasMsg, err := BuildNetworkSliceSpecificAuthenticationCommand(amfUe, ue.Ran.AnType, snssai, eapMessage)
+	if err != nil {
+		additionalCause = nasMetrics.NAS_MSG_BUILD_ERR
+		amfUe.GmmLog.Error(err.Error())
+		return
+	}
+	isNasMsgSent = true
+	ngap_message.SendDownlinkNasTransport(ue, nasMsg, nil)
+
+	amfUe.StopT3575()
+	if context.GetSelf().T3575Cfg.Enable {
+		cfg := context.GetSelf().T3575Cfg
+		amfUe.GmmLog.Infof("Start T3575 timer")
+		amfUe.T3575 = context.NewTimer(cfg.ExpireTime, cfg.MaxRetryTimes, func(expireTimes int32) {
+			amfUe.GmmLog.Warnf("T3575 expires, retransmit Network Slice-Specific Authentication Command (retry: %d)",
+				expireTimes)
+			timerAdditionalCause := "Timer expired, retransmit network slice-specific authentication command"
+			defer nasMetrics.IncrMetricsSentNasMsgs(
+				networkSliceSpecificAuthenticationCommandTimer, &isNasMsgSent, 0, &timerAdditionalCause)
+			ngap_message.SendDownlinkNasTransport(ue, nasMsg, nil)
+		}, func() {
+			// the S-NSSAI stays pending, its NSSAA is performed again after the next registration
+			amfUe.GmmLog.Warnf("T3575 Expires %d times, abort network slice-specific authentication procedure",
+				cfg.MaxRetryTimes)
+			amfUe.T3575 = nil // clear the timer
+			amfUe.NssaaOngoing = nil
+		})
+	}
+}
+
+// SendNetworkSliceSpecificAuthenticationResult relays the EAP message completing the NSSAA to the UE
+func SendNetworkSliceSpecificAuthenticationResult(ue *context.RanUe, snssai models.Snssai, eapMessage []byte) {
+	isNasMsgSent := false
+	additionalCause := ""
+	defer nasMetrics.IncrMetricsSentNasMsgs(networkSliceSpecificAuthenticationResult, &isNasMsgSent, 0,
+		&additionalCause)
+
+	if ue == nil {
+		additionalCause = nasMetrics.RAN_UE_NIL_ERR
+		logger.GmmLog.Error("SendNetworkSliceSpecificAuthenticationResult: RanUe is nil")
+		return
+	}
+	if ue.AmfUe == nil {
+		additionalCause = nasMetrics.AMF_UE_NIL_ERR
+		logger.GmmLog.Error("SendNetworkSliceSpecificAuthenticationResult: AmfUe is nil")
+		return
+	}
+	amfUe := ue.AmfUe
+	if ue.Ran == nil {
+		additionalCause = nasMetrics.RAN_NIL_ERR
+		logger.GmmLog.Error("SendNetworkSliceSpecificAuthenticationResult: Ran is nil")
+		return
+	}
+	amfUe.GmmLog.Infof("Send Network Slice-Specific Authentication Result for S-NSSAI[%+v]", snssai)
+
+	nasMsg, err := BuildNetworkSliceSpecificAuthenticationResult(amfUe, ue.Ran.AnType, snssai, eapMessage)
+	if err != nil {
+		additionalCause = nasMetrics.NAS_MSG_BUILD_ERR
+		amfUe.GmmLog.Error(err.Error())
+		return
+	}
+	isNasMsgSent = true
+	ngap_message.SendDownlinkNasTransport(ue, nasMsg, nil)
 }
 
 func getErrCauseSingleStr(errCause []uint8) string {
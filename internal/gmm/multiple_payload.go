@@ -0,0 +1,70 @@
+package gmm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/free5gc/amf/internal/context"
+	"github.com/free5gc/nas/nasMessage"
+	"github.com/free5gc/nas/nasType"
+	"github.com/free5gc/openapi/models"
+)
+
+// TS 24.501 5.4.5.2.3 case f), each payload of the multiple payloads is handled as if it had been sent in a UL NAS
+// Transport of its own. The errors of the payloads are aggregated, a payload in error does not prevent the
+// handling of the other payloads.
+func transportMultiplePayload(ue *context.AmfUe, anType models.AccessType,
+	ulNasTransport *nasMessage.ULNASTransport,
+) error {
+	entries, err := context.DecodeMultiplePayload(ulNasTransport.PayloadContainer.GetPayloadContainerContents())
+	if err != nil {
+		return err
+	}
+	ue.GmmLog.Infof("Transport multiple payloads (%d entries)", len(entries))
+
+	var errs []error
+	for i, entry := range entries {
+		if err = transportPayload(ue, anType, payloadContainerEntryToULNASTransport(entry)); err != nil {
+			errs = append(errs, fmt.Errorf("payload container entry[%d]: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// payloadContainerEntryToULNASTransport returns the UL NAS Transport which would have carried the payload alone
+func payloadContainerEntryToULNASTransport(entry *context.PayloadContainerEntry) *nasMessage.ULNASTransport {
+	ulNasTransport := nasMessage.NewULNASTransport(0)
+	ulNasTransport.SetPayloadContainerType(entry.Type)
+	ulNasTransport.PayloadContainer.SetLen(uint16(len(entry.Contents)))
+	ulNasTransport.PayloadContainer.SetPayloadContainerContents(entry.Contents)
+
+	if entry.PduSessionId != nil {
+		ulNasTransport.PduSessionID2Value = nasType.NewPduSessionID2Value(nasMessage.ULNASTransportPduSessionID2ValueType)
+		ulNasTransport.PduSessionID2Value.SetPduSessionID2Value(*entry.PduSessionId)
+	}
+	if entry.OldPduSessionId != nil {
+		ulNasTransport.OldPDUSessionID = nasType.NewOldPDUSessionID(nasMessage.ULNASTransportOldPDUSessionIDType)
+		ulNasTransport.OldPDUSessionID.SetOldPDUSessionID(*entry.OldPduSessionId)
+	}
+	if entry.RequestType != nil {
+		ulNasTransport.RequestType = nasType.NewRequestType(nasMessage.ULNASTransportRequestTypeType)
+		ulNasTransport.RequestType.SetRequestTypeValue(*entry.RequestType)
+	}
+	if len(entry.SNssai) > 0 && len(entry.SNssai) <= len(nasType.SNSSAI{}.Octet) {
+		ulNasTransport.SNSSAI = nasType.NewSNSSAI(nasMessage.ULNASTransportSNSSAIType)
+		ulNasTransport.SNSSAI.SetLen(uint8(len(entry.SNssai)))
+		copy(ulNasTransport.SNSSAI.Octet[:], entry.SNssai)
+	}
+	if len(entry.Dnn) > 0 {
+		ulNasTransport.DNN = nasType.NewDNN(nasMessage.ULNASTransportDNNType)
+		ulNasTransport.DNN.SetLen(uint8(len(entry.Dnn)))
+		copy(ulNasTransport.DNN.Buffer, entry.Dnn)
+	}
+	if len(entry.AdditionalInformation) > 0 {
+		ulNasTransport.AdditionalInformation = nasType.NewAdditionalInformation(
+			nasMessage.ULNASTransportAdditionalInformationType)
+		ulNasTransport.AdditionalInformation.SetLen(uint8(len(entry.AdditionalInformation)))
+		ulNasTransport.AdditionalInformation.SetAdditionalInformationValue(entry.AdditionalInformation)
+	}
+	return ulNasTransport
+}
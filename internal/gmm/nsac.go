@@ -0,0 +1,128 @@
+package gmm
+
+import (
+	"fmt"
+
+	"github.com/free5gc/amf/internal/context"
+	gmm_common "github.com/free5gc/amf/internal/gmm/common"
+	gmm_message "github.com/free5gc/amf/internal/gmm/message"
+	"github.com/free5gc/amf/internal/sbi/consumer"
+	"github.com/free5gc/openapi"
+	"github.com/free5gc/openapi/models"
+)
+
+// admitAllowedNssai performs the Network Slice Admission Control of the allowed S-NSSAIs, the S-NSSAIs whose
+// maximum number of UEs is reached are rejected (TS 23.501 5.15.11.1, TS 23.502 4.2.11.2). The UE is rejected
+// if no S-NSSAI remains allowed. The S-NSSAIs subject to NSSAA are admitted once their NSSAA succeeds
+// (TS 23.502 4.2.11.2 step 2).
+func admitAllowedNssai(ue *context.AmfUe, anType models.AccessType) error {
+	ue.NsacRejectedNssai = nil
+	nsacf := context.GetSelf().Nsacf
+	// the network slices of an emergency registered UE are not subject to NSAC
+	if nsacf == nil || ue.EmergencyRegistered {
+		return nil
+	}
+
+	var releasedNssai []models.Snssai
+	for _, snssai := range ue.NsacAdmittedNssai {
+		if !ue.InAllowedNssai(snssai, models.AccessType__3_GPP_ACCESS) &&
+			!ue.InAllowedNssai(snssai, models.AccessType_NON_3_GPP_ACCESS) {
+			releasedNssai = append(releasedNssai, snssai)
+		}
+	}
+	gmm_common.ReleaseNsacAdmission(ue, releasedNssai)
+
+	var snssaiList []models.Snssai
+	for _, allowedSnssai := range ue.AllowedNssai[anType] {
+		snssai := *allowedSnssai.AllowedSnssai
+		if nsacf.SubjectToNsac(snssai) && !awaitingNssaa(ue, snssai) {
+			snssaiList = append(snssaiList, snssai)
+		}
+	}
+	if len(snssaiList) == 0 {
+		return nil
+	}
+
+	rejectedNssai, err := consumer.GetConsumer().NumOfUEsUpdate(ue, snssaiList, consumer.NsacUpdateFlagIncrease)
+	if err != nil {
+		ue.GmmLog.Errorf("NSAC Number of UEs Update Error[%+v]", err)
+		return nil
+	}
+	for _, snssai := range snssaiList {
+		if containsSnssai(rejectedNssai, snssai) {
+			ue.GmmLog.Warnf("Maximum number of UEs reached for S-NSSAI[%+v]", snssai)
+			ue.RemoveAllowedSnssai(snssai, anType)
+			ue.NsacRejectedNssai = append(ue.NsacRejectedNssai, snssai)
+		} else {
+			ue.AddNsacAdmittedSnssai(snssai)
+		}
+	}
+
+	if len(ue.AllowedNssai[anType]) == 0 && len(ue.NsacRejectedNssai) > 0 {
+		gmm_message.SendRegistrationReject(ue.RanUe[anType], cause5GMMNoNetworkSlicesAvailable, "")
+		return fmt.Errorf("maximum number of UEs reached for all the S-NSSAIs of the UE")
+	}
+	return nil
+}
+
+// admitAuthenticatedSnssai performs the Network Slice Admission Control of an S-NSSAI whose NSSAA succeeded, the
+// S-NSSAI is rejected if its maximum number of UEs is reached
+func admitAuthenticatedSnssai(ue *context.AmfUe, snssai models.Snssai) bool {
+	nsacf := context.GetSelf().Nsacf
+	if nsacf == nil || ue.EmergencyRegistered || !nsacf.SubjectToNsac(snssai) {
+		return true
+	}
+
+	rejectedNssai, err := consumer.GetConsumer().NumOfUEsUpdate(ue, []models.Snssai{snssai},
+		consumer.NsacUpdateFlagIncrease)
+	if err != nil {
+		ue.GmmLog.Errorf("NSAC Number of UEs Update Error[%+v]", err)
+		return true
+	}
+	if containsSnssai(rejectedNssai, snssai) {
+		ue.GmmLog.Warnf("Maximum number of UEs reached for S-NSSAI[%+v]", snssai)
+		if !containsSnssai(ue.NsacRejectedNssai, snssai) {
+			ue.NsacRejectedNssai = append(ue.NsacRejectedNssai, snssai)
+		}
+		return false
+	}
+	ue.AddNsacAdmittedSnssai(snssai)
+	return true
+}
+
+// awaitingNssaa tells whether the S-NSSAI is subject to NSSAA and has not been authenticated successfully yet, it is
+// left out of the allowed NSSAI by selectPendingNssai
+func awaitingNssaa(ue *context.AmfUe, snssai models.Snssai) bool {
+	if ue.EmergencyRegistered || !ue.NssaaRequired(snssai) {
+		return false
+	}
+	status, ok := ue.NssaaStatus(snssai)
+	return !ok || status != models.AuthStatus_EAP_SUCCESS
+}
+
+// releaseNsacAdmissionAtDeregistration counts the UE out of the S-NSSAIs it is no longer registered to once it is
+// deregistered over an access
+func releaseNsacAdmissionAtDeregistration(ue *context.AmfUe, anType models.AccessType) {
+	otherAnType := models.AccessType_NON_3_GPP_ACCESS
+	if anType == models.AccessType_NON_3_GPP_ACCESS {
+		otherAnType = models.AccessType__3_GPP_ACCESS
+	}
+	otherAnRegistered := ue.State[otherAnType] != nil && ue.State[otherAnType].Is(context.Registered)
+
+	var releasedNssai []models.Snssai
+	for _, snssai := range ue.NsacAdmittedNssai {
+		if !otherAnRegistered || !ue.InAllowedNssai(snssai, otherAnType) {
+			releasedNssai = append(releasedNssai, snssai)
+		}
+	}
+	gmm_common.ReleaseNsacAdmission(ue, releasedNssai)
+}
+
+func containsSnssai(snssaiList []models.Snssai, snssai models.Snssai) bool {
+	for _, s := range snssaiList {
+		if openapi.SnssaiEqualFold(s, snssai) {
+			return true
+		}
+	}
+	return false
+}
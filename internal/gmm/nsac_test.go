@@ -0,0 +1,42 @@
+package gmm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/free5gc/amf/internal/context"
+	"github.com/free5gc/amf/pkg/factory"
+	"github.com/free5gc/openapi/models"
+)
+
+func TestNsacOfNetworkSliceSubjectToNssaa(t *testing.T) {
+	snssai := models.Snssai{Sst: 1, Sd: "010203"}
+	nssaaSnssai := models.Snssai{Sst: 1, Sd: "040506"}
+	amfSelf := context.GetSelf()
+	nsacf := amfSelf.Nsacf
+	defer func() {
+		amfSelf.Nsacf = nsacf
+	}()
+	amfSelf.Nsacf = context.NewLocalNsacf([]factory.NsacQuota{
+		{Snssai: &snssai, MaxNumOfUes: 1},
+		{Snssai: &nssaaSnssai, MaxNumOfUes: 1},
+	})
+
+	anType := models.AccessType__3_GPP_ACCESS
+	ue, _ := newConnectedTestUe(t, "imsi-208930000000017")
+	ue.NssaaRequiredNssai = []models.Snssai{nssaaSnssai}
+	ue.AllowedNssai[anType] = []models.AllowedSnssai{{AllowedSnssai: &snssai}, {AllowedSnssai: &nssaaSnssai}}
+
+	// the S-NSSAI subject to NSSAA is not admitted before its NSSAA succeeds
+	require.NoError(t, admitAllowedNssai(ue, anType))
+	require.Equal(t, []models.Snssai{snssai}, ue.NsacAdmittedNssai)
+	require.Empty(t, ue.NsacRejectedNssai)
+
+	// another UE takes the last place of the S-NSSAI, the S-NSSAI is rejected once its NSSAA succeeds
+	other, _ := newConnectedTestUe(t, "imsi-208930000000018")
+	require.True(t, admitAuthenticatedSnssai(other, nssaaSnssai))
+	require.False(t, admitAuthenticatedSnssai(ue, nssaaSnssai))
+	require.Equal(t, []models.Snssai{snssai}, ue.NsacAdmittedNssai)
+	require.Equal(t, []models.Snssai{nssaaSnssai}, ue.NsacRejectedNssai)
+}
@@ -0,0 +1,323 @@
+package gmm
+
+import (
+	"fmt"
+
+	"github.com/free5gc/amf/internal/context"
+	gmm_common "github.com/free5gc/amf/internal/gmm/common"
+	gmm_message "github.com/free5gc/amf/internal/gmm/message"
+	"github.com/free5gc/amf/internal/nas/nas_codec"
+	"github.com/free5gc/amf/internal/sbi/consumer"
+	"github.com/free5gc/amf/internal/util"
+	"github.com/free5gc/nas/nasMessage"
+	"github.com/free5gc/openapi"
+	"github.com/free5gc/openapi/models"
+	Nnrf_NFDiscovery "github.com/free5gc/openapi/nrf/NFDiscovery"
+)
+
+// cause5GMMNoNetworkSlicesAvailable is the 5GMM cause #62 of TS 24.501 9.11.3.2, not defined by the NAS library
+const cause5GMMNoNetworkSlicesAvailable uint8 = 0x3e
+
+// eapIdentityRequest starts the NSSAA of an S-NSSAI, the AMF asks the UE for its EAP identity
+// (TS 23.502 4.2.9.2 step 2, RFC 3748 5.1)
+var eapIdentityRequest = []byte{0x01, 0x01, 0x00, 0x05, 0x01}
+
+// selectPendingNssai takes the allowed S-NSSAIs subject to Network Slice-Specific Authentication and Authorization
+// out of the allowed NSSAI, they are pending until their NSSAA succeeds or rejected if it failed
+// (TS 23.501 5.15.10, TS 23.502 4.2.2.2.2 step 21)
+func selectPendingNssai(ue *context.AmfUe, anType models.AccessType) {
+	ue.PendingNssai = nil
+	// the network slices of an emergency registered UE are not subject to NSSAA
+	if ue.EmergencyRegistered {
+		return
+	}
+
+	allowedNssai := append([]models.AllowedSnssai(nil), ue.AllowedNssai[anType]...)
+	for _, allowedSnssai := range allowedNssai {
+		snssai := *allowedSnssai.AllowedSnssai
+		if !ue.NssaaRequired(snssai) {
+			continue
+		}
+		status, ok := ue.NssaaStatus(snssai)
+		switch {
+		case ok && status == models.AuthStatus_EAP_SUCCESS:
+			continue
+		case ok && status == models.AuthStatus_EAP_FAILURE:
+			ue.AddNssaaRejectedSnssai(snssai)
+		default:
+			ue.NssaaContextOf(snssai)
+			ue.PendingNssai = append(ue.PendingNssai, snssai)
+		}
+		ue.RemoveAllowedSnssai(snssai, anType)
+	}
+	if len(ue.PendingNssai) > 0 {
+		ue.GmmLog.Infof("Pending NSSAI: %+v", ue.PendingNssai)
+	}
+}
+
+// selectNssaaf discovers an NSSAAF through the NRF
+func selectNssaaf(ue *context.AmfUe) bool {
+	amfSelf := context.GetSelf()
+	param := Nnrf_NFDiscovery.SearchNFInstancesRequest{
+		Supi: &ue.Supi,
+	}
+	if amfSelf.Locality != "" {
+		param.PreferredLocality = &amfSelf.Locality
+	}
+
+	resp, err := consumer.GetConsumer().SendSearchNFInstances(
+		amfSelf.NrfUri, models.NrfNfManagementNfType_NSSAAF, models.NrfNfManagementNfType_AMF, &param)
+	if err != nil {
+		ue.GmmLog.Errorf("AMF can not select an NSSAAF by NRF: %+v", err)
+		return false
+	}
+	for index := range resp.NfInstances {
+		nssaafUri := util.SearchNFServiceUri(&resp.NfInstances[index], models.ServiceName_NNSSAAF_NSSAA,
+			models.NfServiceStatus_REGISTERED)
+		if nssaafUri != "" {
+			ue.NssaafId = resp.NfInstances[index].NfInstanceId
+			ue.NssaafUri = nssaafUri
+			return true
+		}
+	}
+	ue.GmmLog.Error("AMF can not select an NSSAAF by NRF")
+	return false
+}
+
+// nextNssaaSnssai returns the next S-NSSAI to authenticate, a pending one or an allowed one whose
+// re-authentication was requested
+func nextNssaaSnssai(ue *context.AmfUe, anType models.AccessType) (models.Snssai, bool) {
+	if len(ue.PendingNssai) > 0 {
+		return ue.PendingNssai[0], true
+	}
+	for _, allowedSnssai := range ue.AllowedNssai[anType] {
+		if status, ok := ue.NssaaStatus(*allowedSnssai.AllowedSnssai); ok && status == models.AuthStatus_PENDING {
+			return *allowedSnssai.AllowedSnssai, true
+		}
+	}
+	return models.Snssai{}, false
+}
+
+// StartNetworkSliceSpecificAuthentication runs the NSSAA of the next S-NSSAI waiting for it, one S-NSSAI is
+// authenticated at a time (TS 23.502 4.2.9.2)
+func StartNetworkSliceSpecificAuthentication(ue *context.AmfUe, anType models.AccessType) {
+	if ue.NssaaOngoing != nil || !ue.CmConnect(anType) {
+		return
+	}
+	snssai, ok := nextNssaaSnssai(ue, anType)
+	if !ok {
+		return
+	}
+	if ue.NssaafUri == "" && !selectNssaaf(ue) {
+		return
+	}
+
+	nssaaCtx := ue.NssaaContextOf(snssai)
+	nssaaCtx.AuthCtxId = ""
+	nssaaCtx.Status = models.AuthStatus_PENDING
+	ue.NssaaOngoing = nssaaCtx
+	gmm_message.SendNetworkSliceSpecificAuthenticationCommand(ue.RanUe[anType], snssai, eapIdentityRequest)
+}
+
+// TS 24.501 5.4.7.2.2, the EAP message of the UE is relayed to the NSSAAF
+func HandleNetworkSliceSpecificAuthenticationComplete(ue *context.AmfUe, anType models.AccessType,
+	complete *nas_codec.NssaaMessage,
+) error {
+	ue.GmmLog.Info("Handle Network Slice-Specific Authentication Complete")
+
+	if complete == nil {
+		return fmt.Errorf("network slice-specific authentication complete is not decoded")
+	}
+	nssaaCtx := ue.NssaaOngoing
+	if nssaaCtx == nil || !openapi.SnssaiEqualFold(nssaaCtx.Snssai, complete.Snssai) {
+		return fmt.Errorf("unexpected network slice-specific authentication complete for S-NSSAI[%+v]",
+			complete.Snssai)
+	}
+	ue.StopT3575()
+
+	var eapMessage []byte
+	authResult := models.AuthStatus_PENDING
+	if nssaaCtx.AuthCtxId == "" {
+		sliceAuthContext, problemDetails, err := consumer.GetConsumer().NSSAAAuthenticate(ue, nssaaCtx.Snssai,
+			complete.EapMessage)
+		if problemDetails != nil || err != nil {
+			failNetworkSliceSpecificAuthentication(ue, anType, nssaaCtx, complete.EapMessage)
+			return fmt.Errorf("NSSAA Authenticate failed, Problem[%+v] Error[%+v]", problemDetails, err)
+		}
+		nssaaCtx.AuthCtxId = sliceAuthContext.AuthCtxId
+		eapMessage = sliceAuthContext.EapMessage
+	} else {
+		confirmationResponse, problemDetails, err := consumer.GetConsumer().NSSAAConfirm(ue, nssaaCtx.AuthCtxId,
+			nssaaCtx.Snssai, complete.EapMessage)
+		if problemDetails != nil || err != nil {
+			failNetworkSliceSpecificAuthentication(ue, anType, nssaaCtx, complete.EapMessage)
+			return fmt.Errorf("NSSAA Confirm failed, Problem[%+v] Error[%+v]", problemDetails, err)
+		}
+		eapMessage = confirmationResponse.EapMessage
+		if confirmationResponse.AuthResult != "" {
+			authResult = confirmationResponse.AuthResult
+		}
+	}
+
+	if authResult == models.AuthStatus_PENDING {
+		gmm_message.SendNetworkSliceSpecificAuthenticationCommand(ue.RanUe[anType], nssaaCtx.Snssai, eapMessage)
+		return nil
+	}
+
+	ue.NssaaOngoing = nil
+	gmm_message.SendNetworkSliceSpecificAuthenticationResult(ue.RanUe[anType], nssaaCtx.Snssai, eapMessage)
+	completeNetworkSliceSpecificAuthentication(ue, anType, nssaaCtx, authResult)
+	StartNetworkSliceSpecificAuthentication(ue, anType)
+	return nil
+}
+
+// failNetworkSliceSpecificAuthentication ends the NSSAA the NSSAAF could not carry on with an EAP-Failure, the
+// S-NSSAI is rejected and the NSSAA of the next S-NSSAI is started (TS 24.501 5.4.7.2.3)
+func failNetworkSliceSpecificAuthentication(ue *context.AmfUe, anType models.AccessType,
+	nssaaCtx *context.NssaaContext, eapResponse []byte,
+) {
+	ue.NssaaOngoing = nil
+	// the EAP-Failure has the identifier of the last EAP response of the UE (RFC 3748 4.2)
+	eapFailure := []byte{0x04, 0x00, 0x00, 0x04}
+	if len(eapResponse) > 1 {
+		eapFailure[1] = eapResponse[1]
+	}
+	gmm_message.SendNetworkSliceSpecificAuthenticationResult(ue.RanUe[anType], nssaaCtx.Snssai, eapFailure)
+	completeNetworkSliceSpecificAuthentication(ue, anType, nssaaCtx, models.AuthStatus_EAP_FAILURE)
+	StartNetworkSliceSpecificAuthentication(ue, anType)
+}
+
+// completeNetworkSliceSpecificAuthentication updates the NSSAI of the UE with the result of the NSSAA of an
+// S-NSSAI (TS 23.502 4.2.9.2 step 21)
+func completeNetworkSliceSpecificAuthentication(ue *context.AmfUe, anType models.AccessType,
+	nssaaCtx *context.NssaaContext, authResult models.AuthStatus,
+) {
+	ue.GmmLog.Infof("NSSAA of S-NSSAI[%+v] completed: %s", nssaaCtx.Snssai, authResult)
+	nssaaCtx.Status = authResult
+
+	snssai := nssaaCtx.Snssai
+	wasPending := ue.InPendingNssai(snssai)
+	ue.RemovePendingSnssai(snssai)
+	if authResult == models.AuthStatus_EAP_SUCCESS {
+		ue.RemoveNssaaRejectedSnssai(snssai)
+		if !wasPending {
+			// a successful re-authentication does not change the NSSAI
+			return
+		}
+		if !ue.InAllowedNssai(snssai, anType) && admitAuthenticatedSnssai(ue, snssai) {
+			ue.AllowedNssai[anType] = append(ue.AllowedNssai[anType], models.AllowedSnssai{
+				AllowedSnssai: &models.Snssai{
+					Sst: snssai.Sst,
+					Sd:  snssai.Sd,
+				},
+			})
+		}
+	} else {
+		rejectNssaaSnssai(ue, anType, snssai)
+	}
+	updateNssaiAfterNssaa(ue, anType)
+}
+
+// rejectNssaaSnssai rejects the S-NSSAI whose NSSAA failed or was revoked, and releases its PDU sessions
+// (TS 23.502 4.2.9.4 step 3)
+func rejectNssaaSnssai(ue *context.AmfUe, anType models.AccessType, snssai models.Snssai) {
+	ue.RemovePendingSnssai(snssai)
+	ue.RemoveAllowedSnssai(snssai, anType)
+	ue.AddNssaaRejectedSnssai(snssai)
+	gmm_common.ReleaseNsacAdmission(ue, []models.Snssai{snssai})
+
+	cause := models.SmfPduSessionCause_REL_DUE_TO_SLICE_NOT_AVAILABLE
+	ue.SmContextList.Range(func(key, value interface{}) bool {
+		smContext := value.(*context.SmContext)
+		if smContext.AccessType() != anType || !openapi.SnssaiEqualFold(smContext.Snssai(), snssai) {
+			return true
+		}
+		problemDetail, err := consumer.GetConsumer().SendReleaseSmContextRequest(ue, smContext,
+			&context.CauseAll{Cause: &cause}, "", nil)
+		if problemDetail != nil {
+			ue.GmmLog.Errorf("Release SmContext[pduSessionId: %d] Failed Problem[%+v]",
+				smContext.PduSessionID(), problemDetail)
+		} else if err != nil {
+			ue.GmmLog.Errorf("Release SmContext[pduSessionId: %d] Error[%v]", smContext.PduSessionID(), err)
+		}
+		return true
+	})
+}
+
+// updateNssaiAfterNssaa indicates the new allowed and rejected NSSAI to the UE with a Configuration Update
+// Command, the UE is deregistered if no network slice remains available
+func updateNssaiAfterNssaa(ue *context.AmfUe, anType models.AccessType) {
+	if !ue.CmConnect(anType) {
+		ue.GmmLog.Info("The NSSAI is updated in the next registration of the UE in CM-IDLE")
+		return
+	}
+	if len(ue.AllowedNssai[anType]) == 0 && len(ue.PendingNssai) == 0 {
+		ue.GmmLog.Warn("No network slice is available after NSSAA, deregister the UE")
+		ue.DeregistrationTargetAccessType = nasMessage.AccessType3GPP
+		if anType == models.AccessType_NON_3_GPP_ACCESS {
+			ue.DeregistrationTargetAccessType = nasMessage.AccessTypeNon3GPP
+		}
+		gmm_message.SendDeregistrationRequest(ue.RanUe[anType], ue.DeregistrationTargetAccessType, false,
+			cause5GMMNoNetworkSlicesAvailable)
+		releaseDeregisteredUeResources(ue, anType, ue.DeregistrationTargetAccessType)
+		return
+	}
+	RequestConfigurationUpdate(ue, anType, &context.ConfigurationUpdateCommandFlags{
+		NeedAllowedNSSAI: true,
+		NeedRejectNSSAI:  true,
+	})
+}
+
+// registeredAccessType returns the access over which the UE is registered, 3GPP access is preferred
+func registeredAccessType(ue *context.AmfUe) (models.AccessType, bool) {
+	for _, anType := range []models.AccessType{models.AccessType__3_GPP_ACCESS, models.AccessType_NON_3_GPP_ACCESS} {
+		if ue.State[anType].Is(context.Registered) {
+			return anType, true
+		}
+	}
+	return "", false
+}
+
+// ReauthenticateSnssai runs the NSSAA of the S-NSSAI again on the request of the AAA server, the S-NSSAI stays
+// allowed during the re-authentication (TS 23.502 4.2.9.3)
+func ReauthenticateSnssai(ue *context.AmfUe, snssai models.Snssai) error {
+	anType, ok := registeredAccessType(ue)
+	if !ok {
+		return fmt.Errorf("UE is not registered")
+	}
+	if !ue.InAllowedNssai(snssai, anType) && !ue.InPendingNssai(snssai) {
+		return fmt.Errorf("S-NSSAI[%+v] is neither allowed nor pending", snssai)
+	}
+
+	nssaaCtx := ue.NssaaContextOf(snssai)
+	if ue.NssaaOngoing == nssaaCtx {
+		return nil
+	}
+	nssaaCtx.Status = models.AuthStatus_PENDING
+	// the re-authentication of a UE in CM-IDLE is performed once it is CM-CONNECTED again
+	StartNetworkSliceSpecificAuthentication(ue, anType)
+	return nil
+}
+
+// RevokeSnssai revokes the authorization of the S-NSSAI on the request of the AAA server (TS 23.502 4.2.9.4)
+func RevokeSnssai(ue *context.AmfUe, snssai models.Snssai) error {
+	anType, ok := registeredAccessType(ue)
+	if !ok {
+		return fmt.Errorf("UE is not registered")
+	}
+
+	nssaaCtx := ue.NssaaContextOf(snssai)
+	nssaaCtx.Status = models.AuthStatus_EAP_FAILURE
+	if ue.NssaaOngoing == nssaaCtx {
+		ue.StopT3575()
+		ue.NssaaOngoing = nil
+	}
+	if !ue.InAllowedNssai(snssai, anType) && !ue.InPendingNssai(snssai) {
+		ue.AddNssaaRejectedSnssai(snssai)
+		return nil
+	}
+	rejectNssaaSnssai(ue, anType, snssai)
+	updateNssaiAfterNssaa(ue, anType)
+	StartNetworkSliceSpecificAuthentication(ue, anType)
+	return nil
+}
@@ -0,0 +1,162 @@
+package gmm
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/free5gc/amf/internal/context"
+	"github.com/free5gc/amf/internal/nas/nas_codec"
+	"github.com/free5gc/amf/internal/sbi/consumer"
+	"github.com/free5gc/openapi/models"
+)
+
+func TestDeregistrationWithoutNetworkSliceAfterNssaa(t *testing.T) {
+	requests := make(chan string, 3)
+	nf := newH2CServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r.Method + " " + r.URL.Path
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer nf.Close()
+
+	ue, conn := newConnectedTestUe(t, "imsi-208930000000011")
+	anType := models.AccessType__3_GPP_ACCESS
+	smContext := context.NewSmContext(10)
+	smContext.SetSmContextRef("sm-context-10")
+	smContext.SetAccessType(anType)
+	smContext.SetSmfUri(nf.URL)
+	ue.StoreSmContext(10, smContext)
+	ue.PcfUri = nf.URL
+	ue.PolicyAssociationId = "policy-1"
+	ue.AmPolicyAssociation = new(models.PcfAmPolicyControlPolicyAssociation)
+	ue.NudmUECMUri = nf.URL
+	ue.ContextValid = true
+	ue.UeCmRegistered[anType] = true
+
+	// the last S-NSSAI failed NSSAA, the UE is deregistered and its resources are released
+	updateNssaiAfterNssaa(ue, anType)
+	require.Len(t, conn.MsgList, 1)
+	require.Len(t, requests, 3)
+	require.Equal(t, "POST /nsmf-pdusession/v1/sm-contexts/sm-context-10/release", <-requests)
+	require.Equal(t, "DELETE /npcf-am-policy-control/v1/policies/policy-1", <-requests)
+	require.Equal(t, "PATCH /nudm-uecm/v1/imsi-208930000000011/registrations/amf-3gpp-access", <-requests)
+	_, ok := ue.SmContextFindByPDUSessionID(10)
+	require.False(t, ok)
+	require.Nil(t, ue.AmPolicyAssociation)
+	require.False(t, ue.UeCmRegistered[anType])
+}
+
+func TestNetworkSliceSpecificAuthenticationComplete(t *testing.T) {
+	snssai := models.Snssai{Sst: 1, Sd: "010203"}
+	eapIdentityResponse := []byte{0x02, 0x01, 0x00, 0x0a, 0x01, 'u', 's', 'e', 'r', '1'}
+	eapRequest := []byte{0x01, 0x02, 0x00, 0x06, 0x04, 0x10}
+	eapResponse := []byte{0x02, 0x02, 0x00, 0x06, 0x04, 0x10}
+	eapSuccess := []byte{0x03, 0x02, 0x00, 0x04}
+
+	requests := make(chan string, 2)
+	nssaaf := newH2CServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r.Method + " " + r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodPost:
+			var sliceAuthInfo consumer.SliceAuthInfo
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&sliceAuthInfo))
+			require.Equal(t, eapIdentityResponse, sliceAuthInfo.EapIdRsp)
+			w.WriteHeader(http.StatusCreated)
+			require.NoError(t, json.NewEncoder(w).Encode(consumer.SliceAuthContext{
+				Snssai:     snssai,
+				AuthCtxId:  "auth-ctx-1",
+				EapMessage: eapRequest,
+			}))
+		case http.MethodPut:
+			var confirmationData consumer.SliceAuthConfirmationData
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&confirmationData))
+			require.Equal(t, eapResponse, confirmationData.EapMessage)
+			require.NoError(t, json.NewEncoder(w).Encode(consumer.SliceAuthConfirmationResponse{
+				Snssai:     snssai,
+				EapMessage: eapSuccess,
+				AuthResult: models.AuthStatus_EAP_SUCCESS,
+			}))
+		}
+	}))
+	defer nssaaf.Close()
+
+	anType := models.AccessType__3_GPP_ACCESS
+	ue, conn := newConnectedTestUe(t, "imsi-208930000000015")
+	ue.PlmnId = models.PlmnId{Mcc: "208", Mnc: "93"}
+	ue.NssaafUri = nssaaf.URL
+	ue.PendingNssai = []models.Snssai{snssai}
+	nssaaCtx := ue.NssaaContextOf(snssai)
+	nssaaCtx.Status = models.AuthStatus_PENDING
+	ue.NssaaOngoing = nssaaCtx
+
+	// the message could not be decoded, or is not for the S-NSSAI being authenticated
+	require.Error(t, HandleNetworkSliceSpecificAuthenticationComplete(ue, anType, nil))
+	require.Error(t, HandleNetworkSliceSpecificAuthenticationComplete(ue, anType, &nas_codec.NssaaMessage{
+		Snssai:     models.Snssai{Sst: 2},
+		EapMessage: eapIdentityResponse,
+	}))
+	require.Equal(t, nssaaCtx, ue.NssaaOngoing)
+	require.Empty(t, conn.MsgList)
+
+	// the EAP identity response starts the NSSAA, the EAP request of the AAA server is relayed to the UE
+	require.NoError(t, HandleNetworkSliceSpecificAuthenticationComplete(ue, anType, &nas_codec.NssaaMessage{
+		Snssai:     snssai,
+		EapMessage: eapIdentityResponse,
+	}))
+	require.Equal(t, "POST /nnssaaf-nssaa/v1/slice-authentications", <-requests)
+	require.Equal(t, "auth-ctx-1", nssaaCtx.AuthCtxId)
+	require.Equal(t, nssaaCtx, ue.NssaaOngoing)
+	require.Len(t, conn.MsgList, 1)
+
+	// the EAP success completes the NSSAA, the S-NSSAI is allowed
+	require.NoError(t, HandleNetworkSliceSpecificAuthenticationComplete(ue, anType, &nas_codec.NssaaMessage{
+		Snssai:     snssai,
+		EapMessage: eapResponse,
+	}))
+	require.Equal(t, "PUT /nnssaaf-nssaa/v1/slice-authentications/auth-ctx-1", <-requests)
+	require.Nil(t, ue.NssaaOngoing)
+	require.Empty(t, ue.PendingNssai)
+	require.True(t, ue.InAllowedNssai(snssai, anType))
+	require.Equal(t, models.AuthStatus_EAP_SUCCESS, nssaaCtx.Status)
+	// Network Slice-Specific Authentication Result and Configuration Update Command
+	require.Len(t, conn.MsgList, 3)
+}
+
+func TestNetworkSliceSpecificAuthenticationFailure(t *testing.T) {
+	snssai := models.Snssai{Sst: 1, Sd: "010203"}
+	otherSnssai := models.Snssai{Sst: 1, Sd: "040506"}
+	eapIdentityResponse := []byte{0x02, 0x07, 0x00, 0x0a, 0x01, 'u', 's', 'e', 'r', '1'}
+
+	nssaaf := newH2CServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusInternalServerError)
+		require.NoError(t, json.NewEncoder(w).Encode(models.ProblemDetails{Status: http.StatusInternalServerError}))
+	}))
+	defer nssaaf.Close()
+
+	anType := models.AccessType__3_GPP_ACCESS
+	ue, conn := newConnectedTestUe(t, "imsi-208930000000016")
+	ue.PlmnId = models.PlmnId{Mcc: "208", Mnc: "93"}
+	ue.NssaafUri = nssaaf.URL
+	ue.AllowedNssai[anType] = []models.AllowedSnssai{{AllowedSnssai: &otherSnssai}}
+	ue.PendingNssai = []models.Snssai{snssai}
+	nssaaCtx := ue.NssaaContextOf(snssai)
+	nssaaCtx.Status = models.AuthStatus_PENDING
+	ue.NssaaOngoing = nssaaCtx
+
+	// the NSSAAF fails, the NSSAA ends with an EAP failure and the S-NSSAI is rejected
+	require.Error(t, HandleNetworkSliceSpecificAuthenticationComplete(ue, anType, &nas_codec.NssaaMessage{
+		Snssai:     snssai,
+		EapMessage: eapIdentityResponse,
+	}))
+	require.Nil(t, ue.NssaaOngoing)
+	require.Empty(t, ue.PendingNssai)
+	require.False(t, ue.InAllowedNssai(snssai, anType))
+	require.True(t, ue.InAllowedNssai(otherSnssai, anType))
+	require.Equal(t, models.AuthStatus_EAP_FAILURE, nssaaCtx.Status)
+	// Network Slice-Specific Authentication Result and Configuration Update Command
+	require.Len(t, conn.MsgList, 2)
+}
@@ -0,0 +1,191 @@
+package gmm
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/free5gc/amf/internal/context"
+	"github.com/free5gc/amf/internal/logger"
+	"github.com/free5gc/amf/internal/nas/nas_security"
+	nastesting "github.com/free5gc/amf/internal/nas/testing"
+	ngaptesting "github.com/free5gc/amf/internal/ngap/testing"
+	"github.com/free5gc/amf/internal/sbi/consumer"
+	"github.com/free5gc/amf/pkg/factory"
+	"github.com/free5gc/nas"
+	"github.com/free5gc/nas/nasMessage"
+	"github.com/free5gc/nas/nasType"
+	"github.com/free5gc/nas/security"
+	"github.com/free5gc/ngap"
+	"github.com/free5gc/ngap/ngapType"
+	"github.com/free5gc/openapi/models"
+	"github.com/free5gc/util/fsm"
+)
+
+// newRegisteringTestUe returns a UE on a RAN of the AMF before its Registration Request, the UE is authenticated by
+// the local authentication without UDM and PCF
+func newRegisteringTestUe(t *testing.T, supi, k, opc string) (*context.AmfUe, *ngaptesting.SctpConnStub,
+	*nastesting.UE,
+) {
+	_, err := consumer.NewConsumer(nil)
+	require.NoError(t, err)
+
+	amfConfig := factory.AmfConfig
+	amfSelf := context.GetSelf()
+	servedGuamiList := amfSelf.ServedGuamiList
+	supportTaiLists := amfSelf.SupportTaiLists
+	plmnSupportList := amfSelf.PlmnSupportList
+	t3550Cfg := amfSelf.T3550Cfg
+	securityAlgorithm := amfSelf.SecurityAlgorithm
+	t.Cleanup(func() {
+		factory.AmfConfig = amfConfig
+		amfSelf.ServedGuamiList = servedGuamiList
+		amfSelf.SupportTaiLists = supportTaiLists
+		amfSelf.PlmnSupportList = plmnSupportList
+		amfSelf.T3550Cfg = t3550Cfg
+		amfSelf.SecurityAlgorithm = securityAlgorithm
+		amfSelf.LocalAusf = nil
+	})
+
+	factory.AmfConfig = &factory.Config{Configuration: &factory.Configuration{}}
+	tai := models.Tai{PlmnId: &models.PlmnId{Mcc: "208", Mnc: "93"}, Tac: "000001"}
+	amfSelf.ServedGuamiList = []models.Guami{
+		{PlmnId: &models.PlmnIdNid{Mcc: "208", Mnc: "93"}, AmfId: "cafe00"},
+	}
+	amfSelf.SupportTaiLists = []models.Tai{tai}
+	amfSelf.PlmnSupportList = []factory.PlmnSupportItem{
+		{PlmnId: tai.PlmnId, SNssaiList: []models.Snssai{{Sst: 1, Sd: "010203"}}},
+	}
+	amfSelf.T3550Cfg = factory.TimerValue{Enable: true, ExpireTime: 6 * time.Second, MaxRetryTimes: 4}
+	amfSelf.SecurityAlgorithm.IntegrityOrder = []uint8{security.AlgIntegrity128NIA2}
+	amfSelf.SecurityAlgorithm.CipheringOrder = []uint8{security.AlgCiphering128NEA2}
+	amfSelf.LocalAusf, err = context.NewLocalAusf(&factory.LocalAuthentication{
+		Enable:  true,
+		SkipUdm: true,
+		SkipPcf: true,
+	}, []factory.LocalSubscriber{{Supi: supi, K: k, Opc: opc, Sqn: "000000000020"}})
+	require.NoError(t, err)
+
+	conn := new(ngaptesting.SctpConnStub)
+	ran := amfSelf.NewAmfRan(conn)
+	ran.AnType = models.AccessType__3_GPP_ACCESS
+	ranUe, err := ran.NewRanUe(1)
+	require.NoError(t, err)
+	ranUe.Tai = tai
+	ue := amfSelf.NewAmfUe("")
+	ue.AttachRanUe(ranUe)
+	t.Cleanup(func() {
+		ue.Remove()
+		amfSelf.AmfRanPool.Delete(conn)
+	})
+
+	kBytes, err := hex.DecodeString(k)
+	require.NoError(t, err)
+	opcBytes, err := hex.DecodeString(opc)
+	require.NoError(t, err)
+	return ue, conn, nastesting.NewUE(supi, kBytes, opcBytes)
+}
+
+// handleUplinkNas checks and deciphers an uplink NAS message of the UE and sends it to the GMM state machine
+func handleUplinkNas(t *testing.T, ue *context.AmfUe, procedureCode int64, pdu []byte, initialMessage bool) {
+	anType := models.AccessType__3_GPP_ACCESS
+	msg, integrityProtected, err := nas_security.Decode(ue, anType, pdu, initialMessage)
+	require.NoError(t, err)
+	ue.NasPduValue = pdu
+	ue.MacFailed = !integrityProtected
+	require.NoError(t, GmmFSM.SendEvent(ue.State[anType], GmmMessageEvent, fsm.ArgsType{
+		ArgAmfUe:         ue,
+		ArgAccessType:    anType,
+		ArgNASMessage:    msg.GmmMessage,
+		ArgProcedureCode: procedureCode,
+	}, logger.GmmLog))
+}
+
+// decodeDownlinkNas checks and deciphers the NAS message of the last NGAP message sent to the RAN
+func decodeDownlinkNas(t *testing.T, conn *ngaptesting.SctpConnStub, ue *nastesting.UE) *nas.Message {
+	require.NotEmpty(t, conn.MsgList)
+	pdu, err := ngap.Decoder(conn.MsgList[len(conn.MsgList)-1])
+	require.NoError(t, err)
+	require.NotNil(t, pdu.InitiatingMessage)
+
+	var nasPdu []byte
+	switch pdu.InitiatingMessage.ProcedureCode.Value {
+	case ngapType.ProcedureCodeDownlinkNASTransport:
+		for _, ie := range pdu.InitiatingMessage.Value.DownlinkNASTransport.ProtocolIEs.List {
+			if ie.Id.Value == ngapType.ProtocolIEIDNASPDU {
+				nasPdu = ie.Value.NASPDU.Value
+			}
+		}
+	case ngapType.ProcedureCodeInitialContextSetup:
+		for _, ie := range pdu.InitiatingMessage.Value.InitialContextSetupRequest.ProtocolIEs.List {
+			if ie.Id.Value == ngapType.ProtocolIEIDNASPDU {
+				nasPdu = ie.Value.NASPDU.Value
+			}
+		}
+	}
+	require.NotNil(t, nasPdu, "no NAS-PDU in procedure code %d", pdu.InitiatingMessage.ProcedureCode.Value)
+	msg, err := ue.DecodeDownlink(nasPdu)
+	require.NoError(t, err)
+	return msg
+}
+
+func TestRegistrationFlow(t *testing.T) {
+	supi := "imsi-208930000000017"
+	amfUe, conn, ue := newRegisteringTestUe(t, supi,
+		"8baf473f2f8fd09487cccbd7097c6862", "8e27b6af0e692e750f32667a3b14605d")
+	anType := models.AccessType__3_GPP_ACCESS
+
+	// Registration Request with the SUCI of the null scheme
+	suci := []uint8{0x01, 0x02, 0xf8, 0x39, 0xf0, 0xff, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x71}
+	mobileIdentity := nasType.MobileIdentity5GS{Len: uint16(len(suci)), Buffer: suci}
+	ueSecurityCapability := nasType.NewUESecurityCapability(nasMessage.RegistrationRequestUESecurityCapabilityType)
+	ueSecurityCapability.SetLen(2)
+	ueSecurityCapability.SetEA2_128_5G(1)
+	ueSecurityCapability.SetIA2_128_5G(1)
+	capability5GMM := &nasType.Capability5GMM{
+		Iei:   nasMessage.RegistrationRequestCapability5GMMType,
+		Len:   1,
+		Octet: [13]uint8{0x07},
+	}
+	registrationRequest := nastesting.GetRegistrationRequest(nasMessage.RegistrationType5GSInitialRegistration,
+		mobileIdentity, nil, ueSecurityCapability, capability5GMM, nil, nil)
+	handleUplinkNas(t, amfUe, ngapType.ProcedureCodeInitialUEMessage, registrationRequest, true)
+	require.True(t, amfUe.State[anType].Is(context.Authentication))
+
+	// Authentication Request and Response
+	msg := decodeDownlinkNas(t, conn, ue)
+	require.Equal(t, nas.MsgTypeAuthenticationRequest, msg.GmmHeader.GetMessageType())
+	resStar, err := ue.Authenticate(msg.AuthenticationRequest, "5G:mnc093.mcc208.3gppnetwork.org")
+	require.NoError(t, err)
+	handleUplinkNas(t, amfUe, ngapType.ProcedureCodeUplinkNASTransport,
+		nastesting.GetAuthenticationResponse(resStar, ""), false)
+	require.True(t, amfUe.State[anType].Is(context.SecurityMode))
+	require.Equal(t, supi, amfUe.Supi)
+
+	// Security Mode Command and Complete, the UE takes the new 5G NAS security context into use
+	msg = decodeDownlinkNas(t, conn, ue)
+	require.Equal(t, nas.MsgTypeSecurityModeCommand, msg.GmmHeader.GetMessageType())
+	require.Equal(t, nas.SecurityHeaderTypeIntegrityProtectedWithNew5gNasSecurityContext, msg.SecurityHeaderType)
+	securityModeComplete, err := ue.EncodeUplink(nastesting.GetSecurityModeComplete(registrationRequest),
+		nas.SecurityHeaderTypeIntegrityProtectedAndCipheredWithNew5gNasSecurityContext)
+	require.NoError(t, err)
+	handleUplinkNas(t, amfUe, ngapType.ProcedureCodeUplinkNASTransport, securityModeComplete, false)
+	require.True(t, amfUe.SecurityContextAvailable)
+	require.True(t, amfUe.State[anType].Is(context.ContextSetup))
+
+	// Registration Accept ciphered and Registration Complete
+	msg = decodeDownlinkNas(t, conn, ue)
+	require.Equal(t, nas.MsgTypeRegistrationAccept, msg.GmmHeader.GetMessageType())
+	require.Equal(t, nas.SecurityHeaderTypeIntegrityProtectedAndCiphered, msg.SecurityHeaderType)
+	require.NotNil(t, amfUe.T3550)
+	registrationComplete, err := ue.EncodeUplink(nastesting.GetRegistrationComplete(nil),
+		nas.SecurityHeaderTypeIntegrityProtectedAndCiphered)
+	require.NoError(t, err)
+	handleUplinkNas(t, amfUe, ngapType.ProcedureCodeUplinkNASTransport, registrationComplete, false)
+	require.True(t, amfUe.State[anType].Is(context.Registered))
+	require.Nil(t, amfUe.T3550)
+	// the Registration Complete is the second uplink message of the new 5G NAS security context
+	require.Equal(t, uint32(1), amfUe.ULCount.Get())
+}
@@ -8,6 +8,7 @@ import (
 	gmm_message "github.com/free5gc/amf/internal/gmm/message"
 	"github.com/free5gc/amf/internal/logger"
 	business_metrics "github.com/free5gc/amf/internal/metrics/business"
+	"github.com/free5gc/amf/internal/nas/nas_codec"
 	ngap_message "github.com/free5gc/amf/internal/ngap/message"
 	"github.com/free5gc/amf/internal/sbi/consumer"
 	"github.com/free5gc/nas"
@@ -24,6 +25,8 @@ func DeRegistered(state *fsm.State, event fsm.EventType, args fsm.ArgsType) {
 		amfUe := args[ArgAmfUe].(*context.AmfUe)
 		accessType := args[ArgAccessType].(models.AccessType)
 		amfUe.ClearRegistrationRequestData(accessType)
+		amfUe.EmergencyRegistered = false
+		releaseNsacAdmissionAtDeregistration(amfUe, accessType)
 		amfUe.GmmLog.Debugln("EntryEvent at GMM State[DeRegistered]")
 	case GmmMessageEvent:
 		amfUe := args[ArgAmfUe].(*context.AmfUe)
@@ -33,7 +36,9 @@ func DeRegistered(state *fsm.State, event fsm.EventType, args fsm.ArgsType) {
 		amfUe.GmmLog.Debugln("GmmMessageEvent at GMM State[DeRegistered]")
 		switch gmmMessage.GetMessageType() {
 		case nas.MsgTypeRegistrationRequest:
-			if err := HandleRegistrationRequest(amfUe, accessType, procedureCode, gmmMessage.RegistrationRequest); err != nil {
+			requestedEdrx, _ := args[ArgRequestedEdrx].(*context.ExtendedDRXParameters)
+			if err := HandleRegistrationRequest(amfUe, accessType, procedureCode, gmmMessage.RegistrationRequest,
+				requestedEdrx); err != nil {
 				logger.GmmLog.Errorln(err)
 			} else {
 				if errSendEvent := GmmFSM.SendEvent(state, StartAuthEvent, fsm.ArgsType{
@@ -50,8 +55,7 @@ func DeRegistered(state *fsm.State, event fsm.EventType, args fsm.ArgsType) {
 				logger.GmmLog.Errorln(err)
 			}
 		default:
-			amfUe.GmmLog.Errorf("state mismatch: receieve gmm message[message type 0x%0x] at %s state",
-				gmmMessage.GetMessageType(), state.Current())
+			handleUnexpectedMessage(amfUe, accessType, state, gmmMessage)
 		}
 	case StartAuthEvent:
 		logger.GmmLog.Debugln(event)
@@ -86,7 +90,9 @@ func Registered(state *fsm.State, event fsm.EventType, args fsm.ArgsType) {
 		switch gmmMessage.GetMessageType() {
 		// Mobility Registration update / Periodic Registration update
 		case nas.MsgTypeRegistrationRequest:
-			if err := HandleRegistrationRequest(amfUe, accessType, procedureCode, gmmMessage.RegistrationRequest); err != nil {
+			requestedEdrx, _ := args[ArgRequestedEdrx].(*context.ExtendedDRXParameters)
+			if err := HandleRegistrationRequest(amfUe, accessType, procedureCode, gmmMessage.RegistrationRequest,
+				requestedEdrx); err != nil {
 				logger.GmmLog.Errorln(err)
 			} else {
 				if errSendEvent := GmmFSM.SendEvent(state, StartAuthEvent, fsm.ArgsType{
@@ -113,6 +119,14 @@ func Registered(state *fsm.State, event fsm.EventType, args fsm.ArgsType) {
 			if err := HandleNotificationResponse(amfUe, gmmMessage.NotificationResponse); err != nil {
 				logger.GmmLog.Errorln(err)
 			}
+		case nas_codec.MsgTypeNetworkSliceSpecificAuthenticationComplete:
+			nssaaComplete, _ := args[ArgNssaaComplete].(*nas_codec.NssaaMessage)
+			if err := HandleNetworkSliceSpecificAuthenticationComplete(amfUe, accessType, nssaaComplete); err != nil {
+				logger.GmmLog.Errorln(err)
+			}
+		// network-initiated deregistration, TS 24.501 5.5.2.3.2
+		case nas.MsgTypeDeregistrationAcceptUETerminatedDeregistration:
+			fallthrough
 		case nas.MsgTypeDeregistrationRequestUEOriginatingDeregistration:
 			if err := GmmFSM.SendEvent(state, InitDeregistrationEvent, fsm.ArgsType{
 				ArgAmfUe:      amfUe,
@@ -126,8 +140,7 @@ func Registered(state *fsm.State, event fsm.EventType, args fsm.ArgsType) {
 				logger.GmmLog.Errorln(err)
 			}
 		default:
-			amfUe.GmmLog.Errorf("state mismatch: receieve gmm message[message type 0x%0x] at %s state",
-				gmmMessage.GetMessageType(), state.Current())
+			handleUnexpectedMessage(amfUe, accessType, state, gmmMessage)
 		}
 	case StartAuthEvent:
 		logger.GmmLog.Debugln(event)
@@ -174,6 +187,8 @@ func Authentication(state *fsm.State, event fsm.EventType, args fsm.ArgsType) {
 			if errSendEvent := GmmFSM.SendEvent(state, AuthSuccessEvent, fsm.ArgsType{
 				ArgAmfUe:      amfUe,
 				ArgAccessType: accessType,
+				ArgEAPSuccess: false,
+				ArgEAPMessage: "",
 			}, logger.GmmLog); errSendEvent != nil {
 				logger.GmmLog.Errorln(errSendEvent)
 			}
@@ -218,10 +233,9 @@ func Authentication(state *fsm.State, event fsm.EventType, args fsm.ArgsType) {
 				logger.GmmLog.Errorln(err)
 			}
 		default:
-			logger.GmmLog.Errorf("UE state mismatch: receieve gmm message[message type 0x%0x] at %s state",
-				gmmMessage.GetMessageType(), state.Current())
+			handleUnexpectedMessage(amfUe, accessType, state, gmmMessage)
 		}
-	case AuthSuccessEvent:
+	case AuthSuccessEvent, ProcedureAbortEvent, AbortToRegisteredEvent:
 		logger.GmmLog.Debugln(event)
 	case AuthErrorEvent:
 		amfUe = args[ArgAmfUe].(*context.AmfUe)
@@ -268,7 +282,8 @@ func SecurityMode(state *fsm.State, event fsm.EventType, args fsm.ArgsType) {
 		amfUe.UpdateLogFields(accessType)
 
 		amfUe.GmmLog.Debugln("EntryEvent at GMM State[SecurityMode]")
-		if amfUe.SecurityContextIsValid() {
+		// a K_AMF horizontally derived is taken into use with a security mode control procedure
+		if amfUe.SecurityContextIsValid() && !amfUe.KamfHorizontallyDerived {
 			amfUe.GmmLog.Debugln("UE has a valid security context - skip security mode control procedure")
 			if err := GmmFSM.SendEvent(state, SecurityModeSuccessEvent, fsm.ArgsType{
 				ArgAmfUe:      amfUe,
@@ -281,9 +296,8 @@ func SecurityMode(state *fsm.State, event fsm.EventType, args fsm.ArgsType) {
 			eapSuccess := args[ArgEAPSuccess].(bool)
 			eapMessage := args[ArgEAPMessage].(string)
 			// Select enc/int algorithm based on ue security capability & amf's policy,
-			amfSelf := context.GetSelf()
-			if err := amfUe.SelectSecurityAlg(amfSelf.SecurityAlgorithm.IntegrityOrder,
-				amfSelf.SecurityAlgorithm.CipheringOrder); err != nil {
+			integrityOrder, cipheringOrder := context.GetSelf().SecurityAlgorithmOrder(amfUe, accessType)
+			if err := amfUe.SelectSecurityAlg(integrityOrder, cipheringOrder); err != nil {
 				amfUe.GmmLog.Errorf("Select security algorithm failed: %s", err)
 				gmm_message.SendRegistrationReject(amfUe.RanUe[accessType], nasMessage.Cause5GMMUESecurityCapabilitiesMismatch, "")
 				err = GmmFSM.SendEvent(state, SecurityModeFailEvent, fsm.ArgsType{
@@ -325,12 +339,11 @@ func SecurityMode(state *fsm.State, event fsm.EventType, args fsm.ArgsType) {
 				logger.GmmLog.Errorln(err)
 			}
 		default:
-			amfUe.GmmLog.Errorf("state mismatch: receieve gmm message[message type 0x%0x] at %s state",
-				gmmMessage.GetMessageType(), state.Current())
+			handleUnexpectedMessage(amfUe, accessType, state, gmmMessage)
 		}
 	case SecurityModeSuccessEvent:
 		logger.GmmLog.Debugln(event)
-	case SecurityModeFailEvent:
+	case SecurityModeFailEvent, ProcedureAbortEvent, AbortToRegisteredEvent:
 		logger.GmmLog.Debugln(event)
 	case fsm.ExitEvent:
 		logger.GmmLog.Debugln(event)
@@ -356,7 +369,7 @@ func ContextSetup(state *fsm.State, event fsm.EventType, args fsm.ArgsType) {
 		case *nasMessage.RegistrationRequest:
 			amfUe.RegistrationRequest = message
 			switch amfUe.RegistrationType5GS {
-			case nasMessage.RegistrationType5GSInitialRegistration:
+			case nasMessage.RegistrationType5GSInitialRegistration, nasMessage.RegistrationType5GSEmergencyRegistration:
 				if err := HandleInitialRegistration(amfUe, accessType); err != nil {
 					logger.GmmLog.Errorln(err)
 					err = GmmFSM.SendEvent(state, ContextSetupFailEvent, fsm.ArgsType{
@@ -398,7 +411,7 @@ func ContextSetup(state *fsm.State, event fsm.EventType, args fsm.ArgsType) {
 				logger.GmmLog.Errorln(err)
 			} else {
 				switch amfUe.RegistrationType5GS {
-				case nasMessage.RegistrationType5GSInitialRegistration:
+				case nasMessage.RegistrationType5GSInitialRegistration, nasMessage.RegistrationType5GSEmergencyRegistration:
 					if err2 := HandleInitialRegistration(amfUe, accessType); err2 != nil {
 						logger.GmmLog.Errorln(err2)
 						err2 = GmmFSM.SendEvent(state, ContextSetupFailEvent, fsm.ArgsType{
@@ -433,12 +446,11 @@ func ContextSetup(state *fsm.State, event fsm.EventType, args fsm.ArgsType) {
 				logger.GmmLog.Errorln(err)
 			}
 		default:
-			amfUe.GmmLog.Errorf("state mismatch: receieve gmm message[message type 0x%0x] at %s state",
-				gmmMessage.GetMessageType(), state.Current())
+			handleUnexpectedMessage(amfUe, accessType, state, gmmMessage)
 		}
-	case ContextSetupSuccessEvent:
+	case ContextSetupSuccessEvent, AbortToRegisteredEvent:
 		logger.GmmLog.Debugln(event)
-	case ContextSetupFailEvent:
+	case ContextSetupFailEvent, ProcedureAbortEvent:
 		logger.GmmLog.Debugln(event)
 		amfUe := args[ArgAmfUe].(*context.AmfUe)
 		if amfUe.UeCmRegistered[accessType] {
@@ -468,9 +480,23 @@ func DeregisteredInitiated(state *fsm.State, event fsm.EventType, args fsm.ArgsT
 		amfUe := args[ArgAmfUe].(*context.AmfUe)
 		gmmMessage := args[ArgNASMessage].(*nas.GmmMessage)
 		amfUe.GmmLog.Debugln("EntryEvent at GMM State[DeregisteredInitiated]")
-		if err := HandleDeregistrationRequest(amfUe, accessType,
-			gmmMessage.DeregistrationRequestUEOriginatingDeregistration); err != nil {
-			logger.GmmLog.Errorln(err)
+		switch gmmMessage.GetMessageType() {
+		case nas.MsgTypeDeregistrationRequestUEOriginatingDeregistration:
+			if err := HandleDeregistrationRequest(amfUe, accessType,
+				gmmMessage.DeregistrationRequestUEOriginatingDeregistration); err != nil {
+				logger.GmmLog.Errorln(err)
+			}
+		// the UE accepted the network-initiated deregistration, TS 24.501 5.5.2.3.2
+		case nas.MsgTypeDeregistrationAcceptUETerminatedDeregistration:
+			if err := HandleDeregistrationAccept(amfUe, accessType,
+				gmmMessage.DeregistrationAcceptUETerminatedDeregistration); err != nil {
+				logger.GmmLog.Errorln(err)
+			} else if err = GmmFSM.SendEvent(state, DeregistrationAcceptEvent, fsm.ArgsType{
+				ArgAmfUe:      amfUe,
+				ArgAccessType: accessType,
+			}, logger.GmmLog); err != nil {
+				logger.GmmLog.Errorln(err)
+			}
 		}
 	case GmmMessageEvent:
 		amfUe := args[ArgAmfUe].(*context.AmfUe)
@@ -483,8 +509,7 @@ func DeregisteredInitiated(state *fsm.State, event fsm.EventType, args fsm.ArgsT
 				logger.GmmLog.Errorln(err)
 			}
 		default:
-			amfUe.GmmLog.Errorf("state mismatch: receieve gmm message[message type 0x%0x] at %s state",
-				gmmMessage.GetMessageType(), state.Current())
+			handleUnexpectedMessage(amfUe, accessType, state, gmmMessage)
 		}
 	case DeregistrationAcceptEvent:
 		logger.GmmLog.Debugln(event)
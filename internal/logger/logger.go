@@ -43,6 +43,7 @@ func init() {
 	}
 
 	Log = logger_util.New(fieldsOrder)
+	Log.AddHook(&redactHook{})
 	NfLog = Log.WithField(logger_util.FieldNF, "AMF")
 	MainLog = NfLog.WithField(logger_util.FieldCategory, "Main")
 	InitLog = NfLog.WithField(logger_util.FieldCategory, "Init")
@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RedactedValue replaces key material in the logs
+const RedactedValue string = "[REDACTED]"
+
+// keyFields are the names of the UE context fields holding key material (TS 33.501 6.2), a log field with one of
+// these names never reaches the log output in the clear
+var keyFields = []string{
+	"KnasInt",
+	"KnasEnc",
+	"Kgnb",
+	"Kn3iwf",
+	"NH",
+	"Kamf",
+	"Kseaf",
+}
+
+// IsKeyField reports whether the log field name holds key material, the comparison is case-insensitive
+func IsKeyField(name string) bool {
+	for _, field := range keyFields {
+		if strings.EqualFold(name, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactHook masks the log fields holding key material before the entry is written by any output, it is the first
+// hook of the logger so that the hooks added later, e.g. the log file hooks, only see the masked fields
+type redactHook struct{}
+
+func (h *redactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *redactHook) Fire(entry *logrus.Entry) error {
+	for name := range entry.Data {
+		if IsKeyField(name) {
+			entry.Data[name] = RedactedValue
+		}
+	}
+	return nil
+}
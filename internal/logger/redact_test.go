@@ -0,0 +1,42 @@
+package logger_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/free5gc/amf/internal/logger"
+)
+
+func TestIsKeyField(t *testing.T) {
+	for _, name := range []string{"KnasInt", "KnasEnc", "Kgnb", "Kn3iwf", "NH", "Kamf", "Kseaf", "kamf"} {
+		require.True(t, logger.IsKeyField(name), name)
+	}
+	for _, name := range []string{logger.FieldSupi, logger.FieldAmfUeNgapID, "NasCount"} {
+		require.False(t, logger.IsKeyField(name), name)
+	}
+}
+
+func TestLog_RedactKeyFields(t *testing.T) {
+	var buf bytes.Buffer
+	out, level := logger.Log.Out, logger.Log.GetLevel()
+	defer func() {
+		logger.Log.SetOutput(out)
+		logger.Log.SetLevel(level)
+	}()
+	logger.Log.SetOutput(&buf)
+	logger.Log.SetLevel(logrus.TraceLevel)
+
+	logger.CtxLog.WithFields(logrus.Fields{
+		"Kamf":           "0a0b0c0d",
+		"KnasInt":        [16]uint8{0xde, 0xad},
+		logger.FieldSupi: "imsi-208930000000001",
+	}).Tracef("Derive NAS keys")
+
+	require.NotContains(t, buf.String(), "0a0b0c0d")
+	require.NotContains(t, buf.String(), "222 173")
+	require.Contains(t, buf.String(), logger.RedactedValue)
+	require.Contains(t, buf.String(), "imsi-208930000000001")
+}
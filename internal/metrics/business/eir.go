@@ -0,0 +1,34 @@
+package business
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/free5gc/util/metrics/utils"
+)
+
+// eirCheckCounter The number of PEI checks by the 5G-EIR per equipment status, e.g. the greylisted UEs
+var eirCheckCounter *prometheus.CounterVec
+
+func GetEirHandlerMetrics(namespace string) []prometheus.Collector {
+	var collectors []prometheus.Collector
+
+	eirCheckCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: SUBSYSTEM_NAME,
+			Name:      EIR_CHECK_COUNTER_NAME,
+			Help:      EIR_CHECK_COUNTER_DESC,
+		},
+		[]string{EIR_STATUS_LABEL},
+	)
+
+	collectors = append(collectors, eirCheckCounter)
+
+	return collectors
+}
+
+func IncrEirCheckCounter(status string) {
+	if utils.IsBusinessMetricsEnabled() && IsEirMetricsEnabled() {
+		eirCheckCounter.With(prometheus.Labels{EIR_STATUS_LABEL: status}).Inc()
+	}
+}
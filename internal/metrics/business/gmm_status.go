@@ -0,0 +1,42 @@
+package business
+
+import (
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/free5gc/nas/nasMessage"
+	"github.com/free5gc/util/metrics/utils"
+)
+
+var causeValueSuffix = regexp.MustCompile(`\s*\(\d+\)$`)
+
+// gmmStatusCounter The number of 5GMM STATUS messages received from and sent to the UEs per 5GMM cause
+var gmmStatusCounter *prometheus.CounterVec
+
+func GetGmmStatusHandlerMetrics(namespace string) []prometheus.Collector {
+	var collectors []prometheus.Collector
+
+	gmmStatusCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: SUBSYSTEM_NAME,
+			Name:      GMM_STATUS_COUNTER_NAME,
+			Help:      GMM_STATUS_COUNTER_DESC,
+		},
+		[]string{GMM_STATUS_DIRECTION_LABEL, GMM_STATUS_CAUSE_LABEL},
+	)
+
+	collectors = append(collectors, gmmStatusCounter)
+
+	return collectors
+}
+
+func IncrGmmStatusCounter(direction string, cause uint8) {
+	if utils.IsBusinessMetricsEnabled() && IsGmmStatusMetricsEnabled() {
+		gmmStatusCounter.With(prometheus.Labels{
+			GMM_STATUS_DIRECTION_LABEL: direction,
+			GMM_STATUS_CAUSE_LABEL:     causeValueSuffix.ReplaceAllString(nasMessage.Cause5GMMToString(cause), ""),
+		}).Inc()
+	}
+}
@@ -0,0 +1,58 @@
+package business
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/free5gc/openapi/models"
+	"github.com/free5gc/util/metrics/utils"
+)
+
+// nasReplayedMsgCounter The number of uplink NAS messages discarded because their NAS COUNT was already received
+var nasReplayedMsgCounter *prometheus.CounterVec
+
+// nasSecurityAlgCounter The number of NAS security mode control procedures per selected algorithm pair
+var nasSecurityAlgCounter *prometheus.CounterVec
+
+func GetNasSecurityHandlerMetrics(namespace string) []prometheus.Collector {
+	var collectors []prometheus.Collector
+
+	nasReplayedMsgCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: SUBSYSTEM_NAME,
+			Name:      NAS_REPLAYED_MSG_COUNTER_NAME,
+			Help:      NAS_REPLAYED_MSG_COUNTER_DESC,
+		},
+		[]string{NAS_SECURITY_ACCESS_TYPE_LABEL},
+	)
+
+	nasSecurityAlgCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: SUBSYSTEM_NAME,
+			Name:      NAS_SECURITY_ALG_COUNTER_NAME,
+			Help:      NAS_SECURITY_ALG_COUNTER_DESC,
+		},
+		[]string{NAS_SECURITY_ACCESS_TYPE_LABEL, NAS_SECURITY_INTEGRITY_LABEL, NAS_SECURITY_CIPHERING_LABEL},
+	)
+
+	collectors = append(collectors, nasReplayedMsgCounter, nasSecurityAlgCounter)
+
+	return collectors
+}
+
+func IncrNasReplayedMsgCounter(accessType models.AccessType) {
+	if utils.IsBusinessMetricsEnabled() && IsNasSecurityMetricsEnabled() {
+		nasReplayedMsgCounter.With(prometheus.Labels{NAS_SECURITY_ACCESS_TYPE_LABEL: string(accessType)}).Inc()
+	}
+}
+
+func IncrNasSecurityAlgCounter(accessType models.AccessType, integrityAlg, cipheringAlg string) {
+	if utils.IsBusinessMetricsEnabled() && IsNasSecurityMetricsEnabled() {
+		nasSecurityAlgCounter.With(prometheus.Labels{
+			NAS_SECURITY_ACCESS_TYPE_LABEL: string(accessType),
+			NAS_SECURITY_INTEGRITY_LABEL:   integrityAlg,
+			NAS_SECURITY_CIPHERING_LABEL:   cipheringAlg,
+		}).Inc()
+	}
+}
@@ -0,0 +1,34 @@
+package business
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/free5gc/util/metrics/utils"
+)
+
+// nsacRegisteredUeGauge The number of UEs admitted to each S-NSSAI subject to Network Slice Admission Control
+var nsacRegisteredUeGauge *prometheus.GaugeVec
+
+func GetNsacHandlerMetrics(namespace string) []prometheus.Collector {
+	var collectors []prometheus.Collector
+
+	nsacRegisteredUeGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: SUBSYSTEM_NAME,
+			Name:      NSAC_REGISTERED_UE_GAUGE_NAME,
+			Help:      NSAC_REGISTERED_UE_GAUGE_DESC,
+		},
+		[]string{NSAC_SNSSAI_LABEL},
+	)
+
+	collectors = append(collectors, nsacRegisteredUeGauge)
+
+	return collectors
+}
+
+func SetNsacRegisteredUeGauge(snssai string, numOfUes int) {
+	if utils.IsBusinessMetricsEnabled() && IsNsacMetricsEnabled() {
+		nsacRegisteredUeGauge.With(prometheus.Labels{NSAC_SNSSAI_LABEL: snssai}).Set(float64(numOfUes))
+	}
+}
@@ -10,6 +10,10 @@ const (
 	PDU_METRICS             = "pdu"
 	GMM_STATE_METRICS       = "gmm-state"
 	UE_CONNECTIVITY_METRICS = "ue-connectivity"
+	NSAC_METRICS            = "nsac"
+	NAS_SECURITY_METRICS    = "nas-security"
+	EIR_METRICS             = "eir"
+	GMM_STATUS_METRICS      = "gmm-status"
 )
 
 // Collectors information
@@ -39,6 +43,21 @@ const (
 	UE_CONNECTIVITY_GAUGE_NAME = "ue_connectivity"
 	UE_CONNECTIVITY_GAUGE_DESC = "Number of user equipment that are connected to the core network " +
 		"(cm-connected + gmm-registered)"
+
+	NSAC_REGISTERED_UE_GAUGE_NAME = "nsac_registered_ue_count"
+	NSAC_REGISTERED_UE_GAUGE_DESC = "Number of UEs registered to each S-NSSAI subject to Network Slice Admission Control"
+
+	NAS_REPLAYED_MSG_COUNTER_NAME = "nas_replayed_messages_total"
+	NAS_REPLAYED_MSG_COUNTER_DESC = "Count of the uplink NAS messages discarded as replayed"
+
+	NAS_SECURITY_ALG_COUNTER_NAME = "nas_security_algorithms_total"
+	NAS_SECURITY_ALG_COUNTER_DESC = "Count of the NAS security algorithm pairs negotiated with the UEs"
+
+	EIR_CHECK_COUNTER_NAME = "eir_equipment_identity_checks_total"
+	EIR_CHECK_COUNTER_DESC = "Count of the PEI checks by the 5G-EIR per equipment status"
+
+	GMM_STATUS_COUNTER_NAME = "gmm_status_messages_total"
+	GMM_STATUS_COUNTER_DESC = "Count of the 5GMM STATUS messages received from and sent to the UEs per 5GMM cause"
 )
 
 // Label names
@@ -65,6 +84,21 @@ const (
 
 	// UE-Connectivity
 	UE_CONNECTIVITY_ACCESS_TYPE_LABEL = "access_type"
+
+	// NSAC
+	NSAC_SNSSAI_LABEL = "snssai"
+
+	// NAS security
+	NAS_SECURITY_ACCESS_TYPE_LABEL = "access_type"
+	NAS_SECURITY_INTEGRITY_LABEL   = "integrity_algorithm"
+	NAS_SECURITY_CIPHERING_LABEL   = "ciphering_algorithm"
+
+	// 5G-EIR
+	EIR_STATUS_LABEL = "status"
+
+	// 5GMM STATUS
+	GMM_STATUS_DIRECTION_LABEL = "direction"
+	GMM_STATUS_CAUSE_LABEL     = "cause"
 )
 
 // Metrics Values
@@ -80,6 +114,10 @@ const (
 
 	PDU_SESSION_CREATION_EVENT = "creation"
 	PDU_SESSION_RELEASE_EVENT  = "release"
+
+	// 5GMM STATUS
+	GMM_STATUS_RECEIVED_VALUE = "received"
+	GMM_STATUS_SENT_VALUE     = "sent"
 )
 
 // Potential Causes
@@ -93,6 +131,8 @@ const (
 	HANDOVER_PDU_SESSION_RES_REL_LIST_ERR              = "some pdu session could not been release for handover"
 	HANDOVER_BETWEEN_DIFFERENT_AMF_NOT_SUPPORTED       = "handover between different amf has not been implemented yet"
 	HANDOVER_NOT_YET_IMPLEMENT_N2_HANDOVER_BETWEEN_AMF = "n2 Handover between amf has not been implemented yet"
+	HANDOVER_TRELOCPREP_EXPIRY_ERR                     = "trelocprep timer expired"
+	HANDOVER_TRELOCOVERALL_EXPIRY_ERR                  = "trelocoverall timer expired"
 	HANDOVER_EMPTY_CAUSE                               = ""
 )
 
@@ -147,3 +187,43 @@ func IsUeConnectivityMetricsEnabled() bool {
 func EnableUeConnectivityMetrics() {
 	ueConnectivityMetricsEnabled = true
 }
+
+var nsacMetricsEnabled bool
+
+func IsNsacMetricsEnabled() bool {
+	return nsacMetricsEnabled
+}
+
+func EnableNsacMetrics() {
+	nsacMetricsEnabled = true
+}
+
+var nasSecurityMetricsEnabled bool
+
+func IsNasSecurityMetricsEnabled() bool {
+	return nasSecurityMetricsEnabled
+}
+
+func EnableNasSecurityMetrics() {
+	nasSecurityMetricsEnabled = true
+}
+
+var eirMetricsEnabled bool
+
+func IsEirMetricsEnabled() bool {
+	return eirMetricsEnabled
+}
+
+func EnableEirMetrics() {
+	eirMetricsEnabled = true
+}
+
+var gmmStatusMetricsEnabled bool
+
+func IsGmmStatusMetricsEnabled() bool {
+	return gmmStatusMetricsEnabled
+}
+
+func EnableGmmStatusMetrics() {
+	gmmStatusMetricsEnabled = true
+}
@@ -7,12 +7,12 @@ import (
 	"github.com/free5gc/amf/internal/context"
 	"github.com/free5gc/amf/internal/gmm"
 	"github.com/free5gc/amf/internal/logger"
-	"github.com/free5gc/nas"
+	"github.com/free5gc/amf/internal/nas/nas_codec"
 	"github.com/free5gc/openapi/models"
 	"github.com/free5gc/util/fsm"
 )
 
-func Dispatch(ue *context.AmfUe, accessType models.AccessType, procedureCode int64, msg *nas.Message) error {
+func Dispatch(ue *context.AmfUe, accessType models.AccessType, procedureCode int64, msg *nas_codec.Message) error {
 	if msg.GmmMessage == nil {
 		return errors.New("gmm Message is nil")
 	}
@@ -29,6 +29,8 @@ func Dispatch(ue *context.AmfUe, accessType models.AccessType, procedureCode int
 		gmm.ArgAmfUe:         ue,
 		gmm.ArgAccessType:    accessType,
 		gmm.ArgNASMessage:    msg.GmmMessage,
+		gmm.ArgRequestedEdrx: msg.RequestedEdrx,
+		gmm.ArgNssaaComplete: msg.NssaaComplete,
 		gmm.ArgProcedureCode: procedureCode,
 	}, logger.GmmLog)
 }
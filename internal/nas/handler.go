@@ -1,10 +1,13 @@
 package nas
 
 import (
+	"errors"
 	"fmt"
 
 	amf_context "github.com/free5gc/amf/internal/context"
+	"github.com/free5gc/amf/internal/gmm"
 	gmm_common "github.com/free5gc/amf/internal/gmm/common"
+	gmm_message "github.com/free5gc/amf/internal/gmm/message"
 	"github.com/free5gc/amf/internal/logger"
 	"github.com/free5gc/amf/internal/nas/nas_security"
 	"github.com/free5gc/nas"
@@ -53,10 +56,14 @@ func HandleNAS(ranUe *amf_context.RanUe, procedureCode int64, nasPdu []byte, ini
 	if err != nil {
 		metricCause = nas_metrics.DECODE_NAS_MSG_ERR
 		ranUe.AmfUe.NASLog.Errorln(err)
+		var undecodableErr *nas_security.UndecodableMessageError
+		if errors.As(err, &undecodableErr) {
+			gmm_message.SendStatus5GMM(ranUe, undecodableErr.Cause)
+		}
 		return
 	}
 
-	nasMsg = msg
+	nasMsg = msg.Message
 
 	ranUe.AmfUe.NasPduValue = nasPdu
 	ranUe.AmfUe.MacFailed = !integrityProtected
@@ -72,6 +79,10 @@ func HandleNAS(ranUe *amf_context.RanUe, procedureCode int64, nasPdu []byte, ini
 		ranUe.AmfUe.NASLog.Errorf("Handle NAS Error: %v", errDispatch)
 		isNasMsgRcv = false
 	}
+
+	if ranUe.AmfUe != nil {
+		gmm.RequestNasCountRekeying(ranUe.AmfUe, ranUe.Ran.AnType)
+	}
 }
 
 // Get5GSMobileIdentityFromNASPDU is used to find MobileIdentity from plain nas
@@ -0,0 +1,267 @@
+package nas_test
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	amf_context "github.com/free5gc/amf/internal/context"
+	amf_nas "github.com/free5gc/amf/internal/nas"
+	nastesting "github.com/free5gc/amf/internal/nas/testing"
+	ngaptesting "github.com/free5gc/amf/internal/ngap/testing"
+	"github.com/free5gc/amf/internal/sbi/consumer"
+	"github.com/free5gc/amf/pkg/factory"
+	"github.com/free5gc/nas"
+	"github.com/free5gc/nas/nasMessage"
+	"github.com/free5gc/nas/nasType"
+	"github.com/free5gc/nas/security"
+	"github.com/free5gc/ngap"
+	"github.com/free5gc/ngap/ngapType"
+	"github.com/free5gc/openapi/models"
+)
+
+const (
+	testSupi = "imsi-208930000000001"
+	testK    = "8baf473f2f8fd09487cccbd7097c6862"
+	testOpc  = "8e27b6af0e692e750f32667a3b14605d"
+)
+
+// lastDownlinkNasPdu returns the NAS-PDU of the last NGAP message sent to the RAN
+func lastDownlinkNasPdu(t *testing.T, conn *ngaptesting.SctpConnStub) []byte {
+	require.NotEmpty(t, conn.MsgList)
+	pdu, err := ngap.Decoder(conn.MsgList[len(conn.MsgList)-1])
+	require.NoError(t, err)
+	require.NotNil(t, pdu.InitiatingMessage)
+
+	switch pdu.InitiatingMessage.ProcedureCode.Value {
+	case ngapType.ProcedureCodeDownlinkNASTransport:
+		for _, ie := range pdu.InitiatingMessage.Value.DownlinkNASTransport.ProtocolIEs.List {
+			if ie.Id.Value == ngapType.ProtocolIEIDNASPDU {
+				return ie.Value.NASPDU.Value
+			}
+		}
+	case ngapType.ProcedureCodeInitialContextSetup:
+		for _, ie := range pdu.InitiatingMessage.Value.InitialContextSetupRequest.ProtocolIEs.List {
+			if ie.Id.Value == ngapType.ProtocolIEIDNASPDU {
+				return ie.Value.NASPDU.Value
+			}
+		}
+	}
+	require.Failf(t, "no NAS-PDU", "procedure code %d", pdu.InitiatingMessage.ProcedureCode.Value)
+	return nil
+}
+
+// newNasTestUe returns a UE on a RAN of the AMF, the UE is authenticated by the local authentication
+func newNasTestUe(t *testing.T, ranUeNgapId int64) (*amf_context.RanUe, *ngaptesting.SctpConnStub, *nastesting.UE) {
+	_, err := consumer.NewConsumer(nil)
+	require.NoError(t, err)
+
+	amfConfig := factory.AmfConfig
+	amfSelf := amf_context.GetSelf()
+	servedGuamiList := amfSelf.ServedGuamiList
+	supportTaiLists := amfSelf.SupportTaiLists
+	plmnSupportList := amfSelf.PlmnSupportList
+	t3550Cfg := amfSelf.T3550Cfg
+	securityAlgorithm := amfSelf.SecurityAlgorithm
+	t.Cleanup(func() {
+		factory.AmfConfig = amfConfig
+		amfSelf.ServedGuamiList = servedGuamiList
+		amfSelf.SupportTaiLists = supportTaiLists
+		amfSelf.PlmnSupportList = plmnSupportList
+		amfSelf.T3550Cfg = t3550Cfg
+		amfSelf.SecurityAlgorithm = securityAlgorithm
+		amfSelf.LocalAusf = nil
+	})
+
+	factory.AmfConfig = &factory.Config{Configuration: &factory.Configuration{}}
+	tai := models.Tai{
+		PlmnId: &models.PlmnId{Mcc: "208", Mnc: "93"},
+		Tac:    "000001",
+	}
+	snssai := models.Snssai{Sst: 1, Sd: "010203"}
+	amfSelf.ServedGuamiList = []models.Guami{
+		{PlmnId: &models.PlmnIdNid{Mcc: "208", Mnc: "93"}, AmfId: "cafe00"},
+	}
+	amfSelf.SupportTaiLists = []models.Tai{tai}
+	amfSelf.PlmnSupportList = []factory.PlmnSupportItem{
+		{PlmnId: &models.PlmnId{Mcc: "208", Mnc: "93"}, SNssaiList: []models.Snssai{snssai}},
+	}
+	amfSelf.T3550Cfg = factory.TimerValue{Enable: true, ExpireTime: 6 * time.Second, MaxRetryTimes: 4}
+	amfSelf.SecurityAlgorithm.IntegrityOrder = []uint8{security.AlgIntegrity128NIA2}
+	amfSelf.SecurityAlgorithm.CipheringOrder = []uint8{security.AlgCiphering128NEA2}
+	amfSelf.LocalAusf, err = amf_context.NewLocalAusf(&factory.LocalAuthentication{
+		Enable:  true,
+		SkipUdm: true,
+		SkipPcf: true,
+	}, []factory.LocalSubscriber{{Supi: testSupi, K: testK, Opc: testOpc, Sqn: "000000000020"}})
+	require.NoError(t, err)
+
+	conn := new(ngaptesting.SctpConnStub)
+	ran := amfSelf.NewAmfRan(conn)
+	ran.AnType = models.AccessType__3_GPP_ACCESS
+	ranUe, err := ran.NewRanUe(ranUeNgapId)
+	require.NoError(t, err)
+	ranUe.Tai = tai
+	t.Cleanup(func() {
+		if ranUe.AmfUe != nil {
+			ranUe.AmfUe.Remove()
+		}
+		amfSelf.AmfRanPool.Delete(conn)
+	})
+
+	k, _ := hex.DecodeString(testK)
+	opc, _ := hex.DecodeString(testOpc)
+	return ranUe, conn, nastesting.NewUE(testSupi, k, opc)
+}
+
+// startSecurityMode runs the registration of the UE until the Security Mode Command, the Registration Request is
+// returned for the Security Mode Complete
+func startSecurityMode(t *testing.T, ranUe *amf_context.RanUe, conn *ngaptesting.SctpConnStub,
+	ue *nastesting.UE,
+) []byte {
+	// Registration Request with the SUCI of the null scheme
+	suci := []uint8{0x01, 0x02, 0xf8, 0x39, 0xf0, 0xff, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x10}
+	mobileIdentity := nasType.MobileIdentity5GS{Len: uint16(len(suci)), Buffer: suci}
+	requestedNssai := nasType.NewRequestedNSSAI(nasMessage.RegistrationRequestRequestedNSSAIType)
+	requestedNssai.SetLen(5)
+	requestedNssai.SetSNSSAIValue([]uint8{0x04, 0x01, 0x01, 0x02, 0x03})
+	ueSecurityCapability := nasType.NewUESecurityCapability(nasMessage.RegistrationRequestUESecurityCapabilityType)
+	ueSecurityCapability.SetLen(2)
+	ueSecurityCapability.SetEA2_128_5G(1)
+	ueSecurityCapability.SetIA2_128_5G(1)
+	capability5GMM := &nasType.Capability5GMM{
+		Iei:   nasMessage.RegistrationRequestCapability5GMMType,
+		Len:   1,
+		Octet: [13]uint8{0x07},
+	}
+	registrationRequest := nastesting.GetRegistrationRequest(nasMessage.RegistrationType5GSInitialRegistration,
+		mobileIdentity, requestedNssai, ueSecurityCapability, capability5GMM, nil, nil)
+	amf_nas.HandleNAS(ranUe, ngapType.ProcedureCodeInitialUEMessage, registrationRequest, true)
+
+	// Authentication Request and Response
+	msg, err := ue.DecodeDownlink(lastDownlinkNasPdu(t, conn))
+	require.NoError(t, err)
+	require.Equal(t, nas.MsgTypeAuthenticationRequest, msg.GmmHeader.GetMessageType())
+	resStar, err := ue.Authenticate(msg.AuthenticationRequest, "5G:mnc093.mcc208.3gppnetwork.org")
+	require.NoError(t, err)
+	amf_nas.HandleNAS(ranUe, ngapType.ProcedureCodeUplinkNASTransport,
+		nastesting.GetAuthenticationResponse(resStar, ""), false)
+
+	// Security Mode Command, the MAC of the command is checked with the new security context
+	msg, err = ue.DecodeDownlink(lastDownlinkNasPdu(t, conn))
+	require.NoError(t, err)
+	require.Equal(t, nas.MsgTypeSecurityModeCommand, msg.GmmHeader.GetMessageType())
+	require.Equal(t, nas.SecurityHeaderTypeIntegrityProtectedWithNew5gNasSecurityContext, msg.SecurityHeaderType)
+	require.Equal(t, security.AlgIntegrity128NIA2, ue.IntegrityAlg)
+	require.Equal(t, security.AlgCiphering128NEA2, ue.CipheringAlg)
+	return registrationRequest
+}
+
+// completeRegistration sends the Security Mode Complete with the Registration Request and completes the registration
+// of the UE
+func completeRegistration(t *testing.T, ranUe *amf_context.RanUe, conn *ngaptesting.SctpConnStub,
+	ue *nastesting.UE, registrationRequest []byte,
+) *amf_context.AmfUe {
+	// Security Mode Complete with the Registration Request in the NAS message container
+	securityModeComplete, err := ue.EncodeUplink(nastesting.GetSecurityModeComplete(registrationRequest),
+		nas.SecurityHeaderTypeIntegrityProtectedAndCipheredWithNew5gNasSecurityContext)
+	require.NoError(t, err)
+	amf_nas.HandleNAS(ranUe, ngapType.ProcedureCodeUplinkNASTransport, securityModeComplete, false)
+
+	// Registration Accept ciphered and Registration Complete
+	amfUe := ranUe.AmfUe
+	require.NotNil(t, amfUe)
+	require.True(t, amfUe.SecurityContextAvailable)
+	require.Equal(t, testSupi, amfUe.Supi)
+	msg, err := ue.DecodeDownlink(lastDownlinkNasPdu(t, conn))
+	require.NoError(t, err)
+	require.Equal(t, nas.MsgTypeRegistrationAccept, msg.GmmHeader.GetMessageType())
+	require.Equal(t, nas.SecurityHeaderTypeIntegrityProtectedAndCiphered, msg.SecurityHeaderType)
+	registrationComplete, err := ue.EncodeUplink(nastesting.GetRegistrationComplete(nil),
+		nas.SecurityHeaderTypeIntegrityProtectedAndCiphered)
+	require.NoError(t, err)
+	amf_nas.HandleNAS(ranUe, ngapType.ProcedureCodeUplinkNASTransport, registrationComplete, false)
+	require.True(t, amfUe.State[models.AccessType__3_GPP_ACCESS].Is(amf_context.Registered))
+	return amfUe
+}
+
+func TestRegistrationWithNasSecurity(t *testing.T) {
+	ranUe, conn, ue := newNasTestUe(t, 1)
+	amfUe := completeRegistration(t, ranUe, conn, ue, startSecurityMode(t, ranUe, conn, ue))
+	require.Equal(t, uint32(1), amfUe.ULCount.Get())
+
+	// a downlink message with a tampered MAC is discarded by the UE
+	pdu := lastDownlinkNasPdu(t, conn)
+	pdu[2] ^= 0xff
+	_, err := ue.DecodeDownlink(pdu)
+	require.Error(t, err)
+
+	// an uplink message with a tampered MAC is not accepted by the AMF
+	tampered, err := ue.EncodeUplink(nastesting.GetRegistrationComplete(nil),
+		nas.SecurityHeaderTypeIntegrityProtectedAndCiphered)
+	require.NoError(t, err)
+	tampered[2] ^= 0xff
+	amf_nas.HandleNAS(ranUe, ngapType.ProcedureCodeUplinkNASTransport, tampered, false)
+	require.Equal(t, uint32(1), amfUe.ULCount.Get())
+}
+
+func TestStatus5GMMAbortsRegistration(t *testing.T) {
+	ranUe, conn, ue := newNasTestUe(t, 2)
+	startSecurityMode(t, ranUe, conn, ue)
+	amfUe := ranUe.AmfUe
+	require.True(t, amfUe.State[models.AccessType__3_GPP_ACCESS].Is(amf_context.SecurityMode))
+
+	// the UE can not handle the Security Mode Command
+	status, err := ue.EncodeUplink(
+		nastesting.GetStatus5GMM(nasMessage.Cause5GMMMessageTypeNotCompatibleWithTheProtocolState),
+		nas.SecurityHeaderTypeIntegrityProtectedAndCiphered)
+	require.NoError(t, err)
+	amf_nas.HandleNAS(ranUe, ngapType.ProcedureCodeUplinkNASTransport, status, false)
+	require.True(t, amfUe.State[models.AccessType__3_GPP_ACCESS].Is(amf_context.Deregistered))
+	require.Nil(t, amfUe.T3560)
+}
+
+func TestStatus5GMMForInvalidMessages(t *testing.T) {
+	ranUe, conn, ue := newNasTestUe(t, 3)
+	completeRegistration(t, ranUe, conn, ue, startSecurityMode(t, ranUe, conn, ue))
+
+	testCases := []struct {
+		name    string
+		payload []byte
+		cause   uint8
+	}{
+		{
+			name:    "Unknown message type",
+			payload: []byte{nasMessage.Epd5GSMobilityManagementMessage, nas.SecurityHeaderTypePlainNas, 0x70},
+			cause:   nasMessage.Cause5GMMMessageTypeNonExistentOrNotImplemented,
+		},
+		{
+			name:    "Missing mandatory IE",
+			payload: []byte{nasMessage.Epd5GSMobilityManagementMessage, nas.SecurityHeaderTypePlainNas, nas.MsgTypeStatus5GMM},
+			cause:   nasMessage.Cause5GMMInvalidMandatoryInformation,
+		},
+		{
+			name:    "Unexpected message",
+			payload: nastesting.GetSecurityModeReject(nasMessage.Cause5GMMUESecurityCapabilitiesMismatch),
+			cause:   nasMessage.Cause5GMMMessageTypeNotCompatibleWithTheProtocolState,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sent := len(conn.MsgList)
+			pdu, err := ue.EncodeUplink(tc.payload, nas.SecurityHeaderTypeIntegrityProtectedAndCiphered)
+			require.NoError(t, err)
+			amf_nas.HandleNAS(ranUe, ngapType.ProcedureCodeUplinkNASTransport, pdu, false)
+
+			require.Len(t, conn.MsgList, sent+1)
+			msg, err := ue.DecodeDownlink(lastDownlinkNasPdu(t, conn))
+			require.NoError(t, err)
+			require.Equal(t, nas.MsgTypeStatus5GMM, msg.GmmHeader.GetMessageType())
+			require.Equal(t, tc.cause, msg.Status5GMM.Cause5GMM.GetCauseValue())
+			require.True(t, ranUe.AmfUe.State[models.AccessType__3_GPP_ACCESS].Is(amf_context.Registered))
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package nas_codec
+
+import (
+	"github.com/free5gc/amf/internal/context"
+	"github.com/free5gc/nas"
+)
+
+// Message is a plain NAS message with the IEs and the 5GMM messages the NAS library does not support
+type Message struct {
+	*nas.Message
+
+	// RequestedEdrx is the requested extended DRX parameters IE of a Registration Request
+	RequestedEdrx *context.ExtendedDRXParameters
+	// NssaaComplete is a Network Slice-Specific Authentication Complete, the 5GMM message has only its header
+	NssaaComplete *NssaaMessage
+}
+
+// DecodePlainNas decodes a plain NAS message into msg, the IEs and the 5GMM messages the NAS library does not
+// support are decoded first
+func DecodePlainNas(msg *nas.Message, payload []byte) (*Message, error) {
+	payload, requestedEdrx := StripRequestedExtendedDRXParameters(payload)
+	gmmMessage, nssaaComplete, err := decodeNetworkSliceSpecificAuthenticationComplete(payload)
+	if err != nil {
+		return nil, err
+	}
+	if nssaaComplete != nil {
+		msg.GmmMessage = gmmMessage
+	} else if err = msg.PlainNasDecode(&payload); err != nil {
+		return nil, err
+	}
+
+	decoded := &Message{
+		Message:       msg,
+		NssaaComplete: nssaaComplete,
+	}
+	if msg.GmmMessage != nil && msg.GmmHeader.GetMessageType() == nas.MsgTypeRegistrationRequest {
+		decoded.RequestedEdrx = requestedEdrx
+	}
+	return decoded, nil
+}
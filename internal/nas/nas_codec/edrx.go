@@ -0,0 +1,74 @@
+package nas_codec
+
+import (
+	"encoding/binary"
+
+	"github.com/free5gc/amf/internal/context"
+	"github.com/free5gc/nas"
+	"github.com/free5gc/nas/nasMessage"
+)
+
+// The extended DRX parameters IE (TS 24.501 9.11.3.60) is not supported by the NAS library, it is taken out of the
+// plain Registration Request before decoding it and appended to the plain Registration Accept after encoding it.
+const (
+	RegistrationRequestRequestedExtendedDRXParametersType uint8 = 0x6E
+	RegistrationAcceptNegotiatedExtendedDRXParametersType uint8 = 0x6E
+)
+
+// Extended protocol discriminator, security header type, message type, ngKSI and registration type, and the length
+// of the 5GS mobile identity of a plain Registration Request
+const registrationRequestHeaderLen = 6
+
+// StripRequestedExtendedDRXParameters takes the requested extended DRX parameters IE out of a plain Registration
+// Request, the payload is returned as is if it is not a Registration Request or does not include the IE
+func StripRequestedExtendedDRXParameters(payload []byte) ([]byte, *context.ExtendedDRXParameters) {
+	if len(payload) < registrationRequestHeaderLen || payload[0] != nasMessage.Epd5GSMobilityManagementMessage ||
+		payload[1]&0x0f != nas.SecurityHeaderTypePlainNas || payload[2] != nas.MsgTypeRegistrationRequest {
+		return payload, nil
+	}
+
+	offset := registrationRequestHeaderLen + int(binary.BigEndian.Uint16(payload[4:registrationRequestHeaderLen]))
+	for offset < len(payload) {
+		iei := payload[offset]
+		var ieLen int
+		switch {
+		case iei >= 0x80: // type 1 IE
+			ieLen = 1
+		case iei&0xf0 == 0x70: // TLV-E IE
+			if offset+3 > len(payload) {
+				return payload, nil
+			}
+			ieLen = 3 + int(binary.BigEndian.Uint16(payload[offset+1:offset+3]))
+		default: // TLV IE
+			if offset+2 > len(payload) {
+				return payload, nil
+			}
+			ieLen = 2 + int(payload[offset+1])
+		}
+		if offset+ieLen > len(payload) {
+			return payload, nil
+		}
+
+		if iei == RegistrationRequestRequestedExtendedDRXParametersType {
+			if ieLen != 3 {
+				return payload, nil
+			}
+			edrx := context.NewExtendedDRXParameters(payload[offset+2])
+			stripped := make([]byte, 0, len(payload)-ieLen)
+			stripped = append(stripped, payload[:offset]...)
+			stripped = append(stripped, payload[offset+ieLen:]...)
+			return stripped, edrx
+		}
+		offset += ieLen
+	}
+	return payload, nil
+}
+
+// EncodeNegotiatedExtendedDRXParameters encodes the negotiated extended DRX parameters IE of a Registration Accept,
+// nil is returned if eDRX is not used
+func EncodeNegotiatedExtendedDRXParameters(edrx *context.ExtendedDRXParameters) []byte {
+	if edrx == nil {
+		return nil
+	}
+	return []byte{RegistrationAcceptNegotiatedExtendedDRXParametersType, 1, edrx.Octet()}
+}
@@ -0,0 +1,58 @@
+package nas_codec_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	amf_context "github.com/free5gc/amf/internal/context"
+	"github.com/free5gc/amf/internal/nas/nas_codec"
+)
+
+func TestStripRequestedExtendedDRXParameters(t *testing.T) {
+	registrationRequest := []byte{
+		0x7e, 0x00, 0x41, 0x79, // initial registration
+		0x00, 0x0b, 0xf2, 0x02, 0xf8, 0x39, 0xca, 0xfe, 0x00, 0x00, 0x00, 0x00, 0x01, // 5G-GUTI
+		0x2e, 0x02, 0x80, 0x20, // UE security capability
+	}
+	requestedDRXParameters := []byte{0x51, 0x01, 0x03}
+	requestedExtendedDRXParameters := []byte{0x6e, 0x01, 0x35}
+
+	testCases := []struct {
+		name     string
+		payload  []byte
+		expected []byte
+		edrx     *amf_context.ExtendedDRXParameters
+	}{
+		{
+			name:     "No extended DRX parameters",
+			payload:  append(append([]byte{}, registrationRequest...), requestedDRXParameters...),
+			expected: append(append([]byte{}, registrationRequest...), requestedDRXParameters...),
+		},
+		{
+			name: "Extended DRX parameters",
+			payload: append(append(append([]byte{}, registrationRequest...), requestedExtendedDRXParameters...),
+				requestedDRXParameters...),
+			expected: append(append([]byte{}, registrationRequest...), requestedDRXParameters...),
+			edrx:     &amf_context.ExtendedDRXParameters{PagingTimeWindow: 0x03, EdrxValue: 0x05},
+		},
+		{
+			name:     "Not a Registration Request",
+			payload:  []byte{0x7e, 0x00, 0x43, 0x6e, 0x01, 0x35},
+			expected: []byte{0x7e, 0x00, 0x43, 0x6e, 0x01, 0x35},
+		},
+		{
+			name:     "Truncated IE",
+			payload:  append(append([]byte{}, registrationRequest...), 0x6e, 0x01),
+			expected: append(append([]byte{}, registrationRequest...), 0x6e, 0x01),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			payload, edrx := nas_codec.StripRequestedExtendedDRXParameters(tc.payload)
+			require.Equal(t, tc.expected, payload)
+			require.Equal(t, tc.edrx, edrx)
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package nas_codec
+
+import (
+	"github.com/free5gc/nas/nasConvert"
+	"github.com/free5gc/openapi/models"
+)
+
+// The rejected NSSAI IE of the Registration Reject (TS 24.501 8.2.9.5) and the rejected S-NSSAI cause of the
+// maximum number of UEs (TS 24.501 9.11.3.46) are not supported by the NAS library.
+const (
+	RegistrationRejectRejectedNSSAIType uint8 = 0x69
+
+	RejectedSnssaiCauseMaximumNumberOfUesReached uint8 = 0x03
+)
+
+// EncodeRejectedNSSAI encodes the rejected NSSAI IE of a Registration Reject with the S-NSSAIs whose maximum number
+// of UEs is reached, nil is returned if there is none
+func EncodeRejectedNSSAI(rejectedNssai []models.Snssai) []byte {
+	if len(rejectedNssai) == 0 {
+		return nil
+	}
+	var buf []byte
+	for _, snssai := range rejectedNssai {
+		buf = append(buf, nasConvert.RejectedSnssaiToNas(snssai, RejectedSnssaiCauseMaximumNumberOfUesReached)...)
+	}
+	return append([]byte{RegistrationRejectRejectedNSSAIType, uint8(len(buf))}, buf...)
+}
@@ -0,0 +1,96 @@
+package nas_codec
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/free5gc/nas"
+	"github.com/free5gc/nas/nasConvert"
+	"github.com/free5gc/nas/nasMessage"
+	"github.com/free5gc/nas/nasType"
+	"github.com/free5gc/openapi/models"
+)
+
+// The network slice-specific authentication messages (TS 24.501 8.2.31-8.2.33) and the pending NSSAI IE
+// (TS 24.501 9.11.3.46) are not supported by the NAS library, they are encoded and decoded on the plain NAS
+// message.
+const (
+	MsgTypeNetworkSliceSpecificAuthenticationCommand  uint8 = 0x50
+	MsgTypeNetworkSliceSpecificAuthenticationComplete uint8 = 0x51
+	MsgTypeNetworkSliceSpecificAuthenticationResult   uint8 = 0x52
+
+	RegistrationAcceptPendingNSSAIType uint8 = 0x39
+)
+
+// NssaaMessage is the content of a Network Slice-Specific Authentication Complete, TS 24.501 8.2.32
+type NssaaMessage struct {
+	Snssai     models.Snssai
+	EapMessage []byte
+}
+
+// Extended protocol discriminator, security header type and message type of a plain 5GMM message
+const gmmHeaderLen = 3
+
+// EncodeNetworkSliceSpecificAuthenticationMessage encodes a plain Network Slice-Specific Authentication Command or
+// Result, the S-NSSAI is an LV IE and the EAP message an LV-E IE
+func EncodeNetworkSliceSpecificAuthenticationMessage(msgType uint8, snssai models.Snssai, eapMessage []byte) []byte {
+	payload := []byte{nasMessage.Epd5GSMobilityManagementMessage, nas.SecurityHeaderTypePlainNas, msgType}
+	payload = append(payload, nasConvert.SnssaiToNas(snssai)...)
+	payload = binary.BigEndian.AppendUint16(payload, uint16(len(eapMessage)))
+	return append(payload, eapMessage...)
+}
+
+// decodeNetworkSliceSpecificAuthenticationComplete decodes a plain Network Slice-Specific Authentication
+// Complete, the message is returned with only its 5GMM header and nil is returned for any other message
+func decodeNetworkSliceSpecificAuthenticationComplete(payload []byte) (*nas.GmmMessage, *NssaaMessage,
+	error,
+) {
+	if len(payload) < gmmHeaderLen || payload[0] != nasMessage.Epd5GSMobilityManagementMessage ||
+		payload[2] != MsgTypeNetworkSliceSpecificAuthenticationComplete {
+		return nil, nil, nil
+	}
+
+	offset := gmmHeaderLen
+	if offset >= len(payload) {
+		return nil, nil, fmt.Errorf("network slice-specific authentication complete: S-NSSAI is missing")
+	}
+	snssaiLen := int(payload[offset])
+	if snssaiLen == 0 || snssaiLen > len(nasType.SNSSAI{}.Octet) || offset+1+snssaiLen > len(payload) {
+		return nil, nil, fmt.Errorf("network slice-specific authentication complete: invalid S-NSSAI length %d",
+			snssaiLen)
+	}
+	nasSnssai := nasType.NewSNSSAI(0)
+	nasSnssai.SetLen(uint8(snssaiLen))
+	copy(nasSnssai.Octet[:], payload[offset+1:offset+1+snssaiLen])
+	offset += 1 + snssaiLen
+
+	if offset+2 > len(payload) {
+		return nil, nil, fmt.Errorf("network slice-specific authentication complete: EAP message is missing")
+	}
+	eapLen := int(binary.BigEndian.Uint16(payload[offset : offset+2]))
+	offset += 2
+	if eapLen == 0 || offset+eapLen > len(payload) {
+		return nil, nil, fmt.Errorf("network slice-specific authentication complete: invalid EAP message length %d",
+			eapLen)
+	}
+
+	gmmMessage := nas.NewGmmMessage()
+	copy(gmmMessage.GmmHeader.Octet[:], payload[:gmmHeaderLen])
+	return gmmMessage, &NssaaMessage{
+		Snssai:     nasConvert.SnssaiToModels(nasSnssai),
+		EapMessage: append([]byte(nil), payload[offset:offset+eapLen]...),
+	}, nil
+}
+
+// EncodePendingNSSAI encodes the pending NSSAI IE of a Registration Accept with the S-NSSAIs waiting for NSSAA, nil
+// is returned if there is none
+func EncodePendingNSSAI(pendingNssai []models.Snssai) []byte {
+	if len(pendingNssai) == 0 {
+		return nil
+	}
+	var buf []byte
+	for _, snssai := range pendingNssai {
+		buf = append(buf, nasConvert.SnssaiToNas(snssai)...)
+	}
+	return append([]byte{RegistrationAcceptPendingNSSAIType, uint8(len(buf))}, buf...)
+}
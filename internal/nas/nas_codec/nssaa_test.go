@@ -0,0 +1,58 @@
+package nas_codec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/free5gc/openapi/models"
+)
+
+func TestDecodeNetworkSliceSpecificAuthenticationComplete(t *testing.T) {
+	header := []byte{0x7e, 0x00, MsgTypeNetworkSliceSpecificAuthenticationComplete}
+	snssai := []byte{0x04, 0x01, 0x01, 0x02, 0x03}
+	eapMessage := []byte{0x02, 0x01, 0x00, 0x05, 0x01}
+
+	testCases := []struct {
+		name    string
+		payload []byte
+		wantErr bool
+	}{
+		{"S-NSSAI missing", header, true},
+		{"zero S-NSSAI length", append(append([]byte{}, header...), 0x00), true},
+		{"S-NSSAI too long", append(append([]byte{}, header...), 0x09, 0x01, 0x01, 0x02, 0x03, 0x04, 0x05,
+			0x06, 0x07, 0x08, 0x00, 0x01, 0x01), true},
+		{"S-NSSAI truncated", append(append([]byte{}, header...), 0x04, 0x01, 0x01), true},
+		{"EAP message missing", append(append([]byte{}, header...), snssai...), true},
+		{"zero EAP message length", append(append(append([]byte{}, header...), snssai...), 0x00, 0x00), true},
+		{"EAP message truncated", append(append(append([]byte{}, header...), snssai...), 0x00, 0x06, 0x02), true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gmmMessage, nssaaMessage, err := decodeNetworkSliceSpecificAuthenticationComplete(tc.payload)
+			require.Error(t, err)
+			require.Nil(t, gmmMessage)
+			require.Nil(t, nssaaMessage)
+		})
+	}
+
+	payload := append(append(append([]byte{}, header...), snssai...), 0x00, 0x05)
+	payload = append(payload, eapMessage...)
+	gmmMessage, nssaaMessage, err := decodeNetworkSliceSpecificAuthenticationComplete(payload)
+	require.NoError(t, err)
+	require.Equal(t, MsgTypeNetworkSliceSpecificAuthenticationComplete, gmmMessage.GmmHeader.GetMessageType())
+	require.Equal(t, models.Snssai{Sst: 1, Sd: "010203"}, nssaaMessage.Snssai)
+	require.Equal(t, eapMessage, nssaaMessage.EapMessage)
+
+	// any other 5GMM message is left to the NAS library
+	gmmMessage, nssaaMessage, err = decodeNetworkSliceSpecificAuthenticationComplete([]byte{0x7e, 0x00, 0x55})
+	require.NoError(t, err)
+	require.Nil(t, gmmMessage)
+	require.Nil(t, nssaaMessage)
+}
+
+func TestEncodePendingNSSAI(t *testing.T) {
+	require.Nil(t, EncodePendingNSSAI(nil))
+	require.Equal(t, []byte{RegistrationAcceptPendingNSSAIType, 0x05, 0x04, 0x01, 0x01, 0x02, 0x03},
+		EncodePendingNSSAI([]models.Snssai{{Sst: 1, Sd: "010203"}}))
+}
@@ -0,0 +1,35 @@
+package nas_security
+
+import (
+	"github.com/free5gc/amf/internal/context"
+	"github.com/free5gc/nas/security"
+)
+
+// estimateULCount reconstructs the uplink NAS COUNT of a received message from its sequence number
+// (TS 24.501 4.4.3.1). A sequence number lower than the one of the highest NAS COUNT accepted is a late message if
+// it falls into the replay window, otherwise the overflow counter is incremented.
+func estimateULCount(ulCount *security.Count, window *context.NasReplayWindow, sequenceNumber uint8) {
+	windowSize := context.GetSelf().NasReplayWindowSize()
+	// the distance from the received sequence number to the highest one accepted, modulo 256
+	age := uint32(ulCount.SQN() - sequenceNumber)
+	late := window.Valid() && age != 0 && age < windowSize
+	switch {
+	case ulCount.SQN() > sequenceNumber && !late:
+		ulCount.SetOverflow(ulCount.Overflow() + 1)
+	case ulCount.SQN() < sequenceNumber && late && ulCount.Overflow() > 0:
+		// a late message sent before the overflow counter was incremented
+		ulCount.SetOverflow(ulCount.Overflow() - 1)
+	}
+	ulCount.SetSQN(sequenceNumber)
+}
+
+// checkNasCountExhausted requires a new NAS security context once the uplink or downlink NAS COUNT is about to
+// wrap around (TS 33.501 6.4.3.1)
+func checkNasCountExhausted(ue *context.AmfUe) {
+	if ue.NasCountRekeyingRequired || !ue.NasCountExhausted() {
+		return
+	}
+	ue.NASLog.Warnf("NAS COUNT is about to wrap around (ULCount: 0x%0x, DLCount: 0x%0x), "+
+		"a new NAS security context is required", ue.ULCount.Get(), ue.DLCount.Get())
+	ue.NasCountRekeyingRequired = true
+}
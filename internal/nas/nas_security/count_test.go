@@ -0,0 +1,82 @@
+package nas_security_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	amf_context "github.com/free5gc/amf/internal/context"
+	"github.com/free5gc/amf/internal/logger"
+	"github.com/free5gc/amf/internal/nas/nas_security"
+	"github.com/free5gc/nas"
+	"github.com/free5gc/nas/security"
+	"github.com/free5gc/openapi/models"
+)
+
+// configurationUpdateComplete is a plain Configuration Update Complete message
+var configurationUpdateComplete = []byte{0x7e, 0x00, 0x55}
+
+func newSecuredAmfUe() *amf_context.AmfUe {
+	ue := new(amf_context.AmfUe)
+	ue.NASLog = logger.NasLog
+	ue.SecurityContextAvailable = true
+	ue.IntegrityAlg = security.AlgIntegrity128NIA2
+	ue.CipheringAlg = security.AlgCiphering128NEA0
+	return ue
+}
+
+// protectUplink builds an uplink NAS message integrity protected and ciphered with the NAS COUNT given
+func protectUplink(t *testing.T, ue *amf_context.AmfUe, count uint32) []byte {
+	payload := append([]byte{uint8(count)}, configurationUpdateComplete...)
+	mac32, err := security.NASMacCalculate(ue.IntegrityAlg, ue.KnasInt, count, security.Bearer3GPP,
+		security.DirectionUplink, payload)
+	require.NoError(t, err)
+	pdu := []byte{0x7e, nas.SecurityHeaderTypeIntegrityProtectedAndCiphered}
+	pdu = append(pdu, mac32...)
+	return append(pdu, payload...)
+}
+
+func TestDecodeReplayProtection(t *testing.T) {
+	ue := newSecuredAmfUe()
+	decode := func(count uint32) error {
+		_, integrityProtected, err := nas_security.Decode(ue, models.AccessType__3_GPP_ACCESS,
+			protectUplink(t, ue, count), false)
+		if err == nil {
+			require.True(t, integrityProtected)
+		}
+		return err
+	}
+
+	require.NoError(t, decode(0))
+	require.Error(t, decode(0), "a NAS COUNT is accepted only once")
+	require.NoError(t, decode(2))
+	require.NoError(t, decode(1), "a late message within the replay window is accepted")
+	require.Error(t, decode(1))
+	require.Equal(t, uint32(2), ue.ULCount.Get(), "the ULCount is the highest NAS COUNT accepted")
+
+	// the sequence number wraps around
+	require.NoError(t, decode(0xfe))
+	require.NoError(t, decode(0x103))
+	require.NoError(t, decode(0xff), "a late message sent before the overflow counter was incremented")
+	require.Equal(t, uint32(0x103), ue.ULCount.Get())
+	require.Error(t, decode(0xf0), "a message older than the replay window is not accepted")
+}
+
+func TestDecodeNasCountExhausted(t *testing.T) {
+	ue := newSecuredAmfUe()
+	threshold := amf_context.GetSelf().NasCountRekeyingThreshold()
+	ue.ULCount.Set(uint16((threshold-1)>>8), uint8(threshold-1))
+
+	_, _, err := nas_security.Decode(ue, models.AccessType__3_GPP_ACCESS,
+		protectUplink(t, ue, threshold-1), false)
+	require.NoError(t, err)
+	require.False(t, ue.NasCountRekeyingRequired)
+
+	_, _, err = nas_security.Decode(ue, models.AccessType__3_GPP_ACCESS, protectUplink(t, ue, threshold), false)
+	require.NoError(t, err)
+	require.True(t, ue.NasCountRekeyingRequired, "a new NAS security context is required before the wrap-around")
+
+	ue.ResetNasCounts()
+	require.False(t, ue.NasCountRekeyingRequired)
+	require.Equal(t, uint32(0), ue.ULCount.Get())
+}
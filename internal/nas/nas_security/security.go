@@ -7,6 +7,8 @@ import (
 	"reflect"
 
 	"github.com/free5gc/amf/internal/context"
+	business_metrics "github.com/free5gc/amf/internal/metrics/business"
+	"github.com/free5gc/amf/internal/nas/nas_codec"
 	"github.com/free5gc/nas"
 	"github.com/free5gc/nas/nasConvert"
 	"github.com/free5gc/nas/nasMessage"
@@ -15,6 +17,14 @@ import (
 )
 
 func Encode(ue *context.AmfUe, msg *nas.Message, accessType models.AccessType) ([]byte, error) {
+	return EncodeWithOptionalIEs(ue, msg, accessType, nil)
+}
+
+// EncodeWithOptionalIEs encodes the NAS message like Encode, the optional IEs the NAS library does not support are
+// appended to the plain NAS message before it is protected
+func EncodeWithOptionalIEs(ue *context.AmfUe, msg *nas.Message, accessType models.AccessType, optionalIEs []byte) (
+	[]byte, error,
+) {
 	if msg == nil {
 		return nil, fmt.Errorf("NAS Message is nil")
 	}
@@ -45,21 +55,21 @@ func Encode(ue *context.AmfUe, msg *nas.Message, accessType models.AccessType) (
 			return nil, fmt.Errorf("NAS message type %d is requierd security, but security context is not available", msgType)
 		}
 		pdu, err := msg.PlainNasEncode()
-		return pdu, err
+		if err != nil {
+			return nil, err
+		}
+		return append(pdu, optionalIEs...), nil
 	} else {
 		// Security protected NAS Message
 		// a security protected NAS message must be integrity protected, and ciphering is optional
-		needCiphering := false
 		switch msg.SecurityHeader.SecurityHeaderType {
 		case nas.SecurityHeaderTypeIntegrityProtected:
 			ue.NASLog.Debugln("Security header type: Integrity Protected")
 		case nas.SecurityHeaderTypeIntegrityProtectedAndCiphered:
 			ue.NASLog.Debugln("Security header type: Integrity Protected And Ciphered")
-			needCiphering = true
 		case nas.SecurityHeaderTypeIntegrityProtectedWithNew5gNasSecurityContext:
 			ue.NASLog.Debugln("Security header type: Integrity Protected With New 5G Security Context")
-			ue.ULCount.Set(0, 0)
-			ue.DLCount.Set(0, 0)
+			ue.ResetNasCounts()
 		default:
 			return nil, fmt.Errorf("wrong security header type: 0x%0x", msg.SecurityHeader.SecurityHeaderType)
 		}
@@ -69,48 +79,64 @@ func Encode(ue *context.AmfUe, msg *nas.Message, accessType models.AccessType) (
 		if err != nil {
 			return nil, fmt.Errorf("plain NAS encode error: %+v", err)
 		}
+		payload = append(payload, optionalIEs...)
+		return protect(ue, accessType, msg.SecurityHeader.ProtocolDiscriminator, msg.SecurityHeader.SecurityHeaderType,
+			payload)
+	}
+}
 
-		ue.NASLog.Tracef("plain payload:\n%+v", hex.Dump(payload))
-		if needCiphering {
-			ue.NASLog.Debugf("Encrypt NAS message (algorithm: %+v, DLCount: 0x%0x)", ue.CipheringAlg, ue.DLCount.Get())
-			ue.NASLog.Tracef("NAS ciphering key: %0x", ue.KnasEnc)
-			if err = security.NASEncrypt(ue.CipheringAlg, ue.KnasEnc, ue.DLCount.Get(),
-				GetBearerType(accessType), security.DirectionDownlink, payload); err != nil {
-				return nil, fmt.Errorf("encrypt error: %+v", err)
-			}
+// Protect protects a plain 5GMM message the NAS library can not encode, it is integrity protected and ciphered
+func Protect(ue *context.AmfUe, accessType models.AccessType, payload []byte) ([]byte, error) {
+	if ue == nil || !ue.SecurityContextAvailable {
+		return nil, fmt.Errorf("NAS message is requierd security, but security context is not available")
+	}
+	return protect(ue, accessType, nasMessage.Epd5GSMobilityManagementMessage,
+		nas.SecurityHeaderTypeIntegrityProtectedAndCiphered, payload)
+}
+
+// protect ciphers the plain NAS message if required by the security header type, and adds the security header
+func protect(ue *context.AmfUe, accessType models.AccessType, protocolDiscriminator, securityHeaderType uint8,
+	payload []byte,
+) ([]byte, error) {
+	ue.NASLog.Tracef("plain payload:\n%+v", hex.Dump(payload))
+	if securityHeaderType == nas.SecurityHeaderTypeIntegrityProtectedAndCiphered {
+		ue.NASLog.Debugf("Encrypt NAS message (algorithm: %+v, DLCount: 0x%0x)", ue.CipheringAlg, ue.DLCount.Get())
+		if err := security.NASEncrypt(ue.CipheringAlg, ue.KnasEnc, ue.DLCount.Get(),
+			GetBearerType(accessType), security.DirectionDownlink, payload); err != nil {
+			return nil, fmt.Errorf("encrypt error: %+v", err)
 		}
+	}
 
-		// add sequece number
-		addsqn := []byte{}
-		addsqn = append(addsqn, []byte{ue.DLCount.SQN()}...)
-		addsqn = append(addsqn, payload...)
-		payload = addsqn
+	// add sequece number
+	addsqn := []byte{}
+	addsqn = append(addsqn, []byte{ue.DLCount.SQN()}...)
+	addsqn = append(addsqn, payload...)
+	payload = addsqn
 
-		ue.NASLog.Debugf("Calculate NAS MAC (algorithm: %+v, DLCount: 0x%0x)", ue.IntegrityAlg, ue.DLCount.Get())
-		ue.NASLog.Tracef("NAS integrity key: %0x", ue.KnasInt)
-		mac32, err := security.NASMacCalculate(ue.IntegrityAlg, ue.KnasInt, ue.DLCount.Get(),
-			GetBearerType(accessType), security.DirectionDownlink, payload)
-		if err != nil {
-			return nil, fmt.Errorf("MAC calcuate error: %+v", err)
-		}
-		// Add mac value
-		ue.NASLog.Tracef("MAC: 0x%08x", mac32)
-		addmac := []byte{}
-		addmac = append(addmac, mac32...)
-		addmac = append(addmac, payload...)
-		payload = addmac
-
-		// Add EPD and Security Type
-		msgSecurityHeader := []byte{msg.SecurityHeader.ProtocolDiscriminator, msg.SecurityHeader.SecurityHeaderType}
-		encodepayload := []byte{}
-		encodepayload = append(encodepayload, msgSecurityHeader...)
-		encodepayload = append(encodepayload, payload...)
-		payload = encodepayload
-
-		// Increase DL Count
-		ue.DLCount.AddOne()
-		return payload, nil
+	ue.NASLog.Debugf("Calculate NAS MAC (algorithm: %+v, DLCount: 0x%0x)", ue.IntegrityAlg, ue.DLCount.Get())
+	mac32, err := security.NASMacCalculate(ue.IntegrityAlg, ue.KnasInt, ue.DLCount.Get(),
+		GetBearerType(accessType), security.DirectionDownlink, payload)
+	if err != nil {
+		return nil, fmt.Errorf("MAC calcuate error: %+v", err)
 	}
+	// Add mac value
+	ue.NASLog.Tracef("MAC: 0x%08x", mac32)
+	addmac := []byte{}
+	addmac = append(addmac, mac32...)
+	addmac = append(addmac, payload...)
+	payload = addmac
+
+	// Add EPD and Security Type
+	msgSecurityHeader := []byte{protocolDiscriminator, securityHeaderType}
+	encodepayload := []byte{}
+	encodepayload = append(encodepayload, msgSecurityHeader...)
+	encodepayload = append(encodepayload, payload...)
+	payload = encodepayload
+
+	// Increase DL Count
+	ue.DLCount.AddOne()
+	checkNasCountExhausted(ue)
+	return payload, nil
 }
 
 /*
@@ -119,7 +145,7 @@ format is followed TS 24.501 9.1.1
 */
 func Decode(ue *context.AmfUe, accessType models.AccessType, payload []byte,
 	initialMessage bool,
-) (msg *nas.Message, integrityProtected bool, err error) {
+) (decoded *nas_codec.Message, integrityProtected bool, err error) {
 	if ue == nil {
 		return nil, false, fmt.Errorf("amfUe is nil")
 	}
@@ -131,8 +157,9 @@ func Decode(ue *context.AmfUe, accessType models.AccessType, payload []byte,
 	}
 
 	ulCountNew := ue.ULCount
+	ulCountWindow := ue.ULCountWindow
 
-	msg = new(nas.Message)
+	msg := new(nas.Message)
 	msg.ProtocolDiscriminator = payload[0]
 	msg.SecurityHeaderType = nas.GetSecurityHeaderType(payload) & 0x0f
 	ue.NASLog.Traceln("securityHeaderType is ", msg.SecurityHeaderType)
@@ -168,6 +195,7 @@ func Decode(ue *context.AmfUe, accessType models.AccessType, payload []byte,
 			ue.NASLog.Debugln("Security header type: Integrity Protected And Ciphered With New 5G Security Context")
 			ciphered = true
 			ulCountNew.Set(0, 0)
+			ulCountWindow.Reset()
 		default:
 			return nil, false, fmt.Errorf("wrong security header type: 0x%0x", msg.SecurityHeader.SecurityHeaderType)
 		}
@@ -177,14 +205,9 @@ func Decode(ue *context.AmfUe, accessType models.AccessType, payload []byte,
 		}
 
 		if ue.SecurityContextAvailable {
-			if ulCountNew.SQN() > sequenceNumber {
-				ue.NASLog.Debugf("set ULCount overflow")
-				ulCountNew.SetOverflow(ulCountNew.Overflow() + 1)
-			}
-			ulCountNew.SetSQN(sequenceNumber)
+			estimateULCount(&ulCountNew, &ulCountWindow, sequenceNumber)
 
 			ue.NASLog.Debugf("Calculate NAS MAC (algorithm: %+v, ULCount: 0x%0x)", ue.IntegrityAlg, ulCountNew.Get())
-			ue.NASLog.Tracef("NAS integrity key0x: %0x", ue.KnasInt)
 			var mac32 []byte
 			mac32, err = security.NASMacCalculate(ue.IntegrityAlg, ue.KnasInt, ulCountNew.Get(),
 				GetBearerType(accessType), security.DirectionUplink, payload)
@@ -198,6 +221,12 @@ func Decode(ue *context.AmfUe, accessType models.AccessType, payload []byte,
 				ue.NASLog.Tracef("cmac value: 0x%08x", mac32)
 				integrityProtected = true
 			}
+
+			// TS 24.501 4.4.3.1, a NAS message whose NAS COUNT was already accepted is discarded
+			if integrityProtected && ulCountWindow.Replayed(ulCountNew.Get(), context.GetSelf().NasReplayWindowSize()) {
+				business_metrics.IncrNasReplayedMsgCounter(accessType)
+				return nil, false, fmt.Errorf("NAS message replayed (ULCount: 0x%0x)", ulCountNew.Get())
+			}
 		} else {
 			ue.NASLog.Debugln("UE Security Context is not Available, so skip MAC verify")
 		}
@@ -207,7 +236,6 @@ func Decode(ue *context.AmfUe, accessType models.AccessType, payload []byte,
 				return nil, false, fmt.Errorf("NAS message is ciphered, but MAC verification failed")
 			}
 			ue.NASLog.Debugf("Decrypt NAS message (algorithm: %+v, ULCount: 0x%0x)", ue.CipheringAlg, ulCountNew.Get())
-			ue.NASLog.Tracef("NAS ciphering key: %0x", ue.KnasEnc)
 			// decrypt payload without sequence number (payload[1])
 			if err = security.NASEncrypt(ue.CipheringAlg, ue.KnasEnc, ulCountNew.Get(), GetBearerType(accessType),
 				security.DirectionUplink, payload[1:]); err != nil {
@@ -219,8 +247,12 @@ func Decode(ue *context.AmfUe, accessType models.AccessType, payload []byte,
 		payload = payload[1:]
 	}
 
-	err = msg.PlainNasDecode(&payload)
+	decoded, err = nas_codec.DecodePlainNas(msg, payload)
 	if err != nil {
+		// only a message protected by the current security context is answered with a 5GMM STATUS
+		if integrityProtected {
+			err = newUndecodableMessageError(payload, err)
+		}
 		return nil, false, err
 	}
 
@@ -346,9 +378,13 @@ func Decode(ue *context.AmfUe, accessType models.AccessType, payload []byte,
 	}
 
 	if integrityProtected {
-		ue.ULCount = ulCountNew
+		ulCountWindow.Accept(ulCountNew.Get())
+		ue.ULCountWindow = ulCountWindow
+		highest := ulCountWindow.Highest()
+		ue.ULCount.Set(uint16(highest>>8), uint8(highest))
+		checkNasCountExhausted(ue)
 	}
-	return msg, integrityProtected, nil
+	return decoded, integrityProtected, nil
 }
 
 // DecodePlainNas is used to decode plain nas.
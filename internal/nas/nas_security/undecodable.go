@@ -0,0 +1,59 @@
+package nas_security
+
+import (
+	"fmt"
+
+	"github.com/free5gc/amf/internal/nas/nas_codec"
+	"github.com/free5gc/nas"
+	"github.com/free5gc/nas/nasMessage"
+)
+
+// Extended protocol discriminator, security header type and message type of a plain 5GMM message
+const gmmHeaderLen = 3
+
+// UndecodableMessageError is returned for an uplink 5GMM message passing the integrity check which can not be
+// decoded, the AMF reports it to the UE with a 5GMM STATUS of the cause (TS 24.501 7.3, 7.4, 7.5)
+type UndecodableMessageError struct {
+	Cause uint8
+	Err   error
+}
+
+func (e *UndecodableMessageError) Error() string {
+	return fmt.Sprintf("undecodable NAS message (%s): %v", nasMessage.Cause5GMMToString(e.Cause), e.Err)
+}
+
+func (e *UndecodableMessageError) Unwrap() error {
+	return e.Err
+}
+
+// uplinkGmmMessageTypes are the 5GMM messages sent by the UE which are handled by the AMF
+var uplinkGmmMessageTypes = map[uint8]bool{
+	nas.MsgTypeRegistrationRequest:                              true,
+	nas.MsgTypeRegistrationComplete:                             true,
+	nas.MsgTypeDeregistrationRequestUEOriginatingDeregistration: true,
+	nas.MsgTypeDeregistrationAcceptUETerminatedDeregistration:   true,
+	nas.MsgTypeServiceRequest:                                   true,
+	nas.MsgTypeConfigurationUpdateComplete:                      true,
+	nas.MsgTypeAuthenticationResponse:                           true,
+	nas.MsgTypeAuthenticationFailure:                            true,
+	nas.MsgTypeIdentityResponse:                                 true,
+	nas.MsgTypeSecurityModeComplete:                             true,
+	nas.MsgTypeSecurityModeReject:                               true,
+	nas.MsgTypeStatus5GMM:                                       true,
+	nas.MsgTypeNotificationResponse:                             true,
+	nas.MsgTypeULNASTransport:                                   true,
+	nas_codec.MsgTypeNetworkSliceSpecificAuthenticationComplete: true,
+}
+
+// newUndecodableMessageError returns the error of a plain 5GMM message which can not be decoded, the message type is
+// unknown to the AMF or a mandatory IE is missing or invalid
+func newUndecodableMessageError(payload []byte, err error) error {
+	if len(payload) < gmmHeaderLen || payload[0] != nasMessage.Epd5GSMobilityManagementMessage {
+		return err
+	}
+	cause := nasMessage.Cause5GMMInvalidMandatoryInformation
+	if !uplinkGmmMessageTypes[payload[2]] {
+		cause = nasMessage.Cause5GMMMessageTypeNonExistentOrNotImplemented
+	}
+	return &UndecodableMessageError{Cause: cause, Err: err}
+}
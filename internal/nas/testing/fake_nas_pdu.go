@@ -654,7 +654,7 @@ func GetSecurityModeComplete(nasMessageContainer []uint8) []byte {
 	securityModeComplete := nasMessage.NewSecurityModeComplete(0)
 	securityModeComplete.ExtendedProtocolDiscriminator.SetExtendedProtocolDiscriminator(
 		nasMessage.Epd5GSMobilityManagementMessage)
-	// TODO: modify security header type if need security protected
+	// the security header is added by UE.EncodeUplink
 	securityModeComplete.SpareHalfOctetAndSecurityHeaderType.SetSecurityHeaderType(nas.SecurityHeaderTypePlainNas)
 	securityModeComplete.SpareHalfOctetAndSecurityHeaderType.SetSpareHalfOctet(0)
 	securityModeComplete.SecurityModeCompleteMessageIdentity.SetMessageType(nas.MsgTypeSecurityModeComplete)
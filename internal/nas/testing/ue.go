@@ -0,0 +1,236 @@
+package testing
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+
+	"github.com/free5gc/nas"
+	"github.com/free5gc/nas/nasMessage"
+	"github.com/free5gc/nas/security"
+	"github.com/free5gc/util/milenage"
+	"github.com/free5gc/util/ueauth"
+)
+
+var supiRegexp = regexp.MustCompile("(?:imsi|supi)-([0-9]{5,15})")
+
+// UE is the NAS layer of a UE for the tests, it authenticates the network with 5G AKA and keeps the 5G NAS security
+// context taken into use by the security mode control procedure to protect the uplink NAS messages and to check the
+// downlink ones (TS 24.501 4.4, TS 33.501 6.1.3.2, 6.7.2)
+type UE struct {
+	Supi   string
+	K      []byte
+	Opc    []byte
+	Bearer uint8
+
+	Kamf                     []byte
+	KnasEnc                  [16]uint8
+	KnasInt                  [16]uint8
+	CipheringAlg             uint8
+	IntegrityAlg             uint8
+	ULCount                  security.Count
+	DLCount                  security.Count
+	SecurityContextAvailable bool
+}
+
+// NewUE returns a UE without security context on the 3GPP access
+func NewUE(supi string, k, opc []byte) *UE {
+	return &UE{
+		Supi:   supi,
+		K:      k,
+		Opc:    opc,
+		Bearer: security.Bearer3GPP,
+	}
+}
+
+// Authenticate checks the AUTN of the Authentication Request and returns the RES* for the Authentication Response,
+// the K_AMF of the new partial native security context is derived from the K_SEAF and the ABBA (TS 33.501 6.1.3.2
+// step 7, A.4, A.6, A.7)
+func (ue *UE) Authenticate(req *nasMessage.AuthenticationRequest, servingNetworkName string) ([]byte, error) {
+	if req.AuthenticationParameterRAND == nil || req.AuthenticationParameterAUTN == nil {
+		return nil, fmt.Errorf("no RAND or AUTN in the authentication request")
+	}
+	randValue := req.AuthenticationParameterRAND.GetRANDValue()
+	autn := req.AuthenticationParameterAUTN.GetAUTN()
+	_, _, ik, ck, res, err := milenage.GenerateKeysWithAUTN(ue.Opc, ue.K, randValue[:], autn[:])
+	if err != nil {
+		return nil, err
+	}
+
+	ckIk := append(ck, ik...)
+	P0 := []byte(servingNetworkName)
+	sqnXorAk := autn[:6]
+	kausf, err := ueauth.GetKDFValue(ckIk, ueauth.FC_FOR_KAUSF_DERIVATION, P0, ueauth.KDFLen(P0), sqnXorAk,
+		ueauth.KDFLen(sqnXorAk))
+	if err != nil {
+		return nil, err
+	}
+	resStar, err := ueauth.GetKDFValue(ckIk, ueauth.FC_FOR_RES_STAR_XRES_STAR_DERIVATION, P0, ueauth.KDFLen(P0),
+		randValue[:], ueauth.KDFLen(randValue[:]), res, ueauth.KDFLen(res))
+	if err != nil {
+		return nil, err
+	}
+	kseaf, err := ueauth.GetKDFValue(kausf, ueauth.FC_FOR_KSEAF_DERIVATION, P0, ueauth.KDFLen(P0))
+	if err != nil {
+		return nil, err
+	}
+
+	groups := supiRegexp.FindStringSubmatch(ue.Supi)
+	if groups == nil {
+		return nil, fmt.Errorf("invalid SUPI %s", ue.Supi)
+	}
+	P0 = []byte(groups[1])
+	P1 := req.ABBA.GetABBAContents()
+	ue.Kamf, err = ueauth.GetKDFValue(kseaf, ueauth.FC_FOR_KAMF_DERIVATION, P0, ueauth.KDFLen(P0), P1,
+		ueauth.KDFLen(P1))
+	if err != nil {
+		return nil, err
+	}
+	return resStar[len(resStar)-16:], nil
+}
+
+// EncodeUplink adds the security header to the plain NAS message, the message is ciphered unless it is only
+// integrity protected. The uplink NAS COUNT is reset with a new 5G NAS security context (TS 24.501 4.4.3, 9.1.1).
+func (ue *UE) EncodeUplink(payload []byte, securityHeaderType uint8) ([]byte, error) {
+	if securityHeaderType == nas.SecurityHeaderTypePlainNas {
+		return payload, nil
+	}
+	if !ue.SecurityContextAvailable {
+		return nil, fmt.Errorf("no security context to protect the NAS message")
+	}
+	ciphered := false
+	switch securityHeaderType {
+	case nas.SecurityHeaderTypeIntegrityProtected:
+	case nas.SecurityHeaderTypeIntegrityProtectedAndCiphered:
+		ciphered = true
+	case nas.SecurityHeaderTypeIntegrityProtectedAndCipheredWithNew5gNasSecurityContext:
+		ciphered = true
+		ue.ULCount.Set(0, 0)
+	default:
+		return nil, fmt.Errorf("wrong security header type: 0x%0x", securityHeaderType)
+	}
+
+	payload = append([]byte{ue.ULCount.SQN()}, payload...)
+	if ciphered {
+		if err := security.NASEncrypt(ue.CipheringAlg, ue.KnasEnc, ue.ULCount.Get(), ue.Bearer,
+			security.DirectionUplink, payload[1:]); err != nil {
+			return nil, err
+		}
+	}
+	mac32, err := security.NASMacCalculate(ue.IntegrityAlg, ue.KnasInt, ue.ULCount.Get(), ue.Bearer,
+		security.DirectionUplink, payload)
+	if err != nil {
+		return nil, err
+	}
+	ue.ULCount.AddOne()
+
+	pdu := []byte{nasMessage.Epd5GSMobilityManagementMessage, securityHeaderType}
+	pdu = append(pdu, mac32...)
+	return append(pdu, payload...), nil
+}
+
+// DecodeDownlink decodes the downlink NAS message, the MAC of a security protected message is checked and the message
+// is deciphered. The 5G NAS security context of a Security Mode Command is taken into use before its MAC is checked
+// (TS 33.501 6.7.2).
+func (ue *UE) DecodeDownlink(pdu []byte) (*nas.Message, error) {
+	if len(pdu) < 2 {
+		return nil, fmt.Errorf("NAS PDU is too short")
+	}
+	msg := new(nas.Message)
+	msg.ProtocolDiscriminator = pdu[0]
+	msg.SecurityHeaderType = nas.GetSecurityHeaderType(pdu) & 0x0f
+	if msg.SecurityHeaderType == nas.SecurityHeaderTypePlainNas {
+		if err := msg.PlainNasDecode(&pdu); err != nil {
+			return nil, err
+		}
+		return msg, nil
+	}
+
+	if len(pdu) < 1+1+4+1+3 {
+		return nil, fmt.Errorf("NAS PDU is too short")
+	}
+	receivedMac32 := pdu[2:6]
+	msg.MessageAuthenticationCode = binary.BigEndian.Uint32(receivedMac32)
+	msg.SequenceNumber = pdu[6]
+	payload := append([]byte{}, pdu[6:]...)
+
+	ciphered := false
+	switch msg.SecurityHeaderType {
+	case nas.SecurityHeaderTypeIntegrityProtected:
+	case nas.SecurityHeaderTypeIntegrityProtectedAndCiphered:
+		ciphered = true
+	case nas.SecurityHeaderTypeIntegrityProtectedWithNew5gNasSecurityContext:
+		if err := ue.takeSecurityModeCommand(payload[1:]); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("wrong security header type: 0x%0x", msg.SecurityHeaderType)
+	}
+	if !ue.SecurityContextAvailable {
+		return nil, fmt.Errorf("no security context to check the NAS message")
+	}
+
+	dlCount := ue.DLCount
+	if msg.SequenceNumber < dlCount.SQN() {
+		dlCount.SetOverflow(dlCount.Overflow() + 1)
+	}
+	dlCount.SetSQN(msg.SequenceNumber)
+	mac32, err := security.NASMacCalculate(ue.IntegrityAlg, ue.KnasInt, dlCount.Get(), ue.Bearer,
+		security.DirectionDownlink, payload)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(mac32, receivedMac32) {
+		return nil, fmt.Errorf("NAS MAC verification failed (received: 0x%08x, expected: 0x%08x)", receivedMac32, mac32)
+	}
+	if ciphered {
+		if err = security.NASEncrypt(ue.CipheringAlg, ue.KnasEnc, dlCount.Get(), ue.Bearer,
+			security.DirectionDownlink, payload[1:]); err != nil {
+			return nil, err
+		}
+	}
+	ue.DLCount = dlCount
+	ue.DLCount.AddOne()
+
+	payload = payload[1:]
+	if err = msg.PlainNasDecode(&payload); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// takeSecurityModeCommand derives the NAS keys of the algorithms selected by the Security Mode Command and resets the
+// NAS COUNTs of the new 5G NAS security context (TS 33.501 A.8)
+func (ue *UE) takeSecurityModeCommand(payload []byte) error {
+	if ue.Kamf == nil {
+		return fmt.Errorf("no K_AMF for the new security context")
+	}
+	msg := new(nas.Message)
+	if err := msg.PlainNasDecode(&payload); err != nil {
+		return err
+	}
+	if msg.GmmMessage == nil || msg.GmmHeader.GetMessageType() != nas.MsgTypeSecurityModeCommand {
+		return fmt.Errorf("new security context taken into use without Security Mode Command")
+	}
+	algorithms := msg.SecurityModeCommand.SelectedNASSecurityAlgorithms
+	ue.CipheringAlg = algorithms.GetTypeOfCipheringAlgorithm()
+	ue.IntegrityAlg = algorithms.GetTypeOfIntegrityProtectionAlgorithm()
+
+	kenc, err := ueauth.GetKDFValue(ue.Kamf, ueauth.FC_FOR_ALGORITHM_KEY_DERIVATION, []byte{security.NNASEncAlg},
+		ueauth.KDFLen([]byte{security.NNASEncAlg}), []byte{ue.CipheringAlg}, ueauth.KDFLen([]byte{ue.CipheringAlg}))
+	if err != nil {
+		return err
+	}
+	kint, err := ueauth.GetKDFValue(ue.Kamf, ueauth.FC_FOR_ALGORITHM_KEY_DERIVATION, []byte{security.NNASIntAlg},
+		ueauth.KDFLen([]byte{security.NNASIntAlg}), []byte{ue.IntegrityAlg}, ueauth.KDFLen([]byte{ue.IntegrityAlg}))
+	if err != nil {
+		return err
+	}
+	copy(ue.KnasEnc[:], kenc[16:32])
+	copy(ue.KnasInt[:], kint[16:32])
+	ue.ULCount.Set(0, 0)
+	ue.DLCount.Set(0, 0)
+	ue.SecurityContextAvailable = true
+	return nil
+}
@@ -20,7 +20,7 @@ func Dispatch(conn net.Conn, msg []byte) {
 			return
 		}
 		ran.Log.Infof("RAN close the connection.")
-		ran.Remove()
+		removeTNLAssociation(ran, conn)
 		return
 	}
 
@@ -38,7 +38,12 @@ func Dispatch(conn net.Conn, msg []byte) {
 	if !ok {
 		isNGSetup := pdu.Present == ngapType.NGAPPDUPresentInitiatingMessage &&
 			pdu.InitiatingMessage.ProcedureCode.Value == ngapType.ProcedureCodeNGSetup
-		if isNGSetup {
+		if ran, ok = amfRanFindByGlobalRANNodeID(pdu); ok && isNGSetup {
+			ran = restartRan(ran, conn)
+		} else if ok {
+			// additional TNL association of a NG-RAN node
+			addTNLAssociation(ran, conn)
+		} else if isNGSetup {
 			addr := conn.RemoteAddr()
 			if addr == nil {
 				logger.NgapLog.Warn("Addr of new NG connection is nil")
@@ -53,6 +58,10 @@ func Dispatch(conn net.Conn, msg []byte) {
 	}
 
 	dispatchMain(ran, pdu)
+
+	if ran.TNLAssociationNum() > 1 {
+		bindUeTNLAssociation(ran, conn, pdu)
+	}
 }
 
 func HandleSCTPNotification(conn net.Conn, notification sctp.Notification) {
@@ -73,16 +82,16 @@ func HandleSCTPNotification(conn net.Conn, notification sctp.Notification) {
 		switch event.State() {
 		case sctp.SCTP_COMM_LOST:
 			ran.Log.Infof("SCTP state is SCTP_COMM_LOST, close the connection")
-			ran.Remove()
+			removeTNLAssociation(ran, conn)
 		case sctp.SCTP_SHUTDOWN_COMP:
 			ran.Log.Infof("SCTP state is SCTP_SHUTDOWN_COMP, close the connection")
-			ran.Remove()
+			removeTNLAssociation(ran, conn)
 		default:
 			ran.Log.Warnf("SCTP state[%+v] is not handled", event.State())
 		}
 	case sctp.SCTP_SHUTDOWN_EVENT:
 		ran.Log.Infof("SCTP_SHUTDOWN_EVENT notification, close the connection")
-		ran.Remove()
+		removeTNLAssociation(ran, conn)
 	default:
 		ran.Log.Warnf("Non handled notification type: 0x%x", notification.Type())
 	}
@@ -98,5 +107,5 @@ func HandleSCTPConnError(conn net.Conn) {
 		logger.NgapLog.Warnf("RAN context has been removed[addr: %+v]", conn.RemoteAddr())
 		return
 	}
-	ran.Remove()
+	removeTNLAssociation(ran, conn)
 }
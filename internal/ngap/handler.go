@@ -43,6 +43,8 @@ func handleNGSetupRequestMain(ran *context.AmfRan,
 		ran.Log.Tracef("PagingDRX[%d]", pagingDRX.Value)
 	}
 
+	// NG Setup replaces the configuration of a NG-RAN node which sets up again
+	ran.SupportedTAList = ran.SupportedTAList[:0]
 	for i := 0; i < len(supportedTAList.List); i++ {
 		supportedTAItem := supportedTAList.List[i]
 		tac := hex.EncodeToString(supportedTAItem.TAC.Value)
@@ -108,6 +110,7 @@ func handleNGSetupRequestMain(ran *context.AmfRan,
 	}
 	if cause.Present == ngapType.CausePresentNothing {
 		ngap_message.SendNGSetupResponse(ran, &criticalityDiagnostics)
+		advertiseTnlEndpoints(ran)
 	} else {
 		ngap_message.SendNGSetupFailure(ran, cause, &criticalityDiagnostics)
 	}
@@ -332,6 +335,11 @@ func handleUEContextReleaseCompleteMain(ran *context.AmfRan,
 		if err != nil {
 			ran.Log.Errorln(err.Error())
 		}
+		if ran.AnType == models.AccessType__3_GPP_ACCESS {
+			// the positioning procedures end with the NAS signalling connection of the UE
+			amfUe.RemovePositioningSessions("")
+			gmm_common.StartMobileReachableTimer(amfUe)
+		}
 	case context.UeContextReleaseUeContext:
 		ran.Log.Infof("Release UE[%s] Context : Release Ue Context", amfUe.Supi)
 		amfUe.Lock.Lock()
@@ -1011,6 +1019,11 @@ func handleInitialContextSetupResponseMain(ran *context.AmfRan,
 
 	if ranUe.Ran.AnType == models.AccessType_NON_3_GPP_ACCESS {
 		ngap_message.SendDownlinkNasTransport(ranUe, amfUe.RegistrationAcceptForNon3GPPAccess, nil)
+	} else {
+		// the NG-RAN location reporting does not survive CM-IDLE, configure it again
+		amfUe.Lock.Lock()
+		gmm_common.ConfigureLocationReporting(amfUe)
+		amfUe.Lock.Unlock()
 	}
 
 	if criticalityDiagnostics != nil {
@@ -1225,6 +1238,7 @@ func handleHandoverNotifyMain(ran *context.AmfRan,
 			business_metrics.HANDOVER_NOT_YET_IMPLEMENT_N2_HANDOVER_BETWEEN_AMF, targetUe.HandOverStartTime)
 	} else {
 		ran.Log.Info("Handle Handover notification Finshed")
+		sourceUe.StopTRelocOverall()
 		for _, pduSessionID := range targetUe.SuccessPduSessionId {
 			smContext, ok := amfUe.SmContextFindByPDUSessionID(pduSessionID)
 			if !ok {
@@ -1242,6 +1256,7 @@ func handleHandoverNotifyMain(ran *context.AmfRan,
 			utils.SuccessMetric,
 			business_metrics.HANDOVER_EMPTY_CAUSE, targetUe.HandOverStartTime)
 		gmm_common.AttachRanUeToAmfUeAndReleaseOldHandover(amfUe, sourceUe, targetUe)
+		retransmitUndeliveredNasPdus(targetUe, context.OnGoingProcedureN2Handover)
 	}
 
 	// TODO: The UE initiates Mobility Registration Update procedure as described in clause 4.2.2.2.2.
@@ -1389,16 +1404,19 @@ func handlePathSwitchRequestMain(ran *context.AmfRan,
 		}
 		ngap_message.SendPathSwitchRequestAcknowledge(ranUe, pduSessionResourceSwitchedList,
 			pduSessionResourceReleasedListPSAck, false, nil, nil, nil, xnHandoverStartTime)
+		retransmitUndeliveredNasPdus(ranUe, context.OnGoingProcedureXnHandover)
 	} else if len(pduSessionResourceReleasedListPSFail.List) > 0 {
 		ngap_message.SendPathSwitchRequestFailure(ran, sourceAMFUENGAPID.Value, rANUENGAPID.Value,
 			&pduSessionResourceReleasedListPSFail, nil, business_metrics.HANDOVER_PDU_SESSION_RES_REL_LIST_ERR,
 			xnHandoverStartTime)
+		abortUndeliveredNasPdus(ranUe, context.OnGoingProcedureXnHandover)
 		// Can iterate through the list of pduSession and increment
 	} else {
 		// TODO: change the cause error
 		ngap_message.SendPathSwitchRequestFailure(ran, sourceAMFUENGAPID.Value, rANUENGAPID.Value,
 			nil, nil, business_metrics.HANDOVER_EMPTY_CAUSE,
 			xnHandoverStartTime)
+		abortUndeliveredNasPdus(ranUe, context.OnGoingProcedureXnHandover)
 	}
 }
 
@@ -1511,6 +1529,7 @@ func handleHandoverRequestAcknowledgeMain(ran *context.AmfRan,
 	} else {
 		ran.Log.Tracef("Source: RanUeNgapID[%d] AmfUeNgapID[%d]", sourceUe.RanUeNgapId, sourceUe.AmfUeNgapId)
 		ran.Log.Tracef("Target: RanUeNgapID[%d] AmfUeNgapID[%d]", targetUe.RanUeNgapId, targetUe.AmfUeNgapId)
+		sourceUe.StopTRelocPrep()
 		if len(pduSessionResourceHandoverList.List) == 0 {
 			targetUe.Log.Info("Handle Handover Preparation Failure [HoFailure In Target5GC NgranNode Or TargetSystem]")
 			cause := &ngapType.Cause{
@@ -1525,6 +1544,7 @@ func handleHandoverRequestAcknowledgeMain(ran *context.AmfRan,
 		}
 		ngap_message.SendHandoverCommand(sourceUe, pduSessionResourceHandoverList, pduSessionResourceToReleaseList,
 			*targetToSourceTransparentContainer, nil)
+		startTRelocOverall(sourceUe)
 	}
 }
 
@@ -1560,6 +1580,7 @@ func handleHandoverFailureMain(ran *context.AmfRan,
 		// TODO: handle N2 Handover between AMF
 		ran.Log.Error("N2 Handover between AMF has not been implemented yet")
 	} else {
+		sourceUe.StopTRelocPrep()
 		amfUe := targetUe.AmfUe
 		if amfUe != nil {
 			amfUe.SmContextList.Range(func(key, value interface{}) bool {
@@ -1588,6 +1609,7 @@ func handleHandoverFailureMain(ran *context.AmfRan,
 			}
 		}
 		ngap_message.SendHandoverPreparationFailure(sourceUe, *sendCause, criticalityDiagnostics)
+		retransmitUndeliveredNasPdus(sourceUe, context.OnGoingProcedureN2Handover)
 	}
 
 	ngap_message.SendUEContextReleaseCommand(targetUe, context.UeContextReleaseHandover, causePresent, causeValue)
@@ -1703,8 +1725,12 @@ func handleHandoverRequiredMain(ran *context.AmfRan,
 			ngap_message.SendHandoverPreparationFailure(sourceUe, *cause, nil)
 			return
 		}
-		// Update NH
-		amfUe.UpdateNH()
+		// Update NH, the NH chain restarts from a K_AMF' derived for the handover
+		if amfUe.KamfChangeIndicationRequired() {
+			amfUe.UpdateSecurityContext(models.AccessType__3_GPP_ACCESS)
+		} else {
+			amfUe.UpdateNH()
+		}
 		if cause == nil {
 			sourceUe.Log.Warnf("Cause is nil")
 			cause = &ngapType.Cause{
@@ -1716,6 +1742,9 @@ func handleHandoverRequiredMain(ran *context.AmfRan,
 		}
 		ngap_message.SendHandoverRequest(sourceUe, targetRan, *cause, pduSessionReqList,
 			*sourceToTargetTransparentContainer, false)
+		if sourceUe.TargetUe != nil {
+			startTRelocPrep(sourceUe)
+		}
 	}
 }
 
@@ -1728,6 +1757,8 @@ func handleHandoverCancelMain(ran *context.AmfRan,
 	if cause != nil {
 		causePresent, causeValue = printAndGetCause(ran, cause)
 	}
+	sourceUe.StopTRelocPrep()
+	sourceUe.StopTRelocOverall()
 	targetUe := sourceUe.TargetUe
 	if targetUe == nil {
 		// Described in (23.502 4.11.1.2.3) step 2
@@ -1755,6 +1786,7 @@ func handleHandoverCancelMain(ran *context.AmfRan,
 		}
 		ngap_message.SendUEContextReleaseCommand(targetUe, context.UeContextReleaseHandover, causePresent, causeValue)
 		ngap_message.SendHandoverCancelAcknowledge(sourceUe, nil)
+		retransmitUndeliveredNasPdus(sourceUe, context.OnGoingProcedureN2Handover)
 	}
 }
 
@@ -1787,9 +1819,15 @@ func handleNASNonDeliveryIndicationMain(ran *context.AmfRan,
 		printAndGetCause(ran, cause)
 	}
 
-	if nASPDU != nil {
-		amf_nas.HandleNAS(ranUe, ngapType.ProcedureCodeNASNonDeliveryIndication, nASPDU.Value, false)
+	amfUe := ranUe.AmfUe
+	if amfUe == nil {
+		ranUe.Log.Error("AmfUe is nil")
+		return
 	}
+	if nASPDU == nil || cause == nil {
+		return
+	}
+	handleUndeliveredNasPdu(ranUe, amfUe, nASPDU.Value, *cause)
 }
 
 func handleRANConfigurationUpdateMain(ran *context.AmfRan,
@@ -1799,6 +1837,8 @@ func handleRANConfigurationUpdateMain(ran *context.AmfRan,
 	var cause ngapType.Cause
 
 	if supportedTAList != nil {
+		// the supported TA list replaces the previous one (TS 38.413 8.7.2.2)
+		ran.SupportedTAList = ran.SupportedTAList[:0]
 		for i := 0; i < len(supportedTAList.List); i++ {
 			supportedTAItem := supportedTAList.List[i]
 			tac := hex.EncodeToString(supportedTAItem.TAC.Value)
@@ -1920,29 +1960,27 @@ func handleLocationReportMain(ran *context.AmfRan,
 ) {
 	ranUe.UpdateLocation(userLocationInformation)
 
+	amfUe := ranUe.AmfUe
 	if locationReportingRequestType != nil {
 		ranUe.Log.Tracef("Report Area[%d]", locationReportingRequestType.ReportArea.Value)
 
 		switch locationReportingRequestType.EventType.Value {
 		case ngapType.EventTypePresentDirect:
 			ranUe.Log.Trace("To report directly")
+			if amfUe != nil {
+				notifyLocationReport(ranUe, amfUe)
+			}
 
 		case ngapType.EventTypePresentChangeOfServeCell:
 			ranUe.Log.Trace("To report upon change of serving cell")
+			if amfUe != nil {
+				notifyLocationReport(ranUe, amfUe)
+			}
 
 		case ngapType.EventTypePresentUePresenceInAreaOfInterest:
 			ranUe.Log.Trace("To report UE presence in the area of interest")
-			if uEPresenceInAreaOfInterestList != nil {
-				for _, uEPresenceInAreaOfInterestItem := range uEPresenceInAreaOfInterestList.List {
-					uEPresence := uEPresenceInAreaOfInterestItem.UEPresence.Value
-					referenceID := uEPresenceInAreaOfInterestItem.LocationReportingReferenceID.Value
-
-					for _, AOIitem := range locationReportingRequestType.AreaOfInterestList.List {
-						if referenceID == AOIitem.LocationReportingReferenceID.Value {
-							ran.Log.Tracef("uEPresence[%d], presence AOI ReferenceID[%d]", uEPresence, referenceID)
-						}
-					}
-				}
+			if uEPresenceInAreaOfInterestList != nil && amfUe != nil {
+				handleUEPresenceInAreaOfInterest(ranUe, amfUe, uEPresenceInAreaOfInterestList)
 			}
 
 		case ngapType.EventTypePresentStopChangeOfServeCell:
@@ -2009,8 +2047,25 @@ func handleAMFConfigurationUpdateFailureMain(ran *context.AmfRan,
 }
 
 func handleAMFConfigurationUpdateAcknowledgeMain(ran *context.AmfRan,
+	aMFTNLAssociationSetupList *ngapType.AMFTNLAssociationSetupList,
+	aMFTNLAssociationFailedToSetupList *ngapType.TNLAssociationList,
 	criticalityDiagnostics *ngapType.CriticalityDiagnostics,
 ) {
+	if aMFTNLAssociationSetupList != nil {
+		for _, item := range aMFTNLAssociationSetupList.List {
+			ran.Log.Infof("TNL association to AMF endpoint[%s] set up",
+				tnlAssociationAddressToString(item.AMFTNLAssociationAddress))
+		}
+	}
+
+	if aMFTNLAssociationFailedToSetupList != nil {
+		for _, item := range aMFTNLAssociationFailedToSetupList.List {
+			ran.Log.Warnf("TNL association to AMF endpoint[%s] failed to set up",
+				tnlAssociationAddressToString(item.TNLAssociationAddress))
+			printAndGetCause(ran, &item.Cause)
+		}
+	}
+
 	if criticalityDiagnostics != nil {
 		printCriticalityDiagnostics(ran, criticalityDiagnostics)
 	}
@@ -2106,6 +2161,61 @@ func handleCellTrafficTraceMain(ran *context.AmfRan,
 	// to the Trace Collection Entity.
 }
 
+func handleSecondaryRATDataUsageReportMain(ran *context.AmfRan,
+	ranUe *context.RanUe,
+	pDUSessionResourceSecondaryRATUsageList *ngapType.PDUSessionResourceSecondaryRATUsageList,
+	handoverFlag *ngapType.HandoverFlag,
+) {
+	if ranUe == nil {
+		ran.Log.Error("ranUe is nil")
+		return
+	}
+
+	amfUe := ranUe.AmfUe
+	if amfUe == nil {
+		ranUe.Log.Error("amfUe is nil")
+		return
+	}
+
+	// TS 23.502 4.9.1.3.2: during N2 handover the source NG-RAN reports the usage with the HandoverFlag set,
+	// the report still belongs to the PDU sessions of the source side and is forwarded the same way
+	if handoverFlag != nil && handoverFlag.Value == ngapType.HandoverFlagPresentHandoverPreparation {
+		ranUe.Log.Debugf("SecondaryRATDataUsageReport received during handover preparation")
+	}
+
+	if pDUSessionResourceSecondaryRATUsageList != nil {
+		ranUe.Log.Infof("Send SecondaryRATDataUsageReportTransfer to SMF")
+		forwardSecondaryRATUsageList(ranUe, amfUe, pDUSessionResourceSecondaryRATUsageList)
+	}
+}
+
+// forwardSecondaryRATUsageList sends the SecondaryRATDataUsageReportTransfer of each PDU session to the SMF
+// owning the SmContext, so that the secondary RAT volume can be charged.
+func forwardSecondaryRATUsageList(ranUe *context.RanUe, amfUe *context.AmfUe,
+	usageList *ngapType.PDUSessionResourceSecondaryRATUsageList,
+) {
+	for _, item := range usageList.List {
+		pduSessionID := int32(item.PDUSessionID.Value)
+		transfer := item.SecondaryRATDataUsageReportTransfer
+		smContext, ok := amfUe.SmContextFindByPDUSessionID(pduSessionID)
+		if !ok {
+			ranUe.Log.Warnf("SmContext[PDU Session ID:%d] not found", pduSessionID)
+			continue
+		}
+
+		_, responseErr, problemDetail, err := consumer.GetConsumer().SendUpdateSmContextN2Info(amfUe, smContext,
+			models.N2SmInfoType_SECONDARY_RAT_USAGE, transfer)
+		if err != nil {
+			ranUe.Log.Errorf("SendUpdateSmContextN2Info[SecondaryRATDataUsageReport] Error: %+v", err)
+		} else if responseErr != nil && responseErr.JsonData.Error != nil {
+			ranUe.Log.Errorf("SendUpdateSmContextN2Info[SecondaryRATDataUsageReport] Error: %+v",
+				responseErr.JsonData.Error.Cause)
+		} else if problemDetail != nil {
+			ranUe.Log.Errorf("SendUpdateSmContextN2Info[SecondaryRATDataUsageReport] Failed: %+v", problemDetail)
+		}
+	}
+}
+
 func printAndGetCause(ran *context.AmfRan, cause *ngapType.Cause) (present int, value aper.Enumerated) {
 	present = cause.Present
 	switch cause.Present {
@@ -277,18 +277,13 @@ func handlerAMFConfigurationUpdateAcknowledge(ran *context.AmfRan, successfulOut
 		return
 	}
 
-	if aMFTNLAssociationSetupList != nil {
-		ran.Log.Warn("IE AMF-TNLAssociationSetupList is not implemented")
-	}
-	if aMFTNLAssociationFailedToSetupList != nil {
-		ran.Log.Warn("IE TNLAssociationList is not implemented")
-	}
-
 	metricStatusOk = true
 
 	// func handleAMFConfigurationUpdateAcknowledgeMain(ran *context.AmfRan,
+	//	aMFTNLAssociationSetupList *ngapType.AMFTNLAssociationSetupList,
+	//	aMFTNLAssociationFailedToSetupList *ngapType.TNLAssociationList,
 	//	criticalityDiagnostics *ngapType.CriticalityDiagnostics) {
-	handleAMFConfigurationUpdateAcknowledgeMain(ran, criticalityDiagnostics /* may be nil */)
+	handleAMFConfigurationUpdateAcknowledgeMain(ran, aMFTNLAssociationSetupList /* may be nil */, aMFTNLAssociationFailedToSetupList /* may be nil */, criticalityDiagnostics /* may be nil */)
 }
 
 func handlerAMFConfigurationUpdateFailure(ran *context.AmfRan, unsuccessfulOutcome *ngapType.UnsuccessfulOutcome) {
@@ -9575,21 +9570,6 @@ func handlerSecondaryRATDataUsageReport(ran *context.AmfRan, initiatingMessage *
 	handleSecondaryRATDataUsageReportMain(ran, ranUe /* may be nil */, pDUSessionResourceSecondaryRATUsageList /* may be nil */, handoverFlag /* may be nil */)
 }
 
-func handleSecondaryRATDataUsageReportMain(ran *context.AmfRan, ranUe *context.RanUe, pDUSessionResourceSecondaryRATUsageList *ngapType.PDUSessionResourceSecondaryRATUsageList, handoverFlag *ngapType.HandoverFlag) {
-	ran.Log.Error("Handle SecondaryRATDataUsageReport: not implemented")
-	procedureCode := ngapType.ProcedureCodeSecondaryRATDataUsageReport
-	triggeringMessage := ngapType.TriggeringMessagePresentInitiatingMessage
-	procedureCriticality := ngapType.CriticalityPresentIgnore
-	notImplementedCriticalityDiagnostics := buildCriticalityDiagnostics(&procedureCode, &triggeringMessage, &procedureCriticality, nil)
-	notImplementedCause := &ngapType.Cause{
-		Present: ngapType.CausePresentProtocol,
-		Protocol: &ngapType.CauseProtocol{
-			Value: ngapType.CauseProtocolPresentUnspecified,
-		},
-	}
-	ngap_message.SendErrorIndication(ran, nil, nil, notImplementedCause, &notImplementedCriticalityDiagnostics)
-}
-
 func handlerTraceFailureIndication(ran *context.AmfRan, initiatingMessage *ngapType.InitiatingMessage) {
 	var aMFUENGAPID *ngapType.AMFUENGAPID
 	var rANUENGAPID *ngapType.RANUENGAPID
@@ -2,8 +2,12 @@ package ngap
 
 import (
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/google/uuid"
@@ -13,11 +17,14 @@ import (
 	amf_context "github.com/free5gc/amf/internal/context"
 	"github.com/free5gc/amf/internal/logger"
 	nastesting "github.com/free5gc/amf/internal/nas/testing"
+	ngap_message "github.com/free5gc/amf/internal/ngap/message"
 	ngaptesting "github.com/free5gc/amf/internal/ngap/testing"
+	"github.com/free5gc/amf/internal/sbi/consumer"
 	"github.com/free5gc/amf/pkg/factory"
 	"github.com/free5gc/aper"
 	"github.com/free5gc/nas/nasMessage"
 	"github.com/free5gc/nas/nasType"
+	"github.com/free5gc/nas/security"
 	"github.com/free5gc/ngap"
 	"github.com/free5gc/ngap/ngapType"
 	"github.com/free5gc/openapi/models"
@@ -121,10 +128,6 @@ func NewAmfContext(amfCtx *amf_context.AMFContext) {
 			IntegrityOrder: []uint8{0x02},
 			CipheringOrder: []uint8{0x00},
 		},
-		NetworkName: factory.NetworkName{
-			Full:  "free5GC",
-			Short: "free",
-		},
 		T3502Value:             720,
 		T3512Value:             3600,
 		Non3gppDeregTimerValue: 3240,
@@ -154,6 +157,10 @@ func NewAmfContext(amfCtx *amf_context.AMFContext) {
 			MaxRetryTimes: 4,
 		},
 	}
+	amfCtx.SetNetworkName(factory.NetworkName{
+		Full:  "free5GC",
+		Short: "free",
+	})
 }
 
 func BuildInitialUEMessage(ranUeNgapID int64, nasPdu []byte, fiveGSTmsi string) ngapType.NGAPPDU {
@@ -431,3 +438,367 @@ func TestHandleInitialUEMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleSecondaryRATDataUsageReport(t *testing.T) {
+	amfSelf := amf_context.GetSelf()
+	NewAmfContext(amfSelf)
+	_, err := consumer.NewConsumer(nil)
+	require.NoError(t, err)
+
+	type smfRequest struct {
+		path string
+		body string
+	}
+	rcvCh := make(chan smfRequest, 2)
+	smf := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, errRead := io.ReadAll(r.Body)
+		require.NoError(t, errRead)
+		rcvCh <- smfRequest{path: r.URL.Path, body: string(body)}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	// The SBI clients talk HTTP/2 over cleartext
+	smf.Config.Protocols = new(http.Protocols)
+	smf.Config.Protocols.SetUnencryptedHTTP2(true)
+	smf.Start()
+	defer smf.Close()
+
+	connStub := new(ngaptesting.SctpConnStub)
+	ran := NewAmfRan(connStub)
+	ranUe, err := ran.NewRanUe(1)
+	require.NoError(t, err)
+	amfUe := amfSelf.NewAmfUe("imsi-208930000000001")
+	amfUe.AttachRanUe(ranUe)
+
+	smContext := amf_context.NewSmContext(1)
+	smContext.SetSmfUri(smf.URL)
+	smContext.SetSmContextRef("secondary-rat-ref")
+	amfUe.StoreSmContext(1, smContext)
+
+	usageList := &ngapType.PDUSessionResourceSecondaryRATUsageList{
+		List: []ngapType.PDUSessionResourceSecondaryRATUsageItem{
+			{
+				PDUSessionID:                        ngapType.PDUSessionID{Value: 1},
+				SecondaryRATDataUsageReportTransfer: aper.OctetString{0x40, 0x01, 0x02},
+			},
+			{
+				// Unknown PDU session, must not be forwarded
+				PDUSessionID:                        ngapType.PDUSessionID{Value: 2},
+				SecondaryRATDataUsageReportTransfer: aper.OctetString{0x40, 0x03, 0x04},
+			},
+		},
+	}
+	handoverFlag := &ngapType.HandoverFlag{Value: ngapType.HandoverFlagPresentHandoverPreparation}
+	handleSecondaryRATDataUsageReportMain(ran, ranUe, usageList, handoverFlag)
+
+	require.Len(t, rcvCh, 1)
+	rcv := <-rcvCh
+	require.Equal(t, "/nsmf-pdusession/v1/sm-contexts/secondary-rat-ref/modify", rcv.path)
+	require.Contains(t, rcv.body, string(models.N2SmInfoType_SECONDARY_RAT_USAGE))
+	require.Contains(t, rcv.body, string([]byte{0x40, 0x01, 0x02}))
+	// No ErrorIndication is sent back to the NG-RAN any more
+	require.Empty(t, connStub.MsgList)
+}
+
+func TestHandleLocationReportUEPresenceInAreaOfInterest(t *testing.T) {
+	amfSelf := amf_context.GetSelf()
+	NewAmfContext(amfSelf)
+
+	rcvCh := make(chan models.AmfEventNotification, 2)
+	nf := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notification models.AmfEventNotification
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&notification))
+		rcvCh <- notification
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	nf.Config.Protocols = new(http.Protocols)
+	nf.Config.Protocols.SetUnencryptedHTTP2(true)
+	nf.Start()
+	defer nf.Close()
+
+	connStub := new(ngaptesting.SctpConnStub)
+	ran := NewAmfRan(connStub)
+	ranUe, err := ran.NewRanUe(1)
+	require.NoError(t, err)
+	amfUe := amfSelf.NewAmfUe("imsi-208930000000002")
+	amfUe.AttachRanUe(ranUe)
+
+	amfUe.EventSubscriptionsInfo["1"] = &amf_context.AmfUeEventSubscription{
+		EventSubscription: &models.ExtAmfEventSubscription{
+			EventList: []models.AmfEvent{
+				{Type: models.AmfEventType_PRESENCE_IN_AOI_REPORT, RefId: 7},
+			},
+			EventNotifyUri:      nf.URL + "/notify",
+			NotifyCorrelationId: "aoi-correlation",
+		},
+	}
+	aoi, err := amfUe.NewAreaOfInterest("1", "", models.PresenceInfo{
+		TrackingAreaList: []models.Tai{
+			{PlmnId: &models.PlmnId{Mcc: "208", Mnc: "93"}, Tac: "000001"},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, models.PresenceState_UNKNOWN, aoi.PresenceInfo.PresenceState)
+
+	uePresenceList := &ngapType.UEPresenceInAreaOfInterestList{
+		List: []ngapType.UEPresenceInAreaOfInterestItem{
+			{
+				LocationReportingReferenceID: ngapType.LocationReportingReferenceID{Value: aoi.ReferenceID},
+				UEPresence:                   ngapType.UEPresence{Value: ngapType.UEPresencePresentIn},
+			},
+			{
+				// Unknown area of interest, must be ignored
+				LocationReportingReferenceID: ngapType.LocationReportingReferenceID{Value: 64},
+				UEPresence:                   ngapType.UEPresence{Value: ngapType.UEPresencePresentOut},
+			},
+		},
+	}
+	requestType := &ngapType.LocationReportingRequestType{
+		EventType: ngapType.EventType{Value: ngapType.EventTypePresentUePresenceInAreaOfInterest},
+	}
+	handleLocationReportMain(ran, ranUe, nil, uePresenceList, requestType)
+
+	require.Len(t, rcvCh, 1)
+	notification := <-rcvCh
+	require.Equal(t, "aoi-correlation", notification.NotifyCorrelationId)
+	require.Len(t, notification.ReportList, 1)
+	report := notification.ReportList[0]
+	require.Equal(t, models.AmfEventType_PRESENCE_IN_AOI_REPORT, report.Type)
+	require.Equal(t, int32(7), report.RefId)
+	require.Len(t, report.AreaList, 1)
+	require.Equal(t, models.PresenceState_IN_AREA, report.AreaList[0].PresenceInfo.PresenceState)
+
+	aoi, ok := amfUe.AreaOfInterestFindByReferenceID(aoi.ReferenceID)
+	require.True(t, ok)
+	require.Equal(t, models.PresenceState_IN_AREA, aoi.PresenceInfo.PresenceState)
+
+	// The same presence is not reported twice
+	handleLocationReportMain(ran, ranUe, nil, uePresenceList, requestType)
+	require.Empty(t, rcvCh)
+}
+
+func TestHandleLocationReportPresenceReportingAreaUpdate(t *testing.T) {
+	amfSelf := amf_context.GetSelf()
+	NewAmfContext(amfSelf)
+	_, err := consumer.NewConsumer(nil)
+	require.NoError(t, err)
+
+	rcvCh := make(chan models.PcfAmPolicyControlPolicyAssociationUpdateRequest, 2)
+	pcf := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/npcf-am-policy-control/v1/policies/policy-1/update", r.URL.Path)
+		var updateReq models.PcfAmPolicyControlPolicyAssociationUpdateRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&updateReq))
+		rcvCh <- updateReq
+		// pra-1 is removed and pra-2 is added
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"triggers":["PRA_CH"],"pras":{"pra-1":null,` +
+			`"pra-2":{"praId":"pra-2","trackingAreaList":[{"plmnId":{"mcc":"208","mnc":"93"},"tac":"000002"}]}}}`))
+		require.NoError(t, err)
+	}))
+	pcf.Config.Protocols = new(http.Protocols)
+	pcf.Config.Protocols.SetUnencryptedHTTP2(true)
+	pcf.Start()
+	defer pcf.Close()
+
+	connStub := new(ngaptesting.SctpConnStub)
+	ran := NewAmfRan(connStub)
+	ranUe, err := ran.NewRanUe(1)
+	require.NoError(t, err)
+	amfUe := amfSelf.NewAmfUe("imsi-208930000000003")
+	amfUe.AttachRanUe(ranUe)
+	amfUe.PcfUri = pcf.URL
+	amfUe.PolicyAssociationId = "policy-1"
+	amfUe.AmPolicyAssociation = &models.PcfAmPolicyControlPolicyAssociation{
+		Triggers: []models.PcfAmPolicyControlRequestTrigger{models.PcfAmPolicyControlRequestTrigger_PRA_CH},
+	}
+	aoi, err := amfUe.UpdatePresenceReportingArea("pra-1", models.PresenceInfo{
+		TrackingAreaList: []models.Tai{
+			{PlmnId: &models.PlmnId{Mcc: "208", Mnc: "93"}, Tac: "000001"},
+		},
+	})
+	require.NoError(t, err)
+
+	uePresenceList := &ngapType.UEPresenceInAreaOfInterestList{
+		List: []ngapType.UEPresenceInAreaOfInterestItem{
+			{
+				LocationReportingReferenceID: ngapType.LocationReportingReferenceID{Value: aoi.ReferenceID},
+				UEPresence:                   ngapType.UEPresence{Value: ngapType.UEPresencePresentIn},
+			},
+		},
+	}
+	requestType := &ngapType.LocationReportingRequestType{
+		EventType: ngapType.EventType{Value: ngapType.EventTypePresentUePresenceInAreaOfInterest},
+	}
+	handleLocationReportMain(ran, ranUe, nil, uePresenceList, requestType)
+
+	require.Len(t, rcvCh, 1)
+	updateReq := <-rcvCh
+	require.Equal(t, models.PresenceState_IN_AREA, updateReq.PraStatuses["pra-1"].PresenceState)
+
+	_, ok := amfUe.AreaOfInterestFindByPraId("pra-1")
+	require.False(t, ok)
+	pra2, ok := amfUe.AreaOfInterestFindByPraId("pra-2")
+	require.True(t, ok)
+	// The NG-RAN stops reporting pra-1 and is requested to report pra-2
+	require.Len(t, connStub.MsgList, 2)
+	for _, msg := range connStub.MsgList {
+		pdu, err := ngap.Decoder(msg)
+		require.NoError(t, err)
+		require.Equal(t, ngapType.ProcedureCodeLocationReportingControl,
+			pdu.InitiatingMessage.ProcedureCode.Value)
+	}
+	require.Equal(t, "000002", pra2.PresenceInfo.TrackingAreaList[0].Tac)
+}
+
+func TestHandleNASNonDeliveryIndication(t *testing.T) {
+	amfSelf := amf_context.GetSelf()
+	NewAmfContext(amfSelf)
+
+	rcvCh := make(chan models.N1N2MsgTxfrFailureNotification, 2)
+	smf := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notification models.N1N2MsgTxfrFailureNotification
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&notification))
+		rcvCh <- notification
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	smf.Config.Protocols = new(http.Protocols)
+	smf.Config.Protocols.SetUnencryptedHTTP2(true)
+	smf.Start()
+	defer smf.Close()
+
+	connStub := new(ngaptesting.SctpConnStub)
+	ran := NewAmfRan(connStub)
+	ranUe, err := ran.NewRanUe(1)
+	require.NoError(t, err)
+	amfUe := amfSelf.NewAmfUe("imsi-208930000000003")
+	amfUe.AttachRanUe(ranUe)
+
+	radioNetworkCause := func(value aper.Enumerated) *ngapType.Cause {
+		return &ngapType.Cause{
+			Present:      ngapType.CausePresentRadioNetwork,
+			RadioNetwork: &ngapType.CauseRadioNetwork{Value: value},
+		}
+	}
+	origin := amf_context.N1N2MessageOrigin{
+		N1n2FailureTxfNotifURI: smf.URL + "/n1n2-failure",
+		N1n2MsgDataUri:         "http://127.0.0.18:8000/namf-comm/v1/ue-contexts/imsi-208930000000003/n1-n2-messages",
+	}
+	smfNasPdu := []byte{0x7e, 0x02, 0x01}
+	handoverNasPdu := []byte{0x7e, 0x02, 0x02}
+	amfNasPdu := []byte{0x7e, 0x02, 0x03}
+	amfUe.RecordN1N2NasPdu(smfNasPdu, origin)
+	amfUe.RecordN1N2NasPdu(handoverNasPdu, origin)
+
+	// The SMF is notified of the NAS PDU it requested which cannot reach the UE
+	handleNASNonDeliveryIndicationMain(ran, ranUe, &ngapType.NASPDU{Value: smfNasPdu},
+		radioNetworkCause(ngapType.CauseRadioNetworkPresentRadioConnectionWithUeLost))
+	require.Len(t, rcvCh, 1)
+	notification := <-rcvCh
+	require.Equal(t, models.N1N2MessageTransferCause_UE_NOT_RESPONDING, notification.Cause)
+	require.Equal(t, origin.N1n2MsgDataUri, notification.N1n2MsgDataUri)
+
+	// A NAS PDU originated by the AMF is discarded
+	handleNASNonDeliveryIndicationMain(ran, ranUe, &ngapType.NASPDU{Value: amfNasPdu},
+		radioNetworkCause(ngapType.CauseRadioNetworkPresentRadioConnectionWithUeLost))
+	require.Empty(t, rcvCh)
+
+	// A NAS PDU not delivered because of a handover is sent again once the handover is over
+	handleNASNonDeliveryIndicationMain(ran, ranUe, &ngapType.NASPDU{Value: handoverNasPdu},
+		radioNetworkCause(ngapType.CauseRadioNetworkPresentXnHandoverTriggered))
+	require.Empty(t, rcvCh)
+	require.Empty(t, connStub.MsgList)
+
+	retransmitUndeliveredNasPdus(ranUe, amf_context.OnGoingProcedureXnHandover)
+	require.Len(t, connStub.MsgList, 1)
+	pdu, err := ngap.Decoder(connStub.MsgList[0])
+	require.NoError(t, err)
+	require.Equal(t, ngapType.ProcedureCodeDownlinkNASTransport, pdu.InitiatingMessage.ProcedureCode.Value)
+	var nasPdu *ngapType.NASPDU
+	for _, ie := range pdu.InitiatingMessage.Value.DownlinkNASTransport.ProtocolIEs.List {
+		if ie.Id.Value == ngapType.ProtocolIEIDNASPDU {
+			nasPdu = ie.Value.NASPDU
+		}
+	}
+	require.NotNil(t, nasPdu)
+	require.Equal(t, aper.OctetString(handoverNasPdu), nasPdu.Value)
+
+	// Nothing is left to send again
+	retransmitUndeliveredNasPdus(ranUe, amf_context.OnGoingProcedureXnHandover)
+	require.Len(t, connStub.MsgList, 1)
+
+	// The SMF is notified once the retransmission failed too
+	handleNASNonDeliveryIndicationMain(ran, ranUe, &ngapType.NASPDU{Value: handoverNasPdu},
+		radioNetworkCause(ngapType.CauseRadioNetworkPresentXnHandoverTriggered))
+	require.Len(t, rcvCh, 1)
+	notification = <-rcvCh
+	require.Equal(t, models.N1N2MessageTransferCause_TEMPORARY_REJECT_HANDOVER_ONGOING, notification.Cause)
+}
+
+func TestBuildHandoverRequestKamfChange(t *testing.T) {
+	amfConfig := factory.AmfConfig
+	defer func() { factory.AmfConfig = amfConfig }()
+	factory.AmfConfig = &factory.Config{Configuration: &factory.Configuration{}}
+
+	amfSelf := amf_context.GetSelf()
+	NewAmfContext(amfSelf)
+
+	connStub := new(ngaptesting.SctpConnStub)
+	ran := NewAmfRan(connStub)
+	ranUe, err := ran.NewRanUe(1)
+	require.NoError(t, err)
+	amfUe := amfSelf.NewAmfUe("imsi-208930000000004")
+	defer amfUe.Remove()
+	amfUe.AttachRanUe(ranUe)
+	amfUe.AccessAndMobilitySubscriptionData = &models.AccessAndMobilitySubscriptionData{
+		SubscribedUeAmbr: &models.AmbrRm{Uplink: "1 Gbps", Downlink: "1 Gbps"},
+	}
+	amfUe.UESecurityCapability = *nasType.NewUESecurityCapability(nasMessage.RegistrationRequestUESecurityCapabilityType)
+	amfUe.UESecurityCapability.SetLen(2)
+	amfUe.UESecurityCapability.SetEA2_128_5G(1)
+	amfUe.UESecurityCapability.SetIA2_128_5G(1)
+	amfUe.IntegrityAlg = security.AlgIntegrity128NIA2
+	amfUe.CipheringAlg = security.AlgCiphering128NEA2
+	amfUe.Kamf = "0f8a6ee1b6e1c63f0bd8b7e6b8f4a7e27c8f1b1f6cb9d14e0a5f4b3c2d1e0f9a"
+	amfUe.DLCount.Set(0, 0x2a)
+	require.NoError(t, amfUe.DerivateKamfHorizontally(amf_context.KamfDerivationDirectionHandover, 0x2a))
+	amfUe.DerivateAlgKey()
+	amfUe.UpdateSecurityContext(models.AccessType__3_GPP_ACCESS)
+
+	pduSessionList := ngapType.PDUSessionResourceSetupListHOReq{
+		List: []ngapType.PDUSessionResourceSetupItemHOReq{{
+			PDUSessionID:            ngapType.PDUSessionID{Value: 1},
+			SNSSAI:                  ngapType.SNSSAI{SST: ngapType.SST{Value: aper.OctetString{0x01}}},
+			HandoverRequestTransfer: aper.OctetString{0x00},
+		}},
+	}
+
+	// the K_AMF change is indicated in the NASC of the first Handover Request only
+	for _, kamfChange := range []bool{true, false} {
+		msg, err := ngap_message.BuildHandoverRequest(ranUe, ngapType.Cause{
+			Present: ngapType.CausePresentMisc,
+			Misc:    &ngapType.CauseMisc{Value: ngapType.CauseMiscPresentUnspecified},
+		}, pduSessionList, ngapType.SourceToTargetTransparentContainer{}, false)
+		require.NoError(t, err)
+		pdu, err := ngap.Decoder(msg)
+		require.NoError(t, err)
+		var nasc *ngapType.NASPDU
+		var nsci *ngapType.NewSecurityContextInd
+		for _, ie := range pdu.InitiatingMessage.Value.HandoverRequest.ProtocolIEs.List {
+			switch ie.Id.Value {
+			case ngapType.ProtocolIEIDNASC:
+				nasc = ie.Value.NASC
+			case ngapType.ProtocolIEIDNewSecurityContextInd:
+				nsci = ie.Value.NewSecurityContextInd
+			}
+		}
+		if !kamfChange {
+			require.Nil(t, nasc)
+			require.Nil(t, nsci)
+			continue
+		}
+		require.NotNil(t, nasc)
+		require.Len(t, nasc.Value, 7)
+		require.Equal(t, uint8(0x2a), nasc.Value[6])
+		require.NotNil(t, nsci)
+		require.Equal(t, uint32(0x2b), amfUe.DLCount.Get())
+	}
+}
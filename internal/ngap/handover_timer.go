@@ -0,0 +1,103 @@
+package ngap
+
+import (
+	"github.com/free5gc/amf/internal/context"
+	business_metrics "github.com/free5gc/amf/internal/metrics/business"
+	ngap_message "github.com/free5gc/amf/internal/ngap/message"
+	"github.com/free5gc/amf/internal/sbi/consumer"
+	"github.com/free5gc/aper"
+	"github.com/free5gc/ngap/ngapType"
+	"github.com/free5gc/openapi/models"
+	"github.com/free5gc/util/metrics/utils"
+)
+
+// startTRelocPrep is started on the source RanUe once the HandoverRequest has been sent to the target NG-RAN,
+// and is stopped when the HandoverRequestAcknowledge or HandoverFailure is received.
+func startTRelocPrep(sourceUe *context.RanUe) {
+	cfg := context.GetSelf().TRelocPrepCfg
+	if !cfg.Enable {
+		return
+	}
+
+	sourceUe.StopTRelocPrep()
+	sourceUe.Log.Infof("Start TRELOCprep timer")
+	sourceUe.TRelocPrep = context.NewTimer(cfg.ExpireTime, 0, func(expireTimes int32) {}, func() {
+		sourceUe.Log.Warnf("TRELOCprep expires, abort handover preparation")
+		sourceUe.TRelocPrep = nil // clear the timer
+		handleHandoverTimerExpiry(sourceUe, true, ngapType.CauseRadioNetworkPresentTngrelocprepExpiry,
+			business_metrics.HANDOVER_TRELOCPREP_EXPIRY_ERR)
+	})
+}
+
+// startTRelocOverall is started on the source RanUe once the HandoverCommand has been sent to the source NG-RAN,
+// and is stopped when the HandoverNotify is received from the target NG-RAN.
+func startTRelocOverall(sourceUe *context.RanUe) {
+	cfg := context.GetSelf().TRelocOverallCfg
+	if !cfg.Enable {
+		return
+	}
+
+	sourceUe.StopTRelocOverall()
+	sourceUe.Log.Infof("Start TRELOCoverall timer")
+	sourceUe.TRelocOverall = context.NewTimer(cfg.ExpireTime, 0, func(expireTimes int32) {}, func() {
+		sourceUe.Log.Warnf("TRELOCoverall expires, abort handover execution")
+		sourceUe.TRelocOverall = nil // clear the timer
+		handleHandoverTimerExpiry(sourceUe, false, ngapType.CauseRadioNetworkPresentTngrelocoverallExpiry,
+			business_metrics.HANDOVER_TRELOCOVERALL_EXPIRY_ERR)
+	})
+}
+
+// handleHandoverTimerExpiry cancels the ongoing N2 handover of sourceUe: the SMF is informed of the cancellation,
+// the target RanUe is released and, if the handover was still in preparation, the source NG-RAN receives a
+// HandoverPreparationFailure.
+func handleHandoverTimerExpiry(sourceUe *context.RanUe, inPreparation bool, causeValue aper.Enumerated,
+	hoFailCause string,
+) {
+	business_metrics.IncrHoEventCounter(business_metrics.HANDOVER_TYPE_NGAP_VALUE, utils.FailureMetric,
+		hoFailCause, sourceUe.HandOverStartTime)
+
+	causePresent := ngapType.CausePresentRadioNetwork
+	amfUe := sourceUe.AmfUe
+	if amfUe != nil {
+		amfUe.SmContextList.Range(func(key, value interface{}) bool {
+			pduSessionID := key.(int32)
+			smContext := value.(*context.SmContext)
+			causeAll := context.CauseAll{
+				NgapCause: &models.NgApCause{
+					Group: int32(causePresent),
+					Value: int32(causeValue),
+				},
+			}
+			_, _, _, err := consumer.GetConsumer().SendUpdateSmContextN2HandoverCanceled(amfUe, smContext, causeAll)
+			if err != nil {
+				sourceUe.Log.Errorf("Send UpdateSmContextN2HandoverCanceled Error for pduSessionID[%d]", pduSessionID)
+			}
+			return true
+		})
+	}
+
+	targetUe := sourceUe.TargetUe
+	sourceUe.StopTRelocPrep()
+	sourceUe.StopTRelocOverall()
+	context.DetachSourceUeTargetUe(sourceUe)
+	if targetUe != nil {
+		// The target RanUe has never been attached to the AmfUe, so removing it must not touch the source link
+		targetUe.DetachAmfUe()
+		ngap_message.SendUEContextReleaseCommand(targetUe, context.UeContextReleaseHandover, causePresent, causeValue)
+	}
+
+	if inPreparation {
+		cause := ngapType.Cause{
+			Present: causePresent,
+			RadioNetwork: &ngapType.CauseRadioNetwork{
+				Value: causeValue,
+			},
+		}
+		ngap_message.SendHandoverPreparationFailure(sourceUe, cause, nil)
+	} else if amfUe != nil {
+		amfUe.SetOnGoing(sourceUe.Ran.AnType, &context.OnGoing{
+			Procedure: context.OnGoingProcedureNothing,
+		})
+	}
+	retransmitUndeliveredNasPdus(sourceUe, context.OnGoingProcedureN2Handover)
+}
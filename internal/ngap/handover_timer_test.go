@@ -0,0 +1,119 @@
+package ngap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	amf_context "github.com/free5gc/amf/internal/context"
+	ngaptesting "github.com/free5gc/amf/internal/ngap/testing"
+	"github.com/free5gc/amf/pkg/factory"
+	"github.com/free5gc/ngap"
+	"github.com/free5gc/ngap/ngapType"
+	"github.com/free5gc/openapi/models"
+)
+
+func newHandoverPair(t *testing.T) (*amf_context.RanUe, *amf_context.RanUe,
+	*ngaptesting.SctpConnStub, *ngaptesting.SctpConnStub,
+) {
+	sourceConn := new(ngaptesting.SctpConnStub)
+	targetConn := new(ngaptesting.SctpConnStub)
+	sourceRan := NewAmfRan(sourceConn)
+	targetRan := NewAmfRan(targetConn)
+
+	amfUe := amf_context.GetSelf().NewAmfUe("imsi-208930000000001")
+	sourceUe, err := sourceRan.NewRanUe(1)
+	require.NoError(t, err)
+	amfUe.AttachRanUe(sourceUe)
+	amfUe.SetOnGoing(models.AccessType__3_GPP_ACCESS, &amf_context.OnGoing{
+		Procedure: amf_context.OnGoingProcedureN2Handover,
+	})
+
+	targetUe, err := targetRan.NewRanUe(amf_context.RanUeNgapIdUnspecified)
+	require.NoError(t, err)
+	amf_context.AttachSourceUeTargetUe(sourceUe, targetUe)
+	return sourceUe, targetUe, sourceConn, targetConn
+}
+
+func TestTRelocPrepExpiry(t *testing.T) {
+	amfSelf := amf_context.GetSelf()
+	NewAmfContext(amfSelf)
+	amfSelf.TRelocPrepCfg = factory.TimerValue{
+		Enable:     true,
+		ExpireTime: 50 * time.Millisecond,
+	}
+
+	sourceUe, targetUe, sourceConn, targetConn := newHandoverPair(t)
+	startTRelocPrep(sourceUe)
+	require.NotNil(t, sourceUe.TRelocPrep)
+
+	require.Eventually(t, func() bool {
+		return len(sourceConn.MsgList) == 1 && len(targetConn.MsgList) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	require.Nil(t, sourceUe.TRelocPrep)
+	require.Nil(t, sourceUe.TargetUe)
+	require.Nil(t, targetUe.SourceUe)
+	require.Nil(t, targetUe.AmfUe)
+	require.NotNil(t, sourceUe.AmfUe)
+	require.Equal(t, amf_context.OnGoingProcedureNothing,
+		sourceUe.AmfUe.OnGoing(models.AccessType__3_GPP_ACCESS).Procedure)
+
+	rcv, err := ngap.Decoder(sourceConn.MsgList[0])
+	require.NoError(t, err)
+	require.NotNil(t, rcv.UnsuccessfulOutcome)
+	require.Equal(t, int64(ngapType.ProcedureCodeHandoverPreparation), rcv.UnsuccessfulOutcome.ProcedureCode.Value)
+
+	rcv, err = ngap.Decoder(targetConn.MsgList[0])
+	require.NoError(t, err)
+	require.NotNil(t, rcv.InitiatingMessage)
+	for _, ie := range rcv.InitiatingMessage.Value.UEContextReleaseCommand.ProtocolIEs.List {
+		if ie.Value.Present == ngapType.UEContextReleaseCommandIEsPresentCause {
+			require.Equal(t, ngapType.CauseRadioNetworkPresentTngrelocprepExpiry, ie.Value.Cause.RadioNetwork.Value)
+		}
+	}
+}
+
+func TestTRelocOverallExpiry(t *testing.T) {
+	amfSelf := amf_context.GetSelf()
+	NewAmfContext(amfSelf)
+	amfSelf.TRelocOverallCfg = factory.TimerValue{
+		Enable:     true,
+		ExpireTime: 50 * time.Millisecond,
+	}
+
+	sourceUe, targetUe, sourceConn, targetConn := newHandoverPair(t)
+	startTRelocOverall(sourceUe)
+	require.NotNil(t, sourceUe.TRelocOverall)
+
+	require.Eventually(t, func() bool {
+		return len(targetConn.MsgList) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// The source NG-RAN supervises the execution phase itself, nothing is sent back to it
+	require.Empty(t, sourceConn.MsgList)
+	require.Nil(t, sourceUe.TRelocOverall)
+	require.Nil(t, sourceUe.TargetUe)
+	require.Nil(t, targetUe.AmfUe)
+	require.Equal(t, amf_context.OnGoingProcedureNothing,
+		sourceUe.AmfUe.OnGoing(models.AccessType__3_GPP_ACCESS).Procedure)
+}
+
+func TestHandoverTimerStoppedOnCancel(t *testing.T) {
+	amfSelf := amf_context.GetSelf()
+	NewAmfContext(amfSelf)
+	amfSelf.TRelocPrepCfg = factory.TimerValue{
+		Enable:     true,
+		ExpireTime: 50 * time.Millisecond,
+	}
+
+	sourceUe, _, sourceConn, _ := newHandoverPair(t)
+	startTRelocPrep(sourceUe)
+	handleHandoverCancelMain(sourceUe.Ran, sourceUe, nil)
+	require.Nil(t, sourceUe.TRelocPrep)
+
+	time.Sleep(100 * time.Millisecond)
+	// Only the HandoverCancelAcknowledge, no HandoverPreparationFailure from the timer
+	require.Len(t, sourceConn.MsgList, 1)
+}
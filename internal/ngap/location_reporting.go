@@ -0,0 +1,102 @@
+package ngap
+
+import (
+	"github.com/mohae/deepcopy"
+
+	"github.com/free5gc/amf/internal/context"
+	gmm_common "github.com/free5gc/amf/internal/gmm/common"
+	"github.com/free5gc/amf/internal/sbi/consumer"
+	"github.com/free5gc/ngap/ngapType"
+	"github.com/free5gc/openapi/models"
+)
+
+// notifyLocationReport reports the UE location received from the NG-RAN to the LOCATION_REPORT subscribers
+func notifyLocationReport(ranUe *context.RanUe, amfUe *context.AmfUe) {
+	var notifyList []*gmm_common.AmfEventNotify
+
+	amfUe.Lock.Lock()
+	for subscriptionID := range amfUe.EventSubscriptionsInfo {
+		notify, ok := gmm_common.NewAmfEventReport(amfUe, subscriptionID, models.AmfEventType_LOCATION_REPORT)
+		if !ok {
+			continue
+		}
+		location := deepcopy.Copy(amfUe.Location).(models.UserLocation)
+		notify.Report.Location = &location
+		notifyList = append(notifyList, notify)
+	}
+	amfUe.Lock.Unlock()
+
+	gmm_common.SendAmfEventNotifies(amfUe, notifyList)
+}
+
+func uePresenceToModels(uePresence ngapType.UEPresence) models.PresenceState {
+	switch uePresence.Value {
+	case ngapType.UEPresencePresentIn:
+		return models.PresenceState_IN_AREA
+	case ngapType.UEPresencePresentOut:
+		return models.PresenceState_OUT_OF_AREA
+	default:
+		return models.PresenceState_UNKNOWN
+	}
+}
+
+// handleUEPresenceInAreaOfInterest maps the UE presence reported by the NG-RAN to the areas of interest of the
+// UE. A change is reported to the PRESENCE_IN_AOI_REPORT subscriber of the area, or to the PCF with the PRA_CH
+// policy control request trigger if the area is a Presence Reporting Area.
+func handleUEPresenceInAreaOfInterest(ranUe *context.RanUe, amfUe *context.AmfUe,
+	uEPresenceInAreaOfInterestList *ngapType.UEPresenceInAreaOfInterestList,
+) {
+	var notifyList []*gmm_common.AmfEventNotify
+	praStatuses := make(map[string]models.PresenceInfo)
+
+	amfUe.Lock.Lock()
+	for _, item := range uEPresenceInAreaOfInterestList.List {
+		referenceID := item.LocationReportingReferenceID.Value
+		presenceState := uePresenceToModels(item.UEPresence)
+		ranUe.Log.Tracef("uEPresence[%s], presence AOI ReferenceID[%d]", presenceState, referenceID)
+
+		aoi, ok := amfUe.AreaOfInterestFindByReferenceID(referenceID)
+		if !ok {
+			ranUe.Log.Warnf("Area of interest[ReferenceID:%d] not found", referenceID)
+			continue
+		}
+		if aoi.PresenceInfo.PresenceState == presenceState {
+			continue
+		}
+		aoi.PresenceInfo.PresenceState = presenceState
+
+		if aoi.PraId != "" {
+			praStatuses[aoi.PraId] = aoi.PresenceInfo
+		}
+		if aoi.SubscriptionID != "" {
+			notify, found := gmm_common.NewAmfEventReport(amfUe, aoi.SubscriptionID,
+				models.AmfEventType_PRESENCE_IN_AOI_REPORT)
+			if !found {
+				continue
+			}
+			presenceInfo := aoi.PresenceInfo
+			notify.Report.AreaList = []models.AmfEventArea{{PresenceInfo: &presenceInfo}}
+			notifyList = append(notifyList, notify)
+		}
+	}
+	amfUe.Lock.Unlock()
+
+	gmm_common.SendAmfEventNotifies(amfUe, notifyList)
+
+	if len(praStatuses) > 0 && amfUe.AmPolicyAssociation != nil {
+		updateReq := models.PcfAmPolicyControlPolicyAssociationUpdateRequest{
+			Triggers:    []models.PcfAmPolicyControlRequestTrigger{models.PcfAmPolicyControlRequestTrigger_PRA_CH},
+			PraStatuses: praStatuses,
+		}
+		policyUpdate, problemDetails, err := consumer.GetConsumer().AMPolicyControlUpdate(amfUe, updateReq)
+		if problemDetails != nil {
+			ranUe.Log.Errorf("AM Policy Control Update Failed Problem[%+v]", problemDetails)
+		} else if err != nil {
+			ranUe.Log.Errorf("AM Policy Control Update Error[%v]", err)
+		} else {
+			amfUe.Lock.Lock()
+			gmm_common.UpdatePresenceReportingAreas(amfUe, policyUpdate)
+			amfUe.Lock.Unlock()
+		}
+	}
+}
@@ -3,6 +3,7 @@ package message
 import (
 	"encoding/hex"
 	"fmt"
+	"net"
 	"strings"
 
 	"github.com/free5gc/amf/internal/context"
@@ -10,6 +11,8 @@ import (
 	"github.com/free5gc/amf/internal/util"
 	"github.com/free5gc/amf/pkg/factory"
 	"github.com/free5gc/aper"
+	"github.com/free5gc/nas/nasConvert"
+	"github.com/free5gc/nas/nasMessage"
 	"github.com/free5gc/ngap"
 	"github.com/free5gc/ngap/ngapConvert"
 	"github.com/free5gc/ngap/ngapType"
@@ -1731,6 +1734,21 @@ func BuildHandoverRequest(ue *context.RanUe, cause ngapType.Cause,
 
 	handoverRequestIEs.List = append(handoverRequestIEs.List, ie)
 
+	// NASC (optional), indicates the K_AMF change to the UE (TS 33.501 6.9.2.3.3)
+	if amfUe.KamfChangeIndicationRequired() {
+		nasc, err := amfUe.IntraN1ModeNasTransparentContainer()
+		if err != nil {
+			return nil, err
+		}
+		ie = ngapType.HandoverRequestIEs{}
+		ie.Id.Value = ngapType.ProtocolIEIDNASC
+		ie.Criticality.Value = ngapType.CriticalityPresentReject
+		ie.Value.Present = ngapType.HandoverRequestIEsPresentNASC
+		ie.Value.NASC = &ngapType.NASPDU{Value: nasc}
+		handoverRequestIEs.List = append(handoverRequestIEs.List, ie)
+		nsci = true
+	}
+
 	// PDU Session Resource Setup List
 	ie = ngapType.HandoverRequestIEs{}
 	ie.Id.Value = ngapType.ProtocolIEIDPDUSessionResourceSetupListHOReq
@@ -2980,9 +2998,10 @@ func BuildUETNLABindingReleaseRequest(ue *context.RanUe) ([]byte, error) {
 	return ngap.Encoder(pdu)
 }
 
-// Weight Factor associated with each of the TNL association within the AMF
-func BuildAMFConfigurationUpdate(tNLassociationUsage ngapType.TNLAssociationUsage,
-	tNLAddressWeightFactor ngapType.TNLAddressWeightFactor,
+// The AMF endpoints the NG-RAN node shall set up TNL associations to, stop using, or use differently,
+// with the usage and the Weight Factor associated with each of them
+func BuildAMFConfigurationUpdate(tnlaToAddList, tnlaToRemoveList,
+	tnlaToUpdateList []context.AmfTnlEndpoint,
 ) ([]byte, error) {
 	amfSelf := context.GetSelf()
 	var pdu ngapType.NGAPPDU
@@ -3064,87 +3083,94 @@ func BuildAMFConfigurationUpdate(tNLassociationUsage ngapType.TNLAssociationUsag
 	aMFConfigurationUpdateIEs.List = append(aMFConfigurationUpdateIEs.List, ie)
 
 	//	AMF TNL Association to Add List
-	ie = ngapType.AMFConfigurationUpdateIEs{}
-	ie.Id.Value = ngapType.ProtocolIEIDAMFTNLAssociationToAddList
-	ie.Criticality.Value = ngapType.CriticalityPresentIgnore
-	ie.Value.Present = ngapType.AMFConfigurationUpdateIEsPresentAMFTNLAssociationToAddList
-	ie.Value.AMFTNLAssociationToAddList = new(ngapType.AMFTNLAssociationToAddList)
-
-	aMFTNLAssociationToAddList := ie.Value.AMFTNLAssociationToAddList
+	if len(tnlaToAddList) > 0 {
+		ie = ngapType.AMFConfigurationUpdateIEs{}
+		ie.Id.Value = ngapType.ProtocolIEIDAMFTNLAssociationToAddList
+		ie.Criticality.Value = ngapType.CriticalityPresentIgnore
+		ie.Value.Present = ngapType.AMFConfigurationUpdateIEsPresentAMFTNLAssociationToAddList
+		ie.Value.AMFTNLAssociationToAddList = new(ngapType.AMFTNLAssociationToAddList)
+
+		aMFTNLAssociationToAddList := ie.Value.AMFTNLAssociationToAddList
+		for _, endpoint := range tnlaToAddList {
+			//	AMFTNLAssociationToAddItem in AMFTNLAssociationToAddList
+			aMFTNLAssociationToAddItem := ngapType.AMFTNLAssociationToAddItem{}
+			aMFTNLAssociationToAddItem.AMFTNLAssociationAddress = tnlEndpointToNgap(endpoint.Ip)
+
+			//	AMF TNL Association Usage[optional]
+			aMFTNLAssociationToAddItem.TNLAssociationUsage = &ngapType.TNLAssociationUsage{
+				Value: endpoint.Usage,
+			}
 
-	//	AMFTNLAssociationToAddItem in AMFTNLAssociationToAddList
-	aMFTNLAssociationToAddItem := ngapType.AMFTNLAssociationToAddItem{}
-	aMFTNLAssociationToAddItem.AMFTNLAssociationAddress.Present = ngapType.
-		CPTransportLayerInformationPresentEndpointIPAddress
-	aMFTNLAssociationToAddItem.AMFTNLAssociationAddress.EndpointIPAddress = new(ngapType.TransportLayerAddress)
-	*aMFTNLAssociationToAddItem.AMFTNLAssociationAddress.EndpointIPAddress = ngapConvert.
-		IPAddressToNgap(amfSelf.RegisterIPv4, amfSelf.HttpIPv6Address)
+			//	AMF TNL Address Weight Factor
+			aMFTNLAssociationToAddItem.TNLAddressWeightFactor.Value = endpoint.WeightFactor
 
-	//	AMF TNL Association Usage[optional]
-	if aMFTNLAssociationToAddItem.TNLAssociationUsage != nil {
-		aMFTNLAssociationToAddItem.TNLAssociationUsage = new(ngapType.TNLAssociationUsage)
-		aMFTNLAssociationToAddItem.TNLAssociationUsage = &tNLassociationUsage
+			aMFTNLAssociationToAddList.List = append(aMFTNLAssociationToAddList.List, aMFTNLAssociationToAddItem)
+		}
+		aMFConfigurationUpdateIEs.List = append(aMFConfigurationUpdateIEs.List, ie)
 	}
 
-	//	AMF TNL Address Weight Factor
-	aMFTNLAssociationToAddItem.TNLAddressWeightFactor = tNLAddressWeightFactor
-
-	aMFTNLAssociationToAddList.List = append(aMFTNLAssociationToAddList.List, aMFTNLAssociationToAddItem)
-	aMFConfigurationUpdateIEs.List = append(aMFConfigurationUpdateIEs.List, ie)
-
 	//	AMF TNL Association to Remove List
-	ie = ngapType.AMFConfigurationUpdateIEs{}
-	ie.Id.Value = ngapType.ProtocolIEIDAMFTNLAssociationToRemoveList
-	ie.Criticality.Value = ngapType.CriticalityPresentIgnore
-	ie.Value.Present = ngapType.AMFConfigurationUpdateIEsPresentAMFTNLAssociationToRemoveList
-	ie.Value.AMFTNLAssociationToRemoveList = new(ngapType.AMFTNLAssociationToRemoveList)
-
-	aMFTNLAssociationToRemoveList := ie.Value.AMFTNLAssociationToRemoveList
+	if len(tnlaToRemoveList) > 0 {
+		ie = ngapType.AMFConfigurationUpdateIEs{}
+		ie.Id.Value = ngapType.ProtocolIEIDAMFTNLAssociationToRemoveList
+		ie.Criticality.Value = ngapType.CriticalityPresentIgnore
+		ie.Value.Present = ngapType.AMFConfigurationUpdateIEsPresentAMFTNLAssociationToRemoveList
+		ie.Value.AMFTNLAssociationToRemoveList = new(ngapType.AMFTNLAssociationToRemoveList)
 
-	//	AMFTNLAssociationToRemoveItem
-	aMFTNLAssociationToRemoveItem := ngapType.AMFTNLAssociationToRemoveItem{}
-	aMFTNLAssociationToRemoveItem.AMFTNLAssociationAddress.Present = ngapType.
-		CPTransportLayerInformationPresentEndpointIPAddress
-	aMFTNLAssociationToRemoveItem.AMFTNLAssociationAddress.EndpointIPAddress = new(ngapType.TransportLayerAddress)
-	*aMFTNLAssociationToRemoveItem.AMFTNLAssociationAddress.EndpointIPAddress = ngapConvert.
-		IPAddressToNgap(amfSelf.RegisterIPv4, amfSelf.HttpIPv6Address)
+		aMFTNLAssociationToRemoveList := ie.Value.AMFTNLAssociationToRemoveList
+		for _, endpoint := range tnlaToRemoveList {
+			//	AMFTNLAssociationToRemoveItem
+			aMFTNLAssociationToRemoveItem := ngapType.AMFTNLAssociationToRemoveItem{}
+			aMFTNLAssociationToRemoveItem.AMFTNLAssociationAddress = tnlEndpointToNgap(endpoint.Ip)
 
-	aMFTNLAssociationToRemoveList.List = append(aMFTNLAssociationToRemoveList.List, aMFTNLAssociationToRemoveItem)
-	aMFConfigurationUpdateIEs.List = append(aMFConfigurationUpdateIEs.List, ie)
+			aMFTNLAssociationToRemoveList.List = append(aMFTNLAssociationToRemoveList.List,
+				aMFTNLAssociationToRemoveItem)
+		}
+		aMFConfigurationUpdateIEs.List = append(aMFConfigurationUpdateIEs.List, ie)
+	}
 
 	//	AMFTNLAssociationToUpdateList
-	ie = ngapType.AMFConfigurationUpdateIEs{}
-	ie.Id.Value = ngapType.ProtocolIEIDAMFTNLAssociationToUpdateList
-	ie.Criticality.Value = ngapType.CriticalityPresentIgnore
-	ie.Value.Present = ngapType.AMFConfigurationUpdateIEsPresentAMFTNLAssociationToUpdateList
-	ie.Value.AMFTNLAssociationToUpdateList = new(ngapType.AMFTNLAssociationToUpdateList)
-
-	aMFTNLAssociationToUpdateList := ie.Value.AMFTNLAssociationToUpdateList
-
-	//	AMFTNLAssociationAddress in AMFTNLAssociationtoUpdateItem
-	aMFTNLAssociationToUpdateItem := ngapType.AMFTNLAssociationToUpdateItem{}
-	aMFTNLAssociationToUpdateItem.AMFTNLAssociationAddress.Present = ngapType.
-		CPTransportLayerInformationPresentEndpointIPAddress
-	aMFTNLAssociationToUpdateItem.AMFTNLAssociationAddress.EndpointIPAddress = new(ngapType.TransportLayerAddress)
-	*aMFTNLAssociationToUpdateItem.AMFTNLAssociationAddress.EndpointIPAddress = ngapConvert.
-		IPAddressToNgap(amfSelf.RegisterIPv4, amfSelf.HttpIPv6Address)
-
-	//	TNLAssociationUsage in AMFTNLAssociationtoUpdateItem [optional]
-	if aMFTNLAssociationToUpdateItem.TNLAssociationUsage != nil {
-		aMFTNLAssociationToUpdateItem.TNLAssociationUsage = new(ngapType.TNLAssociationUsage)
-		aMFTNLAssociationToUpdateItem.TNLAssociationUsage = &tNLassociationUsage
-	}
-	//	TNLAddressWeightFactor in AMFTNLAssociationtoUpdateItem [optional]
-	if aMFTNLAssociationToUpdateItem.TNLAddressWeightFactor != nil {
-		aMFTNLAssociationToUpdateItem.TNLAddressWeightFactor = new(ngapType.TNLAddressWeightFactor)
-		aMFTNLAssociationToUpdateItem.TNLAddressWeightFactor = &tNLAddressWeightFactor
-	}
-	aMFTNLAssociationToUpdateList.List = append(aMFTNLAssociationToUpdateList.List, aMFTNLAssociationToUpdateItem)
-	aMFConfigurationUpdateIEs.List = append(aMFConfigurationUpdateIEs.List, ie)
+	if len(tnlaToUpdateList) > 0 {
+		ie = ngapType.AMFConfigurationUpdateIEs{}
+		ie.Id.Value = ngapType.ProtocolIEIDAMFTNLAssociationToUpdateList
+		ie.Criticality.Value = ngapType.CriticalityPresentIgnore
+		ie.Value.Present = ngapType.AMFConfigurationUpdateIEsPresentAMFTNLAssociationToUpdateList
+		ie.Value.AMFTNLAssociationToUpdateList = new(ngapType.AMFTNLAssociationToUpdateList)
+
+		aMFTNLAssociationToUpdateList := ie.Value.AMFTNLAssociationToUpdateList
+		for _, endpoint := range tnlaToUpdateList {
+			//	AMFTNLAssociationAddress in AMFTNLAssociationtoUpdateItem
+			aMFTNLAssociationToUpdateItem := ngapType.AMFTNLAssociationToUpdateItem{}
+			aMFTNLAssociationToUpdateItem.AMFTNLAssociationAddress = tnlEndpointToNgap(endpoint.Ip)
+
+			//	TNLAssociationUsage in AMFTNLAssociationtoUpdateItem [optional]
+			aMFTNLAssociationToUpdateItem.TNLAssociationUsage = &ngapType.TNLAssociationUsage{
+				Value: endpoint.Usage,
+			}
+			//	TNLAddressWeightFactor in AMFTNLAssociationtoUpdateItem [optional]
+			aMFTNLAssociationToUpdateItem.TNLAddressWeightFactor = &ngapType.TNLAddressWeightFactor{
+				Value: endpoint.WeightFactor,
+			}
+			aMFTNLAssociationToUpdateList.List = append(aMFTNLAssociationToUpdateList.List,
+				aMFTNLAssociationToUpdateItem)
+		}
+		aMFConfigurationUpdateIEs.List = append(aMFConfigurationUpdateIEs.List, ie)
+	}
 
 	return ngap.Encoder(pdu)
 }
 
+func tnlEndpointToNgap(ip string) (address ngapType.CPTransportLayerInformation) {
+	address.Present = ngapType.CPTransportLayerInformationPresentEndpointIPAddress
+	address.EndpointIPAddress = new(ngapType.TransportLayerAddress)
+	if net.ParseIP(ip).To4() != nil {
+		*address.EndpointIPAddress = ngapConvert.IPAddressToNgap(ip, "")
+	} else {
+		*address.EndpointIPAddress = ngapConvert.IPAddressToNgap("", ip)
+	}
+	return
+}
+
 // NRPPa PDU is a pdu from LMF to RAN defined in TS 23.502 4.13.5.5 step 3
 // NRPPa PDU is by pass
 func BuildDownlinkUEAssociatedNRPPaTransport(ue *context.RanUe, nRPPaPDU ngapType.NRPPaPDU) ([]byte, error) {
@@ -3215,3 +3241,54 @@ func BuildDownlinkUEAssociatedNRPPaTransport(ue *context.RanUe, nRPPaPDU ngapTyp
 
 	return ngap.Encoder(pdu)
 }
+
+// BuildCoreNetworkAssistanceInformation provides the NG-RAN with the paging and idle mode parameters of the UE
+// (TS 23.501 5.3.3.2.5, TS 38.413 9.3.1.15), nil is returned if the UE has no registration area over 3GPP access.
+// The NR paging eDRX information extension of the negotiated eDRX parameters is not supported by the NGAP library.
+func BuildCoreNetworkAssistanceInformation(amfUe *context.AmfUe) *ngapType.CoreNetworkAssistanceInformation {
+	registrationArea := amfUe.RegistrationArea[models.AccessType__3_GPP_ACCESS]
+	if len(registrationArea) == 0 {
+		return nil
+	}
+
+	coreNetworkAssistanceInfo := new(ngapType.CoreNetworkAssistanceInformation)
+
+	// UE Identity Index value: 5G-S-TMSI mod 1024 (TS 38.304 7.1)
+	ueIdentityIndex := uint16(amfUe.Tmsi) & 0x3ff
+	coreNetworkAssistanceInfo.UEIdentityIndexValue.Present = ngapType.UEIdentityIndexValuePresentIndexLength10
+	coreNetworkAssistanceInfo.UEIdentityIndexValue.IndexLength10 = &aper.BitString{
+		Bytes:     []byte{byte(ueIdentityIndex >> 2), byte(ueIdentityIndex << 6)},
+		BitLength: 10,
+	}
+
+	// UE Specific DRX (optional)
+	if amfUe.UESpecificDRX != nasMessage.DRXValueNotSpecified {
+		coreNetworkAssistanceInfo.UESpecificDRX = &ngapType.PagingDRX{
+			Value: aper.Enumerated(amfUe.UESpecificDRX - nasMessage.DRXcycleParameterT32),
+		}
+	}
+
+	// Periodic Registration Update Timer
+	coreNetworkAssistanceInfo.PeriodicRegistrationUpdateTimer.Value = aper.BitString{
+		Bytes:     []byte{nasConvert.GPRSTimer3ToNas(amfUe.T3512Value)},
+		BitLength: 8,
+	}
+
+	// MICO Mode Indication (optional)
+	if amfUe.MicoMode {
+		coreNetworkAssistanceInfo.MICOModeIndication = &ngapType.MICOModeIndication{
+			Value: ngapType.MICOModeIndicationPresentTrue,
+		}
+	}
+
+	// TAI List for RRC Inactive
+	for _, tai := range registrationArea {
+		if len(coreNetworkAssistanceInfo.TAIListForInactive.List) == 16 {
+			break
+		}
+		coreNetworkAssistanceInfo.TAIListForInactive.List = append(coreNetworkAssistanceInfo.TAIListForInactive.List,
+			ngapType.TAIListForInactiveItem{TAI: ngapConvert.TaiToNgap(tai)})
+	}
+
+	return coreNetworkAssistanceInfo
+}
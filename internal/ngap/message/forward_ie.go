@@ -107,10 +107,58 @@ func AppendPDUSessionResourceToReleaseListRelCmd(list *ngapType.PDUSessionResour
 	list.List = append(list.List, item)
 }
 
+func AppendAreaOfInterestList(list *ngapType.AreaOfInterestList, referenceID int64, info models.PresenceInfo) {
+	var item ngapType.AreaOfInterestItem
+	item.LocationReportingReferenceID.Value = referenceID
+	aoi := &item.AreaOfInterest
+
+	for i, tai := range info.TrackingAreaList {
+		if i == context.MaxNumOfTAI {
+			logger.NgapLog.Warnf("AOI[%d] TAI list truncated to %d items", referenceID, context.MaxNumOfTAI)
+			break
+		}
+		if aoi.AreaOfInterestTAIList == nil {
+			aoi.AreaOfInterestTAIList = new(ngapType.AreaOfInterestTAIList)
+		}
+		aoi.AreaOfInterestTAIList.List = append(aoi.AreaOfInterestTAIList.List,
+			ngapType.AreaOfInterestTAIItem{TAI: ngapConvert.TaiToNgap(tai)})
+	}
+
+	for _, ncgi := range info.NcgiList {
+		if ncgi.PlmnId == nil {
+			continue
+		}
+		if aoi.AreaOfInterestCellList == nil {
+			aoi.AreaOfInterestCellList = new(ngapType.AreaOfInterestCellList)
+		}
+		cellItem := ngapType.AreaOfInterestCellItem{}
+		cellItem.NGRANCGI.Present = ngapType.NGRANCGIPresentNRCGI
+		cellItem.NGRANCGI.NRCGI = new(ngapType.NRCGI)
+		cellItem.NGRANCGI.NRCGI.PLMNIdentity = ngapConvert.PlmnIdToNgap(*ncgi.PlmnId)
+		cellItem.NGRANCGI.NRCGI.NRCellIdentity.Value = ngapConvert.HexToBitString(ncgi.NrCellId, 36)
+		aoi.AreaOfInterestCellList.List = append(aoi.AreaOfInterestCellList.List, cellItem)
+	}
+
+	for _, ranNodeId := range info.GlobalRanNodeIdList {
+		if aoi.AreaOfInterestRANNodeList == nil {
+			aoi.AreaOfInterestRANNodeList = new(ngapType.AreaOfInterestRANNodeList)
+		}
+		aoi.AreaOfInterestRANNodeList.List = append(aoi.AreaOfInterestRANNodeList.List,
+			ngapType.AreaOfInterestRANNodeItem{GlobalRANNodeID: ngapConvert.RanIDToNgap(ranNodeId)})
+	}
+
+	list.List = append(list.List, item)
+}
+
 func BuildIEMobilityRestrictionList(ue *context.AmfUe) ngapType.MobilityRestrictionList {
 	mobilityRestrictionList := ngapType.MobilityRestrictionList{}
 	mobilityRestrictionList.ServingPLMN = ngapConvert.PlmnIdToNgap(ue.PlmnId)
 
+	// TS 23.501 5.3.4.1.1, mobility restrictions do not apply to an emergency registered UE
+	if ue.EmergencyRegistered {
+		return mobilityRestrictionList
+	}
+
 	if ue.AccessAndMobilitySubscriptionData != nil && len(ue.AccessAndMobilitySubscriptionData.RatRestrictions) > 0 {
 		mobilityRestrictionList.RATRestrictions = new(ngapType.RATRestrictions)
 		ratRestrictions := mobilityRestrictionList.RATRestrictions
@@ -142,14 +190,14 @@ func BuildIEMobilityRestrictionList(ue *context.AmfUe) ngapType.MobilityRestrict
 		}
 	}
 
-	if ue.AmPolicyAssociation != nil && ue.AmPolicyAssociation.ServAreaRes != nil {
+	if servAreaRes := ue.ServiceAreaRestriction(); servAreaRes != nil {
 		mobilityRestrictionList.ServiceAreaInformation = new(ngapType.ServiceAreaInformation)
 		serviceAreaInformation := mobilityRestrictionList.ServiceAreaInformation
 
 		item := ngapType.ServiceAreaInformationItem{}
 		item.PLMNIdentity = ngapConvert.PlmnIdToNgap(ue.PlmnId)
 		var tacList []ngapType.TAC
-		for _, area := range ue.AmPolicyAssociation.ServAreaRes.Areas {
+		for _, area := range servAreaRes.Areas {
 			for _, tac := range area.Tacs {
 				tacBytes, err := hex.DecodeString(tac)
 				if err != nil {
@@ -161,7 +209,7 @@ func BuildIEMobilityRestrictionList(ue *context.AmfUe) ngapType.MobilityRestrict
 				tacList = append(tacList, tacNgap)
 			}
 		}
-		if ue.AmPolicyAssociation.ServAreaRes.RestrictionType == models.RestrictionType_ALLOWED_AREAS {
+		if servAreaRes.RestrictionType == models.RestrictionType_ALLOWED_AREAS {
 			item.AllowedTACs = new(ngapType.AllowedTACs)
 			item.AllowedTACs.List = append(item.AllowedTACs.List, tacList...)
 		} else {
@@ -1,6 +1,7 @@
 package message
 
 import (
+	"net"
 	"time"
 
 	"github.com/free5gc/amf/internal/context"
@@ -17,6 +18,15 @@ import (
 var emptyCause = ngapType.Cause{Present: 0}
 
 func SendToRan(ran *context.AmfRan, packet []byte) (bool, string) {
+	if ran == nil {
+		logger.NgapLog.Error("Ran is nil")
+		return false, ngap_metrics.RAN_NIL_ERR
+	}
+	return sendToRanConn(ran, ran.Conn, packet)
+}
+
+// sendToRanConn sends the packet over one of the TNL associations of the RAN
+func sendToRanConn(ran *context.AmfRan, conn net.Conn, packet []byte) (bool, string) {
 	defer func() {
 		// This is workaround.
 		// TODO: Handle ran.Conn close event correctly
@@ -36,19 +46,19 @@ func SendToRan(ran *context.AmfRan, packet []byte) (bool, string) {
 		return false, "packet len is 0"
 	}
 
-	if ran.Conn == nil {
+	if conn == nil {
 		ran.Log.Error("Ran conn is nil")
 		return false, "Ran conn is nil"
 	}
 
-	if ran.Conn.RemoteAddr() == nil {
+	if conn.RemoteAddr() == nil {
 		ran.Log.Error("Ran addr is nil")
 		return false, "Ran addr is nil"
 	}
 
 	ran.Log.Debugf("Send NGAP message To Ran")
 
-	if n, err := ran.Conn.Write(packet); err != nil {
+	if n, err := conn.Write(packet); err != nil {
 		ran.Log.Errorf("Send error: %+v", err)
 		return false, ngap_metrics.SCTP_SOCKET_WRITE_ERR
 	} else {
@@ -74,7 +84,15 @@ func SendToRanUe(ue *context.RanUe, packet []byte) (bool, string) {
 		ue.Log.Warn("AmfUe is nil")
 	}
 
-	return SendToRan(ran, packet)
+	// UE-associated signalling goes over the TNL association the UE is bound to, an unbound UE
+	// uses the association selected with the TNL address weight factors
+	conn := ran.Conn
+	if tnla := ue.TNLAssociation(); tnla != nil {
+		conn = tnla.Conn
+	} else if tnla := ran.SelectTNLAssociation(); tnla != nil {
+		conn = tnla.Conn
+	}
+	return sendToRanConn(ran, conn, packet)
 }
 
 func NasSendToRan(ue *context.AmfUe, accessType models.AccessType, packet []byte) (bool, string) {
@@ -1258,9 +1276,10 @@ func SendUETNLABindingReleaseRequest(ue *context.RanUe) {
 	isUETNLABindingRelReqSent, additionalCause = SendToRanUe(ue, pkt)
 }
 
-// Weight Factor associated with each of the TNL association within the AMF
-func SendAMFConfigurationUpdate(ran *context.AmfRan, usage ngapType.TNLAssociationUsage,
-	weightfactor ngapType.TNLAddressWeightFactor,
+// The AMF endpoints the NG-RAN node shall set up TNL associations to, stop using, or use differently,
+// with the usage and the Weight Factor associated with each of them
+func SendAMFConfigurationUpdate(ran *context.AmfRan, tnlaToAddList, tnlaToRemoveList,
+	tnlaToUpdateList []context.AmfTnlEndpoint,
 ) {
 	isAMFConfigurationUpdateSent := false
 	additionalCause := ""
@@ -1275,7 +1294,7 @@ func SendAMFConfigurationUpdate(ran *context.AmfRan, usage ngapType.TNLAssociati
 
 	ran.Log.Info("Send AMF Configuration Update")
 
-	pkt, err := BuildAMFConfigurationUpdate(usage, weightfactor)
+	pkt, err := BuildAMFConfigurationUpdate(tnlaToAddList, tnlaToRemoveList, tnlaToUpdateList)
 	if err != nil {
 		additionalCause = ngap_metrics.NGAP_MSG_BUILD_ERR
 		ran.Log.Errorf("Build AMFConfigurationUpdate failed : %s", err.Error())
@@ -0,0 +1,112 @@
+package ngap
+
+import (
+	"github.com/free5gc/amf/internal/context"
+	ngap_message "github.com/free5gc/amf/internal/ngap/message"
+	callback "github.com/free5gc/amf/internal/sbi/processor/notifier"
+	"github.com/free5gc/ngap/ngapType"
+	"github.com/free5gc/openapi/models"
+)
+
+// nasNonDeliveryProcedure returns the procedure which prevented the delivery of the NAS PDU, the NAS PDU is sent
+// again once it is over. OnGoingProcedureNothing is returned if the NAS PDU is not to be sent again.
+func nasNonDeliveryProcedure(amfUe *context.AmfUe, anType models.AccessType,
+	cause ngapType.Cause,
+) context.OnGoingProcedure {
+	if cause.Present == ngapType.CausePresentRadioNetwork {
+		switch cause.RadioNetwork.Value {
+		case ngapType.CauseRadioNetworkPresentXnHandoverTriggered:
+			return context.OnGoingProcedureXnHandover
+		case ngapType.CauseRadioNetworkPresentNgIntraSystemHandoverTriggered,
+			ngapType.CauseRadioNetworkPresentNgInterSystemHandoverTriggered:
+			return context.OnGoingProcedureN2Handover
+		}
+	}
+	if amfUe.OnGoing(anType).Procedure == context.OnGoingProcedureN2Handover {
+		return context.OnGoingProcedureN2Handover
+	}
+	return context.OnGoingProcedureNothing
+}
+
+// nasNonDeliveryCauseToModels maps the cause of a NAS Non Delivery Indication to the cause notified to the NF
+// which requested the N1N2 Message Transfer
+func nasNonDeliveryCauseToModels(cause ngapType.Cause) models.N1N2MessageTransferCause {
+	switch cause.Present {
+	case ngapType.CausePresentRadioNetwork:
+		switch cause.RadioNetwork.Value {
+		case ngapType.CauseRadioNetworkPresentRadioConnectionWithUeLost,
+			ngapType.CauseRadioNetworkPresentUserInactivity,
+			ngapType.CauseRadioNetworkPresentFailureInRadioInterfaceProcedure,
+			ngapType.CauseRadioNetworkPresentUeInRrcInactiveStateNotReachable:
+			return models.N1N2MessageTransferCause_UE_NOT_RESPONDING
+		case ngapType.CauseRadioNetworkPresentXnHandoverTriggered,
+			ngapType.CauseRadioNetworkPresentNgIntraSystemHandoverTriggered,
+			ngapType.CauseRadioNetworkPresentNgInterSystemHandoverTriggered:
+			return models.N1N2MessageTransferCause_TEMPORARY_REJECT_HANDOVER_ONGOING
+		}
+	case ngapType.CausePresentTransport:
+		return models.N1N2MessageTransferCause_AN_NOT_RESPONDING
+	}
+	return models.N1N2MessageTransferCause_FAILURE_CAUSE_UNSPECIFIED
+}
+
+// handleUndeliveredNasPdu handles a downlink NAS PDU the NG-RAN node failed to deliver to the UE
+// (TS 23.502 4.2.3.3, TS 38.413 8.6.4). A NAS PDU not delivered because of a handover is buffered and sent
+// again once the handover is over. Otherwise, or if it cannot be sent again, the NF which requested its
+// N1N2 Message Transfer is notified of the failure.
+func handleUndeliveredNasPdu(ranUe *context.RanUe, amfUe *context.AmfUe, nasPdu []byte, cause ngapType.Cause) {
+	procedure := nasNonDeliveryProcedure(amfUe, ranUe.Ran.AnType, cause)
+	if procedure != context.OnGoingProcedureNothing {
+		undelivered, buffered := amfUe.BufferUndeliveredNasPdu(nasPdu, cause, procedure)
+		if buffered {
+			ranUe.Log.Infof("Buffer undelivered NAS PDU until the end of %s", procedure)
+			return
+		}
+		ranUe.Log.Warnf("Undelivered NAS PDU cannot be sent again after %d retransmissions",
+			undelivered.Retransmissions)
+		notifyNasNonDelivery(ranUe, undelivered.Origin, models.N1N2MessageTransferCause_TEMPORARY_REJECT_HANDOVER_ONGOING)
+		return
+	}
+
+	origin := amfUe.N1N2MessageOriginOf(nasPdu)
+	if origin == nil {
+		ranUe.Log.Warn("Discard undelivered NAS PDU originated by the AMF")
+		return
+	}
+	notifyNasNonDelivery(ranUe, origin, nasNonDeliveryCauseToModels(cause))
+}
+
+func notifyNasNonDelivery(ranUe *context.RanUe, origin *context.N1N2MessageOrigin,
+	cause models.N1N2MessageTransferCause,
+) {
+	if origin == nil {
+		return
+	}
+	ranUe.Log.Infof("Notify N1N2 transfer failure[%s] to %s", cause, origin.N1n2FailureTxfNotifURI)
+	callback.SendN1MessageFailureNotification(origin, cause)
+}
+
+// retransmitUndeliveredNasPdus sends again, over the NG-RAN node now serving the UE, the NAS PDUs which could
+// not be delivered during the procedure which is over
+func retransmitUndeliveredNasPdus(ranUe *context.RanUe, procedure context.OnGoingProcedure) {
+	amfUe := ranUe.AmfUe
+	if amfUe == nil {
+		return
+	}
+	for _, undelivered := range amfUe.TakeUndeliveredNasPdus(procedure) {
+		ranUe.Log.Infof("Send again NAS PDU undelivered during %s", procedure)
+		ngap_message.SendDownlinkNasTransport(ranUe, undelivered.NasPdu, nil)
+	}
+}
+
+// abortUndeliveredNasPdus gives up the NAS PDUs which could not be delivered during the procedure which failed,
+// the NFs which requested their N1N2 Message Transfer are notified of the failure
+func abortUndeliveredNasPdus(ranUe *context.RanUe, procedure context.OnGoingProcedure) {
+	amfUe := ranUe.AmfUe
+	if amfUe == nil {
+		return
+	}
+	for _, undelivered := range amfUe.TakeUndeliveredNasPdus(procedure) {
+		notifyNasNonDelivery(ranUe, undelivered.Origin, nasNonDeliveryCauseToModels(undelivered.Cause))
+	}
+}
@@ -236,8 +236,6 @@ func readASN1() {
 
 func fixIEs() {
 	// Not implemented IEs
-	MsgTable["AMFConfigurationUpdateAcknowledge"].IEs["id-AMF-TNLAssociationSetupList"].Unimplemented = true
-	MsgTable["AMFConfigurationUpdateAcknowledge"].IEs["id-AMF-TNLAssociationFailedToSetupList"].Unimplemented = true
 	MsgTable["AMFConfigurationUpdateFailure"].IEs["id-TimeToWait"].Unimplemented = true
 	MsgTable["HandoverRequired"].IEs["id-DirectForwardingPathAvailability"].Unimplemented = true
 	MsgTable["InitialUEMessage"].IEs["id-AMFSetID"].Unimplemented = true
@@ -561,7 +559,6 @@ syntaxCause = &ngapType.Cause{
 			msgName == "PWSCancelResponse" || // XXX not implemented
 			msgName == "PWSFailureIndication" || // XXX not implemented
 			msgName == "PWSRestartIndication" || // XXX not implemented
-			msgName == "SecondaryRATDataUsageReport" || // XXX not implemented
 			msgName == "TraceFailureIndication" || // XXX not implemented
 			msgName == "WriteReplaceWarningResponse" { // XXX not implemented
 			stubCause := "CauseProtocolPresentUnspecified"
@@ -0,0 +1,154 @@
+package ngap
+
+import (
+	"net"
+
+	"github.com/free5gc/amf/internal/context"
+	ngap_message "github.com/free5gc/amf/internal/ngap/message"
+	"github.com/free5gc/ngap/ngapConvert"
+	"github.com/free5gc/ngap/ngapType"
+)
+
+// addTNLAssociation adds an additional TNL association of the NG-RAN node (TS 38.412 7). The UE TNLA
+// bindings are rebalanced so that the new association takes its share of the UE-associated signalling.
+func addTNLAssociation(ran *context.AmfRan, conn net.Conn) {
+	tnla := ran.AddTNLAssociation(conn)
+	ran.Log.Infof("Add TNL association[%s] usage[%d] weight factor[%d]",
+		conn.RemoteAddr(), tnla.Usage, tnla.WeightFactor)
+	rebalanceUeTNLABinding(ran)
+}
+
+// restartRan replaces the RAN context of a NG-RAN node which sends a NG Setup Request over a new TNL association
+// after its restart: the NG Setup erases the application level data of the NG-C interface instance (TS 38.413
+// 8.7.1.2), so the UE contexts of the previous instance are released and the UEs are left in CM-IDLE.
+func restartRan(ran *context.AmfRan, conn net.Conn) *context.AmfRan {
+	ran.Log.Infof("NG-RAN node restarted, NG Setup over a new TNL association[%s]", conn.RemoteAddr())
+	ran.Remove()
+	return context.GetSelf().NewAmfRan(conn)
+}
+
+// amfRanFindByGlobalRANNodeID finds the NG-RAN node a new TNL association belongs to from the Global RAN Node ID
+// of the NG Setup Request or RAN Configuration Update received over it (TS 38.413 8.7.1.2, 8.7.2.2)
+func amfRanFindByGlobalRANNodeID(pdu *ngapType.NGAPPDU) (*context.AmfRan, bool) {
+	if pdu.Present != ngapType.NGAPPDUPresentInitiatingMessage || pdu.InitiatingMessage == nil {
+		return nil, false
+	}
+
+	var globalRANNodeID *ngapType.GlobalRANNodeID
+	value := pdu.InitiatingMessage.Value
+	switch pdu.InitiatingMessage.ProcedureCode.Value {
+	case ngapType.ProcedureCodeNGSetup:
+		if value.NGSetupRequest == nil {
+			return nil, false
+		}
+		for _, ie := range value.NGSetupRequest.ProtocolIEs.List {
+			if ie.Id.Value == ngapType.ProtocolIEIDGlobalRANNodeID {
+				globalRANNodeID = ie.Value.GlobalRANNodeID
+			}
+		}
+	case ngapType.ProcedureCodeRANConfigurationUpdate:
+		if value.RANConfigurationUpdate == nil {
+			return nil, false
+		}
+		for _, ie := range value.RANConfigurationUpdate.ProtocolIEs.List {
+			if ie.Id.Value == ngapType.ProtocolIEIDGlobalRANNodeID {
+				globalRANNodeID = ie.Value.GlobalRANNodeID
+			}
+		}
+	}
+	if globalRANNodeID == nil {
+		return nil, false
+	}
+	return context.GetSelf().AmfRanFindByRanID(ngapConvert.RanIdToModels(*globalRANNodeID))
+}
+
+// removeTNLAssociation removes a TNL association the NG-RAN node lost, the RAN context is removed together
+// with its last association. The NG-RAN node is requested to release the UE TNLA binding of the UEs which
+// were bound to the removed association (TS 38.413 8.3.6), it binds them again on their next UE-associated
+// signalling.
+func removeTNLAssociation(ran *context.AmfRan, conn net.Conn) {
+	if ran.TNLAssociationNum() <= 1 {
+		ran.Remove()
+		return
+	}
+
+	ran.Log.Infof("Remove TNL association[%s]", conn.RemoteAddr())
+	for _, ranUe := range ran.RemoveTNLAssociation(conn) {
+		ngap_message.SendUETNLABindingReleaseRequest(ranUe)
+	}
+}
+
+// rebalanceUeTNLABinding releases the UE TNLA bindings exceeding the share of their association
+func rebalanceUeTNLABinding(ran *context.AmfRan) {
+	for _, ranUe := range ran.UnbalancedRanUes() {
+		// sent over the current binding before it is released
+		ngap_message.SendUETNLABindingReleaseRequest(ranUe)
+		ranUe.UnbindTNLAssociation()
+	}
+}
+
+// bindUeTNLAssociation binds the UE to the TNL association the NG-RAN node used for its UE-associated
+// signalling, the binding is updated on each received message (TS 38.412 7)
+func bindUeTNLAssociation(ran *context.AmfRan, conn net.Conn, pdu *ngapType.NGAPPDU) {
+	tnla, ok := ran.TNLAssociationFindByConn(conn)
+	if !ok {
+		return
+	}
+
+	ueID, found := extractUEID(pdu)
+	if !found {
+		return
+	}
+
+	var ranUe *context.RanUe
+	if pdu.Present == ngapType.NGAPPDUPresentInitiatingMessage &&
+		pdu.InitiatingMessage.ProcedureCode.Value == ngapType.ProcedureCodeInitialUEMessage {
+		ranUe = ran.RanUeFindByRanUeNgapID(int64(ueID))
+	} else {
+		ranUe = context.GetSelf().RanUeFindByAmfUeNgapID(int64(ueID))
+	}
+	if ranUe == nil || ranUe.Ran != ran || ranUe.TNLAssociation() == tnla {
+		return
+	}
+
+	ranUe.Log.Debugf("Bind UE to TNL association[%s]", conn.RemoteAddr())
+	ranUe.BindTNLAssociation(tnla)
+}
+
+// advertiseTnlEndpoints advertises the use of the AMF NGAP endpoints to the NG-RAN node in an AMF
+// Configuration Update: the NG-RAN node is requested to set up TNL associations to the endpoints it has no
+// association with yet, and is informed of the usage and weight factor of the ones it already uses.
+func advertiseTnlEndpoints(ran *context.AmfRan) {
+	amfSelf := context.GetSelf()
+	if len(amfSelf.NgapTnlEndpointList) <= 1 {
+		return
+	}
+
+	usedEndpoints := make(map[string]bool)
+	ran.TnlaList.Range(func(key, value interface{}) bool {
+		tnla := value.(*context.TNLAssociation)
+		usedEndpoints[amfSelf.TnlEndpointByAddr(tnla.Conn.LocalAddr()).Ip] = true
+		return true
+	})
+
+	var tnlaToAddList, tnlaToUpdateList []context.AmfTnlEndpoint
+	for _, endpoint := range amfSelf.NgapTnlEndpointList {
+		if usedEndpoints[endpoint.Ip] {
+			tnlaToUpdateList = append(tnlaToUpdateList, endpoint)
+		} else {
+			tnlaToAddList = append(tnlaToAddList, endpoint)
+		}
+	}
+	ngap_message.SendAMFConfigurationUpdate(ran, tnlaToAddList, nil, tnlaToUpdateList)
+}
+
+func tnlAssociationAddressToString(address ngapType.CPTransportLayerInformation) string {
+	if address.EndpointIPAddress == nil {
+		return ""
+	}
+	ipv4, ipv6 := ngapConvert.IPAddressToString(*address.EndpointIPAddress)
+	if ipv4 != "" {
+		return ipv4
+	}
+	return ipv6
+}
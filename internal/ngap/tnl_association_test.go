@@ -0,0 +1,208 @@
+package ngap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	amf_context "github.com/free5gc/amf/internal/context"
+	ngaptesting "github.com/free5gc/amf/internal/ngap/testing"
+	"github.com/free5gc/ngap"
+	"github.com/free5gc/ngap/ngapConvert"
+	"github.com/free5gc/ngap/ngapType"
+	"github.com/free5gc/openapi/models"
+)
+
+func newUplinkNASTransportPDU(amfUeNgapID int64) *ngapType.NGAPPDU {
+	pdu := &ngapType.NGAPPDU{
+		Present: ngapType.NGAPPDUPresentInitiatingMessage,
+		InitiatingMessage: &ngapType.InitiatingMessage{
+			ProcedureCode: ngapType.ProcedureCode{Value: ngapType.ProcedureCodeUplinkNASTransport},
+			Value: ngapType.InitiatingMessageValue{
+				Present:            ngapType.InitiatingMessagePresentUplinkNASTransport,
+				UplinkNASTransport: new(ngapType.UplinkNASTransport),
+			},
+		},
+	}
+	ie := ngapType.UplinkNASTransportIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDAMFUENGAPID
+	ie.Value.Present = ngapType.UplinkNASTransportIEsPresentAMFUENGAPID
+	ie.Value.AMFUENGAPID = &ngapType.AMFUENGAPID{Value: amfUeNgapID}
+	pdu.InitiatingMessage.Value.UplinkNASTransport.ProtocolIEs.List = append(
+		pdu.InitiatingMessage.Value.UplinkNASTransport.ProtocolIEs.List, ie)
+	return pdu
+}
+
+func newRANConfigurationUpdatePDU(globalRANNodeID ngapType.GlobalRANNodeID) *ngapType.NGAPPDU {
+	pdu := &ngapType.NGAPPDU{
+		Present: ngapType.NGAPPDUPresentInitiatingMessage,
+		InitiatingMessage: &ngapType.InitiatingMessage{
+			ProcedureCode: ngapType.ProcedureCode{Value: ngapType.ProcedureCodeRANConfigurationUpdate},
+			Value: ngapType.InitiatingMessageValue{
+				Present:                ngapType.InitiatingMessagePresentRANConfigurationUpdate,
+				RANConfigurationUpdate: new(ngapType.RANConfigurationUpdate),
+			},
+		},
+	}
+	ie := ngapType.RANConfigurationUpdateIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDGlobalRANNodeID
+	ie.Value.Present = ngapType.RANConfigurationUpdateIEsPresentGlobalRANNodeID
+	ie.Value.GlobalRANNodeID = &globalRANNodeID
+	pdu.InitiatingMessage.Value.RANConfigurationUpdate.ProtocolIEs.List = append(
+		pdu.InitiatingMessage.Value.RANConfigurationUpdate.ProtocolIEs.List, ie)
+	return pdu
+}
+
+func requireUETNLABindingReleaseRequests(t *testing.T, connStub *ngaptesting.SctpConnStub, num int) {
+	require.Len(t, connStub.MsgList, num)
+	for _, msg := range connStub.MsgList {
+		pdu, err := ngap.Decoder(msg)
+		require.NoError(t, err)
+		require.Equal(t, ngapType.ProcedureCodeUETNLABindingRelease, pdu.InitiatingMessage.ProcedureCode.Value)
+	}
+	connStub.MsgList = nil
+}
+
+func TestMultipleTNLAssociations(t *testing.T) {
+	amfSelf := amf_context.GetSelf()
+	NewAmfContext(amfSelf)
+	amfSelf.TNLWeightFactor = 0xff
+
+	conn1 := new(ngaptesting.SctpConnStub)
+	conn2 := new(ngaptesting.SctpConnStub)
+	ran := amfSelf.NewAmfRan(conn1)
+	tnla1, ok := ran.TNLAssociationFindByConn(conn1)
+	require.True(t, ok)
+
+	var ranUeList []*amf_context.RanUe
+	for i := int64(1); i <= 4; i++ {
+		ranUe, err := ran.NewRanUe(i)
+		require.NoError(t, err)
+		bindUeTNLAssociation(ran, conn1, newUplinkNASTransportPDU(ranUe.AmfUeNgapId))
+		require.Equal(t, tnla1, ranUe.TNLAssociation())
+		ranUeList = append(ranUeList, ranUe)
+	}
+
+	// The additional association of the same NG-RAN node is found from the Global RAN Node ID of the
+	// RAN Configuration Update received over it
+	globalRANNodeID := ngapConvert.RanIDToNgap(models.GlobalRanNodeId{
+		PlmnId: &models.PlmnId{Mcc: "208", Mnc: "93"},
+		GNbId:  &models.GNbId{BitLength: 24, GNBValue: "000102"},
+	})
+	_, ok = amfRanFindByGlobalRANNodeID(newRANConfigurationUpdatePDU(globalRANNodeID))
+	require.False(t, ok)
+	ran.SetRanId(&globalRANNodeID)
+	found, ok := amfRanFindByGlobalRANNodeID(newRANConfigurationUpdatePDU(globalRANNodeID))
+	require.True(t, ok)
+	require.Equal(t, ran, found)
+	_, ok = amfRanFindByGlobalRANNodeID(newUplinkNASTransportPDU(1))
+	require.False(t, ok)
+
+	// Half of the UEs are released so that the NG-RAN node binds them to the new association
+	addTNLAssociation(ran, conn2)
+	require.Equal(t, 2, ran.TNLAssociationNum())
+	found, ok = amfSelf.AmfRanFindByConn(conn2)
+	require.True(t, ok)
+	require.Equal(t, ran, found)
+	requireUETNLABindingReleaseRequests(t, conn1, 2)
+	require.Equal(t, int64(2), tnla1.NumOfUe())
+
+	tnla2, ok := ran.TNLAssociationFindByConn(conn2)
+	require.True(t, ok)
+	for _, ranUe := range ranUeList {
+		if ranUe.TNLAssociation() == nil {
+			bindUeTNLAssociation(ran, conn2, newUplinkNASTransportPDU(ranUe.AmfUeNgapId))
+			require.Equal(t, tnla2, ranUe.TNLAssociation())
+		}
+	}
+	require.Equal(t, int64(2), tnla2.NumOfUe())
+
+	// Losing the NG Setup association moves the non UE-associated signalling to the remaining one,
+	// the UEs bound to the lost association are released over the remaining one
+	removeTNLAssociation(ran, conn1)
+	require.Equal(t, 1, ran.TNLAssociationNum())
+	require.Equal(t, conn2, ran.Conn)
+	_, ok = amfSelf.AmfRanFindByConn(conn1)
+	require.False(t, ok)
+	found, ok = amfSelf.AmfRanFindByConn(conn2)
+	require.True(t, ok)
+	require.Equal(t, ran, found)
+	require.Empty(t, conn1.MsgList)
+	requireUETNLABindingReleaseRequests(t, conn2, 2)
+
+	// The last association removes the RAN
+	removeTNLAssociation(ran, conn2)
+	_, ok = amfSelf.AmfRanFindByConn(conn2)
+	require.False(t, ok)
+	require.Zero(t, tnla2.NumOfUe())
+}
+
+func newNGSetupRequestPDU(globalRANNodeID ngapType.GlobalRANNodeID) *ngapType.NGAPPDU {
+	pdu := &ngapType.NGAPPDU{
+		Present: ngapType.NGAPPDUPresentInitiatingMessage,
+		InitiatingMessage: &ngapType.InitiatingMessage{
+			ProcedureCode: ngapType.ProcedureCode{Value: ngapType.ProcedureCodeNGSetup},
+			Value: ngapType.InitiatingMessageValue{
+				Present:        ngapType.InitiatingMessagePresentNGSetupRequest,
+				NGSetupRequest: new(ngapType.NGSetupRequest),
+			},
+		},
+	}
+	ie := ngapType.NGSetupRequestIEs{}
+	ie.Id.Value = ngapType.ProtocolIEIDGlobalRANNodeID
+	ie.Value.Present = ngapType.NGSetupRequestIEsPresentGlobalRANNodeID
+	ie.Value.GlobalRANNodeID = &globalRANNodeID
+	pdu.InitiatingMessage.Value.NGSetupRequest.ProtocolIEs.List = append(
+		pdu.InitiatingMessage.Value.NGSetupRequest.ProtocolIEs.List, ie)
+	return pdu
+}
+
+func TestNGSetupAfterRANRestart(t *testing.T) {
+	amfSelf := amf_context.GetSelf()
+	NewAmfContext(amfSelf)
+	amfSelf.TNLWeightFactor = 0xff
+
+	globalRANNodeID := ngapConvert.RanIDToNgap(models.GlobalRanNodeId{
+		PlmnId: &models.PlmnId{Mcc: "208", Mnc: "93"},
+		GNbId:  &models.GNbId{BitLength: 24, GNBValue: "000102"},
+	})
+	conn1 := new(ngaptesting.SctpConnStub)
+	conn2 := new(ngaptesting.SctpConnStub)
+	conn3 := new(ngaptesting.SctpConnStub)
+	ran := amfSelf.NewAmfRan(conn1)
+	ran.SetRanId(&globalRANNodeID)
+	ranUe, err := ran.NewRanUe(1)
+	require.NoError(t, err)
+	amfUe := amfSelf.NewAmfUe("imsi-208930000000005")
+	defer amfUe.Remove()
+	amfUe.AttachRanUe(ranUe)
+
+	// A RAN Configuration Update over a new association adds it to the NG-RAN node
+	msg, err := ngap.Encoder(*newRANConfigurationUpdatePDU(globalRANNodeID))
+	require.NoError(t, err)
+	Dispatch(conn2, msg)
+	found, ok := amfSelf.AmfRanFindByConn(conn2)
+	require.True(t, ok)
+	require.Equal(t, ran, found)
+	require.Equal(t, 2, ran.TNLAssociationNum())
+	require.Equal(t, ranUe, amfSelf.RanUeFindByAmfUeNgapID(ranUe.AmfUeNgapId))
+
+	// A NG Setup over a new association is sent by the restarted NG-RAN node, the previous RAN context and
+	// its UE contexts are released
+	msg, err = ngap.Encoder(*newNGSetupRequestPDU(globalRANNodeID))
+	require.NoError(t, err)
+	Dispatch(conn3, msg)
+	for _, conn := range []*ngaptesting.SctpConnStub{conn1, conn2} {
+		_, ok = amfSelf.AmfRanFindByConn(conn)
+		require.False(t, ok)
+	}
+	found, ok = amfSelf.AmfRanFindByConn(conn3)
+	require.True(t, ok)
+	defer found.Remove()
+	require.NotEqual(t, ran, found)
+	require.Equal(t, 1, found.TNLAssociationNum())
+	require.Nil(t, amfSelf.RanUeFindByAmfUeNgapID(ranUe.AmfUeNgapId))
+	require.Nil(t, amfUe.RanUe[ran.AnType])
+	require.Nil(t, ranUe.AmfUe)
+	require.NotEmpty(t, conn3.MsgList)
+}
@@ -17,6 +17,11 @@ func ExtractUEID(msg []byte) (uint64, bool) {
 		return 0, false
 	}
 
+	return extractUEID(pdu)
+}
+
+// extractUEID extracts the UE identifier of a decoded NGAP message, see ExtractUEID
+func extractUEID(pdu *ngapType.NGAPPDU) (uint64, bool) {
 	if pdu == nil {
 		logger.NgapLog.Trace("NGAP PDU is nil")
 		return 0, false
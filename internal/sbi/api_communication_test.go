@@ -561,6 +561,69 @@ func TestCommunication_N1N2Message_WithUE(t *testing.T) {
 	}
 }
 
+func TestCommunication_N1N2MessageUnSubscribe_ReleasesPositioningSessions(t *testing.T) {
+	mock := newMockCommunicationAmf()
+	supi := "imsi-208930000000013"
+	ue := mock.ctx.NewAmfUe(supi)
+	defer ue.Remove()
+	ue.N1N2MessageSubscription.Store(int64(1), models.UeN1N2InfoSubscriptionCreateData{
+		N1NotifyCallbackUri: "http://lmf-1/notify",
+		N1MessageClass:      models.N1MessageClass_LPP,
+		NfId:                "lmf-1",
+	})
+	session := ue.NewPositioningSession("lmf-1", "lcs-1")
+	other := ue.NewPositioningSession("lmf-2", "lcs-1")
+
+	s := &Server{ServerAmf: mock}
+	router := setupTestCommunicationRouter(s)
+	req := httptest.NewRequest(http.MethodDelete, "/ue-contexts/"+supi+"/n1-n2-messages/subscriptions/1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if _, ok := ue.N1N2MessageSubscription.Load(int64(1)); ok {
+		t.Fatalf("N1N2 message subscription should have been removed")
+	}
+	// only the positioning sessions of the LMF unsubscribing are released
+	if _, ok := ue.PositioningSessionFindByRoutingInformation(session.RoutingInformation()); ok {
+		t.Fatalf("Positioning session of lmf-1 should have been removed")
+	}
+	if _, ok := ue.PositioningSessionFindByRoutingInformation(other.RoutingInformation()); !ok {
+		t.Fatalf("Positioning session of lmf-2 should have been kept")
+	}
+}
+
+func TestCommunication_N1N2MessageTransfer_ExtendedBuffering(t *testing.T) {
+	s, _ := NewTestServer(t)
+	mock := newMockCommunicationAmf()
+	supi := "imsi-208930000000014"
+	ue := mock.ctx.NewAmfUe(supi)
+	defer ue.Remove()
+	ue.State[models.AccessType__3_GPP_ACCESS].Set(amf_context.Registered)
+	// long eDRX cycle of 81.92 s with a paging time window of 5.12 s
+	ue.NegotiatedEdrx = amf_context.NewExtendedDRXParameters(0x35)
+
+	request := models.N1N2MessageTransferRequest{
+		JsonData: &models.N1N2MessageTransferReqData{ExtBufSupport: true},
+	}
+	_, _, problemDetails, transferErr := s.Processor().N1N2MessageTransferProcedure(supi, "", request)
+	if problemDetails != nil {
+		t.Fatalf("Unexpected problem details: %+v", problemDetails)
+	}
+	if transferErr == nil || transferErr.Error.Status != http.StatusGatewayTimeout ||
+		transferErr.Error.Cause != "UE_NOT_REACHABLE" {
+		t.Fatalf("Expected UE_NOT_REACHABLE, got %+v", transferErr)
+	}
+	if transferErr.ErrInfo == nil || transferErr.ErrInfo.MaxWaitingTime != 87 {
+		t.Fatalf("Expected the max waiting time of 87 s, got %+v", transferErr.ErrInfo)
+	}
+	if ue.N1N2Message != nil {
+		t.Fatalf("The N1N2 message should be buffered by the SMF")
+	}
+}
+
 func TestCommunication_CreateUEContext_WithUE(t *testing.T) {
 	mock := newMockCommunicationAmf()
 
@@ -7,6 +7,7 @@ import (
 
 	amf_context "github.com/free5gc/amf/internal/context"
 	"github.com/free5gc/amf/internal/logger"
+	"github.com/free5gc/amf/internal/sbi/consumer"
 	"github.com/free5gc/openapi"
 	"github.com/free5gc/openapi/models"
 	"github.com/free5gc/util/metrics/sbi"
@@ -51,6 +52,24 @@ func (s *Server) getHttpCallBackRoutes() []Route {
 			Pattern: "/deregistration/:ueid",
 			APIFunc: s.HTTPHandleDeregistrationNotification,
 		},
+		{
+			Name:    "SdmDataChangeNotification",
+			Method:  http.MethodPost,
+			Pattern: "/sdm-notify/:supi",
+			APIFunc: s.HTTPSdmDataChangeNotification,
+		},
+		{
+			Name:    "NssaaReauthNotification",
+			Method:  http.MethodPost,
+			Pattern: "/nssaa-reauth/:supi",
+			APIFunc: s.HTTPSliceAuthNotification,
+		},
+		{
+			Name:    "NssaaRevocNotification",
+			Method:  http.MethodPost,
+			Pattern: "/nssaa-revoc/:supi",
+			APIFunc: s.HTTPSliceAuthNotification,
+		},
 	}
 }
 
@@ -174,6 +193,74 @@ func (s *Server) HTTPSmContextStatusNotify(c *gin.Context) {
 	s.Processor().HandleSmContextStatusNotify(c, smContextStatusNotification)
 }
 
+func (s *Server) HTTPSdmDataChangeNotification(c *gin.Context) {
+	var modificationNotification models.ModificationNotification
+
+	requestBody, err := c.GetRawData()
+	if err != nil {
+		logger.CallbackLog.Errorf("Get Request Body error: %+v", err)
+		problemDetail := models.ProblemDetails{
+			Title:  "System failure",
+			Status: http.StatusInternalServerError,
+			Detail: err.Error(),
+			Cause:  "SYSTEM_FAILURE",
+		}
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetail.Cause)
+		c.JSON(http.StatusInternalServerError, problemDetail)
+		return
+	}
+
+	err = openapi.Deserialize(&modificationNotification, requestBody, "application/json")
+	if err != nil {
+		problemDetail := reqbody + err.Error()
+		rsp := models.ProblemDetails{
+			Title:  "Malformed request syntax",
+			Status: http.StatusBadRequest,
+			Detail: problemDetail,
+		}
+		logger.CallbackLog.Errorln(problemDetail)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(http.StatusBadRequest))
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	s.Processor().HandleSdmDataChangeNotification(c, modificationNotification)
+}
+
+func (s *Server) HTTPSliceAuthNotification(c *gin.Context) {
+	var sliceAuthNotification consumer.SliceAuthNotification
+
+	requestBody, err := c.GetRawData()
+	if err != nil {
+		logger.CallbackLog.Errorf("Get Request Body error: %+v", err)
+		problemDetail := models.ProblemDetails{
+			Title:  "System failure",
+			Status: http.StatusInternalServerError,
+			Detail: err.Error(),
+			Cause:  "SYSTEM_FAILURE",
+		}
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetail.Cause)
+		c.JSON(http.StatusInternalServerError, problemDetail)
+		return
+	}
+
+	err = openapi.Deserialize(&sliceAuthNotification, requestBody, "application/json")
+	if err != nil {
+		problemDetail := reqbody + err.Error()
+		rsp := models.ProblemDetails{
+			Title:  "Malformed request syntax",
+			Status: http.StatusBadRequest,
+			Detail: problemDetail,
+		}
+		logger.CallbackLog.Errorln(problemDetail)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(http.StatusBadRequest))
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	s.Processor().HandleSliceAuthNotification(c, sliceAuthNotification)
+}
+
 func (s *Server) HTTPHandleDeregistrationNotification(c *gin.Context) {
 	// TS 23.502 - 4.2.2.2.2 - step 14d
 	logger.CallbackLog.Traceln("Handle Deregistration Notification")
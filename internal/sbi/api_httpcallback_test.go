@@ -77,6 +77,18 @@ func TestCallbackRoutes_Definitions(t *testing.T) {
 			Method: http.MethodPost,
 			Name:   "HandleDeregistrationNotification",
 		},
+		"/sdm-notify/:supi": {
+			Method: http.MethodPost,
+			Name:   "SdmDataChangeNotification",
+		},
+		"/nssaa-reauth/:supi": {
+			Method: http.MethodPost,
+			Name:   "NssaaReauthNotification",
+		},
+		"/nssaa-revoc/:supi": {
+			Method: http.MethodPost,
+			Name:   "NssaaRevocNotification",
+		},
 	}
 
 	// Assert
@@ -461,3 +473,161 @@ func TestHTTPN1MessageNotify_Procedure(t *testing.T) {
 		}
 	})
 }
+
+// Test the Steering of Roaming and UE parameters update information provided by the UDM after the registration.
+func TestHTTPSdmDataChangeNotification(t *testing.T) {
+	s, _ := NewTestServer(t)
+	router := setupTestRouterCallback(s)
+
+	t.Run("UE not found", func(t *testing.T) {
+		w := PerformJSONRequest(router, http.MethodPost, "/sdm-notify/imsi-208930000000099", `{"notifyItems": []}`)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "CONTEXT_NOT_FOUND")
+	})
+
+	t.Run("SOR information kept for the next registration of a CM-IDLE UE", func(t *testing.T) {
+		fakeUe := &amf_context.AmfUe{
+			Supi:        "imsi-208930000000003",
+			ProducerLog: logger.ProducerLog,
+		}
+		ManageTestUE(t, fakeUe)
+
+		jsonBody := `{
+			"notifyItems": [{
+				"resourceId": "http://udm/nudm-sdm/v2/imsi-208930000000003/am-data",
+				"changes": [{
+					"op": "REPLACE",
+					"path": "/sorInfo",
+					"newValue": {
+						"ackInd": true,
+						"sorMacIausf": "000102030405060708090a0b0c0d0e0f",
+						"countersor": "0001",
+						"provisioningTime": "2026-01-01T00:00:00Z"
+					}
+				}]
+			}]
+		}`
+		w := PerformJSONRequest(router, http.MethodPost, "/sdm-notify/"+fakeUe.Supi, jsonBody)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		if assert.NotNil(t, fakeUe.SorInfo) {
+			assert.Equal(t, "0001", fakeUe.SorInfo.Countersor)
+		}
+		assert.True(t, fakeUe.SorAckRequested)
+	})
+
+	t.Run("UPU information kept for the next registration of a deregistered UE", func(t *testing.T) {
+		fakeUe := &amf_context.AmfUe{
+			Supi:        "imsi-208930000000005",
+			ProducerLog: logger.ProducerLog,
+		}
+		ManageTestUE(t, fakeUe)
+
+		jsonBody := `{
+			"notifyItems": [{
+				"resourceId": "http://udm/nudm-sdm/v2/imsi-208930000000005/am-data",
+				"changes": [{
+					"op": "REPLACE",
+					"path": "/upuInfo",
+					"newValue": {
+						"upuDataList": [{"routingId": "12"}],
+						"upuAckInd": true,
+						"upuMacIausf": "000102030405060708090a0b0c0d0e0f",
+						"counterUpu": "0001",
+						"provisioningTime": "2026-01-01T00:00:00Z"
+					}
+				}]
+			}]
+		}`
+		w := PerformJSONRequest(router, http.MethodPost, "/sdm-notify/"+fakeUe.Supi, jsonBody)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		if assert.NotNil(t, fakeUe.UpuInfo) {
+			assert.Equal(t, "0001", fakeUe.UpuInfo.CounterUpu)
+		}
+		assert.True(t, fakeUe.UpuAckRequested)
+		assert.True(t, fakeUe.HasPendingDLPayloads())
+	})
+
+	t.Run("Invalid UPU information", func(t *testing.T) {
+		fakeUe := &amf_context.AmfUe{
+			Supi:        "imsi-208930000000006",
+			ProducerLog: logger.ProducerLog,
+		}
+		ManageTestUE(t, fakeUe)
+
+		jsonBody := `{
+			"notifyItems": [{
+				"resourceId": "http://udm/nudm-sdm/v2/imsi-208930000000006/am-data",
+				"changes": [{
+					"op": "REPLACE",
+					"path": "/upuInfo",
+					"newValue": {"upuAckInd": true, "upuMacIausf": "0001", "counterUpu": "0001"}
+				}]
+			}]
+		}`
+		w := PerformJSONRequest(router, http.MethodPost, "/sdm-notify/"+fakeUe.Supi, jsonBody)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Nil(t, fakeUe.UpuInfo)
+		assert.False(t, fakeUe.HasPendingDLPayloads())
+	})
+}
+
+func TestHTTPSliceAuthNotification(t *testing.T) {
+	s, _ := NewTestServer(t)
+	router := setupTestRouterCallback(s)
+
+	t.Run("UE not found", func(t *testing.T) {
+		w := PerformJSONRequest(router, http.MethodPost, "/nssaa-revoc/imsi-208930000000099",
+			`{"notifType": "SLICE_REVOCATION", "snssai": {"sst": 1, "sd": "010203"}}`)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "CONTEXT_NOT_FOUND")
+	})
+
+	t.Run("Revocation of an allowed S-NSSAI of a CM-IDLE UE", func(t *testing.T) {
+		snssai := models.Snssai{Sst: 1, Sd: "010203"}
+		fakeUe := &amf_context.AmfUe{
+			Supi:        "imsi-208930000000004",
+			ProducerLog: logger.ProducerLog,
+			GmmLog:      logger.GmmLog,
+			State: map[models.AccessType]*fsm.State{
+				models.AccessType__3_GPP_ACCESS: fsm.NewState(amf_context.Registered),
+			},
+			AllowedNssai: map[models.AccessType][]models.AllowedSnssai{
+				models.AccessType__3_GPP_ACCESS: {
+					{AllowedSnssai: &models.Snssai{Sst: 1}},
+					{AllowedSnssai: &models.Snssai{Sst: 1, Sd: "010203"}},
+				},
+			},
+		}
+		ManageTestUE(t, fakeUe)
+
+		w := PerformJSONRequest(router, http.MethodPost, "/nssaa-revoc/"+fakeUe.Supi,
+			`{"notifType": "SLICE_REVOCATION", "snssai": {"sst": 1, "sd": "010203"}}`)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.False(t, fakeUe.InAllowedNssai(snssai, models.AccessType__3_GPP_ACCESS))
+		assert.Equal(t, []models.Snssai{snssai}, fakeUe.NssaaRejectedNssai)
+		status, ok := fakeUe.NssaaStatus(snssai)
+		assert.True(t, ok)
+		assert.Equal(t, models.AuthStatus_EAP_FAILURE, status)
+	})
+
+	t.Run("Re-authentication of a UE not registered", func(t *testing.T) {
+		fakeUe := &amf_context.AmfUe{
+			Supi:        "imsi-208930000000005",
+			ProducerLog: logger.ProducerLog,
+			GmmLog:      logger.GmmLog,
+			State: map[models.AccessType]*fsm.State{
+				models.AccessType__3_GPP_ACCESS:    fsm.NewState(amf_context.Deregistered),
+				models.AccessType_NON_3_GPP_ACCESS: fsm.NewState(amf_context.Deregistered),
+			},
+		}
+		ManageTestUE(t, fakeUe)
+
+		w := PerformJSONRequest(router, http.MethodPost, "/nssaa-reauth/"+fakeUe.Supi,
+			`{"notifType": "SLICE_RE_AUTH", "snssai": {"sst": 1, "sd": "010203"}}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -1,9 +1,16 @@
 package sbi
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/free5gc/amf/internal/logger"
+	"github.com/free5gc/amf/pkg/factory"
+	"github.com/free5gc/openapi"
+	"github.com/free5gc/openapi/models"
+	"github.com/free5gc/util/metrics/sbi"
 )
 
 func (s *Server) getOAMRoutes() []Route {
@@ -27,6 +34,24 @@ func (s *Server) getOAMRoutes() []Route {
 			Pattern: "/registered-ue-context/:supi",
 			APIFunc: s.HTTPRegisteredUEContext,
 		},
+		{
+			Name:    "GutiReallocation",
+			Method:  http.MethodPost,
+			Pattern: "/registered-ue-context/:supi/guti-reallocation",
+			APIFunc: s.HTTPGutiReallocation,
+		},
+		{
+			Name:    "NetworkName",
+			Method:  http.MethodPut,
+			Pattern: "/network-name",
+			APIFunc: s.HTTPNetworkName,
+		},
+		{
+			Name:    "Ladn",
+			Method:  http.MethodPut,
+			Pattern: "/ladn",
+			APIFunc: s.HTTPLadn,
+		},
 	}
 }
 
@@ -43,3 +68,85 @@ func (s *Server) HTTPRegisteredUEContext(c *gin.Context) {
 	s.setCorsHeader(c)
 	s.Processor().HandleOAMRegisteredUEContext(c)
 }
+
+func (s *Server) HTTPGutiReallocation(c *gin.Context) {
+	s.setCorsHeader(c)
+	s.Processor().HandleOAMGutiReallocation(c)
+}
+
+func (s *Server) HTTPNetworkName(c *gin.Context) {
+	s.setCorsHeader(c)
+
+	var networkName factory.NetworkName
+	requestBody, err := c.GetRawData()
+	if err != nil {
+		logger.ProducerLog.Errorf("Get Request Body error: %+v", err)
+		problemDetail := models.ProblemDetails{
+			Title:  "System failure",
+			Status: http.StatusInternalServerError,
+			Detail: err.Error(),
+			Cause:  "SYSTEM_FAILURE",
+		}
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetail.Cause)
+		c.JSON(http.StatusInternalServerError, problemDetail)
+		return
+	}
+
+	err = openapi.Deserialize(&networkName, requestBody, "application/json")
+	if err == nil && networkName.Full == "" {
+		err = fmt.Errorf("the full network name is missing")
+	}
+	if err != nil {
+		rsp := models.ProblemDetails{
+			Title:  "Malformed request syntax",
+			Status: http.StatusBadRequest,
+			Detail: err.Error(),
+		}
+		logger.ProducerLog.Errorln(err)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(http.StatusBadRequest))
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+	s.Processor().HandleOAMNetworkName(c, networkName)
+}
+
+func (s *Server) HTTPLadn(c *gin.Context) {
+	s.setCorsHeader(c)
+
+	var ladnList []factory.Ladn
+	requestBody, err := c.GetRawData()
+	if err != nil {
+		logger.ProducerLog.Errorf("Get Request Body error: %+v", err)
+		problemDetail := models.ProblemDetails{
+			Title:  "System failure",
+			Status: http.StatusInternalServerError,
+			Detail: err.Error(),
+			Cause:  "SYSTEM_FAILURE",
+		}
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetail.Cause)
+		c.JSON(http.StatusInternalServerError, problemDetail)
+		return
+	}
+
+	err = openapi.Deserialize(&ladnList, requestBody, "application/json")
+	if err == nil {
+		for _, ladn := range ladnList {
+			if ladn.Dnn == "" || len(ladn.TaiList) == 0 {
+				err = fmt.Errorf("the DNN or the TAI list of a LADN is missing")
+				break
+			}
+		}
+	}
+	if err != nil {
+		rsp := models.ProblemDetails{
+			Title:  "Malformed request syntax",
+			Status: http.StatusBadRequest,
+			Detail: err.Error(),
+		}
+		logger.ProducerLog.Errorln(err)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(http.StatusBadRequest))
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+	s.Processor().HandleOAMLadn(c, ladnList)
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	amf_context "github.com/free5gc/amf/internal/context"
+	"github.com/free5gc/amf/internal/logger"
+	"github.com/free5gc/amf/pkg/factory"
 	"github.com/free5gc/openapi/models"
 	"github.com/free5gc/util/fsm"
 )
@@ -45,6 +47,18 @@ func TestOAMRoutes_Definitions(t *testing.T) {
 			Method: http.MethodGet,
 			Name:   "RegisteredUEContext",
 		},
+		"/registered-ue-context/:supi/guti-reallocation": {
+			Method: http.MethodPost,
+			Name:   "GutiReallocation",
+		},
+		"/network-name": {
+			Method: http.MethodPut,
+			Name:   "NetworkName",
+		},
+		"/ladn": {
+			Method: http.MethodPut,
+			Name:   "Ladn",
+		},
 	}
 
 	// Assert
@@ -118,3 +132,116 @@ func TestHTTPRegisteredUEContext_GlobalInjection(t *testing.T) {
 		assert.Contains(t, w.Body.String(), targetSupi)
 	})
 }
+
+// Test the GUTI reallocation of a registered UE in CM-IDLE state, the new 5G-GUTI is sent once the UE connects.
+func TestHTTPGutiReallocation(t *testing.T) {
+	s, _ := NewTestServer(t)
+	router := setupTestRouterOAM(s)
+
+	self := amf_context.GetSelf()
+	servedGuamiList := self.ServedGuamiList
+	self.ServedGuamiList = []models.Guami{{
+		PlmnId: &models.PlmnIdNid{Mcc: "466", Mnc: "92"},
+		AmfId:  "cafe00",
+	}}
+	t.Cleanup(func() {
+		self.ServedGuamiList = servedGuamiList
+	})
+
+	targetSupi := "imsi-466920000000005"
+	fakeUe := &amf_context.AmfUe{
+		Supi: targetSupi,
+		State: map[models.AccessType]*fsm.State{
+			models.AccessType__3_GPP_ACCESS:    fsm.NewState(amf_context.Registered),
+			models.AccessType_NON_3_GPP_ACCESS: fsm.NewState(amf_context.Deregistered),
+		},
+		GmmLog: logger.GmmLog,
+	}
+	self.AllocateGutiToUe(fakeUe)
+	oldGuti := fakeUe.Guti
+	ManageTestUE(t, fakeUe)
+
+	t.Run("Unknown UE", func(t *testing.T) {
+		w := PerformJSONRequest(router, http.MethodPost, "/registered-ue-context/imsi-466920000000099/guti-reallocation", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Registered UE", func(t *testing.T) {
+		w := PerformJSONRequest(router, http.MethodPost, "/registered-ue-context/"+targetSupi+"/guti-reallocation", "")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.NotEqual(t, oldGuti, fakeUe.Guti)
+		assert.Equal(t, oldGuti, fakeUe.OldGuti)
+		if assert.NotNil(t, fakeUe.ConfigurationUpdateCommandFlags) {
+			assert.True(t, fakeUe.ConfigurationUpdateCommandFlags.NeedGUTI)
+		}
+	})
+
+	t.Run("Deregistered UE", func(t *testing.T) {
+		fakeUe.State[models.AccessType__3_GPP_ACCESS] = fsm.NewState(amf_context.Deregistered)
+		w := PerformJSONRequest(router, http.MethodPost, "/registered-ue-context/"+targetSupi+"/guti-reallocation", "")
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+}
+
+// Test the change of the LADNs, the registered UEs whose LADN information changed get the new one.
+func TestHTTPLadn(t *testing.T) {
+	s, _ := NewTestServer(t)
+	router := setupTestRouterOAM(s)
+
+	self := amf_context.GetSelf()
+	ladnList := self.LadnList()
+	t.Cleanup(func() {
+		self.SetLadnList(ladnList)
+	})
+
+	tai1 := models.Tai{PlmnId: &models.PlmnId{Mcc: "466", Mnc: "92"}, Tac: "000001"}
+	tai2 := models.Tai{PlmnId: &models.PlmnId{Mcc: "466", Mnc: "92"}, Tac: "000002"}
+	fakeUe := &amf_context.AmfUe{
+		Supi: "imsi-466920000000006",
+		State: map[models.AccessType]*fsm.State{
+			models.AccessType__3_GPP_ACCESS:    fsm.NewState(amf_context.Registered),
+			models.AccessType_NON_3_GPP_ACCESS: fsm.NewState(amf_context.Deregistered),
+		},
+		RegistrationArea: map[models.AccessType][]models.Tai{
+			models.AccessType__3_GPP_ACCESS: {tai1, tai2},
+		},
+		LadnInfo: []factory.Ladn{{Dnn: "ladn", TaiList: []models.Tai{tai1}}},
+		GmmLog:   logger.GmmLog,
+	}
+	ManageTestUE(t, fakeUe)
+
+	t.Run("Malformed LADN", func(t *testing.T) {
+		w := PerformJSONRequest(router, http.MethodPut, "/ladn", `[{"dnn": "ladn"}]`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Nil(t, fakeUe.ConfigurationUpdateCommandFlags)
+	})
+
+	t.Run("Unchanged LADN", func(t *testing.T) {
+		w := PerformJSONRequest(router, http.MethodPut, "/ladn",
+			`[{"dnn": "ladn", "taiList": [{"plmnId": {"mcc": "466", "mnc": "92"}, "tac": "000001"}]}]`)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Nil(t, fakeUe.ConfigurationUpdateCommandFlags)
+	})
+
+	t.Run("Service Area Changed", func(t *testing.T) {
+		w := PerformJSONRequest(router, http.MethodPut, "/ladn", `[{"dnn": "ladn", "taiList": [`+
+			`{"plmnId": {"mcc": "466", "mnc": "92"}, "tac": "000001"},`+
+			`{"plmnId": {"mcc": "466", "mnc": "92"}, "tac": "000002"}]}]`)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, []factory.Ladn{{Dnn: "ladn", TaiList: []models.Tai{tai1, tai2}}}, fakeUe.LadnInfo)
+		if assert.NotNil(t, fakeUe.ConfigurationUpdateCommandFlags) {
+			assert.True(t, fakeUe.ConfigurationUpdateCommandFlags.NeedLadnInformation)
+		}
+	})
+
+	t.Run("LADN Removed", func(t *testing.T) {
+		fakeUe.ConfigurationUpdateCommandFlags = nil
+		w := PerformJSONRequest(router, http.MethodPut, "/ladn", `[]`)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Empty(t, fakeUe.LadnInfo)
+		if assert.NotNil(t, fakeUe.ConfigurationUpdateCommandFlags) {
+			assert.True(t, fakeUe.ConfigurationUpdateCommandFlags.NeedLadnInformation)
+		}
+	})
+}
@@ -6,7 +6,6 @@ import (
 
 	amf_context "github.com/free5gc/amf/internal/context"
 	"github.com/free5gc/amf/internal/logger"
-	"github.com/free5gc/nas/nasMessage"
 	"github.com/free5gc/openapi"
 	Namf_Communication "github.com/free5gc/openapi/amf/Communication"
 	"github.com/free5gc/openapi/models"
@@ -195,17 +194,12 @@ func (s *namfService) ReleaseUEContextRequest(ue *amf_context.AmfUe, ngapCause m
 		return nil, openapi.ReportError("amf not found")
 	}
 
-	var ueContextId string
-	if ue.Supi != "" {
-		ueContextId = ue.Supi
-	} else {
-		ueContextId = ue.Pei
-	}
+	ueContextId := ue.UeContextId()
 
 	ueContextRelease := models.UeContextRelease{
 		NgapCause: &ngapCause,
 	}
-	if ue.RegistrationType5GS == nasMessage.RegistrationType5GSEmergencyRegistration && ue.UnauthenticatedSupi {
+	if ue.UnauthenticatedEmergency() {
 		ueContextRelease.Supi = ue.Supi
 		ueContextRelease.UnauthenticatedSupi = true
 	}
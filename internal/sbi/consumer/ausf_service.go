@@ -4,7 +4,6 @@ import (
 	"encoding/base64"
 	"fmt"
 	"net/url"
-	"strconv"
 	"strings"
 	"sync"
 
@@ -17,6 +16,30 @@ import (
 	sbi_metrics "github.com/free5gc/util/metrics/sbi"
 )
 
+// UEAuthenticator runs the authentication of the UEs, by the AUSF or by the local AUSF of the AMF context if the
+// local authentication is enabled
+type UEAuthenticator interface {
+	SendUEAuthenticationAuthenticateRequest(ue *amf_context.AmfUe, resynchronizationInfo *models.ResynchronizationInfo) (
+		*models.UeAuthenticationCtx, *models.ProblemDetails, error)
+	SendAuth5gAkaConfirmRequest(ue *amf_context.AmfUe, resStar string) (
+		*models.ConfirmationDataResponse, *models.ProblemDetails, error)
+	SendEapAuthConfirmRequest(ue *amf_context.AmfUe, eapMsg nasType.EAPMessage) (
+		*models.EapSession, *models.ProblemDetails, error)
+}
+
+var (
+	_ UEAuthenticator = (*nausfService)(nil)
+	_ UEAuthenticator = (*amf_context.LocalAusf)(nil)
+)
+
+// UEAuthenticator returns the local AUSF if the local authentication is enabled, the AUSF consumer otherwise
+func (c *Consumer) UEAuthenticator() UEAuthenticator {
+	if ausf := amf_context.GetSelf().LocalAusf; ausf != nil {
+		return ausf
+	}
+	return c.nausfService
+}
+
 type nausfService struct {
 	consumer *Consumer
 
@@ -56,15 +79,12 @@ func (s *nausfService) SendUEAuthenticationAuthenticateRequest(ue *amf_context.A
 		return nil, nil, openapi.ReportError("ausf not found")
 	}
 
-	amfSelf := amf_context.GetSelf()
-	servedGuami := amfSelf.ServedGuamiList[0]
-
 	var authInfo models.AuthenticationInfo
 	authInfo.SupiOrSuci = ue.Suci
-	if mnc, err := strconv.Atoi(servedGuami.PlmnId.Mnc); err != nil {
+	if servingNetworkName, err := amf_context.GetSelf().ServingNetworkName(); err != nil {
 		return nil, nil, err
 	} else {
-		authInfo.ServingNetworkName = fmt.Sprintf("5G:mnc%03d.mcc%s.3gppnetwork.org", mnc, servedGuami.PlmnId.Mcc)
+		authInfo.ServingNetworkName = servingNetworkName
 	}
 	if resynchronizationInfo != nil {
 		authInfo.ResynchronizationInfo = resynchronizationInfo
@@ -30,6 +30,10 @@ type Consumer struct {
 	*nsmfService
 	*nudmService
 	*nausfService
+	*nsmsfService
+	*nnssaafService
+	*nnsacfService
+	*n5gEirService
 }
 
 func GetConsumer() *Consumer {
@@ -77,6 +81,26 @@ func NewConsumer(amf ConsumerAmf) (*Consumer, error) {
 		consumer:                c,
 		UEAuthenticationClients: make(map[string]*Nausf_UEAuthentication.APIClient),
 	}
+
+	c.nsmsfService = &nsmsfService{
+		consumer:         c,
+		SMServiceClients: make(map[string]*smServiceConfiguration),
+	}
+
+	c.nnssaafService = &nnssaafService{
+		consumer:     c,
+		NSSAAClients: make(map[string]*serviceConfiguration),
+	}
+
+	c.nnsacfService = &nnsacfService{
+		consumer: c,
+	}
+
+	c.n5gEirService = &n5gEirService{
+		consumer:   c,
+		EICClients: make(map[string]*serviceConfiguration),
+	}
+
 	consumer = c
 	return c, nil
 }
@@ -0,0 +1,92 @@
+package consumer
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+
+	amf_context "github.com/free5gc/amf/internal/context"
+	"github.com/free5gc/openapi"
+	"github.com/free5gc/openapi/models"
+	sbi_metrics "github.com/free5gc/util/metrics/sbi"
+)
+
+// The openapi module provides no N5g-eir_EquipmentIdentityCheck client, the data types of TS 29.511 6.1.6 used by
+// the AMF are defined here.
+
+// EquipmentStatus is the status of the PEI in the 5G-EIR
+type EquipmentStatus string
+
+const (
+	EquipmentStatusWhitelisted EquipmentStatus = "WHITELISTED"
+	EquipmentStatusBlacklisted EquipmentStatus = "BLACKLISTED"
+	EquipmentStatusGreylisted  EquipmentStatus = "GREYLISTED"
+)
+
+// EirResponseData is the result of the check of the PEI
+type EirResponseData struct {
+	Status EquipmentStatus `json:"status"`
+}
+
+type n5gEirService struct {
+	consumer *Consumer
+
+	EICMu sync.RWMutex
+
+	EICClients map[string]*serviceConfiguration
+}
+
+func (s *n5gEirService) getEICClient(uri string) *serviceConfiguration {
+	if uri == "" {
+		return nil
+	}
+	s.EICMu.RLock()
+	client, ok := s.EICClients[uri]
+	if ok {
+		s.EICMu.RUnlock()
+		return client
+	}
+
+	client = &serviceConfiguration{
+		basePath:    uri + "/n5g-eir-eic/v1",
+		metricsHook: sbi_metrics.SbiMetricHook,
+	}
+
+	s.EICMu.RUnlock()
+	s.EICMu.Lock()
+	defer s.EICMu.Unlock()
+	s.EICClients[uri] = client
+	return client
+}
+
+// EquipmentIdentityCheck gets the status of the PEI of the UE from the 5G-EIR, TS 29.511 5.2.2.2
+func (s *n5gEirService) EquipmentIdentityCheck(ue *amf_context.AmfUe) (
+	*EirResponseData, *models.ProblemDetails, error,
+) {
+	client := s.getEICClient(ue.EirUri)
+	if client == nil {
+		return nil, nil, openapi.ReportError("5g-eir not found")
+	}
+	ctx, _, err := amf_context.GetSelf().GetTokenCtx(models.ServiceName_N5G_EIR_EIC,
+		models.NrfNfManagementNfType__5_G_EIR)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query := url.Values{}
+	query.Set("pei", ue.Pei)
+	if ue.Supi != "" {
+		query.Set("supi", ue.Supi)
+	}
+	if ue.Gpsi != "" {
+		query.Set("gpsi", ue.Gpsi)
+	}
+
+	var eirResponseData EirResponseData
+	problemDetails, err := callService(ctx, client, http.MethodGet, "/equipment-status?"+query.Encode(), nil, "",
+		&eirResponseData)
+	if problemDetails != nil || err != nil {
+		return nil, problemDetails, err
+	}
+	return &eirResponseData, nil, nil
+}
@@ -0,0 +1,46 @@
+package consumer
+
+import (
+	amf_context "github.com/free5gc/amf/internal/context"
+	"github.com/free5gc/openapi"
+	"github.com/free5gc/openapi/models"
+)
+
+// NsacUpdateFlag is the update of the number of UEs requested to the NSACF, TS 29.536 6.1.6.3.3
+type NsacUpdateFlag string
+
+const (
+	NsacUpdateFlagIncrease NsacUpdateFlag = "INCREASE"
+	NsacUpdateFlagDecrease NsacUpdateFlag = "DECREASE"
+)
+
+type nnsacfService struct {
+	consumer *Consumer
+}
+
+// NumOfUEsUpdate requests the NSACF to count the UE in or out of the S-NSSAIs subject to NSAC, the S-NSSAIs
+// whose maximum number of UEs is reached are returned (Nnsacf_NSAC_NumOfUEsUpdate, TS 23.502 5.2.21.2.1).
+// No NSACF is discovered, the local NSACF of the AMF context is used.
+func (s *nnsacfService) NumOfUEsUpdate(ue *amf_context.AmfUe, snssaiList []models.Snssai,
+	updateFlag NsacUpdateFlag,
+) ([]models.Snssai, error) {
+	nsacf := amf_context.GetSelf().Nsacf
+	if nsacf == nil {
+		return nil, openapi.ReportError("nsacf not found")
+	}
+
+	var rejectedNssai []models.Snssai
+	for _, snssai := range snssaiList {
+		switch updateFlag {
+		case NsacUpdateFlagIncrease:
+			if !nsacf.Increase(ue.Supi, snssai) {
+				rejectedNssai = append(rejectedNssai, snssai)
+			}
+		case NsacUpdateFlagDecrease:
+			nsacf.Decrease(ue.Supi, snssai)
+		default:
+			return nil, openapi.ReportError("unknown update flag %s", updateFlag)
+		}
+	}
+	return rejectedNssai, nil
+}
@@ -0,0 +1,168 @@
+package consumer
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+
+	amf_context "github.com/free5gc/amf/internal/context"
+	"github.com/free5gc/amf/pkg/factory"
+	"github.com/free5gc/openapi"
+	"github.com/free5gc/openapi/models"
+	sbi_metrics "github.com/free5gc/util/metrics/sbi"
+)
+
+// The openapi module provides no Nnssaaf_NSSAA client, the data types of TS 29.526 6.1.6 used by the AMF
+// are defined here. The EAP messages are base64 encoded in JSON.
+
+// SliceAuthInfo starts the NSSAA of an S-NSSAI with the first EAP message of the UE
+type SliceAuthInfo struct {
+	Supi           string        `json:"supi,omitempty"`
+	Gpsi           string        `json:"gpsi,omitempty"`
+	Snssai         models.Snssai `json:"snssai"`
+	EapIdRsp       []byte        `json:"eapIdRsp"`
+	AmfInstanceId  string        `json:"amfInstanceId,omitempty"`
+	ReauthNotifUri string        `json:"reauthNotifUri,omitempty"`
+	RevocNotifUri  string        `json:"revocNotifUri,omitempty"`
+}
+
+// SliceAuthContext is the NSSAA context created in the NSSAAF, with the EAP message to relay to the UE
+type SliceAuthContext struct {
+	Supi       string        `json:"supi,omitempty"`
+	Gpsi       string        `json:"gpsi,omitempty"`
+	Snssai     models.Snssai `json:"snssai"`
+	AuthCtxId  string        `json:"authCtxId"`
+	EapMessage []byte        `json:"eapMessage"`
+}
+
+// SliceAuthConfirmationData relays an EAP message of the UE within an ongoing NSSAA
+type SliceAuthConfirmationData struct {
+	Supi       string        `json:"supi,omitempty"`
+	Gpsi       string        `json:"gpsi,omitempty"`
+	Snssai     models.Snssai `json:"snssai"`
+	EapMessage []byte        `json:"eapMessage"`
+}
+
+// SliceAuthConfirmationResponse is the next EAP message to relay to the UE, the NSSAA is completed once
+// AuthResult is present
+type SliceAuthConfirmationResponse struct {
+	Supi       string            `json:"supi,omitempty"`
+	Gpsi       string            `json:"gpsi,omitempty"`
+	Snssai     models.Snssai     `json:"snssai"`
+	EapMessage []byte            `json:"eapMessage"`
+	AuthResult models.AuthStatus `json:"authResult,omitempty"`
+}
+
+// SliceAuthNotificationType is the type of the notifications of the NSSAAF
+type SliceAuthNotificationType string
+
+const (
+	SliceAuthNotificationTypeReauth     SliceAuthNotificationType = "SLICE_RE_AUTH"
+	SliceAuthNotificationTypeRevocation SliceAuthNotificationType = "SLICE_REVOCATION"
+)
+
+// SliceAuthNotification is sent by the NSSAAF to the AMF when the AAA server triggers the re-authentication
+// or the revocation of an S-NSSAI
+type SliceAuthNotification struct {
+	NotifType SliceAuthNotificationType `json:"notifType"`
+	Supi      string                    `json:"supi,omitempty"`
+	Gpsi      string                    `json:"gpsi,omitempty"`
+	Snssai    models.Snssai             `json:"snssai"`
+}
+
+type nnssaafService struct {
+	consumer *Consumer
+
+	NSSAAMu sync.RWMutex
+
+	NSSAAClients map[string]*serviceConfiguration
+}
+
+func (s *nnssaafService) getNSSAAClient(uri string) *serviceConfiguration {
+	if uri == "" {
+		return nil
+	}
+	s.NSSAAMu.RLock()
+	client, ok := s.NSSAAClients[uri]
+	if ok {
+		s.NSSAAMu.RUnlock()
+		return client
+	}
+
+	client = &serviceConfiguration{
+		basePath:    uri + "/nnssaaf-nssaa/v1",
+		metricsHook: sbi_metrics.SbiMetricHook,
+	}
+
+	s.NSSAAMu.RUnlock()
+	s.NSSAAMu.Lock()
+	defer s.NSSAAMu.Unlock()
+	s.NSSAAClients[uri] = client
+	return client
+}
+
+// NSSAAAuthenticate starts the NSSAA of the S-NSSAI with the EAP identity response of the UE,
+// TS 29.526 5.2.2.2.2
+func (s *nnssaafService) NSSAAAuthenticate(ue *amf_context.AmfUe, snssai models.Snssai, eapIdRsp []byte) (
+	*SliceAuthContext, *models.ProblemDetails, error,
+) {
+	client := s.getNSSAAClient(ue.NssaafUri)
+	if client == nil {
+		return nil, nil, openapi.ReportError("nssaaf not found")
+	}
+	amfSelf := amf_context.GetSelf()
+	ctx, _, err := amfSelf.GetTokenCtx(models.ServiceName_NNSSAAF_NSSAA, models.NrfNfManagementNfType_NSSAAF)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	callbackUri := amfSelf.GetIPv4Uri() + factory.AmfCallbackResUriPrefix
+	sliceAuthInfo := SliceAuthInfo{
+		Supi:           ue.Supi,
+		Gpsi:           ue.Gpsi,
+		Snssai:         snssai,
+		EapIdRsp:       eapIdRsp,
+		AmfInstanceId:  amfSelf.NfId,
+		ReauthNotifUri: callbackUri + "/nssaa-reauth/" + url.PathEscape(ue.Supi),
+		RevocNotifUri:  callbackUri + "/nssaa-revoc/" + url.PathEscape(ue.Supi),
+	}
+
+	var sliceAuthContext SliceAuthContext
+	problemDetails, err := callService(ctx, client, http.MethodPost, "/slice-authentications",
+		&sliceAuthInfo, "application/json", &sliceAuthContext)
+	if problemDetails != nil || err != nil {
+		return nil, problemDetails, err
+	}
+	return &sliceAuthContext, nil, nil
+}
+
+// NSSAAConfirm relays an EAP message of the UE within the ongoing NSSAA, TS 29.526 5.2.2.2.3
+func (s *nnssaafService) NSSAAConfirm(ue *amf_context.AmfUe, authCtxId string, snssai models.Snssai,
+	eapMessage []byte,
+) (*SliceAuthConfirmationResponse, *models.ProblemDetails, error) {
+	client := s.getNSSAAClient(ue.NssaafUri)
+	if client == nil {
+		return nil, nil, openapi.ReportError("nssaaf not found")
+	}
+	ctx, _, err := amf_context.GetSelf().GetTokenCtx(models.ServiceName_NNSSAAF_NSSAA,
+		models.NrfNfManagementNfType_NSSAAF)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	confirmationData := SliceAuthConfirmationData{
+		Supi:       ue.Supi,
+		Gpsi:       ue.Gpsi,
+		Snssai:     snssai,
+		EapMessage: eapMessage,
+	}
+
+	var confirmationResponse SliceAuthConfirmationResponse
+	problemDetails, err := callService(ctx, client, http.MethodPut,
+		"/slice-authentications/"+url.PathEscape(authCtxId), &confirmationData, "application/json",
+		&confirmationResponse)
+	if problemDetails != nil || err != nil {
+		return nil, problemDetails, err
+	}
+	return &confirmationResponse, nil, nil
+}
@@ -97,9 +97,15 @@ func (s *npcfService) AMPolicyControlCreate(
 				if trigger == models.PcfAmPolicyControlRequestTrigger_LOC_CH {
 					ue.RequestTriggerLocationChange = true
 				}
-				// if trigger == models.RequestTrigger_PRA_CH {
-				// TODO: Presence Reporting Area handling (TS 23.503 6.1.2.5, TS 23.501 5.6.11)
-				// }
+				// Presence Reporting Area handling (TS 23.503 6.1.2.5, TS 23.501 5.6.11),
+				// the NG-RAN reporting is configured at Initial Context Setup
+				if trigger == models.PcfAmPolicyControlRequestTrigger_PRA_CH {
+					for praId, presenceInfo := range res.PcfAmPolicyControlPolicyAssociation.Pras {
+						if _, err := ue.UpdatePresenceReportingArea(praId, presenceInfo); err != nil {
+							logger.ConsumerLog.Warnf("Presence Reporting Area[%s]: %v", praId, err)
+						}
+					}
+				}
 			}
 		}
 
@@ -126,18 +132,20 @@ func (s *npcfService) AMPolicyControlCreate(
 	return nil, nil
 }
 
+// AMPolicyControlUpdate reports the observed policy control request triggers to the PCF. The returned policy
+// update carries the Presence Reporting Areas the caller applies to the NG-RAN location reporting.
 func (s *npcfService) AMPolicyControlUpdate(
 	ue *amf_context.AmfUe, updateRequest models.PcfAmPolicyControlPolicyAssociationUpdateRequest,
-) (problemDetails *models.ProblemDetails, err error) {
+) (policyUpdate *models.PcfAmPolicyControlPolicyUpdate, problemDetails *models.ProblemDetails, err error) {
 	client := s.getAMPolicyClient(ue.PcfUri)
 	if client == nil {
-		return nil, openapi.ReportError("pcf not found")
+		return nil, nil, openapi.ReportError("pcf not found")
 	}
 
 	ctx, _, err := amf_context.GetSelf().GetTokenCtx(models.ServiceName_NPCF_AM_POLICY_CONTROL,
 		models.NrfNfManagementNfType_PCF)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var policyUpdateReq Npcf_AMPolicy.ReportObservedEventTriggersForIndividualAMPolicyAssociationRequest
@@ -160,29 +168,27 @@ func (s *npcfService) AMPolicyControlUpdate(
 			if trigger == models.PcfAmPolicyControlRequestTrigger_LOC_CH {
 				ue.RequestTriggerLocationChange = true
 			}
-			// if trigger == models.RequestTrigger_PRA_CH {
-			// TODO: Presence Reporting Area handling (TS 23.503 6.1.2.5, TS 23.501 5.6.11)
-			// }
 		}
+		policyUpdate = &res.PcfAmPolicyControlPolicyUpdate
 	} else {
 		switch apiErr := localErr.(type) {
 		// API error
 		case openapi.GenericOpenAPIError:
 			switch errorModel := apiErr.Model().(type) {
 			case Npcf_AMPolicy.ReportObservedEventTriggersForIndividualAMPolicyAssociationError:
-				return &errorModel.ProblemDetails, nil
+				return nil, &errorModel.ProblemDetails, nil
 			case error:
-				return openapi.ProblemDetailsSystemFailure(errorModel.Error()), nil
+				return nil, openapi.ProblemDetailsSystemFailure(errorModel.Error()), nil
 			default:
 				err = openapi.ReportError("openapi error")
 			}
 		case error:
-			return openapi.ProblemDetailsSystemFailure(apiErr.Error()), nil
+			return nil, openapi.ProblemDetailsSystemFailure(apiErr.Error()), nil
 		default:
 			err = openapi.ReportError("server no response")
 		}
 	}
-	return nil, err
+	return policyUpdate, nil, err
 }
 
 func (s *npcfService) AMPolicyControlDelete(ue *amf_context.AmfUe) (problemDetails *models.ProblemDetails, err error) {
@@ -0,0 +1,91 @@
+package consumer
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/free5gc/amf/internal/logger"
+	"github.com/free5gc/openapi"
+	"github.com/free5gc/openapi/models"
+)
+
+// serviceConfiguration is the openapi client configuration of the services the openapi module provides no
+// client for
+type serviceConfiguration struct {
+	basePath    string
+	httpClient  *http.Client
+	metricsHook openapi.RequestMetricsHook
+}
+
+func (c *serviceConfiguration) BasePath() string {
+	return c.basePath
+}
+
+func (c *serviceConfiguration) Host() string {
+	return ""
+}
+
+func (c *serviceConfiguration) UserAgent() string {
+	return "OpenAPI-Generator/1.0.0/go"
+}
+
+func (c *serviceConfiguration) DefaultHeader() map[string]string {
+	return nil
+}
+
+func (c *serviceConfiguration) HTTPClient() *http.Client {
+	return c.httpClient
+}
+
+func (c *serviceConfiguration) Metrics() openapi.RequestMetricsHook {
+	return c.metricsHook
+}
+
+// callService sends the request to the NF and decodes the body of a successful response into rsp, the
+// ProblemDetails of an unsuccessful one are returned
+func callService(ctx context.Context, client *serviceConfiguration, method, path string,
+	body interface{}, contentType string, rsp interface{},
+) (*models.ProblemDetails, error) {
+	headerParams := map[string]string{
+		"Accept": "application/json, application/problem+json",
+	}
+	if contentType != "" {
+		headerParams["Content-Type"] = contentType
+	}
+	req, err := openapi.PrepareRequest(ctx, client, client.BasePath()+path, method, body, headerParams,
+		url.Values{}, url.Values{}, "", "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := openapi.CallAPI(client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := httpResp.Body.Close(); closeErr != nil {
+			logger.ConsumerLog.Errorf("Response body cannot close: %+v", closeErr)
+		}
+	}()
+
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if httpResp.StatusCode >= http.StatusMultipleChoices {
+		var problemDetails models.ProblemDetails
+		if err = openapi.Deserialize(&problemDetails, respBody, httpResp.Header.Get("Content-Type")); err != nil {
+			return openapi.ProblemDetailsSystemFailure(httpResp.Status), nil
+		}
+		return &problemDetails, nil
+	}
+	if rsp != nil && len(respBody) > 0 {
+		if err = openapi.Deserialize(rsp, respBody, httpResp.Header.Get("Content-Type")); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
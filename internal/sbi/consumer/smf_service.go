@@ -59,11 +59,6 @@ func (s *nsmfService) SelectSmf(
 	snssai models.Snssai,
 	dnn string,
 ) (*amf_context.SmContext, uint8, error) {
-	var (
-		smfID  string
-		smfUri string
-	)
-
 	ue.GmmLog.Infof("Select SMF [snssai: %+v, dnn: %+v]", snssai, dnn)
 
 	nrfUri := ue.ServingAMF().NrfUri // default NRF URI is pre-configured by AMF
@@ -111,6 +106,37 @@ func (s *nsmfService) SelectSmf(
 			nrfUri = fmt.Sprintf("%s://%s", nrfApiUri.Scheme, nrfApiUri.Host)
 		}
 	}
+	return s.searchSmf(ue, smContext, nrfUri, snssai, dnn)
+}
+
+// SelectEmergencySmf selects the SMF of an emergency PDU session with the emergency S-NSSAI and DNN of the AMF
+// configuration, no network slice selection is performed (TS 23.501 5.16.4.5)
+func (s *nsmfService) SelectEmergencySmf(
+	ue *amf_context.AmfUe,
+	anType models.AccessType,
+	pduSessionID int32,
+) (*amf_context.SmContext, uint8, error) {
+	snssai, dnn := ue.ServingAMF().EmergencySnssaiAndDnn()
+	ue.GmmLog.Infof("Select emergency SMF [snssai: %+v, dnn: %+v]", snssai, dnn)
+
+	smContext := amf_context.NewSmContext(pduSessionID)
+	smContext.SetSnssai(snssai)
+	smContext.SetDnn(dnn)
+	smContext.SetAccessType(anType)
+	return s.searchSmf(ue, smContext, ue.ServingAMF().NrfUri, snssai, dnn)
+}
+
+func (s *nsmfService) searchSmf(
+	ue *amf_context.AmfUe,
+	smContext *amf_context.SmContext,
+	nrfUri string,
+	snssai models.Snssai,
+	dnn string,
+) (*amf_context.SmContext, uint8, error) {
+	var (
+		smfID  string
+		smfUri string
+	)
 
 	param := Nnrf_NFDiscovery.SearchNFInstancesRequest{
 		ServiceNames: []models.ServiceName{models.ServiceName_NSMF_PDUSESSION},
@@ -155,7 +181,7 @@ func (s *nsmfService) SendCreateSmContextRequest(ue *amf_context.AmfUe, smContex
 	smContextRef string, errorResponse *models.PostSmContextsError,
 	problemDetail *models.ProblemDetails, err1 error,
 ) {
-	smContextCreateData := s.buildCreateSmContextRequest(ue, smContext, nil)
+	smContextCreateData := s.buildCreateSmContextRequest(ue, smContext, requestType)
 
 	postSmContextsRequest := Nsmf_PDUSession.PostSmContextsRequest{
 		PostSmContextsRequest: &models.PostSmContextsRequest{
@@ -235,7 +261,7 @@ func (s *nsmfService) buildCreateSmContextRequest(ue *amf_context.AmfUe, smConte
 	smContextCreateData.UeLocation = &ue.Location
 	smContextCreateData.UeTimeZone = ue.TimeZone
 	smContextCreateData.SmContextStatusUri = context.GetIPv4Uri() + factory.AmfCallbackResUriPrefix + "/smContextStatus/" +
-		ue.Supi + "/" + strconv.Itoa(int(smContext.PduSessionID()))
+		ue.UeContextId() + "/" + strconv.Itoa(int(smContext.PduSessionID()))
 
 	return smContextCreateData
 }
@@ -269,7 +295,7 @@ func (s *nsmfService) SendUpdateSmContextActivateUpCnxState(
 	if smContext.AccessType() != accessType {
 		updateData.AnType = smContext.AccessType()
 	}
-	if ladn, ok := ue.ServingAMF().LadnPool[smContext.Dnn()]; ok {
+	if ladn, ok := ue.ServingAMF().FindLadn(smContext.Dnn()); ok {
 		if amf_context.InTaiList(ue.Tai, ladn.TaiList) {
 			updateData.PresenceInLadn = models.PresenceState_IN_AREA
 		}
@@ -329,7 +355,7 @@ func (s *nsmfService) SendUpdateSmContextXnHandover(
 	}
 	updateData.ToBeSwitched = true
 	updateData.UeLocation = &ue.Location
-	if ladn, ok := ue.ServingAMF().LadnPool[smContext.Dnn()]; ok {
+	if ladn, ok := ue.ServingAMF().FindLadn(smContext.Dnn()); ok {
 		if amf_context.InTaiList(ue.Tai, ladn.TaiList) {
 			updateData.PresenceInLadn = models.PresenceState_IN_AREA
 		} else {
@@ -400,7 +426,7 @@ func (s *nsmfService) SendUpdateSmContextN2HandoverComplete(
 		updateData.ServingNetwork = guami.PlmnId
 		updateData.Guami = guami
 	}
-	if ladn, ok := ue.ServingAMF().LadnPool[smContext.Dnn()]; ok {
+	if ladn, ok := ue.ServingAMF().FindLadn(smContext.Dnn()); ok {
 		if amf_context.InTaiList(ue.Tai, ladn.TaiList) {
 			updateData.PresenceInLadn = models.PresenceState_IN_AREA
 		} else {
@@ -455,7 +481,7 @@ func (s *nsmfService) SendUpdateSmContextHandoverBetweenAMF(
 		if !amf_context.CompareUserLocation(ue.Location, smContext.UserLocation()) {
 			updateData.UeLocation = &ue.Location
 		}
-		if ladn, ok := ue.ServingAMF().LadnPool[smContext.Dnn()]; ok {
+		if ladn, ok := ue.ServingAMF().FindLadn(smContext.Dnn()); ok {
 			if amf_context.InTaiList(ue.Tai, ladn.TaiList) {
 				updateData.PresenceInLadn = models.PresenceState_IN_AREA
 			}
@@ -0,0 +1,261 @@
+package consumer
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+
+	amf_context "github.com/free5gc/amf/internal/context"
+	"github.com/free5gc/amf/internal/logger"
+	"github.com/free5gc/openapi"
+	"github.com/free5gc/openapi/models"
+	sbi_metrics "github.com/free5gc/util/metrics/sbi"
+)
+
+// The openapi module provides no Nsmsf_SMService client, the data types of TS 29.540 6.1.6 used by the AMF
+// are defined here.
+
+// UeSmsContextData is the UE context for SMS created in the SMSF by the Activate service operation
+type UeSmsContextData struct {
+	Supi             string                 `json:"supi"`
+	Gpsi             string                 `json:"gpsi,omitempty"`
+	Pei              string                 `json:"pei,omitempty"`
+	AmfId            string                 `json:"amfId"`
+	GuamiList        []models.Guami         `json:"guamiList,omitempty"`
+	AccessType       models.AccessType      `json:"accessType"`
+	UeLocation       *models.UserLocation   `json:"ueLocation,omitempty"`
+	UeTimeZone       string                 `json:"ueTimeZone,omitempty"`
+	TraceData        *models.TraceData      `json:"traceData,omitempty"`
+	BackupAmfInfo    []models.BackupAmfInfo `json:"backupAmfInfo,omitempty"`
+	UdmGroupId       string                 `json:"udmGroupId,omitempty"`
+	RoutingIndicator string                 `json:"routingIndicator,omitempty"`
+}
+
+// SmsRecordData is an SMS payload sent by the UE, relayed by the UplinkSMS service operation
+type SmsRecordData struct {
+	SmsRecordId string                  `json:"smsRecordId"`
+	SmsPayload  *models.RefToBinaryData `json:"smsPayload"`
+	AccessType  models.AccessType       `json:"accessType,omitempty"`
+	Gpsi        string                  `json:"gpsi,omitempty"`
+	Pei         string                  `json:"pei,omitempty"`
+	UeLocation  *models.UserLocation    `json:"ueLocation,omitempty"`
+	UeTimeZone  string                  `json:"ueTimeZone,omitempty"`
+}
+
+// SmsRecordDeliveryData is the result of the UplinkSMS service operation
+type SmsRecordDeliveryData struct {
+	SmsRecordId    string `json:"smsRecordId"`
+	DeliveryStatus string `json:"deliveryStatus"`
+}
+
+// UplinkSMSRequest is the multipart body of the UplinkSMS service operation
+type UplinkSMSRequest struct {
+	JsonData      *SmsRecordData `json:"jsonData,omitempty" multipart:"contentType:application/json,omitempty"`
+	BinaryPayload []byte         `json:"binaryPayload,omitempty" multipart:"contentType:application/vnd.3gpp.sms,ref:JsonData.SmsPayload.ContentId,omitempty"` //nolint:lll
+}
+
+type smServiceConfiguration struct {
+	basePath    string
+	httpClient  *http.Client
+	metricsHook openapi.RequestMetricsHook
+}
+
+func (c *smServiceConfiguration) BasePath() string {
+	return c.basePath
+}
+
+func (c *smServiceConfiguration) Host() string {
+	return ""
+}
+
+func (c *smServiceConfiguration) UserAgent() string {
+	return "OpenAPI-Generator/1.0.0/go"
+}
+
+func (c *smServiceConfiguration) DefaultHeader() map[string]string {
+	return nil
+}
+
+func (c *smServiceConfiguration) HTTPClient() *http.Client {
+	return c.httpClient
+}
+
+func (c *smServiceConfiguration) Metrics() openapi.RequestMetricsHook {
+	return c.metricsHook
+}
+
+type nsmsfService struct {
+	consumer *Consumer
+
+	SMServiceMu sync.RWMutex
+
+	SMServiceClients map[string]*smServiceConfiguration
+}
+
+func (s *nsmsfService) getSMServiceClient(uri string) *smServiceConfiguration {
+	if uri == "" {
+		return nil
+	}
+	s.SMServiceMu.RLock()
+	client, ok := s.SMServiceClients[uri]
+	if ok {
+		s.SMServiceMu.RUnlock()
+		return client
+	}
+
+	client = &smServiceConfiguration{
+		basePath:    uri + "/nsmsf-sms/v2",
+		metricsHook: sbi_metrics.SbiMetricHook,
+	}
+
+	s.SMServiceMu.RUnlock()
+	s.SMServiceMu.Lock()
+	defer s.SMServiceMu.Unlock()
+	s.SMServiceClients[uri] = client
+	return client
+}
+
+// callSMService sends the request to the SMSF and decodes the body of a successful response into rsp, the
+// ProblemDetails of an unsuccessful one are returned
+func (s *nsmsfService) callSMService(ctx context.Context, client *smServiceConfiguration, method, path string,
+	body interface{}, contentType string, rsp interface{},
+) (*models.ProblemDetails, error) {
+	headerParams := map[string]string{
+		"Accept": "application/json, application/problem+json",
+	}
+	if contentType != "" {
+		headerParams["Content-Type"] = contentType
+	}
+	req, err := openapi.PrepareRequest(ctx, client, client.BasePath()+path, method, body, headerParams,
+		url.Values{}, url.Values{}, "", "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := openapi.CallAPI(client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := httpResp.Body.Close(); closeErr != nil {
+			logger.ConsumerLog.Errorf("Response body cannot close: %+v", closeErr)
+		}
+	}()
+
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if httpResp.StatusCode >= http.StatusMultipleChoices {
+		var problemDetails models.ProblemDetails
+		if err = openapi.Deserialize(&problemDetails, respBody, httpResp.Header.Get("Content-Type")); err != nil {
+			return openapi.ProblemDetailsSystemFailure(httpResp.Status), nil
+		}
+		return &problemDetails, nil
+	}
+	if rsp != nil && len(respBody) > 0 {
+		if err = openapi.Deserialize(rsp, respBody, httpResp.Header.Get("Content-Type")); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// SMServiceActivate creates the UE context for SMS in the SMSF, TS 29.540 5.2.2.2
+func (s *nsmsfService) SMServiceActivate(ue *amf_context.AmfUe, anType models.AccessType) (
+	*models.ProblemDetails, error,
+) {
+	client := s.getSMServiceClient(ue.SmsfUri)
+	if client == nil {
+		return nil, openapi.ReportError("smsf not found")
+	}
+	amfSelf := amf_context.GetSelf()
+	ctx, _, err := amfSelf.GetTokenCtx(models.ServiceName_NSMSF_SMS, models.NrfNfManagementNfType_SMSF)
+	if err != nil {
+		return nil, err
+	}
+
+	ueSmsContextData := UeSmsContextData{
+		Supi:             ue.Supi,
+		Gpsi:             ue.Gpsi,
+		Pei:              ue.Pei,
+		AmfId:            amfSelf.NfId,
+		GuamiList:        amfSelf.ServedGuamiList,
+		AccessType:       anType,
+		UeTimeZone:       ue.TimeZone,
+		TraceData:        ue.TraceData,
+		BackupAmfInfo:    ue.BackupAmfInfo,
+		UdmGroupId:       ue.UdmGroupId,
+		RoutingIndicator: ue.RoutingIndicator,
+	}
+	if ue.Location.NrLocation != nil || ue.Location.EutraLocation != nil || ue.Location.N3gaLocation != nil {
+		location := ue.Location
+		ueSmsContextData.UeLocation = &location
+	}
+
+	return s.callSMService(ctx, client, http.MethodPut, "/ue-contexts/"+url.PathEscape(ue.Supi),
+		&ueSmsContextData, "application/json", nil)
+}
+
+// SMServiceDeactivate deletes the UE context for SMS in the SMSF, TS 29.540 5.2.2.3
+func (s *nsmsfService) SMServiceDeactivate(ue *amf_context.AmfUe) (*models.ProblemDetails, error) {
+	client := s.getSMServiceClient(ue.SmsfUri)
+	if client == nil {
+		return nil, openapi.ReportError("smsf not found")
+	}
+	ctx, _, err := amf_context.GetSelf().GetTokenCtx(models.ServiceName_NSMSF_SMS,
+		models.NrfNfManagementNfType_SMSF)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.callSMService(ctx, client, http.MethodDelete, "/ue-contexts/"+url.PathEscape(ue.Supi),
+		nil, "", nil)
+}
+
+// SMServiceUplinkSMS relays an SMS payload sent by the UE to the SMSF, TS 29.540 5.2.2.4
+func (s *nsmsfService) SMServiceUplinkSMS(ue *amf_context.AmfUe, anType models.AccessType, smsPayload []byte) (
+	*SmsRecordDeliveryData, *models.ProblemDetails, error,
+) {
+	client := s.getSMServiceClient(ue.SmsfUri)
+	if client == nil {
+		return nil, nil, openapi.ReportError("smsf not found")
+	}
+	ctx, _, err := amf_context.GetSelf().GetTokenCtx(models.ServiceName_NSMSF_SMS,
+		models.NrfNfManagementNfType_SMSF)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	smsRecord := UplinkSMSRequest{
+		JsonData: &SmsRecordData{
+			SmsRecordId: strings.ReplaceAll(uuid.New().String(), "-", ""),
+			SmsPayload: &models.RefToBinaryData{
+				ContentId: "sms",
+			},
+			AccessType: anType,
+			Gpsi:       ue.Gpsi,
+			Pei:        ue.Pei,
+			UeTimeZone: ue.TimeZone,
+		},
+		BinaryPayload: smsPayload,
+	}
+	if ue.Location.NrLocation != nil || ue.Location.EutraLocation != nil || ue.Location.N3gaLocation != nil {
+		location := ue.Location
+		smsRecord.JsonData.UeLocation = &location
+	}
+
+	var deliveryData SmsRecordDeliveryData
+	problemDetails, err := s.callSMService(ctx, client, http.MethodPost,
+		"/ue-contexts/"+url.PathEscape(ue.Supi)+"/sendsms", &smsRecord, "multipart/related", &deliveryData)
+	if problemDetails != nil || err != nil {
+		return nil, problemDetails, err
+	}
+	return &deliveryData, nil, nil
+}
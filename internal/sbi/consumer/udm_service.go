@@ -2,9 +2,11 @@ package consumer
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 
 	amf_context "github.com/free5gc/amf/internal/context"
+	"github.com/free5gc/amf/internal/util"
 	"github.com/free5gc/amf/pkg/factory"
 	"github.com/free5gc/openapi"
 	"github.com/free5gc/openapi/models"
@@ -68,7 +70,22 @@ func (s *nudmService) getUEContextMngmntClient(uri string) *Nudm_UEContextManage
 	return client
 }
 
+// PutUpuAck provides the acknowledgement of the UE for the UE parameters update to the UDM, TS 29.503 5.2.2.6
 func (s *nudmService) PutUpuAck(ue *amf_context.AmfUe, upuMacIue string) error {
+	return s.putUpuAck(ue, models.AcknowledgeInfo{
+		UpuMacIue: upuMacIue,
+	})
+}
+
+// PutUpuUeNotReachable tells the UDM the UE parameters update could not be delivered to the UE or was not
+// acknowledged by the UE, TS 29.503 5.2.2.6
+func (s *nudmService) PutUpuUeNotReachable(ue *amf_context.AmfUe) error {
+	return s.putUpuAck(ue, models.AcknowledgeInfo{
+		UeNotReachable: true,
+	})
+}
+
+func (s *nudmService) putUpuAck(ue *amf_context.AmfUe, ackInfo models.AcknowledgeInfo) error {
 	client := s.getSubscriberDMngmntClients(ue.NudmSDMUri)
 	if client == nil {
 		return openapi.ReportError("udm not found")
@@ -79,8 +96,8 @@ func (s *nudmService) PutUpuAck(ue *amf_context.AmfUe, upuMacIue string) error {
 		return err
 	}
 
-	ackInfo := models.AcknowledgeInfo{
-		UpuMacIue: upuMacIue,
+	if ue.UpuInfo != nil {
+		ackInfo.ProvisioningTime = ue.UpuInfo.ProvisioningTime
 	}
 	upuReq := Nudm_SubscriberDataManagement.UpuAckRequest{
 		Supi:            &ue.Supi,
@@ -92,6 +109,33 @@ func (s *nudmService) PutUpuAck(ue *amf_context.AmfUe, upuMacIue string) error {
 	return err
 }
 
+// SorAckInfo provides the acknowledgement of the UE for the Steering of Roaming information to the UDM,
+// TS 29.503 5.2.2.5
+func (s *nudmService) SorAckInfo(ue *amf_context.AmfUe, sorMacIue string) error {
+	client := s.getSubscriberDMngmntClients(ue.NudmSDMUri)
+	if client == nil {
+		return openapi.ReportError("udm not found")
+	}
+
+	ctx, _, err := amf_context.GetSelf().GetTokenCtx(models.ServiceName_NUDM_SDM, models.NrfNfManagementNfType_UDM)
+	if err != nil {
+		return err
+	}
+
+	ackInfo := models.AcknowledgeInfo{
+		SorMacIue:        sorMacIue,
+		ProvisioningTime: ue.SorProvisioningTime,
+	}
+	sorReq := Nudm_SubscriberDataManagement.SorAckInfoRequest{
+		Supi:            &ue.Supi,
+		AcknowledgeInfo: &ackInfo,
+	}
+	_, err = client.ProvidingAcknowledgementOfSteeringOfRoamingApi.
+		SorAckInfo(ctx, &sorReq)
+
+	return err
+}
+
 func (s *nudmService) SDMGetAmData(ue *amf_context.AmfUe) (problemDetails *models.ProblemDetails, err error) {
 	client := s.getSubscriberDMngmntClients(ue.NudmSDMUri)
 	if client == nil {
@@ -115,6 +159,7 @@ func (s *nudmService) SDMGetAmData(ue *amf_context.AmfUe) (problemDetails *model
 		ctx, &getAmDataParamReq)
 	if localErr == nil {
 		ue.AccessAndMobilitySubscriptionData = &data.AccessAndMobilitySubscriptionData
+		ue.SetSorInfo(data.AccessAndMobilitySubscriptionData.SorInfo)
 		if len(data.AccessAndMobilitySubscriptionData.Gpsis) > 0 {
 			ue.Gpsi = data.AccessAndMobilitySubscriptionData.Gpsis[0] // TODO: select GPSI
 		}
@@ -237,6 +282,11 @@ func (s *nudmService) SDMSubscribe(ue *amf_context.AmfUe) (problemDetails *model
 	sdmSubscription := models.SdmSubscription{
 		NfInstanceId: amfSelf.NfId,
 		PlmnId:       &ue.PlmnId,
+		CallbackReference: fmt.Sprintf("%s%s/sdm-notify/%s",
+			amfSelf.GetIPv4Uri(), factory.AmfCallbackResUriPrefix, ue.Supi),
+		MonitoredResourceUris: []string{
+			fmt.Sprintf("%s/nudm-sdm/v2/%s/am-data", ue.NudmSDMUri, ue.Supi),
+		},
 	}
 
 	subscribeReq := Nudm_SubscriberDataManagement.SubscribeRequest{
@@ -275,6 +325,51 @@ func (s *nudmService) SDMSubscribe(ue *amf_context.AmfUe) (problemDetails *model
 	return problemDetails, err
 }
 
+// nssaaRequiredNssai returns the subscribed S-NSSAIs subject to Network Slice-Specific Authentication and
+// Authorization, the additional S-NSSAI data are keyed by the S-NSSAI in hexadecimal (TS 29.503 6.1.6.2.2)
+func nssaaRequiredNssai(nssai *models.Nssai) []models.Snssai {
+	var required []models.Snssai
+	snssais := make([]models.Snssai, 0, len(nssai.DefaultSingleNssais)+len(nssai.SingleNssais))
+	snssais = append(snssais, nssai.DefaultSingleNssais...)
+	for _, snssai := range append(snssais, nssai.SingleNssais...) {
+		key := util.SnssaiModelsToHex(snssai)
+		for snssaiKey, data := range nssai.AdditionalSnssaiData {
+			if data.RequiredAuthnAuthz && strings.EqualFold(strings.ReplaceAll(snssaiKey, "-", ""), key) {
+				required = append(required, snssai)
+				break
+			}
+		}
+	}
+	return required
+}
+
+// ApplySliceSelectionSubscriptionData replaces the subscribed S-NSSAIs of the UE with the ones of the Slice Selection
+// Subscription Data (TS 29.503 6.1.6.2.2)
+func ApplySliceSelectionSubscriptionData(ue *amf_context.AmfUe, nssai *models.Nssai) {
+	ue.SubscribedNssai = nil
+	for _, defaultSnssai := range nssai.DefaultSingleNssais {
+		subscribedSnssai := models.SubscribedSnssai{
+			SubscribedSnssai: &models.Snssai{
+				Sst: defaultSnssai.Sst,
+				Sd:  defaultSnssai.Sd,
+			},
+			DefaultIndication: true,
+		}
+		ue.SubscribedNssai = append(ue.SubscribedNssai, subscribedSnssai)
+	}
+	for _, snssai := range nssai.SingleNssais {
+		subscribedSnssai := models.SubscribedSnssai{
+			SubscribedSnssai: &models.Snssai{
+				Sst: snssai.Sst,
+				Sd:  snssai.Sd,
+			},
+			DefaultIndication: false,
+		}
+		ue.SubscribedNssai = append(ue.SubscribedNssai, subscribedSnssai)
+	}
+	ue.NssaaRequiredNssai = nssaaRequiredNssai(nssai)
+}
+
 func (s *nudmService) SDMGetSliceSelectionSubscriptionData(
 	ue *amf_context.AmfUe,
 ) (problemDetails *models.ProblemDetails, err error) {
@@ -297,26 +392,7 @@ func (s *nudmService) SDMGetSliceSelectionSubscriptionData(
 		GetNSSAI(ctx, &paramReq)
 
 	if localErr == nil {
-		for _, defaultSnssai := range nssai.Nssai.DefaultSingleNssais {
-			subscribedSnssai := models.SubscribedSnssai{
-				SubscribedSnssai: &models.Snssai{
-					Sst: defaultSnssai.Sst,
-					Sd:  defaultSnssai.Sd,
-				},
-				DefaultIndication: true,
-			}
-			ue.SubscribedNssai = append(ue.SubscribedNssai, subscribedSnssai)
-		}
-		for _, snssai := range nssai.Nssai.SingleNssais {
-			subscribedSnssai := models.SubscribedSnssai{
-				SubscribedSnssai: &models.Snssai{
-					Sst: snssai.Sst,
-					Sd:  snssai.Sd,
-				},
-				DefaultIndication: false,
-			}
-			ue.SubscribedNssai = append(ue.SubscribedNssai, subscribedSnssai)
-		}
+		ApplySliceSelectionSubscriptionData(ue, &nssai.Nssai)
 	} else {
 		err = localErr
 		// API error
@@ -1,6 +1,7 @@
 package processor
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"runtime/debug"
@@ -9,11 +10,14 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/free5gc/amf/internal/context"
+	"github.com/free5gc/amf/internal/gmm"
 	gmm_common "github.com/free5gc/amf/internal/gmm/common"
 	gmm_message "github.com/free5gc/amf/internal/gmm/message"
 	"github.com/free5gc/amf/internal/logger"
 	amf_nas "github.com/free5gc/amf/internal/nas"
 	ngap_message "github.com/free5gc/amf/internal/ngap/message"
+	"github.com/free5gc/amf/internal/sbi/consumer"
+	"github.com/free5gc/nas/nasMessage"
 	"github.com/free5gc/ngap/ngapType"
 	"github.com/free5gc/openapi/models"
 	"github.com/free5gc/util/metrics/sbi"
@@ -145,11 +149,10 @@ func (p *Processor) AmPolicyControlUpdateNotifyUpdateProcedure(polAssoID string,
 		if trigger == models.PcfAmPolicyControlRequestTrigger_LOC_CH {
 			ue.RequestTriggerLocationChange = true
 		}
-		// if trigger == models.RequestTrigger_PRA_CH {
-		// TODO: Presence Reporting Area handling (TS 23.503 6.1.2.5, TS 23.501 5.6.11)
-		// }
 	}
 
+	gmm_common.UpdatePresenceReportingAreas(ue, &policyUpdate)
+
 	if policyUpdate.ServAreaRes != nil {
 		ue.AmPolicyAssociation.ServAreaRes = policyUpdate.ServAreaRes
 	}
@@ -180,13 +183,10 @@ func (p *Processor) AmPolicyControlUpdateNotifyUpdateProcedure(polAssoID string,
 
 			// UE is CM-Connected State
 			if ue.CmConnect(models.AccessType__3_GPP_ACCESS) {
-				gmm_message.SendConfigurationUpdateCommand(ue,
-					models.AccessType__3_GPP_ACCESS,
-					configurationUpdateCommandFlags,
-				)
+				gmm.RequestConfigurationUpdate(ue, models.AccessType__3_GPP_ACCESS, configurationUpdateCommandFlags)
 			} else {
 				// UE is CM-IDLE => paging
-				ue.ConfigurationUpdateCommandFlags = configurationUpdateCommandFlags
+				ue.AddPendingConfigurationUpdate(configurationUpdateCommandFlags)
 
 				ue.SetOnGoing(models.AccessType__3_GPP_ACCESS, &context.OnGoing{
 					Procedure: context.OnGoingProcedurePaging,
@@ -260,6 +260,273 @@ func (p *Processor) AmPolicyControlUpdateNotifyTerminateProcedure(polAssoID stri
 	return nil
 }
 
+// TS 29.503 5.2.2.3.2 Nudm_SDM_Notification, the subscription data of the UE changed in the UDM
+func (p *Processor) HandleSdmDataChangeNotification(c *gin.Context,
+	modificationNotification models.ModificationNotification,
+) {
+	logger.ProducerLog.Infoln("[AMF] Handle SDM Data Change Notification")
+
+	supi := c.Param("supi")
+	problemDetails := p.SdmDataChangeNotificationProcedure(supi, modificationNotification)
+	if problemDetails != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+	} else {
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func (p *Processor) SdmDataChangeNotificationProcedure(supi string,
+	modificationNotification models.ModificationNotification,
+) *models.ProblemDetails {
+	ue, ok := context.GetSelf().AmfUeFindBySupi(supi)
+	if !ok {
+		logger.CallbackLog.Warnf("AmfUe Context[%s] not found", supi)
+		return &models.ProblemDetails{
+			Status: http.StatusNotFound,
+			Cause:  "CONTEXT_NOT_FOUND",
+		}
+	}
+
+	ue.Lock.Lock()
+	defer ue.Lock.Unlock()
+
+	for _, notifyItem := range modificationNotification.NotifyItems {
+		for _, change := range notifyItem.Changes {
+			switch change.Path {
+			case "/sorInfo":
+				var sorInfo models.UdmSdmSorInfo
+				if err := mapToModels(change.NewValue, &sorInfo); err != nil {
+					ue.ProducerLog.Errorf("Decode sorInfo failed: %+v", err)
+					return &models.ProblemDetails{
+						Status: http.StatusBadRequest,
+						Cause:  "MANDATORY_IE_INCORRECT",
+						Detail: err.Error(),
+					}
+				}
+				sendSorInfo(ue, &sorInfo)
+			case "/upuInfo":
+				var upuInfo models.UdmSdmUpuInfo
+				if err := mapToModels(change.NewValue, &upuInfo); err != nil {
+					ue.ProducerLog.Errorf("Decode upuInfo failed: %+v", err)
+					return &models.ProblemDetails{
+						Status: http.StatusBadRequest,
+						Cause:  "MANDATORY_IE_INCORRECT",
+						Detail: err.Error(),
+					}
+				}
+				if err := sendUpuInfo(ue, &upuInfo); err != nil {
+					ue.ProducerLog.Errorf("Send UE parameters update failed: %+v", err)
+					return &models.ProblemDetails{
+						Status: http.StatusBadRequest,
+						Cause:  "MANDATORY_IE_INCORRECT",
+						Detail: err.Error(),
+					}
+				}
+			case "/nssai":
+				var nssai models.Nssai
+				if err := mapToModels(change.NewValue, &nssai); err != nil {
+					ue.ProducerLog.Errorf("Decode nssai failed: %+v", err)
+					return &models.ProblemDetails{
+						Status: http.StatusBadRequest,
+						Cause:  "MANDATORY_IE_INCORRECT",
+						Detail: err.Error(),
+					}
+				}
+				updateSubscribedNssai(ue, &nssai)
+			default:
+				ue.ProducerLog.Debugf("Change of %s%s is not handled", notifyItem.ResourceId, change.Path)
+			}
+		}
+	}
+	return nil
+}
+
+// updateSubscribedNssai indicates the change of the subscribed S-NSSAIs to the registered UE, the UE gets its new
+// configured NSSAI and requests the S-NSSAIs to use in a registration procedure (TS 23.502 4.2.4.2)
+func updateSubscribedNssai(ue *context.AmfUe, nssai *models.Nssai) {
+	consumer.ApplySliceSelectionSubscriptionData(ue, nssai)
+	ue.NetworkSlicingSubscriptionChanged = true
+	ue.ConfiguredNssai = nil
+	for _, subscribedSnssai := range ue.SubscribedNssai {
+		ue.ConfiguredNssai = append(ue.ConfiguredNssai, models.ConfiguredSnssai{
+			ConfiguredSnssai: subscribedSnssai.SubscribedSnssai,
+		})
+	}
+	for _, anType := range []models.AccessType{models.AccessType__3_GPP_ACCESS, models.AccessType_NON_3_GPP_ACCESS} {
+		if ue.State[anType] != nil && ue.State[anType].Is(context.Registered) {
+			gmm.RequestConfigurationUpdate(ue, anType, &context.ConfigurationUpdateCommandFlags{
+				NeedConfiguredNSSAI:          true,
+				NeedNetworkSlicingIndication: true,
+			})
+		}
+	}
+}
+
+// TS 29.526 5.2.2.3 Nnssaaf_NSSAA_Re-AuthenticationNotification and Nnssaaf_NSSAA_RevocationNotification,
+// the AAA server triggers the re-authentication or the revocation of an S-NSSAI of the UE
+func (p *Processor) HandleSliceAuthNotification(c *gin.Context,
+	sliceAuthNotification consumer.SliceAuthNotification,
+) {
+	logger.ProducerLog.Infoln("[AMF] Handle Slice Authentication Notification")
+
+	supi := c.Param("supi")
+	problemDetails := p.SliceAuthNotificationProcedure(supi, sliceAuthNotification)
+	if problemDetails != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+	} else {
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func (p *Processor) SliceAuthNotificationProcedure(supi string,
+	sliceAuthNotification consumer.SliceAuthNotification,
+) *models.ProblemDetails {
+	ue, ok := context.GetSelf().AmfUeFindBySupi(supi)
+	if !ok {
+		logger.CallbackLog.Warnf("AmfUe Context[%s] not found", supi)
+		return &models.ProblemDetails{
+			Status: http.StatusNotFound,
+			Cause:  "CONTEXT_NOT_FOUND",
+		}
+	}
+
+	ue.Lock.Lock()
+	defer ue.Lock.Unlock()
+
+	var err error
+	switch sliceAuthNotification.NotifType {
+	case consumer.SliceAuthNotificationTypeReauth:
+		err = gmm.ReauthenticateSnssai(ue, sliceAuthNotification.Snssai)
+	case consumer.SliceAuthNotificationTypeRevocation:
+		err = gmm.RevokeSnssai(ue, sliceAuthNotification.Snssai)
+	default:
+		err = fmt.Errorf("unknown notification type %s", sliceAuthNotification.NotifType)
+	}
+	if err != nil {
+		ue.ProducerLog.Errorf("Handle Slice Authentication Notification failed: %+v", err)
+		return &models.ProblemDetails{
+			Status: http.StatusBadRequest,
+			Cause:  "MANDATORY_IE_INCORRECT",
+			Detail: err.Error(),
+		}
+	}
+	return nil
+}
+
+// sendSorInfo sends the Steering of Roaming information provided by the UDM after the registration in a DL NAS
+// Transport (TS 23.122 Annex C.4). It is kept pending if the UE is not CM-CONNECTED, it is sent after the next
+// Service Accept or in the next Registration Accept.
+func sendSorInfo(ue *context.AmfUe, sorInfo *models.UdmSdmSorInfo) {
+	ue.SetSorInfo(sorInfo)
+
+	sorContainer, err := ue.SorTransparentContainer()
+	if err != nil {
+		ue.ProducerLog.Errorf("Encode SOR transparent container failed: %+v", err)
+		return
+	}
+	ue.RemovePendingDLPayloads(nasMessage.PayloadContainerTypeSOR)
+	ue.AppendPendingDLPayload(&context.PayloadContainerEntry{
+		Type:     nasMessage.PayloadContainerTypeSOR,
+		Contents: sorContainer,
+	})
+
+	anType := models.AccessType__3_GPP_ACCESS
+	if !ue.CmConnect(anType) {
+		ue.ProducerLog.Info("Steering of Roaming information is pending until the UE is CM-CONNECTED")
+		return
+	}
+	gmm_message.SendPendingDLPayloads(ue.RanUe[anType])
+}
+
+// sendUpuInfo sends the UE parameters update information provided by the UDM in a DL NAS Transport
+// (TS 23.502 4.20.2). A CM-IDLE UE is paged and the UPU is sent after the Service Accept, or after the Registration
+// Complete of its next registration. The UDM is told the UE is not reachable if the UE does not acknowledge the UPU
+// requesting it before the UPU ACK timer expires its maximum number of times. It is called with ue.Lock held.
+func sendUpuInfo(ue *context.AmfUe, upuInfo *models.UdmSdmUpuInfo) error {
+	ue.SetUpuInfo(upuInfo)
+
+	upuContainer, err := ue.UpuTransparentContainer()
+	if err != nil {
+		ue.SetUpuInfo(nil)
+		return err
+	}
+	queueUpuContainer(ue, upuContainer)
+
+	ue.StopUpuAckTimer()
+	if cfg := context.GetSelf().UpuAckCfg; ue.UpuAckRequested && cfg.Enable {
+		ue.GmmLog.Infof("Start UPU ACK timer")
+		// the expiry waiting for ue.Lock is discarded if the UPU ACK or a new UPU stopped the timer meanwhile
+		var upuAckTimer *context.Timer
+		upuAckTimer = context.NewTimer(cfg.ExpireTime, cfg.MaxRetryTimes, func(expireTimes int32) {
+			ue.Lock.Lock()
+			defer ue.Lock.Unlock()
+			if ue.UpuAckTimer != upuAckTimer {
+				return
+			}
+			ue.GmmLog.Warnf("UPU ACK timer expires, retransmit UE parameters update (retry: %d)", expireTimes)
+			queueUpuContainer(ue, upuContainer)
+		}, func() {
+			ue.Lock.Lock()
+			defer ue.Lock.Unlock()
+			if ue.UpuAckTimer != upuAckTimer {
+				return
+			}
+			ue.GmmLog.Warnf("UPU ACK timer expires %d times, abort UE parameters update", cfg.MaxRetryTimes)
+			ue.UpuAckTimer = nil // clear the timer
+			ue.RemovePendingDLPayloads(nasMessage.PayloadContainerTypeUEParameterUpdate)
+			ue.UpuAckRequested = false
+			if err := consumer.GetConsumer().PutUpuUeNotReachable(ue); err != nil {
+				ue.ProducerLog.Errorf("Report UE not reachable for UE parameters update failed: %+v", err)
+			}
+		})
+		ue.UpuAckTimer = upuAckTimer
+	}
+	return nil
+}
+
+// queueUpuContainer sends the UE parameters update to the registered UE, the UE in CM-IDLE state is paged. It is
+// called with ue.Lock held.
+func queueUpuContainer(ue *context.AmfUe, upuContainer []byte) {
+	ue.RemovePendingDLPayloads(nasMessage.PayloadContainerTypeUEParameterUpdate)
+	ue.AppendPendingDLPayload(&context.PayloadContainerEntry{
+		Type:     nasMessage.PayloadContainerTypeUEParameterUpdate,
+		Contents: upuContainer,
+	})
+
+	anType := models.AccessType__3_GPP_ACCESS
+	if ue.State[anType] == nil || !ue.State[anType].Is(context.Registered) {
+		ue.ProducerLog.Info("UE parameters update is pending until the UE is registered")
+		return
+	}
+	if ue.CmConnect(anType) {
+		gmm_message.SendPendingDLPayloads(ue.RanUe[anType])
+		return
+	}
+	if ue.OnGoing(anType).Procedure == context.OnGoingProcedurePaging {
+		return
+	}
+	ue.ProducerLog.Info("UE parameters update is pending until the UE is CM-CONNECTED, page the UE")
+	ue.SetOnGoing(anType, &context.OnGoing{
+		Procedure: context.OnGoingProcedurePaging,
+	})
+	pkg, err := ngap_message.BuildPaging(ue, nil, false)
+	if err != nil {
+		logger.NgapLog.Errorf("Build Paging failed : %s", err.Error())
+		return
+	}
+	ngap_message.SendPaging(ue, pkg)
+}
+
+func mapToModels(value map[string]interface{}, data interface{}) error {
+	buf, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(buf, data)
+}
+
 // TS 23.502 4.2.2.2.3 Registration with AMF re-allocation
 func (p *Processor) HandleN1MessageNotify(c *gin.Context, n1MessageNotify models.N1MessageNotifyRequest) {
 	logger.ProducerLog.Infoln("[AMF] Handle N1 Message Notify")
@@ -324,7 +591,7 @@ func (p *Processor) N1MessageNotifyProcedure(n1MessageNotify models.N1MessageNot
 		ranUe := ran.RanUeFindByRanUeNgapID(int64(registrationCtxtContainer.AnN2ApId))
 
 		ranUe.Location = *registrationCtxtContainer.UserLocation
-		amfUe.Location = *registrationCtxtContainer.UserLocation
+		amfUe.SetLocation(*registrationCtxtContainer.UserLocation)
 		ranUe.UeContextRequest = registrationCtxtContainer.UeContextRequest
 		ranUe.OldAmfName = registrationCtxtContainer.InitialAmfName
 
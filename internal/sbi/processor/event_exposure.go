@@ -9,6 +9,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/free5gc/amf/internal/context"
+	gmm_common "github.com/free5gc/amf/internal/gmm/common"
 	"github.com/free5gc/amf/internal/logger"
 	"github.com/free5gc/openapi/models"
 	"github.com/free5gc/util/metrics/sbi"
@@ -117,6 +118,7 @@ func (p *Processor) CreateAMFEventSubscriptionProcedure(createEventSubscription
 			ue.EventSubscriptionsInfo[newSubscriptionID] = new(context.AmfUeEventSubscription)
 			*ue.EventSubscriptionsInfo[newSubscriptionID] = ueEventSubscription
 			contextEventSubscription.UeSupiList = append(contextEventSubscription.UeSupiList, ue.Supi)
+			p.startLocationReporting(ue, newSubscriptionID, subscription.EventList)
 			ue.Lock.Unlock()
 			return true
 		})
@@ -130,6 +132,7 @@ func (p *Processor) CreateAMFEventSubscriptionProcedure(createEventSubscription
 				ue.EventSubscriptionsInfo[newSubscriptionID] = new(context.AmfUeEventSubscription)
 				*ue.EventSubscriptionsInfo[newSubscriptionID] = ueEventSubscription
 				contextEventSubscription.UeSupiList = append(contextEventSubscription.UeSupiList, ue.Supi)
+				p.startLocationReporting(ue, newSubscriptionID, subscription.EventList)
 			}
 			ue.Lock.Unlock()
 			return true
@@ -146,6 +149,7 @@ func (p *Processor) CreateAMFEventSubscriptionProcedure(createEventSubscription
 			ue.EventSubscriptionsInfo[newSubscriptionID] = new(context.AmfUeEventSubscription)
 			*ue.EventSubscriptionsInfo[newSubscriptionID] = ueEventSubscription
 			contextEventSubscription.UeSupiList = append(contextEventSubscription.UeSupiList, ue.Supi)
+			p.startLocationReporting(ue, newSubscriptionID, subscription.EventList)
 			ue.Lock.Unlock()
 		}
 	}
@@ -173,7 +177,7 @@ func (p *Processor) CreateAMFEventSubscriptionProcedure(createEventSubscription
 			}
 			for i, flag := range immediateFlags {
 				if flag {
-					report, ok := p.newAmfEventReport(ue, subscription.EventList[i].Type, newSubscriptionID)
+					report, ok := p.newAmfEventReport(ue, subscription.EventList[i], newSubscriptionID)
 					if ok {
 						reportlist = append(reportlist, report)
 					}
@@ -182,6 +186,7 @@ func (p *Processor) CreateAMFEventSubscriptionProcedure(createEventSubscription
 			// delete subscription
 			if reportlistLen := len(reportlist); reportlistLen > 0 && (!reportlist[reportlistLen-1].State.Active) {
 				delete(ue.EventSubscriptionsInfo, newSubscriptionID)
+				gmm_common.StopAreaOfInterestReporting(ue, ue.AreaOfInterestListBySubscriptionID(newSubscriptionID))
 			}
 			return true
 		})
@@ -197,7 +202,7 @@ func (p *Processor) CreateAMFEventSubscriptionProcedure(createEventSubscription
 			if ue.GroupID == subscription.GroupId {
 				for i, flag := range immediateFlags {
 					if flag {
-						report, ok := p.newAmfEventReport(ue, subscription.EventList[i].Type, newSubscriptionID)
+						report, ok := p.newAmfEventReport(ue, subscription.EventList[i], newSubscriptionID)
 						if ok {
 							reportlist = append(reportlist, report)
 						}
@@ -206,6 +211,7 @@ func (p *Processor) CreateAMFEventSubscriptionProcedure(createEventSubscription
 				// delete subscription
 				if reportlistLen := len(reportlist); reportlistLen > 0 && (!reportlist[reportlistLen-1].State.Active) {
 					delete(ue.EventSubscriptionsInfo, newSubscriptionID)
+					gmm_common.StopAreaOfInterestReporting(ue, ue.AreaOfInterestListBySubscriptionID(newSubscriptionID))
 				}
 			}
 			return true
@@ -220,7 +226,7 @@ func (p *Processor) CreateAMFEventSubscriptionProcedure(createEventSubscription
 		}
 		for i, flag := range immediateFlags {
 			if flag {
-				report, ok := p.newAmfEventReport(ue, subscription.EventList[i].Type, newSubscriptionID)
+				report, ok := p.newAmfEventReport(ue, subscription.EventList[i], newSubscriptionID)
 				if ok {
 					reportlist = append(reportlist, report)
 				}
@@ -229,6 +235,7 @@ func (p *Processor) CreateAMFEventSubscriptionProcedure(createEventSubscription
 		// delete subscription
 		if reportlistLen := len(reportlist); reportlistLen > 0 && (!reportlist[reportlistLen-1].State.Active) {
 			delete(ue.EventSubscriptionsInfo, newSubscriptionID)
+			gmm_common.StopAreaOfInterestReporting(ue, ue.AreaOfInterestListBySubscriptionID(newSubscriptionID))
 		}
 	}
 	if len(reportlist) > 0 {
@@ -272,6 +279,7 @@ func (p *Processor) DeleteAMFEventSubscriptionProcedure(subscriptionID string) *
 		if ue, okAmfUeFindBySupi := amfSelf.AmfUeFindBySupi(supi); okAmfUeFindBySupi {
 			ue.Lock.Lock()
 			delete(ue.EventSubscriptionsInfo, subscriptionID)
+			gmm_common.StopAreaOfInterestReporting(ue, ue.AreaOfInterestListBySubscriptionID(subscriptionID))
 			ue.Lock.Unlock()
 		}
 	}
@@ -423,6 +431,32 @@ func (p *Processor) ModifyAMFEventSubscriptionProcedure(
 	return updatedEventSubscription, nil
 }
 
+// startLocationReporting keeps track of the areas of interest of the PRESENCE_IN_AOI_REPORT events and
+// configures the NG-RAN location reporting of the UE accordingly. The caller shall hold ue.Lock.
+func (p *Processor) startLocationReporting(ue *context.AmfUe, subscriptionID string, eventList []models.AmfEvent) {
+	needReporting := false
+	for _, event := range eventList {
+		switch event.Type {
+		case models.AmfEventType_LOCATION_REPORT:
+			needReporting = true
+		case models.AmfEventType_PRESENCE_IN_AOI_REPORT:
+			for _, area := range event.AreaList {
+				if area.PresenceInfo == nil {
+					continue
+				}
+				if _, err := ue.NewAreaOfInterest(subscriptionID, "", *area.PresenceInfo); err != nil {
+					ue.ProducerLog.Warnf("Subscription[%s] area of interest: %v", subscriptionID, err)
+					continue
+				}
+				needReporting = true
+			}
+		}
+	}
+	if needReporting {
+		gmm_common.ConfigureLocationReporting(ue)
+	}
+}
+
 func (p *Processor) subReports(ue *context.AmfUe, subscriptionId string) {
 	remainReport := ue.EventSubscriptionsInfo[subscriptionId].RemainReports
 	if remainReport == nil {
@@ -431,10 +465,10 @@ func (p *Processor) subReports(ue *context.AmfUe, subscriptionId string) {
 	*remainReport--
 }
 
-// DO NOT handle AmfEventType_PRESENCE_IN_AOI_REPORT and AmfEventType_UES_IN_AREA_REPORT(about area)
-func (p *Processor) newAmfEventReport(ue *context.AmfUe, amfEventType models.AmfEventType, subscriptionId string) (
+func (p *Processor) newAmfEventReport(ue *context.AmfUe, event models.AmfEvent, subscriptionId string) (
 	report models.AmfEventReport, ok bool,
 ) {
+	amfEventType := event.Type
 	ueSubscription, ok := ue.EventSubscriptionsInfo[subscriptionId]
 	if !ok {
 		return report, ok
@@ -461,9 +495,16 @@ func (p *Processor) newAmfEventReport(ue *context.AmfUe, amfEventType models.Amf
 
 	switch amfEventType {
 	case models.AmfEventType_LOCATION_REPORT:
-		report.Location = &ue.Location
-	// case models.AmfEventType_PRESENCE_IN_AOI_REPORT:
-	// report.AreaList = (*subscription.EventList)[eventIndex].AreaList
+		location := ue.LocationCopy()
+		report.Location = &location
+	case models.AmfEventType_PRESENCE_IN_AOI_REPORT:
+		for _, aoi := range ue.AreaOfInterestListBySubscriptionID(subscriptionId) {
+			presenceInfo := aoi.PresenceInfo
+			report.AreaList = append(report.AreaList, models.AmfEventArea{PresenceInfo: &presenceInfo})
+		}
+	case models.AmfEventType_UES_IN_AREA_REPORT:
+		report.AreaList = event.AreaList
+		report.NumberOfUes = p.numberOfUesInArea(event.AreaList)
 	case models.AmfEventType_TIMEZONE_REPORT:
 		report.Timezone = ue.TimeZone
 	case models.AmfEventType_ACCESS_TYPE_REPORT:
@@ -499,6 +540,22 @@ func (p *Processor) newAmfEventReport(ue *context.AmfUe, amfEventType models.Amf
 	return report, ok
 }
 
+// numberOfUesInArea counts the UEs served by the AMF whose last known location is in one of the areas
+func (p *Processor) numberOfUesInArea(areaList []models.AmfEventArea) (numberOfUes int32) {
+	context.GetSelf().UePool.Range(func(key, value interface{}) bool {
+		location := value.(*context.AmfUe).LocationCopy()
+		for _, area := range areaList {
+			if area.PresenceInfo != nil &&
+				context.PresenceStateInArea(area.PresenceInfo, location) == models.PresenceState_IN_AREA {
+				numberOfUes++
+				break
+			}
+		}
+		return true
+	})
+	return numberOfUes
+}
+
 func (p *Processor) getDuration(expiry *time.Time, remainDuration *int32) bool {
 	if expiry != nil {
 		if time.Now().After(*expiry) {
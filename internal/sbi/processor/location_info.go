@@ -57,7 +57,8 @@ func (p *Processor) ProvideLocationInfoProcedure(requestLocInfo models.RequestLo
 	ranUe := ue.RanUe[anType]
 	if requestLocInfo.Req5gsLoc || requestLocInfo.ReqCurrentLoc {
 		provideLocInfo.CurrentLoc = true
-		provideLocInfo.Location = &ue.Location
+		location := ue.LocationCopy()
+		provideLocInfo.Location = &location
 	}
 
 	if requestLocInfo.ReqRatType {
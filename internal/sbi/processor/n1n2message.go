@@ -200,8 +200,21 @@ func (p *Processor) N1N2MessageTransferProcedure(ueContextID string, reqUri stri
 			err    error
 		)
 		if n1Msg != nil {
-			nasPdu, err = gmm_message.
-				BuildDLNASTransport(ue, anType, n1MsgType, n1Msg, uint8(requestData.PduSessionId), nil, nil, 0)
+			if n1MsgType == nasMessage.PayloadContainerTypeLPP {
+				session := ue.NewPositioningSession(requestData.N1MessageContainer.NfId, requestData.LcsCorrelationId)
+				if session == nil {
+					problemDetails = &models.ProblemDetails{
+						Status: http.StatusInternalServerError,
+						Cause:  "SYSTEM_FAILURE",
+						Detail: "no routing identifier available for the positioning session",
+					}
+					return nil, "", problemDetails, nil
+				}
+				nasPdu, err = gmm_message.BuildDLNASTransportLPP(ue, anType, n1Msg, session.RoutingInformation())
+			} else {
+				nasPdu, err = gmm_message.
+					BuildDLNASTransport(ue, anType, n1MsgType, n1Msg, uint8(requestData.PduSessionId), nil, nil, 0)
+			}
 			if err != nil {
 				ue.ProducerLog.Errorf("Build DL NAS Transport error: %+v", err)
 				problemDetails = &models.ProblemDetails{
@@ -214,6 +227,13 @@ func (p *Processor) N1N2MessageTransferProcedure(ueContextID string, reqUri stri
 			}
 			if n2Info == nil {
 				ue.ProducerLog.Debug("Forward N1 Message to UE")
+				if uri := requestData.N1n2FailureTxfNotifURI; uri != "" {
+					// no resource is created for the transfer, the NF is notified with the collection URI
+					ue.RecordN1N2NasPdu(nasPdu, context.N1N2MessageOrigin{
+						N1n2FailureTxfNotifURI: uri,
+						N1n2MsgDataUri:         context.GetSelf().GetIPv4Uri() + reqUri,
+					})
+				}
 				ngap_message.SendDownlinkNasTransport(ue.RanUe[anType], nasPdu, nil)
 				n1n2MessageTransferRspData = new(models.N1N2MessageTransferRspData)
 				n1n2MessageTransferRspData.Cause = models.N1N2MessageTransferCause_N1_N2_TRANSFER_INITIATED
@@ -280,7 +300,8 @@ func (p *Processor) N1N2MessageTransferProcedure(ueContextID string, reqUri stri
 		return nil, "", nil, transferErr
 	}
 	// 504: the UE in MICO mode or the UE is only registered over Non-3GPP access and its state is CM-IDLE
-	if !ue.State[models.AccessType__3_GPP_ACCESS].Is(context.Registered) {
+	if !ue.State[models.AccessType__3_GPP_ACCESS].Is(context.Registered) ||
+		ue.MicoMode || ue.Reachability == models.UeReachability_UNREACHABLE {
 		transferErr = new(models.N1N2MessageTransferError)
 		transferErr.Error = &models.ProblemDetails{
 			Status: http.StatusGatewayTimeout,
@@ -288,6 +309,18 @@ func (p *Processor) N1N2MessageTransferProcedure(ueContextID string, reqUri stri
 		}
 		return nil, "", nil, transferErr
 	}
+	// 504: the SMF supporting extended buffering keeps the downlink data until the UE in a long eDRX cycle is
+	// reachable for paging, it is given the estimated maximum waiting time (TS 23.502 4.2.3.3 step 3a)
+	if maxWaitingTime := ue.EdrxMaxWaitingTime(); anType == models.AccessType__3_GPP_ACCESS &&
+		requestData.ExtBufSupport && maxWaitingTime > 0 {
+		transferErr = new(models.N1N2MessageTransferError)
+		transferErr.Error = &models.ProblemDetails{
+			Status: http.StatusGatewayTimeout,
+			Cause:  "UE_NOT_REACHABLE",
+		}
+		transferErr.ErrInfo = &models.N1N2MsgTxfrErrDetail{MaxWaitingTime: maxWaitingTime}
+		return nil, "", nil, transferErr
+	}
 
 	n1n2MessageTransferRspData = new(models.N1N2MessageTransferRspData)
 
@@ -520,6 +553,16 @@ func (p *Processor) N1N2MessageUnSubscribeProcedure(ueContextID string, subscrip
 	ue.Lock.Lock()
 	defer ue.Lock.Unlock()
 
-	ue.N1N2MessageSubscription.Delete(subscriptionID)
+	id, err := strconv.ParseInt(subscriptionID, 10, 64)
+	if err != nil {
+		return nil
+	}
+	if value, loaded := ue.N1N2MessageSubscription.LoadAndDelete(id); loaded {
+		subscription := value.(models.UeN1N2InfoSubscriptionCreateData)
+		// the LMF releases its positioning sessions with the UE
+		if subscription.N1MessageClass == models.N1MessageClass_LPP {
+			ue.RemovePositioningSessions(subscription.NfId)
+		}
+	}
 	return nil
 }
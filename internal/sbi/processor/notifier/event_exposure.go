@@ -0,0 +1,33 @@
+package callback
+
+import (
+	"context"
+
+	"github.com/free5gc/amf/internal/logger"
+	Namf_EventExposure "github.com/free5gc/openapi/amf/EventExposure"
+	"github.com/free5gc/openapi/models"
+)
+
+// SendAmfEventNotify sends the event reports of a subscription to its eventNotifyUri, TS 29.518 5.3.2.4.1
+func SendAmfEventNotify(subscription *models.ExtAmfEventSubscription, reportList []models.AmfEventReport) {
+	if subscription == nil || subscription.EventNotifyUri == "" || len(reportList) == 0 {
+		return
+	}
+
+	configuration := Namf_EventExposure.NewConfiguration()
+	client := Namf_EventExposure.NewAPIClient(configuration)
+
+	notification := models.AmfEventNotification{
+		NotifyCorrelationId: subscription.NotifyCorrelationId,
+		ReportList:          reportList,
+	}
+	var notifyReq Namf_EventExposure.CreateSubscriptionOnEventReportPostRequest
+	notifyReq.SetAmfEventNotification(notification)
+
+	logger.ProducerLog.Infof("[AMF] Send Amf Event Notify to %s", subscription.EventNotifyUri)
+	_, err := client.SubscriptionsCollectionCollectionApi.
+		CreateSubscriptionOnEventReportPost(context.Background(), subscription.EventNotifyUri, &notifyReq)
+	if err != nil {
+		HttpLog.Errorln(err.Error())
+	}
+}
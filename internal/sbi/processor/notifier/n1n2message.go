@@ -25,20 +25,7 @@ func SendN1N2TransferFailureNotification(ue *amf_context.AmfUe, cause models.N1N
 	n1n2Message := ue.N1N2Message
 	uri := n1n2Message.Request.JsonData.N1n2FailureTxfNotifURI
 	if n1n2Message.Status == models.N1N2MessageTransferCause_ATTEMPTING_TO_REACH_UE && uri != "" {
-		configuration := Namf_Communication.NewConfiguration()
-		client := Namf_Communication.NewAPIClient(configuration)
-
-		n1N2MsgTxfrFailureNotificationReq := Namf_Communication.N1N2TransferFailureNotificationRequest{
-			N1N2MsgTxfrFailureNotification: &models.N1N2MsgTxfrFailureNotification{
-				Cause:          cause,
-				N1n2MsgDataUri: n1n2Message.ResourceUri,
-			},
-		}
-
-		_, err := client.N1N2MessageCollectionCollectionApi.
-			N1N2TransferFailureNotification(context.Background(), uri, &n1N2MsgTxfrFailureNotificationReq)
-
-		if err != nil {
+		if err := sendN1N2TransferFailureNotification(uri, n1n2Message.ResourceUri, cause); err != nil {
 			HttpLog.Errorln(err.Error())
 		} else {
 			ue.N1N2Message = nil
@@ -46,6 +33,34 @@ func SendN1N2TransferFailureNotification(ue *amf_context.AmfUe, cause models.N1N
 	}
 }
 
+// SendN1MessageFailureNotification notifies the NF which requested an N1N2 Message Transfer that its N1
+// message, sent to the UE in CM-CONNECTED state, could not be delivered
+func SendN1MessageFailureNotification(origin *amf_context.N1N2MessageOrigin, cause models.N1N2MessageTransferCause) {
+	if origin == nil || origin.N1n2FailureTxfNotifURI == "" {
+		return
+	}
+	if err := sendN1N2TransferFailureNotification(origin.N1n2FailureTxfNotifURI, origin.N1n2MsgDataUri,
+		cause); err != nil {
+		HttpLog.Errorln(err.Error())
+	}
+}
+
+func sendN1N2TransferFailureNotification(uri, n1n2MsgDataUri string, cause models.N1N2MessageTransferCause) error {
+	configuration := Namf_Communication.NewConfiguration()
+	client := Namf_Communication.NewAPIClient(configuration)
+
+	n1N2MsgTxfrFailureNotificationReq := Namf_Communication.N1N2TransferFailureNotificationRequest{
+		N1N2MsgTxfrFailureNotification: &models.N1N2MsgTxfrFailureNotification{
+			Cause:          cause,
+			N1n2MsgDataUri: n1n2MsgDataUri,
+		},
+	}
+
+	_, err := client.N1N2MessageCollectionCollectionApi.
+		N1N2TransferFailureNotification(context.Background(), uri, &n1N2MsgTxfrFailureNotificationReq)
+	return err
+}
+
 func SendN1MessageNotify(ue *amf_context.AmfUe, n1class models.N1MessageClass, n1Msg []byte,
 	registerContext *models.RegistrationContextContainer,
 ) {
@@ -54,35 +69,64 @@ func SendN1MessageNotify(ue *amf_context.AmfUe, n1class models.N1MessageClass, n
 		subscription := value.(models.UeN1N2InfoSubscriptionCreateData)
 
 		if subscription.N1NotifyCallbackUri != "" && subscription.N1MessageClass == n1class {
-			configuration := Namf_Communication.NewConfiguration()
-			client := Namf_Communication.NewAPIClient(configuration)
-			n1MessageNotify := models.N1MessageNotifyRequest{
-				JsonData: &models.N1MessageNotification{
-					N1NotifySubscriptionId: strconv.Itoa(int(subscriptionID)),
-					N1MessageContainer: &models.N1MessageContainer{
-						N1MessageClass: subscription.N1MessageClass,
-						N1MessageContent: &models.RefToBinaryData{
-							ContentId: "n1Msg",
-						},
-					},
-					RegistrationCtxtContainer: registerContext,
-				},
-				BinaryDataN1Message: n1Msg,
-			}
-
-			n1MessageNotifyReq := Namf_Communication.N1MessageNotifyRequest{
-				N1MessageNotifyRequest: &n1MessageNotify,
-			}
-			_, err := client.N1N2SubscriptionsCollectionForIndividualUEContextsCollectionApi.
-				N1MessageNotify(context.Background(), subscription.N1NotifyCallbackUri, &n1MessageNotifyReq)
-			if err != nil {
-				HttpLog.Errorln(err.Error())
-			}
+			sendN1MessageNotify(subscriptionID, subscription, &models.N1MessageNotification{
+				RegistrationCtxtContainer: registerContext,
+			}, n1Msg)
 		}
 		return true
 	})
 }
 
+// SendLppMessageNotify forwards an LPP message of the UE to the LMF owning the positioning session, through the
+// N1 message subscription of the LMF (TS 23.273 6.11.1). It returns false if no LMF subscribed to LPP messages.
+func SendLppMessageNotify(ue *amf_context.AmfUe, lmfId, lcsCorrelationId string, n1Msg []byte) bool {
+	notified := false
+	ue.N1N2MessageSubscription.Range(func(key, value interface{}) bool {
+		subscriptionID := key.(int64)
+		subscription := value.(models.UeN1N2InfoSubscriptionCreateData)
+
+		if subscription.N1NotifyCallbackUri == "" || subscription.N1MessageClass != models.N1MessageClass_LPP {
+			return true
+		}
+		// the LMF is not known if it did not give its NF instance ID
+		if lmfId != "" && subscription.NfId != "" && subscription.NfId != lmfId {
+			return true
+		}
+		sendN1MessageNotify(subscriptionID, subscription, &models.N1MessageNotification{
+			LcsCorrelationId: lcsCorrelationId,
+		}, n1Msg)
+		notified = true
+		return false
+	})
+	return notified
+}
+
+func sendN1MessageNotify(subscriptionID int64, subscription models.UeN1N2InfoSubscriptionCreateData,
+	notification *models.N1MessageNotification, n1Msg []byte,
+) {
+	configuration := Namf_Communication.NewConfiguration()
+	client := Namf_Communication.NewAPIClient(configuration)
+
+	notification.N1NotifySubscriptionId = strconv.Itoa(int(subscriptionID))
+	notification.N1MessageContainer = &models.N1MessageContainer{
+		N1MessageClass: subscription.N1MessageClass,
+		N1MessageContent: &models.RefToBinaryData{
+			ContentId: "n1Msg",
+		},
+	}
+	n1MessageNotifyReq := Namf_Communication.N1MessageNotifyRequest{
+		N1MessageNotifyRequest: &models.N1MessageNotifyRequest{
+			JsonData:            notification,
+			BinaryDataN1Message: n1Msg,
+		},
+	}
+	_, err := client.N1N2SubscriptionsCollectionForIndividualUEContextsCollectionApi.
+		N1MessageNotify(context.Background(), subscription.N1NotifyCallbackUri, &n1MessageNotifyReq)
+	if err != nil {
+		HttpLog.Errorln(err.Error())
+	}
+}
+
 // TS 29.518 5.2.2.3.5.2
 func SendN1MessageNotifyAtAMFReAllocation(
 	ue *amf_context.AmfUe, n1Msg []byte, registerContext *models.RegistrationContextContainer,
@@ -3,11 +3,14 @@ package processor
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/free5gc/amf/internal/context"
+	"github.com/free5gc/amf/internal/gmm"
 	"github.com/free5gc/amf/internal/logger"
+	"github.com/free5gc/amf/pkg/factory"
 	"github.com/free5gc/openapi/models"
 	"github.com/free5gc/util/metrics/sbi"
 )
@@ -32,6 +35,8 @@ type UEContext struct {
 	PduSessions []PduSession
 	/*Connection state */
 	CmState models.CmState
+	/* Last Configuration Update Command */
+	ConfigurationUpdateStatus context.ConfigurationUpdateStatus `json:",omitempty"`
 }
 
 type UEContexts []UEContext
@@ -124,7 +129,92 @@ func (p *Processor) buildUEContext(ue *context.AmfUe, accessType models.AccessTy
 		} else {
 			ueContext.CmState = models.CmState_IDLE
 		}
+		if ue.ConfigurationUpdate != nil && ue.ConfigurationUpdate.AccessType == accessType {
+			ueContext.ConfigurationUpdateStatus = ue.ConfigurationUpdate.Status
+		}
 		return ueContext
 	}
 	return nil
 }
+
+func (p *Processor) HandleOAMGutiReallocation(c *gin.Context) {
+	logger.ProducerLog.Infof("[OAM] Handle GUTI Reallocation")
+
+	supi := c.Param("supi")
+
+	problemDetails := p.OAMGutiReallocationProcedure(supi)
+	if problemDetails != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+	} else {
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// OAMGutiReallocationProcedure assigns a new 5G-GUTI to a registered UE with a Configuration Update Command
+// (TS 23.502 4.2.4.2)
+func (p *Processor) OAMGutiReallocationProcedure(supi string) *models.ProblemDetails {
+	ue, ok := context.GetSelf().AmfUeFindBySupi(supi)
+	if !ok {
+		return &models.ProblemDetails{
+			Status: http.StatusNotFound,
+			Cause:  "CONTEXT_NOT_FOUND",
+		}
+	}
+
+	ue.Lock.Lock()
+	defer ue.Lock.Unlock()
+
+	if err := gmm.ReallocateGuti(ue); err != nil {
+		return &models.ProblemDetails{
+			Status: http.StatusConflict,
+			Cause:  "UE_NOT_REGISTERED",
+			Detail: err.Error(),
+		}
+	}
+	return nil
+}
+
+func (p *Processor) HandleOAMNetworkName(c *gin.Context, networkName factory.NetworkName) {
+	logger.ProducerLog.Infof("[OAM] Handle Network Name")
+
+	p.OAMNetworkNameProcedure(networkName)
+	c.Status(http.StatusNoContent)
+}
+
+// OAMNetworkNameProcedure changes the network name of the AMF, the registered UEs get the new network name in a
+// Configuration Update Command (TS 24.501 5.4.4.1)
+func (p *Processor) OAMNetworkNameProcedure(networkName factory.NetworkName) {
+	if !context.GetSelf().SetNetworkName(networkName) {
+		return
+	}
+	gmm.ConfigurationUpdateAllUes(&context.ConfigurationUpdateCommandFlags{
+		NeedNITZ: true,
+	})
+}
+
+// TimeZoneProcedure updates the local time zone of the AMF, e.g. on a daylight saving time change, the registered UEs
+// get the new local time zone in a Configuration Update Command (TS 24.501 5.4.4.1)
+func (p *Processor) TimeZoneProcedure(now time.Time) {
+	if !context.GetSelf().UpdateTimeZone(now) {
+		return
+	}
+	logger.ProducerLog.Infof("Local time zone changed to %s", context.GetSelf().TimeZone())
+	gmm.ConfigurationUpdateAllUes(&context.ConfigurationUpdateCommandFlags{
+		NeedNITZ: true,
+	})
+}
+
+func (p *Processor) HandleOAMLadn(c *gin.Context, ladnList []factory.Ladn) {
+	logger.ProducerLog.Infof("[OAM] Handle LADN")
+
+	p.OAMLadnProcedure(ladnList)
+	c.Status(http.StatusNoContent)
+}
+
+// OAMLadnProcedure replaces the LADNs of the AMF, the registered UEs whose LADN information changed get the new one in
+// a Configuration Update Command (TS 24.501 5.4.4.1)
+func (p *Processor) OAMLadnProcedure(ladnList []factory.Ladn) {
+	context.GetSelf().SetLadnList(ladnList)
+	gmm.UpdateLadnInformation()
+}
@@ -88,6 +88,16 @@ func (p *Processor) CreateUEContextProcedure(ueContextID string, createUeContext
 
 	ue.RoutingIndicator = ueContextCreateData.UeContext.RoutingIndicator
 
+	// the security context of the UE is transferred by the source AMF (TS 33.501 6.9.2.3.3)
+	if seafData := ueContextCreateData.UeContext.SeafData; seafData != nil {
+		ue.SecurityContextAvailable = true
+		ue.CopyDataFromUeContextModel(ueContextCreateData.UeContext)
+		if ue.SecurityContextAvailable {
+			ue.DerivateKamfOnMobility(seafData, context.KamfDerivationDirectionHandover, ue.DLCount.Get())
+			ue.DerivateAlgKey()
+		}
+	}
+
 	// optional
 	ue.UdmGroupId = ueContextCreateData.UeContext.UdmGroupId
 	ue.AusfGroupId = ueContextCreateData.UeContext.AusfGroupId
@@ -111,7 +121,6 @@ func (p *Processor) CreateUEContextProcedure(ueContextID string, createUeContext
 	// ue.UEAMBR = ueContextCreateData.UeContext.SubUeAmbr
 	// ueContextCreateData.UeContext.SmsSupport
 	// ueContextCreateData.UeContext.SmsfId
-	// ueContextCreateData.UeContext.SeafData
 	// ueContextCreateData.UeContext.Var5gMmCapability
 	// ueContextCreateData.UeContext.PcfId
 	// ueContextCreateData.UeContext.PcfAmPolicyUri
@@ -163,16 +172,6 @@ func (p *Processor) ReleaseUEContextProcedure(ueContextID string,
 ) *models.ProblemDetails {
 	amfSelf := context.GetSelf()
 
-	// TODO: UE is emergency registered and the SUPI is not authenticated
-	if ueContextRelease.Supi != "" {
-		logger.GmmLog.Warnf("Emergency registered UE is not supported.")
-		problemDetails := &models.ProblemDetails{
-			Status: http.StatusForbidden,
-			Cause:  "UNSPECIFIED",
-		}
-		return problemDetails
-	}
-
 	if ueContextRelease.NgapCause == nil {
 		problemDetails := &models.ProblemDetails{
 			Status: http.StatusBadRequest,
@@ -193,6 +192,18 @@ func (p *Processor) ReleaseUEContextProcedure(ueContextID string,
 		return problemDetails
 	}
 
+	// TS 29.518 6.1.6.2.13: the SUPI is provided if the UE is emergency registered and the SUPI is not
+	// authenticated, it shall match the SUPI of the UE context identified by its PEI
+	if ueContextRelease.Supi != "" && (!ue.UnauthenticatedEmergency() || ue.Supi != ueContextRelease.Supi) {
+		logger.CtxLog.Warnf("SUPI[%s] does not match the emergency registered UE[%s]", ueContextRelease.Supi,
+			ueContextID)
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  "SUPI_OR_PEI_UNKNOWN",
+		}
+		return problemDetails
+	}
+
 	ue.Lock.Lock()
 	defer ue.Lock.Unlock()
 
@@ -394,7 +405,9 @@ func (p *Processor) buildUEContextModel(ue *context.AmfUe, reason models.Transfe
 		}
 		SeafData.Ncc = int32(ue.NCC)
 		SeafData.KeyAmfChangeInd = false
-		SeafData.KeyAmfHDerivationInd = false
+		// the new AMF derives the K_AMF' according to the local policy of the old AMF (TS 33.501 6.9.3)
+		SeafData.KeyAmfHDerivationInd = context.GetSelf().HorizontalKamfDerivationRequired(
+			context.KamfDerivationDirectionMobility)
 		ueContext.SeafData = SeafData
 		mmContext.NasSecurityMode = NasSecurityMode
 		if ue.UESecurityCapability.Buffer != nil {
@@ -7,6 +7,7 @@ package factory
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
 	"sync"
 	"time"
@@ -14,6 +15,7 @@ import (
 	"github.com/asaskevich/govalidator"
 	"github.com/davecgh/go-spew/spew"
 	"github.com/google/uuid"
+	"gopkg.in/yaml.v2"
 
 	"github.com/free5gc/amf/internal/logger"
 	"github.com/free5gc/openapi/models"
@@ -76,39 +78,52 @@ type Info struct {
 }
 
 type Configuration struct {
-	AmfName                string            `yaml:"amfName,omitempty" valid:"required, type(string)"`
-	NfInstanceId           string            `yaml:"nfInstanceId,omitempty" valid:"optional,uuidv4"`
-	NgapIpList             []string          `yaml:"ngapIpList,omitempty" valid:"required"`
-	NgapPort               int               `yaml:"ngapPort,omitempty" valid:"optional,port"`
-	Sbi                    *Sbi              `yaml:"sbi,omitempty" valid:"required"`
-	Metrics                *Metrics          `yaml:"metrics,omitempty" valid:"optional"`
-	ServiceNameList        []string          `yaml:"serviceNameList,omitempty" valid:"required"`
-	ServedGumaiList        []models.Guami    `yaml:"servedGuamiList,omitempty" valid:"required"`
-	SupportTAIList         []models.Tai      `yaml:"supportTaiList,omitempty" valid:"required"`
-	PlmnSupportList        []PlmnSupportItem `yaml:"plmnSupportList,omitempty" valid:"required"`
-	SupportDnnList         []string          `yaml:"supportDnnList,omitempty" valid:"required"`
-	SupportLadnList        []Ladn            `yaml:"supportLadnList,omitempty" valid:"optional"`
-	NrfUri                 string            `yaml:"nrfUri,omitempty" valid:"required, url"`
-	NrfCertPem             string            `yaml:"nrfCertPem,omitempty" valid:"optional"`
-	Security               *Security         `yaml:"security,omitempty" valid:"required"`
-	NetworkName            NetworkName       `yaml:"networkName,omitempty" valid:"required"`
-	NgapIE                 *NgapIE           `yaml:"ngapIE,omitempty" valid:"optional"`
-	NasIE                  *NasIE            `yaml:"nasIE,omitempty" valid:"optional"`
-	T3502Value             int               `yaml:"t3502Value,omitempty" valid:"required, type(int)"`
-	T3512Value             int               `yaml:"t3512Value,omitempty" valid:"required, type(int)"`
-	Non3gppDeregTimerValue int               `yaml:"non3gppDeregTimerValue,omitempty" valid:"-"`
-	T3513                  TimerValue        `yaml:"t3513" valid:"required"`
-	T3522                  TimerValue        `yaml:"t3522" valid:"required"`
-	T3550                  TimerValue        `yaml:"t3550" valid:"required"`
-	T3560                  TimerValue        `yaml:"t3560" valid:"required"`
-	T3565                  TimerValue        `yaml:"t3565" valid:"required"`
-	T3570                  TimerValue        `yaml:"t3570" valid:"required"`
-	T3555                  TimerValue        `yaml:"t3555" valid:"required"`
-	Locality               string            `yaml:"locality,omitempty" valid:"type(string),optional"`
-	SCTP                   *Sctp             `yaml:"sctp,omitempty" valid:"optional"`
-	DefaultUECtxReq        bool              `yaml:"defaultUECtxReq,omitempty" valid:"type(bool),optional"`
-	NgapWorkerPoolSize     int               `yaml:"ngapWorkerPoolSize,omitempty" valid:"type(int),optional"`
-	NgapTaskBufferSize     int               `yaml:"ngapTaskBufferSize,omitempty" valid:"type(int),optional"`
+	AmfName                string               `yaml:"amfName,omitempty" valid:"required, type(string)"`
+	NfInstanceId           string               `yaml:"nfInstanceId,omitempty" valid:"optional,uuidv4"`
+	NgapIpList             []string             `yaml:"ngapIpList,omitempty" valid:"required"`
+	NgapPort               int                  `yaml:"ngapPort,omitempty" valid:"optional,port"`
+	NgapTnlAssociationList []TnlAssociation     `yaml:"ngapTnlAssociationList,omitempty" valid:"optional"`
+	Sbi                    *Sbi                 `yaml:"sbi,omitempty" valid:"required"`
+	Metrics                *Metrics             `yaml:"metrics,omitempty" valid:"optional"`
+	ServiceNameList        []string             `yaml:"serviceNameList,omitempty" valid:"required"`
+	ServedGumaiList        []models.Guami       `yaml:"servedGuamiList,omitempty" valid:"required"`
+	SupportTAIList         []models.Tai         `yaml:"supportTaiList,omitempty" valid:"required"`
+	PlmnSupportList        []PlmnSupportItem    `yaml:"plmnSupportList,omitempty" valid:"required"`
+	SupportDnnList         []string             `yaml:"supportDnnList,omitempty" valid:"required"`
+	SupportLadnList        []Ladn               `yaml:"supportLadnList,omitempty" valid:"optional"`
+	NrfUri                 string               `yaml:"nrfUri,omitempty" valid:"required, url"`
+	NrfCertPem             string               `yaml:"nrfCertPem,omitempty" valid:"optional"`
+	Security               *Security            `yaml:"security,omitempty" valid:"required"`
+	NetworkName            NetworkName          `yaml:"networkName,omitempty" valid:"required"`
+	NgapIE                 *NgapIE              `yaml:"ngapIE,omitempty" valid:"optional"`
+	NasIE                  *NasIE               `yaml:"nasIE,omitempty" valid:"optional"`
+	T3502Value             int                  `yaml:"t3502Value,omitempty" valid:"required, type(int)"`
+	T3512Value             int                  `yaml:"t3512Value,omitempty" valid:"required, type(int)"`
+	Non3gppDeregTimerValue int                  `yaml:"non3gppDeregTimerValue,omitempty" valid:"-"`
+	T3513                  TimerValue           `yaml:"t3513" valid:"required"`
+	T3522                  TimerValue           `yaml:"t3522" valid:"required"`
+	T3550                  TimerValue           `yaml:"t3550" valid:"required"`
+	T3560                  TimerValue           `yaml:"t3560" valid:"required"`
+	T3565                  TimerValue           `yaml:"t3565" valid:"required"`
+	T3570                  TimerValue           `yaml:"t3570" valid:"required"`
+	T3555                  TimerValue           `yaml:"t3555" valid:"required"`
+	T3575                  TimerValue           `yaml:"t3575,omitempty" valid:"optional"`
+	TRelocPrep             TimerValue           `yaml:"tRelocPrep,omitempty" valid:"optional"`
+	TRelocOverall          TimerValue           `yaml:"tRelocOverall,omitempty" valid:"optional"`
+	Locality               string               `yaml:"locality,omitempty" valid:"type(string),optional"`
+	SCTP                   *Sctp                `yaml:"sctp,omitempty" valid:"optional"`
+	DefaultUECtxReq        bool                 `yaml:"defaultUECtxReq,omitempty" valid:"type(bool),optional"`
+	NgapWorkerPoolSize     int                  `yaml:"ngapWorkerPoolSize,omitempty" valid:"type(int),optional"`
+	NgapTaskBufferSize     int                  `yaml:"ngapTaskBufferSize,omitempty" valid:"type(int),optional"`
+	Emergency              *Emergency           `yaml:"emergency,omitempty" valid:"optional"`
+	Mico                   *Mico                `yaml:"mico,omitempty" valid:"optional"`
+	Edrx                   *Edrx                `yaml:"edrx,omitempty" valid:"optional"`
+	Nsac                   *Nsac                `yaml:"nsac,omitempty" valid:"optional"`
+	Eir                    *Eir                 `yaml:"eir,omitempty" valid:"optional"`
+	LocalAuthentication    *LocalAuthentication `yaml:"localAuthentication,omitempty" valid:"optional"`
+	MobileReachable        *TimerValue          `yaml:"mobileReachableTimer,omitempty" valid:"optional"`
+	ImplicitDeregistration *TimerValue          `yaml:"implicitDeregistrationTimer,omitempty" valid:"optional"`
+	UpuAck                 TimerValue           `yaml:"upuAckTimer,omitempty" valid:"optional"`
 }
 
 type Logger struct {
@@ -131,6 +146,18 @@ func (c *Configuration) validate() (bool, error) {
 		}
 	}
 
+	if c.NgapTnlAssociationList != nil {
+		var errs govalidator.Errors
+		for _, v := range c.NgapTnlAssociationList {
+			if _, err := v.validate(c.NgapIpList); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) > 0 {
+			return false, error(errs)
+		}
+	}
+
 	if c.NfInstanceId == "" {
 		c.NfInstanceId = uuid.New().String()
 	}
@@ -307,12 +334,76 @@ func (c *Configuration) validate() (bool, error) {
 		return false, err
 	}
 
+	if _, err := c.T3575.validate(); err != nil {
+		return false, err
+	}
+
+	if _, err := c.TRelocPrep.validate(); err != nil {
+		return false, err
+	}
+
+	if _, err := c.TRelocOverall.validate(); err != nil {
+		return false, err
+	}
+
 	if c.SCTP != nil {
 		if _, err := c.SCTP.validate(); err != nil {
 			return false, err
 		}
 	}
 
+	if c.Emergency != nil {
+		if _, err := c.Emergency.validate(); err != nil {
+			return false, err
+		}
+	}
+
+	if c.Mico != nil {
+		if _, err := c.Mico.validate(); err != nil {
+			return false, err
+		}
+	}
+
+	if c.Edrx != nil {
+		if _, err := c.Edrx.validate(); err != nil {
+			return false, err
+		}
+	}
+
+	if c.Nsac != nil {
+		if _, err := c.Nsac.validate(); err != nil {
+			return false, err
+		}
+	}
+
+	if c.Eir != nil {
+		if _, err := c.Eir.validate(); err != nil {
+			return false, err
+		}
+	}
+
+	if c.LocalAuthentication != nil && c.LocalAuthentication.Enable {
+		if _, err := c.LocalAuthentication.validate(); err != nil {
+			return false, err
+		}
+	}
+
+	if c.MobileReachable != nil {
+		if _, err := c.MobileReachable.validate(); err != nil {
+			return false, err
+		}
+	}
+
+	if c.ImplicitDeregistration != nil {
+		if _, err := c.ImplicitDeregistration.validate(); err != nil {
+			return false, err
+		}
+	}
+
+	if _, err := c.UpuAck.validate(); err != nil {
+		return false, err
+	}
+
 	if _, err := govalidator.ValidateStruct(c); err != nil {
 		return false, appendInvalid(err)
 	}
@@ -405,9 +496,51 @@ func (t *Tls) validate() (bool, error) {
 	return result, err
 }
 
+// Bounds of the NAS COUNT protection (TS 24.501 4.4.3), the NAS COUNT is made of a 16-bit overflow counter and
+// an 8-bit sequence number
+const (
+	MaxNasCount        = 0xffffff
+	MaxNasReplayWindow = 64
+)
+
 type Security struct {
 	IntegrityOrder []string `yaml:"integrityOrder,omitempty" valid:"-"`
 	CipheringOrder []string `yaml:"cipheringOrder,omitempty" valid:"-"`
+	// the null ciphering algorithm NEA0 is only allowed in the cipheringOrder above of an AMF of a test network
+	AllowNullCiphering bool `yaml:"allowNullCiphering,omitempty" valid:"type(bool)"`
+	// number of uplink NAS COUNT values below the highest one received which are still accepted once
+	NasReplayWindow int `yaml:"nasReplayWindow,omitempty" valid:"-"`
+	// a new NAS security context is established when this many NAS COUNT values remain before the wrap-around
+	NasCountRekeyingMargin int `yaml:"nasCountRekeyingMargin,omitempty" valid:"-"`
+	// local policy of the K_AMF horizontal derivation in mobility between AMFs (TS 33.501 6.9.2.3.3, 6.9.3)
+	HorizontalKamfDerivation *HorizontalKamfDerivation `yaml:"horizontalKamfDerivation,omitempty" valid:"optional"`
+	// the integrity and ciphering orders of the most specific policy matching the UE replace the orders above
+	AlgorithmPolicies []SecurityAlgorithmPolicy `yaml:"algorithmPolicies,omitempty" valid:"optional"`
+	// request the IMEISV of every UE in the Security Mode Command, not only of the UEs whose PEI is not known
+	RequestImeisv bool `yaml:"requestImeisv,omitempty" valid:"type(bool)"`
+}
+
+// SecurityAlgorithmPolicy is the order of the NAS security algorithms selected for the UEs of a PLMN, requesting an
+// S-NSSAI or registering over an access type, the criteria not set match all the UEs. The null ciphering algorithm
+// NEA0 is only allowed in the policy of a test PLMN. A PLMN is a test PLMN as asserted by the operator with testPlmn,
+// its PLMN ID must be one of the test network (MCC 001) or of internal use (MCC 999) of ITU-T E.212.
+type SecurityAlgorithmPolicy struct {
+	PlmnId         *models.PlmnId    `yaml:"plmnId,omitempty" valid:"optional"`
+	Snssai         *models.Snssai    `yaml:"snssai,omitempty" valid:"optional"`
+	AccessType     models.AccessType `yaml:"accessType,omitempty" valid:"in(3GPP_ACCESS|NON_3GPP_ACCESS),optional"`
+	IntegrityOrder []string          `yaml:"integrityOrder" valid:"required"`
+	CipheringOrder []string          `yaml:"cipheringOrder" valid:"required"`
+	TestPlmn       bool              `yaml:"testPlmn,omitempty" valid:"type(bool)"`
+}
+
+// testPlmnMccs are the MCCs of the test networks (001) and of internal use (999) of ITU-T E.212
+var testPlmnMccs = []string{"001", "999"}
+
+type HorizontalKamfDerivation struct {
+	// derive a new K_AMF when the UE registers with an AMF from another AMF
+	MobilityRegistration bool `yaml:"mobilityRegistration,omitempty" valid:"type(bool)"`
+	// derive a new K_AMF when the UE is handed over to an AMF from another AMF
+	Handover bool `yaml:"handover,omitempty" valid:"type(bool)"`
 }
 
 func (s *Security) validate() (bool, error) {
@@ -429,6 +562,25 @@ func (s *Security) validate() (bool, error) {
 			}
 		}
 	}
+	if slices.Contains(s.CipheringOrder, "NEA0") && !s.AllowNullCiphering {
+		errs = append(errs, fmt.Errorf("invalid cipheringOrder: NEA0 is only allowed with allowNullCiphering or "+
+			"in the policy of a test PLMN"))
+	}
+	for i := range s.AlgorithmPolicies {
+		if _, err := s.AlgorithmPolicies[i].validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if result := govalidator.InRangeInt(s.NasReplayWindow, 0, MaxNasReplayWindow); !result {
+		err := fmt.Errorf("invalid nasReplayWindow: %d, should be in the range of 0~%d", s.NasReplayWindow,
+			MaxNasReplayWindow)
+		errs = append(errs, err)
+	}
+	if result := govalidator.InRangeInt(s.NasCountRekeyingMargin, 0, MaxNasCount); !result {
+		err := fmt.Errorf("invalid nasCountRekeyingMargin: %d, should be in the range of 0~%d",
+			s.NasCountRekeyingMargin, MaxNasCount)
+		errs = append(errs, err)
+	}
 
 	if len(errs) > 0 {
 		return false, error(errs)
@@ -437,6 +589,54 @@ func (s *Security) validate() (bool, error) {
 	return true, nil
 }
 
+func (p *SecurityAlgorithmPolicy) validate() (bool, error) {
+	if _, err := govalidator.ValidateStruct(p); err != nil {
+		return false, appendInvalid(err)
+	}
+
+	var errs govalidator.Errors
+	for _, val := range p.IntegrityOrder {
+		if result := govalidator.IsIn(val, "NIA0", "NIA1", "NIA2", "NIA3"); !result {
+			err := fmt.Errorf("invalid integrityOrder: %s, should be NIA-series integrity algorithms", val)
+			errs = append(errs, err)
+		}
+	}
+	for _, val := range p.CipheringOrder {
+		if result := govalidator.IsIn(val, "NEA0", "NEA1", "NEA2", "NEA3"); !result {
+			err := fmt.Errorf("invalid cipheringOrder: %s, should be NEA-series ciphering algorithms", val)
+			errs = append(errs, err)
+		}
+	}
+	if slices.Contains(p.CipheringOrder, "NEA0") && !p.TestPlmn {
+		errs = append(errs, fmt.Errorf("invalid cipheringOrder: NEA0 is only allowed in the policy of a test PLMN"))
+	}
+	if p.TestPlmn {
+		if p.PlmnId == nil {
+			errs = append(errs, fmt.Errorf("invalid algorithm policy: testPlmn requires plmnId"))
+		} else if !slices.Contains(testPlmnMccs, p.PlmnId.Mcc) {
+			errs = append(errs, fmt.Errorf("invalid algorithm policy: testPlmn requires the MCC of a test PLMN %v, "+
+				"not %s", testPlmnMccs, p.PlmnId.Mcc))
+		}
+	}
+	if p.Snssai != nil {
+		if result := govalidator.InRangeInt(p.Snssai.Sst, 0, 255); !result {
+			err := fmt.Errorf("invalid sst: %d, should be in the range of 0~255", p.Snssai.Sst)
+			errs = append(errs, err)
+		}
+		if sd := p.Snssai.Sd; sd != "" {
+			if result := govalidator.StringMatches(sd, "^[A-Fa-f0-9]{6}$"); !result {
+				err := fmt.Errorf("invalid sd: %s, should be 3 bytes hex string, range: 000000~FFFFFF", sd)
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return false, error(errs)
+	}
+	return true, nil
+}
+
 type PlmnSupportItem struct {
 	PlmnId     *models.PlmnId  `yaml:"plmnId" valid:"required"`
 	SNssaiList []models.Snssai `yaml:"snssaiList,omitempty" valid:"required"`
@@ -483,6 +683,264 @@ func (p *PlmnSupportItem) validate() (bool, error) {
 	return true, nil
 }
 
+// TnlAssociation is the use NG-RAN nodes shall make of the TNL associations towards an AMF NGAP IP,
+// advertised in the AMF Configuration Update (TS 38.413 9.3.2.9, 9.3.2.10)
+type TnlAssociation struct {
+	Ip           string `yaml:"ip" valid:"host,required"`
+	Usage        string `yaml:"usage,omitempty" valid:"in(ue|non-ue|both),optional"`
+	WeightFactor *int64 `yaml:"weightFactor,omitempty" valid:"optional"`
+}
+
+func (t *TnlAssociation) validate(ngapIpList []string) (bool, error) {
+	if _, err := govalidator.ValidateStruct(t); err != nil {
+		return false, appendInvalid(err)
+	}
+
+	var errs govalidator.Errors
+	if !slices.Contains(ngapIpList, t.Ip) {
+		errs = append(errs, fmt.Errorf("invalid ngapTnlAssociationList: %s is not in ngapIpList", t.Ip))
+	}
+	if t.WeightFactor != nil && (*t.WeightFactor < 0 || *t.WeightFactor > 255) {
+		errs = append(errs, fmt.Errorf("invalid ngapTnlAssociationList: weightFactor %d, range: 0~255", *t.WeightFactor))
+	}
+	if len(errs) > 0 {
+		return false, error(errs)
+	}
+	return true, nil
+}
+
+// Emergency policies of the UEs allowed to obtain emergency services (TS 23.501 5.16.4.1)
+const (
+	EmergencyPolicyAuthenticated       = "authenticated"       // only UEs successfully authenticated
+	EmergencyPolicyUnauthenticatedSupi = "unauthenticatedSupi" // UEs providing a SUPI, authentication may fail
+	EmergencyPolicyAll                 = "all"                 // all UEs, including UEs identified by IMEI only
+)
+
+// Emergency is the support of emergency services, the emergency PDU sessions are established towards an SMF
+// serving the emergency DNN and S-NSSAI (TS 23.501 5.16.4.5)
+type Emergency struct {
+	Enable bool           `yaml:"enable" valid:"type(bool)"`
+	Policy string         `yaml:"policy,omitempty" valid:"in(authenticated|unauthenticatedSupi|all),optional"`
+	Dnn    string         `yaml:"dnn" valid:"type(string),minstringlength(1),required"`
+	Snssai *models.Snssai `yaml:"snssai" valid:"required"`
+}
+
+func (e *Emergency) validate() (bool, error) {
+	if _, err := govalidator.ValidateStruct(e); err != nil {
+		return false, appendInvalid(err)
+	}
+
+	var errs govalidator.Errors
+	if result := govalidator.InRangeInt(e.Snssai.Sst, 0, 255); !result {
+		errs = append(errs, fmt.Errorf("invalid emergency sst: %d, should be in the range of 0~255", e.Snssai.Sst))
+	}
+	if e.Snssai.Sd != "" {
+		if result := govalidator.StringMatches(e.Snssai.Sd, "^[A-Fa-f0-9]{6}$"); !result {
+			errs = append(errs, fmt.Errorf("invalid emergency sd: %s, should be 3 bytes hex string", e.Snssai.Sd))
+		}
+	}
+	if len(errs) > 0 {
+		return false, error(errs)
+	}
+	return true, nil
+}
+
+// Policies of the registration area allocated to a UE in MICO mode (TS 23.501 5.4.1.3)
+const (
+	MicoRaaiPolicyNever       = "never"       // a registration area is allocated as to any other UE
+	MicoRaaiPolicyAsRequested = "asRequested" // an all PLMN registration area is allocated if the UE requests it
+)
+
+// Mico is the support of the Mobile Initiated Connection Only mode, a UE in MICO mode is not reachable in CM-IDLE
+// (TS 23.501 5.4.1.3)
+type Mico struct {
+	Enable     bool   `yaml:"enable" valid:"type(bool)"`
+	RaaiPolicy string `yaml:"raaiPolicy,omitempty" valid:"in(never|asRequested),optional"`
+}
+
+func (m *Mico) validate() (bool, error) {
+	if _, err := govalidator.ValidateStruct(m); err != nil {
+		return false, appendInvalid(err)
+	}
+	return true, nil
+}
+
+// Policies of the eDRX parameters negotiated with a UE (TS 23.501 5.31.7.2)
+const (
+	EdrxPolicyAsRequested  = "asRequested"  // the eDRX parameters requested by the UE are accepted
+	EdrxPolicySubscription = "subscription" // the eDRX value subscribed for NR is used if any
+)
+
+// Edrx is the support of the extended idle mode DRX, a UE in eDRX is reachable for paging only once per eDRX cycle
+// (TS 23.501 5.31.7.2)
+type Edrx struct {
+	Enable bool   `yaml:"enable" valid:"type(bool)"`
+	Policy string `yaml:"policy,omitempty" valid:"in(asRequested|subscription),optional"`
+}
+
+func (e *Edrx) validate() (bool, error) {
+	if _, err := govalidator.ValidateStruct(e); err != nil {
+		return false, appendInvalid(err)
+	}
+	return true, nil
+}
+
+// Nsac is the Network Slice Admission Control of the number of UEs registered to an S-NSSAI, the quotas are
+// enforced by the local NSACF of the AMF (TS 23.501 5.15.11.1)
+type Nsac struct {
+	Enable bool        `yaml:"enable" valid:"type(bool)"`
+	Quotas []NsacQuota `yaml:"quotas,omitempty" valid:"optional"`
+}
+
+// NsacQuota is the maximum number of UEs registered to an S-NSSAI, the S-NSSAIs without quota are not subject
+// to NSAC
+type NsacQuota struct {
+	Snssai      *models.Snssai `yaml:"snssai" valid:"required"`
+	MaxNumOfUes int            `yaml:"maxNumOfUes" valid:"-"`
+}
+
+func (n *Nsac) validate() (bool, error) {
+	if _, err := govalidator.ValidateStruct(n); err != nil {
+		return false, appendInvalid(err)
+	}
+
+	var errs govalidator.Errors
+	for _, quota := range n.Quotas {
+		if quota.Snssai == nil {
+			errs = append(errs, fmt.Errorf("invalid NSAC quota: snssai is required"))
+			continue
+		}
+		if result := govalidator.InRangeInt(quota.Snssai.Sst, 0, 255); !result {
+			err := fmt.Errorf("invalid sst: %d, should be in the range of 0~255", quota.Snssai.Sst)
+			errs = append(errs, err)
+		}
+		if sd := quota.Snssai.Sd; sd != "" {
+			if result := govalidator.StringMatches(sd, "^[A-Fa-f0-9]{6}$"); !result {
+				err := fmt.Errorf("invalid sd: %s, should be 3 bytes hex string, range: 000000~FFFFFF", sd)
+				errs = append(errs, err)
+			}
+		}
+		if quota.MaxNumOfUes < 0 {
+			err := fmt.Errorf("invalid maxNumOfUes: %d, should not be negative", quota.MaxNumOfUes)
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return false, error(errs)
+	}
+	return true, nil
+}
+
+// Eir is the check of the PEI of the registering UEs by the 5G-EIR (TS 23.502 4.2.2.2.2 step 14), the UEs of the
+// PLMNs in skipPlmnList are not checked
+type Eir struct {
+	Enable       bool            `yaml:"enable" valid:"type(bool)"`
+	SkipPlmnList []models.PlmnId `yaml:"skipPlmnList,omitempty" valid:"optional"`
+}
+
+func (e *Eir) validate() (bool, error) {
+	if _, err := govalidator.ValidateStruct(e); err != nil {
+		return false, appendInvalid(err)
+	}
+
+	var errs govalidator.Errors
+	for _, plmnId := range e.SkipPlmnList {
+		if result := govalidator.StringMatches(plmnId.Mcc, "^[0-9]{3}$"); !result {
+			err := fmt.Errorf("invalid mcc: %s, should be a 3-digit number", plmnId.Mcc)
+			errs = append(errs, err)
+		}
+		if result := govalidator.StringMatches(plmnId.Mnc, "^[0-9]{2,3}$"); !result {
+			err := fmt.Errorf("invalid mnc: %s, should be a 2 or 3-digit number", plmnId.Mnc)
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return false, error(errs)
+	}
+	return true, nil
+}
+
+const (
+	SuciProfileA = "profileA"
+	SuciProfileB = "profileB"
+)
+
+// LocalAuthentication authenticates the UEs with 5G AKA in the AMF, in place of the AUSF, from the subscribers of
+// subscriberFile. It is meant for test setups without a 5G core, e.g. to register a UE through a gNB simulator, and
+// must not be enabled in a network. The UDM and the PCF are still used unless skipUdm or skipPcf is set, the
+// subscribed S-NSSAIs are then the ones supported by the AMF and no access and mobility policy is applied.
+type LocalAuthentication struct {
+	Enable          bool             `yaml:"enable" valid:"type(bool)"`
+	SubscriberFile  string           `yaml:"subscriberFile" valid:"required"`
+	HomeNetworkKeys []HomeNetworkKey `yaml:"homeNetworkKeys,omitempty" valid:"optional"`
+	SkipUdm         bool             `yaml:"skipUdm,omitempty" valid:"type(bool)"`
+	SkipPcf         bool             `yaml:"skipPcf,omitempty" valid:"type(bool)"`
+}
+
+// HomeNetworkKey is the private key of the home network to de-conceal the SUCIs of a protection scheme
+// (TS 33.501 C.3), identified by the scheme and its ID. The SUCIs of the null scheme are always accepted.
+type HomeNetworkKey struct {
+	Id         uint8  `yaml:"id" valid:"-"`
+	Scheme     string `yaml:"scheme" valid:"required,in(profileA|profileB)"`
+	PrivateKey string `yaml:"privateKey" valid:"required,hexadecimal"`
+}
+
+// LocalSubscriber is the authentication subscription of a UE in the subscriber file of the local authentication
+type LocalSubscriber struct {
+	Supi string `yaml:"supi" valid:"required,matches(^imsi-[0-9]+$)"`
+	K    string `yaml:"k" valid:"required,hexadecimal,length(32|32)"`
+	Opc  string `yaml:"opc" valid:"required,hexadecimal,length(32|32)"`
+	Sqn  string `yaml:"sqn" valid:"required,hexadecimal,length(12|12)"`
+	Amf  string `yaml:"amf,omitempty" valid:"optional,hexadecimal,length(4|4)"`
+}
+
+type localSubscriberFile struct {
+	Subscribers []LocalSubscriber `yaml:"subscribers"`
+}
+
+func (l *LocalAuthentication) validate() (bool, error) {
+	if _, err := govalidator.ValidateStruct(l); err != nil {
+		return false, appendInvalid(err)
+	}
+
+	var errs govalidator.Errors
+	ids := make(map[HomeNetworkKey]bool)
+	for _, key := range l.HomeNetworkKeys {
+		id := HomeNetworkKey{Id: key.Id, Scheme: key.Scheme}
+		if ids[id] {
+			errs = append(errs, fmt.Errorf("duplicate home network key id: %d of scheme %s", key.Id, key.Scheme))
+		}
+		ids[id] = true
+		if len(key.PrivateKey) != 64 {
+			err := fmt.Errorf("invalid privateKey of home network key %d, should be 32 bytes", key.Id)
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return false, error(errs)
+	}
+	return true, nil
+}
+
+// LoadSubscribers reads the subscribers of subscriberFile, the file is read when the AMF context is initialized
+// rather than by the validation of the configuration
+func (l *LocalAuthentication) LoadSubscribers() ([]LocalSubscriber, error) {
+	content, err := os.ReadFile(l.SubscriberFile)
+	if err != nil {
+		return nil, fmt.Errorf("read subscriber file failed: %w", err)
+	}
+	var subscriberFile localSubscriberFile
+	if err = yaml.Unmarshal(content, &subscriberFile); err != nil {
+		return nil, fmt.Errorf("parse subscriber file failed: %w", err)
+	}
+	for i := range subscriberFile.Subscribers {
+		if _, err = govalidator.ValidateStruct(&subscriberFile.Subscribers[i]); err != nil {
+			return nil, appendInvalid(err)
+		}
+	}
+	return subscriberFile.Subscribers, nil
+}
+
 type Ladn struct {
 	Dnn     string       `yaml:"dnn" valid:"type(string),minstringlength(1),required"`
 	TaiList []models.Tai `yaml:"taiList" valid:"required"`
@@ -5,9 +5,13 @@
 package factory
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/asaskevich/govalidator"
+
+	"github.com/free5gc/openapi/models"
 )
 
 func TestSctp_validate(t *testing.T) {
@@ -110,3 +114,504 @@ func TestSctp_validate(t *testing.T) {
 		})
 	}
 }
+
+func TestTnlAssociation_validate(t *testing.T) {
+	weightFactor := func(v int64) *int64 { return &v }
+	ngapIpList := []string{"10.0.0.1", "10.0.0.2"}
+	tests := []struct {
+		name    string
+		tnla    TnlAssociation
+		want    bool
+		wantErr bool
+	}{
+		{
+			name: "test OK",
+			tnla: TnlAssociation{Ip: "10.0.0.2", Usage: "ue", WeightFactor: weightFactor(255)},
+			want: true,
+		},
+		{
+			name: "test OK -- default usage and weight factor",
+			tnla: TnlAssociation{Ip: "10.0.0.1"},
+			want: true,
+		},
+		{
+			name:    "test Error -- not an NGAP IP",
+			tnla:    TnlAssociation{Ip: "10.0.0.3", Usage: "both"},
+			wantErr: true,
+		},
+		{
+			name:    "test Error -- usage",
+			tnla:    TnlAssociation{Ip: "10.0.0.1", Usage: "ue-only"},
+			wantErr: true,
+		},
+		{
+			name:    "test Error -- weight factor",
+			tnla:    TnlAssociation{Ip: "10.0.0.1", WeightFactor: weightFactor(256)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.tnla.validate(ngapIpList)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("TnlAssociation.validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("TnlAssociation.validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmergency_validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		emergency Emergency
+		want      bool
+		wantErr   bool
+	}{
+		{
+			name: "test OK",
+			emergency: Emergency{
+				Enable: true,
+				Policy: EmergencyPolicyAll,
+				Dnn:    "sos",
+				Snssai: &models.Snssai{Sst: 1, Sd: "010203"},
+			},
+			want: true,
+		},
+		{
+			name:      "test OK -- default policy",
+			emergency: Emergency{Enable: true, Dnn: "sos", Snssai: &models.Snssai{Sst: 1}},
+			want:      true,
+		},
+		{
+			name:      "test Error -- policy",
+			emergency: Emergency{Policy: "anyone", Dnn: "sos", Snssai: &models.Snssai{Sst: 1}},
+			wantErr:   true,
+		},
+		{
+			name:      "test Error -- no DNN",
+			emergency: Emergency{Snssai: &models.Snssai{Sst: 1}},
+			wantErr:   true,
+		},
+		{
+			name:      "test Error -- sd",
+			emergency: Emergency{Dnn: "sos", Snssai: &models.Snssai{Sst: 1, Sd: "0102"}},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.emergency.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Emergency.validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Emergency.validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMico_validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mico    Mico
+		want    bool
+		wantErr bool
+	}{
+		{
+			name: "test OK",
+			mico: Mico{Enable: true, RaaiPolicy: MicoRaaiPolicyAsRequested},
+			want: true,
+		},
+		{
+			name: "test OK -- default RAAI policy",
+			mico: Mico{Enable: true},
+			want: true,
+		},
+		{
+			name:    "test Error -- RAAI policy",
+			mico:    Mico{Enable: true, RaaiPolicy: "always"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.mico.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Mico.validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Mico.validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNsac_validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		nsac    Nsac
+		want    bool
+		wantErr bool
+	}{
+		{
+			name: "test OK",
+			nsac: Nsac{Enable: true, Quotas: []NsacQuota{
+				{Snssai: &models.Snssai{Sst: 1, Sd: "010203"}, MaxNumOfUes: 100},
+				{Snssai: &models.Snssai{Sst: 2}, MaxNumOfUes: 0},
+			}},
+			want: true,
+		},
+		{
+			name:    "test Error -- missing snssai",
+			nsac:    Nsac{Enable: true, Quotas: []NsacQuota{{MaxNumOfUes: 100}}},
+			wantErr: true,
+		},
+		{
+			name: "test Error -- sd",
+			nsac: Nsac{Enable: true, Quotas: []NsacQuota{
+				{Snssai: &models.Snssai{Sst: 1, Sd: "0102"}, MaxNumOfUes: 100},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "test Error -- negative maxNumOfUes",
+			nsac: Nsac{Enable: true, Quotas: []NsacQuota{
+				{Snssai: &models.Snssai{Sst: 1}, MaxNumOfUes: -1},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.nsac.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Nsac.validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Nsac.validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEir_validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		eir     Eir
+		want    bool
+		wantErr bool
+	}{
+		{
+			name: "test OK",
+			eir:  Eir{Enable: true, SkipPlmnList: []models.PlmnId{{Mcc: "001", Mnc: "01"}, {Mcc: "208", Mnc: "093"}}},
+			want: true,
+		},
+		{
+			name:    "test Error -- mcc",
+			eir:     Eir{Enable: true, SkipPlmnList: []models.PlmnId{{Mcc: "01", Mnc: "01"}}},
+			wantErr: true,
+		},
+		{
+			name:    "test Error -- mnc",
+			eir:     Eir{Enable: true, SkipPlmnList: []models.PlmnId{{Mcc: "001", Mnc: "1"}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.eir.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Eir.validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Eir.validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalAuthentication_validate(t *testing.T) {
+	privateKey := "c53c22208b61860b06c62e5406a7b330c2b577aa5558981510d128247d38bd1d"
+
+	tests := []struct {
+		name                string
+		localAuthentication LocalAuthentication
+		want                bool
+		wantErr             bool
+	}{
+		{
+			name: "test OK",
+			localAuthentication: LocalAuthentication{
+				Enable:         true,
+				SubscriberFile: "subscribers.yaml",
+				HomeNetworkKeys: []HomeNetworkKey{
+					{Id: 1, Scheme: SuciProfileA, PrivateKey: privateKey},
+					{Id: 1, Scheme: SuciProfileB, PrivateKey: privateKey},
+				},
+			},
+			want: true,
+		},
+		{
+			name:                "test Error -- subscriberFile missing",
+			localAuthentication: LocalAuthentication{Enable: true},
+			wantErr:             true,
+		},
+		{
+			name: "test Error -- scheme",
+			localAuthentication: LocalAuthentication{
+				Enable:          true,
+				SubscriberFile:  "subscribers.yaml",
+				HomeNetworkKeys: []HomeNetworkKey{{Id: 1, Scheme: "profileC", PrivateKey: privateKey}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test Error -- duplicate id",
+			localAuthentication: LocalAuthentication{
+				Enable:         true,
+				SubscriberFile: "subscribers.yaml",
+				HomeNetworkKeys: []HomeNetworkKey{
+					{Id: 1, Scheme: SuciProfileA, PrivateKey: privateKey},
+					{Id: 1, Scheme: SuciProfileA, PrivateKey: privateKey},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.localAuthentication.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LocalAuthentication.validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("LocalAuthentication.validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalAuthentication_LoadSubscribers(t *testing.T) {
+	dir := t.TempDir()
+	subscriberFile := filepath.Join(dir, "subscribers.yaml")
+	err := os.WriteFile(subscriberFile, []byte(`subscribers:
+  - supi: imsi-208930000000001
+    k: 8baf473f2f8fd09487cccbd7097c6862
+    opc: 8e27b6af0e692e750f32667a3b14605d
+    sqn: "000000000020"
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalidSubscriberFile := filepath.Join(dir, "invalid.yaml")
+	err = os.WriteFile(invalidSubscriberFile, []byte(`subscribers:
+  - supi: imsi-208930000000001
+    k: 8baf473f2f8fd094
+    opc: 8e27b6af0e692e750f32667a3b14605d
+    sqn: "000000000020"
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		subscriberFile string
+		want           int
+		wantErr        bool
+	}{
+		{
+			name:           "test OK",
+			subscriberFile: subscriberFile,
+			want:           1,
+		},
+		{
+			name:           "test Error -- subscriberFile missing",
+			subscriberFile: filepath.Join(dir, "none.yaml"),
+			wantErr:        true,
+		},
+		{
+			name:           "test Error -- invalid K",
+			subscriberFile: invalidSubscriberFile,
+			wantErr:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			localAuthentication := LocalAuthentication{Enable: true, SubscriberFile: tt.subscriberFile}
+			got, err := localAuthentication.LoadSubscribers()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LocalAuthentication.LoadSubscribers() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if len(got) != tt.want {
+				t.Errorf("LocalAuthentication.LoadSubscribers() = %v, want %d subscriber", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecurity_validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		security Security
+		want     bool
+		wantErr  bool
+	}{
+		{
+			name:     "test OK",
+			security: Security{IntegrityOrder: []string{"NIA2"}, NasReplayWindow: 16, NasCountRekeyingMargin: 256},
+			want:     true,
+		},
+		{
+			name:     "test OK -- default NAS COUNT protection",
+			security: Security{CipheringOrder: []string{"NEA2"}},
+			want:     true,
+		},
+		{
+			name:     "test OK -- null ciphering allowed",
+			security: Security{CipheringOrder: []string{"NEA0", "NEA2"}, AllowNullCiphering: true},
+			want:     true,
+		},
+		{
+			name:     "test Error -- null ciphering",
+			security: Security{CipheringOrder: []string{"NEA2", "NEA0"}},
+			wantErr:  true,
+		},
+		{
+			name:     "test Error -- nasReplayWindow",
+			security: Security{NasReplayWindow: MaxNasReplayWindow + 1},
+			wantErr:  true,
+		},
+		{
+			name:     "test Error -- nasCountRekeyingMargin",
+			security: Security{NasCountRekeyingMargin: -1},
+			wantErr:  true,
+		},
+		{
+			name: "test OK -- algorithm policies",
+			security: Security{
+				CipheringOrder: []string{"NEA2"},
+				AlgorithmPolicies: []SecurityAlgorithmPolicy{
+					{
+						Snssai:         &models.Snssai{Sst: 1, Sd: "010203"},
+						IntegrityOrder: []string{"NIA2"},
+						CipheringOrder: []string{"NEA2"},
+					},
+					{
+						AccessType:     models.AccessType_NON_3_GPP_ACCESS,
+						IntegrityOrder: []string{"NIA2", "NIA1"},
+						CipheringOrder: []string{"NEA2", "NEA1"},
+					},
+					{
+						PlmnId:         &models.PlmnId{Mcc: "001", Mnc: "01"},
+						IntegrityOrder: []string{"NIA2"},
+						CipheringOrder: []string{"NEA0"},
+						TestPlmn:       true,
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "test Error -- null ciphering in the default order with algorithm policies",
+			security: Security{
+				CipheringOrder: []string{"NEA0"},
+				AlgorithmPolicies: []SecurityAlgorithmPolicy{
+					{
+						AccessType:     models.AccessType__3_GPP_ACCESS,
+						IntegrityOrder: []string{"NIA2"},
+						CipheringOrder: []string{"NEA2"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test Error -- null ciphering out of a test PLMN",
+			security: Security{
+				AlgorithmPolicies: []SecurityAlgorithmPolicy{
+					{
+						PlmnId:         &models.PlmnId{Mcc: "208", Mnc: "93"},
+						IntegrityOrder: []string{"NIA2"},
+						CipheringOrder: []string{"NEA0"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test Error -- test PLMN of a commercial MCC",
+			security: Security{
+				AlgorithmPolicies: []SecurityAlgorithmPolicy{
+					{
+						PlmnId:         &models.PlmnId{Mcc: "208", Mnc: "93"},
+						IntegrityOrder: []string{"NIA2"},
+						CipheringOrder: []string{"NEA0"},
+						TestPlmn:       true,
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test Error -- test PLMN without PLMN ID",
+			security: Security{
+				AlgorithmPolicies: []SecurityAlgorithmPolicy{
+					{
+						IntegrityOrder: []string{"NIA2"},
+						CipheringOrder: []string{"NEA0"},
+						TestPlmn:       true,
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test Error -- unknown algorithm and access type",
+			security: Security{
+				AlgorithmPolicies: []SecurityAlgorithmPolicy{
+					{
+						AccessType:     "5G_ACCESS",
+						IntegrityOrder: []string{"NIA4"},
+						CipheringOrder: []string{"NEA2"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "test Error -- missing orders",
+			security: Security{
+				AlgorithmPolicies: []SecurityAlgorithmPolicy{
+					{
+						Snssai: &models.Snssai{Sst: 1},
+					},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.security.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Security.validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Security.validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
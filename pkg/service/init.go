@@ -6,6 +6,7 @@ import (
 	"os"
 	"runtime/debug"
 	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
@@ -36,6 +37,9 @@ type AmfAppInterface interface {
 
 var AMF AmfAppInterface
 
+// interval of the check of the local time zone, e.g. for a daylight saving time change
+const timeZoneCheckInterval = time.Minute
+
 type AmfApp struct {
 	AmfAppInterface
 
@@ -121,6 +125,26 @@ func getCustomMetrics(cfg *factory.Config) map[utils.MetricTypeEnabled][]prometh
 
 	business_metrics.EnableUeConnectivityMetrics()
 
+	customMetrics[business_metrics.NSAC_METRICS] = business_metrics.GetNsacHandlerMetrics(
+		cfg.GetMetricsNamespace())
+
+	business_metrics.EnableNsacMetrics()
+
+	customMetrics[business_metrics.NAS_SECURITY_METRICS] = business_metrics.GetNasSecurityHandlerMetrics(
+		cfg.GetMetricsNamespace())
+
+	business_metrics.EnableNasSecurityMetrics()
+
+	customMetrics[business_metrics.EIR_METRICS] = business_metrics.GetEirHandlerMetrics(
+		cfg.GetMetricsNamespace())
+
+	business_metrics.EnableEirMetrics()
+
+	customMetrics[business_metrics.GMM_STATUS_METRICS] = business_metrics.GetGmmStatusHandlerMetrics(
+		cfg.GetMetricsNamespace())
+
+	business_metrics.EnableGmmStatusMetrics()
+
 	return customMetrics
 }
 
@@ -187,7 +211,9 @@ func (a *AmfApp) SetReportCaller(reportCaller bool) {
 
 func (a *AmfApp) Start() {
 	self := a.Context()
-	amf_context.InitAmfContext(self)
+	if err := amf_context.InitAmfContext(self); err != nil {
+		logger.MainLog.Fatalf("Initialize AMF context failed: %+v", err)
+	}
 
 	// Initialize NGAP worker pool and scheduler
 	workerPoolSize := a.cfg.GetNgapWorkerPoolSize()
@@ -211,6 +237,9 @@ func (a *AmfApp) Start() {
 	a.wg.Add(1)
 	go a.listenShutdownEvent()
 
+	a.wg.Add(1)
+	go a.watchTimeZone()
+
 	if a.cfg.AreMetricsEnabled() && a.metricsServer != nil {
 		go func() {
 			a.metricsServer.Run(&a.wg)
@@ -274,6 +303,28 @@ func (a *AmfApp) listenShutdownEvent() {
 	a.terminateProcedure()
 }
 
+// watchTimeZone sends the new local time zone to the registered UEs when the local time zone of the AMF changes
+func (a *AmfApp) watchTimeZone() {
+	defer func() {
+		if p := recover(); p != nil {
+			// Print stack for panic to log. Fatalf() will let program exit.
+			logger.MainLog.Fatalf("panic: %v\n%s", p, string(debug.Stack()))
+		}
+		a.wg.Done()
+	}()
+
+	ticker := time.NewTicker(timeZoneCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case now := <-ticker.C:
+			a.Processor().TimeZoneProcedure(now)
+		}
+	}
+}
+
 func (a *AmfApp) CallServerStop() {
 	if a.sbiServer != nil {
 		a.sbiServer.Stop()